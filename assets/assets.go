@@ -2,6 +2,7 @@ package assets
 
 import (
 	"embed"
+	"io/fs"
 )
 
 //go:embed *
@@ -11,3 +12,8 @@ var f embed.FS
 func ReadFile(name string) ([]byte, error) {
 	return f.ReadFile(name)
 }
+
+// ReadDir reads and returns the entries of the named directory.
+func ReadDir(name string) ([]fs.DirEntry, error) {
+	return f.ReadDir(name)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/openshift/cluster-storage-operator/pkg/cleanup"
+)
+
+// NewCleanupCommand returns a command that lists or deletes all objects
+// created by the operator.
+func NewCleanupCommand() *cobra.Command {
+	var kubeconfig string
+	var deleteObjects bool
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "List or delete all objects created by the Cluster Storage Operator",
+		Long: `List all objects created by the Cluster Storage Operator. With --delete, delete them.
+
+The operator and the cluster version operator must be scaled down before the
+objects are deleted, otherwise they re-create them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+			loadingRules.ExplicitPath = kubeconfig
+			config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+			if err != nil {
+				return err
+			}
+			client, err := dynamic.NewForConfig(config)
+			if err != nil {
+				return err
+			}
+
+			objects, err := cleanup.List(cmd.Context(), client)
+			if err != nil {
+				return err
+			}
+			for _, obj := range objects {
+				fmt.Fprintln(cmd.OutOrStdout(), obj)
+			}
+			if !deleteObjects {
+				return nil
+			}
+			if err := cleanup.Delete(cmd.Context(), client, objects); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted %d objects\n", len(objects))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig, defaults to $KUBECONFIG or ~/.kube/config")
+	cmd.Flags().BoolVar(&deleteObjects, "delete", false, "Delete the listed objects")
+	return cmd
+}
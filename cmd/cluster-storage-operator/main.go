@@ -11,10 +11,7 @@ import (
 	k8sflag "k8s.io/component-base/cli/flag"
 	"k8s.io/component-base/logs"
 
-	"github.com/openshift/library-go/pkg/controller/controllercmd"
-
 	"github.com/openshift/cluster-storage-operator/pkg/operator"
-	"github.com/openshift/cluster-storage-operator/pkg/version"
 )
 
 func main() {
@@ -41,15 +38,9 @@ func NewOperatorCommand() *cobra.Command {
 		},
 	}
 
-	ctrlCmd := controllercmd.NewControllerCommandConfig(
-		"cluster-storage-operator",
-		version.Get(),
-		operator.RunOperator,
-	).NewCommand()
-	ctrlCmd.Use = "start"
-	ctrlCmd.Short = "Start the Cluster Storage Operator"
-
-	cmd.AddCommand(ctrlCmd)
+	cmd.AddCommand(operator.NewStartCommand())
+	cmd.AddCommand(NewRBACAuditCommand())
+	cmd.AddCommand(NewCleanupCommand())
 
 	return cmd
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/cluster-storage-operator/pkg/rbacaudit"
+)
+
+// NewRBACAuditCommand returns a command that compares RBAC rules shipped
+// with CSI driver operators with API calls recorded in an audit log.
+func NewRBACAuditCommand() *cobra.Command {
+	var auditLogPath string
+	cmd := &cobra.Command{
+		Use:   "rbac-audit",
+		Short: "Report RBAC rules of managed operands that are not used according to an audit log",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if auditLogPath == "" {
+				return fmt.Errorf("--audit-log is required")
+			}
+			auditLog, err := os.Open(auditLogPath)
+			if err != nil {
+				return err
+			}
+			defer auditLog.Close()
+
+			findings, err := rbacaudit.Audit(rbacaudit.AssetDirs, auditLog)
+			if err != nil {
+				return err
+			}
+			for _, finding := range findings {
+				fmt.Fprintln(cmd.OutOrStdout(), finding)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&auditLogPath, "audit-log", "", "Path to API server audit log with events of the whole cluster lifecycle (JSON lines)")
+	return cmd
+}
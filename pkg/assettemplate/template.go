@@ -0,0 +1,246 @@
+// Package assettemplate renders CSO assets as Go templates with per-cluster
+// values, such as platform, topology, proxy, network stack and images of operands.
+//
+// Assets reference the values as fields of Values, e.g.:
+//
+//	image: "{{.Images.Operator}}"
+//	args:
+//	- -v={{.LogLevel}}
+//
+// Referencing a value that's not set (e.g. an image that's not provided by
+// CSIOperatorConfig) is an error, so typos in assets are caught early.
+package assettemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"text/template"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configlisters "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
+	"github.com/openshift/library-go/pkg/operator/loglevel"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	infraConfigName   = "cluster"
+	networkConfigName = "cluster"
+)
+
+// Keys of Values.Images shared by all CSI driver operators.
+const (
+	ImageOperator            = "Operator"
+	ImageDriver              = "Driver"
+	ImageProvisioner         = "Provisioner"
+	ImageAttacher            = "Attacher"
+	ImageResizer             = "Resizer"
+	ImageSnapshotter         = "Snapshotter"
+	ImageNodeDriverRegistrar = "NodeDriverRegistrar"
+	ImageLivenessProbe       = "LivenessProbe"
+	ImageKubeRBACProxy       = "KubeRBACProxy"
+)
+
+// Values are per-cluster values available to asset templates.
+type Values struct {
+	// Platform is type of the cloud the cluster runs on.
+	Platform configv1.PlatformType
+	// ControlPlaneTopology is topology of the cluster control plane, e.g.
+	// External for HyperShift.
+	ControlPlaneTopology configv1.TopologyMode
+	// InfrastructureTopology is topology of the cluster infrastructure
+	// nodes.
+	InfrastructureTopology configv1.TopologyMode
+	// Proxy is the observed cluster-wide proxy.
+	Proxy Proxy
+	// Network is the IP stack of the cluster.
+	Network Network
+	// Images are pull specs of operand images, indexed by their names
+	// (see Image* constants).
+	Images map[string]string
+	// LogLevel is klog verbosity of operands.
+	LogLevel int
+}
+
+// Proxy is cluster-wide proxy configuration.
+type Proxy struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// Network is the IP stack of the cluster, so operands listen and are
+// exposed on the right IP families, e.g.:
+//
+//	args:
+//	- --listen={{.Network.ListenHost}}:8444
+type Network struct {
+	// IPFamilies of the cluster service network, the primary one first.
+	IPFamilies []corev1.IPFamily
+	// IPFamilyPolicy of Services, PreferDualStack in dual-stack clusters and
+	// SingleStack otherwise.
+	IPFamilyPolicy corev1.IPFamilyPolicyType
+	// ListenHost is host of a wildcard listen address, "0.0.0.0" in IPv4
+	// single-stack clusters and "[::]" otherwise. "[::]" accepts both IPv6
+	// and IPv4 connections.
+	ListenHost string
+}
+
+// NewNetwork returns Network of the cluster with given Network config. IPv4
+// single-stack is assumed when the config is nil or its service network is
+// not known yet.
+func NewNetwork(network *configv1.Network) (Network, error) {
+	var cidrs []string
+	if network != nil {
+		cidrs = network.Status.ServiceNetwork
+		if len(cidrs) == 0 {
+			cidrs = network.Spec.ServiceNetwork
+		}
+	}
+	n := Network{}
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return Network{}, fmt.Errorf("failed to parse service network %q: %w", cidr, err)
+		}
+		family := corev1.IPv4Protocol
+		if ip.To4() == nil {
+			family = corev1.IPv6Protocol
+		}
+		if !hasIPFamily(n.IPFamilies, family) {
+			n.IPFamilies = append(n.IPFamilies, family)
+		}
+	}
+	if len(n.IPFamilies) == 0 {
+		n.IPFamilies = []corev1.IPFamily{corev1.IPv4Protocol}
+	}
+
+	n.IPFamilyPolicy = corev1.IPFamilyPolicySingleStack
+	if len(n.IPFamilies) > 1 {
+		n.IPFamilyPolicy = corev1.IPFamilyPolicyPreferDualStack
+	}
+	n.ListenHost = "0.0.0.0"
+	if hasIPFamily(n.IPFamilies, corev1.IPv6Protocol) {
+		n.ListenHost = "[::]"
+	}
+	return n, nil
+}
+
+func hasIPFamily(families []corev1.IPFamily, family corev1.IPFamily) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// NewValues returns Values of the cluster described by the Infrastructure,
+// the Network config and the operator spec, with given images.
+func NewValues(infra *configv1.Infrastructure, network *configv1.Network, opSpec *operatorv1.OperatorSpec, images map[string]string) (*Values, error) {
+	clusterNetwork, err := NewNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	values := &Values{
+		Images:   map[string]string{},
+		LogLevel: loglevel.LogLevelToVerbosity(opSpec.LogLevel),
+		Network:  clusterNetwork,
+	}
+	for name, image := range images {
+		values.Images[name] = image
+	}
+	if infra != nil {
+		if infra.Status.PlatformStatus != nil {
+			values.Platform = infra.Status.PlatformStatus.Type
+		}
+		values.ControlPlaneTopology = infra.Status.ControlPlaneTopology
+		values.InfrastructureTopology = infra.Status.InfrastructureTopology
+	}
+
+	if len(opSpec.ObservedConfig.Raw) > 0 {
+		observedConfig := map[string]interface{}{}
+		if err := json.Unmarshal(opSpec.ObservedConfig.Raw, &observedConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse observed config: %w", err)
+		}
+		proxyConfig, _, err := unstructured.NestedStringMap(observedConfig, util.ProxyConfigPath()...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse observed proxy config: %w", err)
+		}
+		values.Proxy = Proxy{
+			HTTPProxy:  proxyConfig["HTTP_PROXY"],
+			HTTPSProxy: proxyConfig["HTTPS_PROXY"],
+			NoProxy:    proxyConfig["NO_PROXY"],
+		}
+	}
+	return values, nil
+}
+
+// GetNetwork returns the cluster Network config, or nil when it does not
+// exist.
+func GetNetwork(networkLister configlisters.NetworkLister) (*configv1.Network, error) {
+	network, err := networkLister.Get(networkConfigName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get network resource: %w", err)
+	}
+	return network, nil
+}
+
+// ClusterValuesFunc returns function that returns current Values of the
+// cluster with given images, to be used with AssetFunc.
+func ClusterValuesFunc(operatorClient v1helpers.OperatorClient, infraLister configlisters.InfrastructureLister, networkLister configlisters.NetworkLister, images map[string]string) func() (*Values, error) {
+	return func() (*Values, error) {
+		opSpec, _, _, err := operatorClient.GetOperatorState()
+		if err != nil {
+			return nil, err
+		}
+		infra, err := infraLister.Get(infraConfigName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get infrastructure resource: %w", err)
+		}
+		network, err := GetNetwork(networkLister)
+		if err != nil {
+			return nil, err
+		}
+		return NewValues(infra, network, opSpec, images)
+	}
+}
+
+// Render renders content of the named asset with given values.
+func Render(name string, content []byte, values *Values) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse asset %s: %w", name, err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, values); err != nil {
+		return nil, fmt.Errorf("failed to render asset %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// AssetFunc returns AssetFunc that renders assets returned by assetFunc with
+// values returned by valuesFunc.
+func AssetFunc(assetFunc resourceapply.AssetFunc, valuesFunc func() (*Values, error)) resourceapply.AssetFunc {
+	return func(name string) ([]byte, error) {
+		content, err := assetFunc(name)
+		if err != nil {
+			return nil, err
+		}
+		values, err := valuesFunc()
+		if err != nil {
+			return nil, err
+		}
+		return Render(name, content, values)
+	}
+}
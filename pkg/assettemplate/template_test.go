@@ -0,0 +1,112 @@
+package assettemplate
+
+import (
+	"reflect"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNewNetwork(t *testing.T) {
+	tests := []struct {
+		name           string
+		serviceNetwork []string
+		expected       Network
+	}{
+		{
+			name:     "unknown",
+			expected: Network{IPFamilies: []corev1.IPFamily{corev1.IPv4Protocol}, IPFamilyPolicy: corev1.IPFamilyPolicySingleStack, ListenHost: "0.0.0.0"},
+		},
+		{
+			name:           "IPv6",
+			serviceNetwork: []string{"fd02::/112"},
+			expected:       Network{IPFamilies: []corev1.IPFamily{corev1.IPv6Protocol}, IPFamilyPolicy: corev1.IPFamilyPolicySingleStack, ListenHost: "[::]"},
+		},
+		{
+			name:           "dual-stack",
+			serviceNetwork: []string{"172.30.0.0/16", "fd02::/112"},
+			expected:       Network{IPFamilies: []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol}, IPFamilyPolicy: corev1.IPFamilyPolicyPreferDualStack, ListenHost: "[::]"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			network := &configv1.Network{
+				Status: configv1.NetworkStatus{ServiceNetwork: test.serviceNetwork},
+			}
+			n, err := NewNetwork(network)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(n, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, n)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	infra := &configv1.Infrastructure{
+		Status: configv1.InfrastructureStatus{
+			PlatformStatus:       &configv1.PlatformStatus{Type: configv1.AWSPlatformType},
+			ControlPlaneTopology: configv1.ExternalTopologyMode,
+		},
+	}
+	opSpec := &operatorv1.OperatorSpec{
+		LogLevel: operatorv1.Debug,
+		ObservedConfig: runtime.RawExtension{
+			Raw: []byte(`{"targetconfig":{"proxy":{"HTTPS_PROXY":"https://proxy:3128"}}}`),
+		},
+	}
+	values, err := NewValues(infra, nil, opSpec, map[string]string{ImageOperator: "quay.io/openshift/operator:latest"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "image and log level",
+			content:  `image: "{{.Images.Operator}}" args: -v={{.LogLevel}}`,
+			expected: `image: "quay.io/openshift/operator:latest" args: -v=4`,
+		},
+		{
+			name:     "cluster values",
+			content:  `{{.Platform}} {{.ControlPlaneTopology}} {{.Proxy.HTTPSProxy}}`,
+			expected: `AWS External https://proxy:3128`,
+		},
+		{
+			name:     "network",
+			content:  `--listen={{.Network.ListenHost}}:8444 {{.Network.IPFamilyPolicy}}`,
+			expected: `--listen=0.0.0.0:8444 SingleStack`,
+		},
+		{
+			name:        "missing image",
+			content:     `image: "{{.Images.Driver}}"`,
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rendered, err := Render(test.name, []byte(test.content), values)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(rendered) != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, string(rendered))
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Package cleanup finds and removes all objects created by CSO, i.e. objects
+// labeled with csoutils.OwnedByLabel. It's meant for uninstalling CSO from
+// lab and CI clusters, CSO and CVO must not run while the objects are
+// removed, otherwise they're re-created.
+package cleanup
+
+import (
+	"context"
+	"fmt"
+
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Resources are resources of objects created by CSO, in the order of their
+// removal. Deployments of operators go first, so they don't re-create their
+// operands.
+var Resources = []schema.GroupVersionResource{
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+	{Group: "operator.openshift.io", Version: "v1", Resource: "clustercsidrivers"},
+	{Group: "cloudcredential.openshift.io", Version: "v1", Resource: "credentialsrequests"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"},
+	{Group: "monitoring.coreos.com", Version: "v1", Resource: "prometheusrules"},
+	{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	{Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"},
+	{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshotclasses"},
+	{Version: "v1", Resource: "services"},
+	{Version: "v1", Resource: "configmaps"},
+	{Version: "v1", Resource: "serviceaccounts"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"},
+}
+
+// Object is an object created by CSO.
+type Object struct {
+	Resource  schema.GroupVersionResource
+	Namespace string
+	Name      string
+	// Component is the CSO component that created the object.
+	Component string
+	// Labels are all labels of the object.
+	Labels map[string]string
+}
+
+func (o Object) String() string {
+	name := o.Name
+	if o.Namespace != "" {
+		name = o.Namespace + "/" + o.Name
+	}
+	return fmt.Sprintf("%s %s (%s)", o.Resource.GroupResource(), name, o.Component)
+}
+
+// List returns all objects created by CSO. Resources that are not served by
+// the API server (e.g. CRDs of optional capabilities) are skipped.
+func List(ctx context.Context, client dynamic.Interface) ([]Object, error) {
+	var objects []Object
+	for _, resource := range Resources {
+		list, err := client.Resource(resource).List(ctx, metav1.ListOptions{LabelSelector: csoutils.OwnedBySelector})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", resource.GroupResource(), err)
+		}
+		for _, item := range list.Items {
+			objects = append(objects, Object{
+				Resource:  resource,
+				Namespace: item.GetNamespace(),
+				Name:      item.GetName(),
+				Component: item.GetLabels()[csoutils.ComponentLabel],
+				Labels:    item.GetLabels(),
+			})
+		}
+	}
+	return objects, nil
+}
+
+// Delete deletes the objects. Objects that do not exist are skipped.
+func Delete(ctx context.Context, client dynamic.Interface, objects []Object) error {
+	for _, obj := range objects {
+		err := client.Resource(obj.Resource).Namespace(obj.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s: %w", obj, err)
+		}
+	}
+	return nil
+}
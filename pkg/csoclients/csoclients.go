@@ -1,12 +1,11 @@
 package csoclients
 
 import (
-	"k8s.io/apimachinery/pkg/api/meta"
-	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/discovery/cached/memory"
-	"k8s.io/client-go/restmapper"
+	"fmt"
+	"os"
 	"time"
 
+	configv1 "github.com/openshift/api/config/v1"
 	cfgclientset "github.com/openshift/client-go/config/clientset/versioned"
 	cfginformers "github.com/openshift/client-go/config/informers/externalversions"
 	opclient "github.com/openshift/client-go/operator/clientset/versioned"
@@ -18,8 +17,14 @@ import (
 	promclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
 	apiextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apiextinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/klog/v2"
 )
 
 type Clients struct {
@@ -52,10 +57,31 @@ type Clients struct {
 
 	// Dynamic client for OLM and old CSI operator APIs
 	DynamicClient dynamic.Interface
+	// Dynamic informers for fields the typed API vendored in CSO does not
+	// know, e.g. FeatureGate status.
+	DynamicInformers dynamicinformer.DynamicSharedInformerFactory
 
 	// Rest Mapper for mapping GVK to GVR
 	RestMapper       meta.RESTMapper
 	CategoryExpander restmapper.CategoryExpander
+
+	// Platform from PlatformOverrideEnv, if set
+	PlatformOverride configv1.PlatformType
+
+	// Kubernetes API client and informers of the cluster where CSI driver
+	// operators run. It's the same cluster as KubeClient, unless the
+	// operator runs in HyperShift split-client mode (see GuestKubeconfigEnv),
+	// then it's the management cluster.
+	ControlPlaneKubeClient    kubernetes.Interface
+	ControlPlaneKubeInformers v1helpers.KubeInformersForNamespaces
+	// Namespace of CSI driver operator Deployments in the control plane
+	// cluster.
+	ControlPlaneNamespace string
+	// SplitClients is true in HyperShift split-client mode.
+	SplitClients bool
+
+	// KubeVirt infra cluster, nil unless InfraKubeconfigEnv is set.
+	InfraCluster *InfraCluster
 }
 
 const (
@@ -77,9 +103,18 @@ var (
 
 func NewClients(controllerConfig *controllercmd.ControllerContext, resync time.Duration) (*Clients, error) {
 	c := &Clients{}
-	var err error
+	kubeConfig, protoKubeConfig := controllerConfig.KubeConfig, controllerConfig.ProtoKubeConfig
+	guestConfig, err := getGuestConfig()
+	if err != nil {
+		return nil, err
+	}
+	if guestConfig != nil {
+		// All clients except ControlPlaneKubeClient talk to the guest
+		// cluster.
+		kubeConfig, protoKubeConfig = guestConfig, protoConfig(guestConfig)
+	}
 	// Kubernetes client, used to manipulate StorageClasses
-	c.KubeClient, err = kubernetes.NewForConfig(controllerConfig.ProtoKubeConfig)
+	c.KubeClient, err = kubernetes.NewForConfig(protoKubeConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -88,33 +123,34 @@ func NewClients(controllerConfig *controllercmd.ControllerContext, resync time.D
 		c.KubeClient,
 		informerNamespaces...)
 
-	c.DynamicClient, err = dynamic.NewForConfig(controllerConfig.KubeConfig)
+	c.DynamicClient, err = dynamic.NewForConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
+	c.DynamicInformers = dynamicinformer.NewDynamicSharedInformerFactory(c.DynamicClient, resync)
 
 	// operator.openshift.io client, used to manipulate the operator CR
-	c.OperatorClientSet, err = opclient.NewForConfig(controllerConfig.KubeConfig)
+	c.OperatorClientSet, err = opclient.NewForConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
 	c.OperatorInformers = opinformers.NewSharedInformerFactory(c.OperatorClientSet, resync)
 
 	// config.openshift.io client, used to get Infrastructure
-	c.ConfigClientSet, err = cfgclientset.NewForConfig(controllerConfig.KubeConfig)
+	c.ConfigClientSet, err = cfgclientset.NewForConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
 	c.ConfigInformers = cfginformers.NewSharedInformerFactory(c.ConfigClientSet, resync)
 
 	// CRD client, used to list CRDs
-	c.ExtensionClientSet, err = apiextclient.NewForConfig(controllerConfig.KubeConfig)
+	c.ExtensionClientSet, err = apiextclient.NewForConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
 	c.ExtensionInformer = apiextinformers.NewSharedInformerFactory(c.ExtensionClientSet, resync)
 
-	c.MonitoringClient, err = promclient.NewForConfig(controllerConfig.KubeConfig)
+	c.MonitoringClient, err = promclient.NewForConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -125,13 +161,40 @@ func NewClients(controllerConfig *controllercmd.ControllerContext, resync time.D
 		Client:    c.OperatorClientSet,
 	}
 
-	dc, err := discovery.NewDiscoveryClientForConfig(controllerConfig.KubeConfig)
+	dc, err := discovery.NewDiscoveryClientForConfig(kubeConfig)
 	if err != nil {
 		return nil, err
 	}
 	c.RestMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc))
 	c.CategoryExpander = restmapper.NewDiscoveryCategoryExpander(dc)
 
+	c.ControlPlaneKubeClient = c.KubeClient
+	c.ControlPlaneKubeInformers = c.KubeInformers
+	c.ControlPlaneNamespace = CSIOperatorNamespace
+	if guestConfig != nil {
+		c.SplitClients = true
+		c.ControlPlaneNamespace = os.Getenv(ControlPlaneNamespaceEnv)
+		if c.ControlPlaneNamespace == "" {
+			c.ControlPlaneNamespace = controllerConfig.OperatorNamespace
+		}
+		klog.V(2).Infof("Running in HyperShift split-client mode, CSI driver operators run in namespace %s of the management cluster", c.ControlPlaneNamespace)
+		c.ControlPlaneKubeClient, err = kubernetes.NewForConfig(controllerConfig.ProtoKubeConfig)
+		if err != nil {
+			return nil, err
+		}
+		c.ControlPlaneKubeInformers = v1helpers.NewKubeInformersForNamespaces(c.ControlPlaneKubeClient, c.ControlPlaneNamespace)
+	}
+
+	c.InfraCluster, err = getInfraCluster()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load KubeVirt infra cluster kubeconfig: %w", err)
+	}
+
+	if platform := os.Getenv(PlatformOverrideEnv); platform != "" {
+		klog.Warningf("Overriding platform of the cluster with %s, this is not supported in production", platform)
+		c.PlatformOverride = configv1.PlatformType(platform)
+	}
+
 	return c, nil
 }
 
@@ -140,10 +203,12 @@ func StartInformers(clients *Clients, stopCh <-chan struct{}) {
 		Start(stopCh <-chan struct{})
 	}{
 		clients.KubeInformers,
+		clients.ControlPlaneKubeInformers,
 		clients.OperatorInformers,
 		clients.ConfigInformers,
 		clients.ExtensionInformer,
 		clients.MonitoringInformer,
+		clients.DynamicInformers,
 	} {
 		informer.Start(stopCh)
 	}
@@ -12,6 +12,9 @@ import (
 	fakeextapi "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
 	apiextinformers "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	fakecore "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -22,8 +25,19 @@ type FakeTestObjects struct {
 func WaitForSync(clients *Clients, stopCh <-chan struct{}) {
 	clients.OperatorInformers.WaitForCacheSync(stopCh)
 	clients.ExtensionInformer.WaitForCacheSync(stopCh)
-	clients.KubeInformers.InformersFor("").WaitForCacheSync(stopCh)
+	for _, ns := range informerNamespaces {
+		clients.KubeInformers.InformersFor(ns).WaitForCacheSync(stopCh)
+	}
 	clients.ConfigInformers.WaitForCacheSync(stopCh)
+	clients.DynamicInformers.WaitForCacheSync(stopCh)
+}
+
+// fakeDynamicListKinds are list kinds of resources that the fake dynamic
+// client can list, i.e. of resources read by DynamicInformers.
+var fakeDynamicListKinds = map[schema.GroupVersionResource]string{
+	{Group: "config.openshift.io", Version: "v1", Resource: "featuregates"}:    "FeatureGateList",
+	{Group: "config.openshift.io", Version: "v1", Resource: "clusterversions"}: "ClusterVersionList",
+	{Group: "config.openshift.io", Version: "v1", Resource: "infrastructures"}: "InfrastructureList",
 }
 
 func NewFakeClients(initialObjects *FakeTestObjects) *Clients {
@@ -42,7 +56,8 @@ func NewFakeClients(initialObjects *FakeTestObjects) *Clients {
 	monitoringClient := fakemonitoring.NewSimpleClientset(initialObjects.MonitoringObjects...)
 	monitoringInformer := prominformer.NewSharedInformerFactory(monitoringClient, 0)
 
-	//dynamicClient := fake.NewSimpleDynamicClient()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), fakeDynamicListKinds, initialObjects.DynamicObjects...)
+	dynamicInformers := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
 
 	opClient := operatorclient.OperatorClient{
 		Client:    operatorClient,
@@ -50,17 +65,21 @@ func NewFakeClients(initialObjects *FakeTestObjects) *Clients {
 	}
 
 	return &Clients{
-		OperatorClient:     &opClient,
-		KubeClient:         kubeClient,
-		KubeInformers:      kubeInformers,
-		ExtensionClientSet: apiExtClient,
-		ExtensionInformer:  apiExtInformerFactory,
-		OperatorClientSet:  operatorClient,
-		OperatorInformers:  operatorInformerFactory,
-		ConfigClientSet:    configClient,
-		ConfigInformers:    configInformerFactory,
-		MonitoringClient:   monitoringClient,
-		MonitoringInformer: monitoringInformer,
-		//		DynamicClient:      dynamicClient,
+		OperatorClient:            &opClient,
+		KubeClient:                kubeClient,
+		KubeInformers:             kubeInformers,
+		ExtensionClientSet:        apiExtClient,
+		ExtensionInformer:         apiExtInformerFactory,
+		OperatorClientSet:         operatorClient,
+		OperatorInformers:         operatorInformerFactory,
+		ConfigClientSet:           configClient,
+		ConfigInformers:           configInformerFactory,
+		MonitoringClient:          monitoringClient,
+		MonitoringInformer:        monitoringInformer,
+		ControlPlaneKubeClient:    kubeClient,
+		ControlPlaneKubeInformers: kubeInformers,
+		ControlPlaneNamespace:     CSIOperatorNamespace,
+		DynamicClient:             dynamicClient,
+		DynamicInformers:          dynamicInformers,
 	}
 }
@@ -0,0 +1,40 @@
+package csoclients
+
+import (
+	"os"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// GuestKubeconfigEnv is env. var of the operator with path to kubeconfig
+	// of the guest cluster. When it's set, the operator runs in HyperShift
+	// split-client mode: the kubeconfig given by the operator command is the
+	// management cluster, where CSI driver operator Deployments run in
+	// ControlPlaneNamespaceEnv namespace, while all other objects (CRDs,
+	// ClusterCSIDrivers, StorageClasses, node DaemonSets, ...) are in the
+	// guest cluster.
+	GuestKubeconfigEnv = "GUEST_KUBECONFIG"
+	// ControlPlaneNamespaceEnv is env. var of the operator with namespace of
+	// the hosted control plane in the management cluster.
+	ControlPlaneNamespaceEnv = "CONTROL_PLANE_NAMESPACE"
+)
+
+// getGuestConfig returns rest config of the guest cluster or nil when the
+// operator does not run in HyperShift split-client mode.
+func getGuestConfig() (*rest.Config, error) {
+	path := os.Getenv(GuestKubeconfigEnv)
+	if path == "" {
+		return nil, nil
+	}
+	return clientcmd.BuildConfigFromFlags("", path)
+}
+
+// protoConfig returns a copy of the config that uses protobuf for core APIs.
+func protoConfig(config *rest.Config) *rest.Config {
+	protoConfig := rest.CopyConfig(config)
+	protoConfig.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
+	protoConfig.ContentType = "application/vnd.kubernetes.protobuf"
+	return protoConfig
+}
@@ -0,0 +1,62 @@
+package csoclients
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// InfraKubeconfigEnv is env. var of the operator with path to kubeconfig
+	// of the KubeVirt infra cluster, i.e. the cluster where VMs with nodes of
+	// this cluster run. The KubeVirt CSI driver provisions PVs of this
+	// cluster as PVCs in the infra cluster and hot-plugs them to the VMs.
+	InfraKubeconfigEnv = "INFRA_KUBECONFIG"
+	// InfraNamespaceEnv is env. var of the operator with namespace of the
+	// VMs in the infra cluster.
+	InfraNamespaceEnv = "INFRA_NAMESPACE"
+)
+
+// InfraCluster is the KubeVirt infra cluster.
+type InfraCluster struct {
+	// Kubeconfig is content of the infra cluster kubeconfig, it's passed to
+	// the KubeVirt CSI driver operator.
+	Kubeconfig []byte
+	// Namespace of the VMs and PVCs in the infra cluster.
+	Namespace string
+	// DynamicClient is client of the infra cluster, for KubeVirt APIs.
+	DynamicClient dynamic.Interface
+}
+
+// getInfraCluster returns the KubeVirt infra cluster or nil when
+// InfraKubeconfigEnv is not set. The kubeconfig is read only once, CSO must
+// be restarted when it changes.
+func getInfraCluster() (*InfraCluster, error) {
+	path := os.Getenv(InfraKubeconfigEnv)
+	if path == "" {
+		return nil, nil
+	}
+	namespace := os.Getenv(InfraNamespaceEnv)
+	if namespace == "" {
+		return nil, fmt.Errorf("%s is set, but %s is empty", InfraKubeconfigEnv, InfraNamespaceEnv)
+	}
+	kubeconfig, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &InfraCluster{
+		Kubeconfig:    kubeconfig,
+		Namespace:     namespace,
+		DynamicClient: dynamicClient,
+	}, nil
+}
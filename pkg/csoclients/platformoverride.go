@@ -0,0 +1,64 @@
+package csoclients
+
+import (
+	configv1 "github.com/openshift/api/config/v1"
+	configlisters "github.com/openshift/client-go/config/listers/config/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PlatformOverrideEnv is env. var. with platform type that CSO uses instead
+// of the platform in Infrastructure status, e.g. "PowerVS". It's meant for
+// testing CSI drivers of platforms that are not supported by the installer
+// yet. Only the platform type is overridden, platform specific status (e.g.
+// AWS region) is not. The cluster is not upgradeable while it's set.
+const PlatformOverrideEnv = "PLATFORM_OVERRIDE"
+
+// InfrastructureLister returns lister of Infrastructures with the platform
+// overridden by PlatformOverrideEnv. All controllers must use it instead of
+// the lister from ConfigInformers.
+func (c *Clients) InfrastructureLister() configlisters.InfrastructureLister {
+	lister := c.ConfigInformers.Config().V1().Infrastructures().Lister()
+	if c.PlatformOverride == "" {
+		return lister
+	}
+	return &platformOverrideLister{
+		InfrastructureLister: lister,
+		platform:             c.PlatformOverride,
+	}
+}
+
+type platformOverrideLister struct {
+	configlisters.InfrastructureLister
+	platform configv1.PlatformType
+}
+
+var _ configlisters.InfrastructureLister = &platformOverrideLister{}
+
+func (l *platformOverrideLister) List(selector labels.Selector) ([]*configv1.Infrastructure, error) {
+	infras, err := l.InfrastructureLister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	overridden := make([]*configv1.Infrastructure, 0, len(infras))
+	for _, infra := range infras {
+		overridden = append(overridden, l.override(infra))
+	}
+	return overridden, nil
+}
+
+func (l *platformOverrideLister) Get(name string) (*configv1.Infrastructure, error) {
+	infra, err := l.InfrastructureLister.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.override(infra), nil
+}
+
+func (l *platformOverrideLister) override(infra *configv1.Infrastructure) *configv1.Infrastructure {
+	infraCopy := infra.DeepCopy()
+	if infraCopy.Status.PlatformStatus == nil || infraCopy.Status.PlatformStatus.Type != l.platform {
+		infraCopy.Status.PlatformStatus = &configv1.PlatformStatus{Type: l.platform}
+	}
+	infraCopy.Status.Platform = l.platform
+	return infraCopy
+}
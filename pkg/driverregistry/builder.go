@@ -0,0 +1,123 @@
+package driverregistry
+
+import (
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+)
+
+// Option sets an optional field of CSIOperatorConfig created by NewConfig.
+type Option func(cfg *CSIOperatorConfig)
+
+// NewConfig returns CSIOperatorConfig with all required fields, see
+// Register. Optional fields are set by the options.
+func NewConfig(csiDriverName, conditionPrefix string, platform configv1.PlatformType, assetFunc resourceapply.AssetFunc, crAsset, deploymentAsset string, opts ...Option) CSIOperatorConfig {
+	cfg := CSIOperatorConfig{
+		CSIDriverName:   csiDriverName,
+		ConditionPrefix: conditionPrefix,
+		Platform:        platform,
+		AssetFunc:       assetFunc,
+		CRAsset:         crAsset,
+		DeploymentAsset: deploymentAsset,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithExternalPlatformName runs the CSI driver operator only on External
+// platform clusters of the given cloud provider. Platform of the config must
+// be External.
+func WithExternalPlatformName(name string) Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.ExternalPlatformName = name
+	}
+}
+
+// WithImages adds images available to the Deployment asset as
+// {{.Images.<name>}}.
+func WithImages(images map[string]string) Option {
+	return func(cfg *CSIOperatorConfig) {
+		if cfg.Images == nil {
+			cfg.Images = map[string]string{}
+		}
+		for name, image := range images {
+			cfg.Images[name] = image
+		}
+	}
+}
+
+// WithStaticAssets adds static assets created when the CSI driver operator
+// starts.
+func WithStaticAssets(assets ...string) Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.StaticAssets = append(cfg.StaticAssets, assets...)
+	}
+}
+
+// WithProfileStaticAssets sets static assets created instead of the default
+// ones on clusters with given profile.
+func WithProfileStaticAssets(profile ClusterProfile, assets ...string) Option {
+	return func(cfg *CSIOperatorConfig) {
+		if cfg.ProfileStaticAssets == nil {
+			cfg.ProfileStaticAssets = map[ClusterProfile][]string{}
+		}
+		cfg.ProfileStaticAssets[profile] = append(cfg.ProfileStaticAssets[profile], assets...)
+	}
+}
+
+// WithCredentialsRequest sets asset with CredentialsRequest of the CSI
+// driver operator.
+func WithCredentialsRequest(asset string) Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.CredentialsRequestAsset = asset
+	}
+}
+
+// WithOperandNamespaces sets namespaces where the CSI driver operator and
+// its operands run.
+func WithOperandNamespaces(namespaces ...string) Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.OperandNamespaces = append(cfg.OperandNamespaces, namespaces...)
+	}
+}
+
+// WithRequireFeatureGates runs the CSI driver operator only when all given
+// FeatureGates are enabled.
+func WithRequireFeatureGates(featureGates ...string) Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.RequireFeatureGates = append(cfg.RequireFeatureGates, featureGates...)
+	}
+}
+
+// WithOptionalDriver runs the CSI driver operator only when the cluster
+// admin creates its ClusterCSIDriver.
+func WithOptionalDriver() Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.OptionalDriver = true
+	}
+}
+
+// WithStatusFilter runs the CSI driver operator only on clusters accepted by
+// the filter.
+func WithStatusFilter(filter StatusFilterFunc) Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.StatusFilter = filter
+	}
+}
+
+// WithDeploymentHooks adds hooks that modify Deployment of the CSI driver
+// operator before it's applied.
+func WithDeploymentHooks(hooks ...DeploymentHookFunc) Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.DeploymentHooks = append(cfg.DeploymentHooks, hooks...)
+	}
+}
+
+// WithExtraControllers sets function that returns controllers that run
+// together with the CSI driver operator.
+func WithExtraControllers(fn ExtraControllersFunc) Option {
+	return func(cfg *CSIOperatorConfig) {
+		cfg.ExtraControllersFunc = fn
+	}
+}
@@ -0,0 +1,107 @@
+package driverregistry
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigMapName is name of the ConfigMap in the operator namespace with
+// configs of additional CSI driver operators. They're loaded and reloaded
+// by CSO at runtime, so a distribution can add a CSI driver operator
+// without building its own CSO binary. Each key of the ConfigMap holds
+// a single driver config in YAML, e.g.:
+//
+//	csiDriverName: csi.example.com
+//	conditionPrefix: ExampleCSIDriverOperator
+//	platform: AWS
+//	images:
+//	  OPERATOR_IMAGE: quay.io/example/operator:v1
+//	staticAssets:
+//	- 01_sa.yaml
+//	crAsset: 02_cr.yaml
+//	deploymentAsset: 03_deployment.yaml
+//	assets:
+//	  01_sa.yaml: |
+//	    apiVersion: v1
+//	    kind: ServiceAccount
+//	    ...
+//
+// Content of the assets is inline in the config, the Deployment asset is
+// rendered as a template the same way as assets shipped with CSO. Third-party
+// cloud providers integrated through the External platform select their
+// clusters by Infrastructure spec.platformSpec.external.platformName:
+//
+//	platform: External
+//	externalPlatformName: example-cloud
+const ConfigMapName = "csi-driver-configs"
+
+// driverConfig is the serialized form of a CSIOperatorConfig in
+// ConfigMapName. Only fields that can be expressed as data are supported,
+// hooks and extra controllers require Register.
+type driverConfig struct {
+	CSIDriverName           string              `json:"csiDriverName"`
+	ConditionPrefix         string              `json:"conditionPrefix"`
+	Platform                string              `json:"platform"`
+	ExternalPlatformName    string              `json:"externalPlatformName,omitempty"`
+	Images                  map[string]string   `json:"images,omitempty"`
+	StaticAssets            []string            `json:"staticAssets,omitempty"`
+	ProfileStaticAssets     map[string][]string `json:"profileStaticAssets,omitempty"`
+	CredentialsRequestAsset string              `json:"credentialsRequestAsset,omitempty"`
+	CRAsset                 string              `json:"crAsset"`
+	DeploymentAsset         string              `json:"deploymentAsset"`
+	OperandNamespaces       []string            `json:"operandNamespaces,omitempty"`
+	RequireFeatureGates     []string            `json:"requireFeatureGates,omitempty"`
+	DisabledByFeatureGates  []string            `json:"disabledByFeatureGates,omitempty"`
+	RequireCapability       string              `json:"requireCapability,omitempty"`
+	OptionalDriver          bool                `json:"optionalDriver,omitempty"`
+	AllowDisabled           bool                `json:"allowDisabled,omitempty"`
+	// Assets is content of all assets of the driver, by asset name.
+	Assets map[string]string `json:"assets"`
+}
+
+// ParseConfig returns CSIOperatorConfig of a driver config stored in
+// ConfigMapName. It returns an error when the config is not complete, any
+// of its assets is missing or its CR, Deployment or CredentialsRequest asset
+// cannot be decoded.
+func ParseConfig(data []byte) (CSIOperatorConfig, error) {
+	var dc driverConfig
+	if err := yaml.UnmarshalStrict(data, &dc); err != nil {
+		return CSIOperatorConfig{}, err
+	}
+	cfg := CSIOperatorConfig{
+		CSIDriverName:           dc.CSIDriverName,
+		ConditionPrefix:         dc.ConditionPrefix,
+		Platform:                configv1.PlatformType(dc.Platform),
+		ExternalPlatformName:    dc.ExternalPlatformName,
+		Images:                  dc.Images,
+		StaticAssets:            dc.StaticAssets,
+		CredentialsRequestAsset: dc.CredentialsRequestAsset,
+		CRAsset:                 dc.CRAsset,
+		DeploymentAsset:         dc.DeploymentAsset,
+		OperandNamespaces:       dc.OperandNamespaces,
+		RequireFeatureGates:     dc.RequireFeatureGates,
+		DisabledByFeatureGates:  dc.DisabledByFeatureGates,
+		RequireCapability:       dc.RequireCapability,
+		OptionalDriver:          dc.OptionalDriver,
+		AllowDisabled:           dc.AllowDisabled,
+		AssetFunc: func(name string) ([]byte, error) {
+			content, found := dc.Assets[name]
+			if !found {
+				return nil, fmt.Errorf("asset %s not found", name)
+			}
+			return []byte(content), nil
+		},
+	}
+	for profile, assets := range dc.ProfileStaticAssets {
+		if cfg.ProfileStaticAssets == nil {
+			cfg.ProfileStaticAssets = map[ClusterProfile][]string{}
+		}
+		cfg.ProfileStaticAssets[ClusterProfile(profile)] = assets
+	}
+	if err := validate(cfg); err != nil {
+		return CSIOperatorConfig{}, err
+	}
+	return cfg, nil
+}
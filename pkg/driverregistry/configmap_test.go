@@ -0,0 +1,75 @@
+package driverregistry
+
+import (
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+csiDriverName: csi.example.com
+conditionPrefix: Example
+platform: AWS
+images:
+  OPERATOR_IMAGE: quay.io/example/operator:v1
+crAsset: cr.yaml
+deploymentAsset: deployment.yaml
+assets:
+  cr.yaml: |
+    apiVersion: operator.openshift.io/v1
+    kind: ClusterCSIDriver
+    metadata:
+      name: csi.example.com
+  deployment.yaml: |
+    apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: example-csi-driver-operator
+`
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.CSIDriverName != "csi.example.com" || cfg.Platform != "AWS" || cfg.Images["OPERATOR_IMAGE"] != "quay.io/example/operator:v1" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	content, err := cfg.AssetFunc("deployment.yaml")
+	if err != nil || !strings.Contains(string(content), "kind: Deployment\n") {
+		t.Errorf("unexpected deployment asset %q: %v", content, err)
+	}
+
+	if _, err := ParseConfig([]byte(testConfig + "staticAssets: [missing.yaml]\n")); err == nil {
+		t.Errorf("expected error for missing asset")
+	}
+	if _, err := ParseConfig([]byte(testConfig + "unknownField: true\n")); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
+
+func TestParseConfigUndecodableAsset(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "CR of a wrong kind",
+			config: strings.Replace(testConfig, "kind: ClusterCSIDriver", "kind: Deployment", 1),
+		},
+		{
+			name:   "Deployment without apiVersion",
+			config: strings.Replace(testConfig, "    apiVersion: apps/v1\n", "", 1),
+		},
+		{
+			name:   "CredentialsRequest that is not an object",
+			config: strings.Replace(testConfig, "assets:\n", "credentialsRequestAsset: credentials.yaml\nassets:\n  credentials.yaml: not an object\n", 1),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseConfig([]byte(test.config)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
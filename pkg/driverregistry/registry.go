@@ -0,0 +1,197 @@
+// Package driverregistry allows products layered on top of OpenShift to run
+// their CSI driver operators by CSO, without forking it.
+//
+// A layered product builds its own cluster-storage-operator binary, calls
+// Register for each of its drivers from main() and then starts the operator
+// with the command returned by operator.NewStartCommand:
+//
+//	func main() {
+//		driverregistry.MustRegister(driverregistry.NewConfig(
+//			"csi.example.com", "ExampleCSIDriverOperator", configv1.AWSPlatformType,
+//			assets.ReadFile, "cr.yaml", "deployment.yaml",
+//			driverregistry.WithStaticAssets("sa.yaml", "rbac.yaml"),
+//			driverregistry.WithExtraControllers(newExampleControllers),
+//		))
+//		if err := operator.NewStartCommand().Execute(); err != nil {
+//			os.Exit(1)
+//		}
+//	}
+//
+// CSO then manages the registered CSI driver operators the same way
+// as the ones shipped with CSO: it creates their static assets, ClusterCSIDriver
+// CR and Deployment on the platform given by CSIOperatorConfig.Platform.
+//
+// The extension points are fields of CSIOperatorConfig:
+//   - AssetFunc provides content of the driver assets (StaticAssets,
+//     CredentialsRequestAsset, CRAsset, DeploymentAsset). It's required, the
+//     driver assets are not shipped with CSO.
+//   - DeploymentHooks modify the rendered Deployment of the CSI driver
+//     operator before it's applied.
+//   - ExtraControllersFunc returns controllers that run together with the
+//     CSI driver operator, created with clients of CSO.
+//
+// Drivers shipped with CSO take precedence, a registered driver with the same
+// CSIDriverName is ignored.
+package driverregistry
+
+import (
+	"fmt"
+	"sync"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// CSIOperatorConfig is configuration of a CSI driver operator.
+type CSIOperatorConfig = csioperatorclient.CSIOperatorConfig
+
+// ClusterProfile is profile of a cluster.
+type ClusterProfile = csioperatorclient.ClusterProfile
+
+// StatusFilterFunc returns false when a CSI driver should not run on a
+// cluster.
+type StatusFilterFunc = csioperatorclient.StatusFilterFunc
+
+// ExtraControllersFunc returns controllers that run together with a CSI
+// driver operator.
+type ExtraControllersFunc = csioperatorclient.ExtraControllersFunc
+
+// DeploymentHookFunc modifies Deployment of a CSI driver operator before
+// it's applied.
+type DeploymentHookFunc = csioperatorclient.DeploymentHookFunc
+
+var (
+	lock    sync.Mutex
+	configs []CSIOperatorConfig
+
+	operatorScheme = runtime.NewScheme()
+	operatorCodecs = serializer.NewCodecFactory(operatorScheme)
+)
+
+func init() {
+	if err := operatorv1.AddToScheme(operatorScheme); err != nil {
+		panic(err)
+	}
+}
+
+// Register adds a CSI driver operator to the list of operators managed by
+// CSO. It must be called before the operator starts, typically from main().
+// It returns an error when the config is not complete or a driver with the
+// same name or condition prefix is already registered.
+func Register(cfg CSIOperatorConfig) error {
+	if err := validate(cfg); err != nil {
+		return err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for _, existing := range configs {
+		if existing.CSIDriverName == cfg.CSIDriverName {
+			return fmt.Errorf("CSI driver %s is already registered", cfg.CSIDriverName)
+		}
+		if existing.ConditionPrefix == cfg.ConditionPrefix {
+			return fmt.Errorf("condition prefix %s of CSI driver %s is already used by CSI driver %s", cfg.ConditionPrefix, cfg.CSIDriverName, existing.CSIDriverName)
+		}
+	}
+	configs = append(configs, cfg)
+	return nil
+}
+
+// MustRegister is like Register, but it panics on error.
+func MustRegister(cfg CSIOperatorConfig) {
+	if err := Register(cfg); err != nil {
+		panic(err)
+	}
+}
+
+// Registered returns configs of all registered CSI driver operators, in the
+// order of registration.
+func Registered() []CSIOperatorConfig {
+	lock.Lock()
+	defer lock.Unlock()
+	return append([]CSIOperatorConfig{}, configs...)
+}
+
+func validate(cfg CSIOperatorConfig) error {
+	if cfg.CSIDriverName == "" {
+		return fmt.Errorf("CSIDriverName must be set")
+	}
+	if cfg.ConditionPrefix == "" {
+		return fmt.Errorf("ConditionPrefix of CSI driver %s must be set", cfg.CSIDriverName)
+	}
+	if cfg.Platform == "" {
+		return fmt.Errorf("Platform of CSI driver %s must be set", cfg.CSIDriverName)
+	}
+	if cfg.ExternalPlatformName != "" && cfg.Platform != csioperatorclient.ExternalPlatformType {
+		return fmt.Errorf("ExternalPlatformName of CSI driver %s requires Platform %s", cfg.CSIDriverName, csioperatorclient.ExternalPlatformType)
+	}
+	if cfg.AssetFunc == nil {
+		return fmt.Errorf("AssetFunc of CSI driver %s must be set", cfg.CSIDriverName)
+	}
+	if cfg.CRAsset == "" || cfg.DeploymentAsset == "" {
+		return fmt.Errorf("CRAsset and DeploymentAsset of CSI driver %s must be set", cfg.CSIDriverName)
+	}
+	for _, asset := range append([]string{cfg.CRAsset, cfg.DeploymentAsset, cfg.CredentialsRequestAsset}, cfg.GetOwnStaticAssets()...) {
+		if asset == "" {
+			continue
+		}
+		if _, err := cfg.AssetFunc(asset); err != nil {
+			return fmt.Errorf("failed to read asset %s of CSI driver %s: %w", asset, cfg.CSIDriverName, err)
+		}
+	}
+	// The controllers read these assets with *OrDie functions, which would
+	// crash CSO on an invalid asset.
+	if err := decodeAsset(cfg, cfg.CRAsset, func(content []byte) error {
+		obj, err := runtime.Decode(operatorCodecs.UniversalDecoder(operatorv1.SchemeGroupVersion), content)
+		if err == nil {
+			if _, ok := obj.(*operatorv1.ClusterCSIDriver); !ok {
+				err = fmt.Errorf("expected ClusterCSIDriver, got %T", obj)
+			}
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := decodeAsset(cfg, cfg.DeploymentAsset, func(content []byte) error {
+		obj, err := runtime.Decode(scheme.Codecs.UniversalDecoder(appsv1.SchemeGroupVersion), content)
+		if err == nil {
+			if _, ok := obj.(*appsv1.Deployment); !ok {
+				err = fmt.Errorf("expected Deployment, got %T", obj)
+			}
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	if cfg.CredentialsRequestAsset != "" {
+		if err := decodeAsset(cfg, cfg.CredentialsRequestAsset, func(content []byte) error {
+			obj, _, err := scheme.Codecs.UniversalDecoder().Decode(content, nil, &unstructured.Unstructured{})
+			if err == nil {
+				if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "CredentialsRequest" {
+					err = fmt.Errorf("expected CredentialsRequest, got %s", kind)
+				}
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeAsset checks that the asset can be decoded by decode.
+func decodeAsset(cfg CSIOperatorConfig, asset string, decode func(content []byte) error) error {
+	content, err := cfg.AssetFunc(asset)
+	if err != nil {
+		return fmt.Errorf("failed to read asset %s of CSI driver %s: %w", asset, cfg.CSIDriverName, err)
+	}
+	if err := decode(content); err != nil {
+		return fmt.Errorf("failed to decode asset %s of CSI driver %s: %w", asset, cfg.CSIDriverName, err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package eventsink copies events emitted by the operator to additional
+// sinks, such as stdout, a file or structured logs, in addition to
+// Kubernetes Events. The sinks are useful in CI and must-gather, where
+// Kubernetes Events may be already garbage collected.
+package eventsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// Event is a copy of an event written to sinks.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Component string    `json:"component"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+}
+
+// Sink receives copies of events.
+type Sink interface {
+	Write(event Event)
+}
+
+// NewSinks returns sinks parsed from their specification:
+//   - "stdout" writes events as JSON lines to stdout.
+//   - "file:<path>" appends events as JSON lines to the file.
+//   - "log" writes events as structured log messages.
+func NewSinks(specs []string) ([]Sink, error) {
+	var sinks []Sink
+	for _, spec := range specs {
+		switch {
+		case spec == "stdout":
+			sinks = append(sinks, newJSONSink(os.Stdout))
+		case spec == "log":
+			sinks = append(sinks, logSink{})
+		case strings.HasPrefix(spec, "file:"):
+			path := strings.TrimPrefix(spec, "file:")
+			if path == "" {
+				return nil, fmt.Errorf("event sink %q: missing file path", spec)
+			}
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return nil, fmt.Errorf("event sink %q: %w", spec, err)
+			}
+			sinks = append(sinks, newJSONSink(f))
+		default:
+			return nil, fmt.Errorf("unknown event sink %q, expected one of: stdout, log, file:<path>", spec)
+		}
+	}
+	return sinks, nil
+}
+
+// NewRecorder returns Recorder that records events with the given recorder
+// and writes their copies to the sinks.
+func NewRecorder(recorder events.Recorder, sinks []Sink) events.Recorder {
+	if len(sinks) == 0 {
+		return recorder
+	}
+	return &sinkRecorder{
+		delegate: recorder,
+		sinks:    sinks,
+	}
+}
+
+type sinkRecorder struct {
+	delegate events.Recorder
+	sinks    []Sink
+}
+
+var _ events.Recorder = &sinkRecorder{}
+
+func (r *sinkRecorder) Event(reason, message string) {
+	r.delegate.Event(reason, message)
+	r.write(corev1.EventTypeNormal, reason, message)
+}
+
+func (r *sinkRecorder) Eventf(reason, messageFmt string, args ...interface{}) {
+	r.Event(reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *sinkRecorder) Warning(reason, message string) {
+	r.delegate.Warning(reason, message)
+	r.write(corev1.EventTypeWarning, reason, message)
+}
+
+func (r *sinkRecorder) Warningf(reason, messageFmt string, args ...interface{}) {
+	r.Warning(reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *sinkRecorder) ForComponent(componentName string) events.Recorder {
+	return &sinkRecorder{
+		delegate: r.delegate.ForComponent(componentName),
+		sinks:    r.sinks,
+	}
+}
+
+func (r *sinkRecorder) WithComponentSuffix(componentNameSuffix string) events.Recorder {
+	return &sinkRecorder{
+		delegate: r.delegate.WithComponentSuffix(componentNameSuffix),
+		sinks:    r.sinks,
+	}
+}
+
+func (r *sinkRecorder) ComponentName() string {
+	return r.delegate.ComponentName()
+}
+
+func (r *sinkRecorder) Shutdown() {
+	r.delegate.Shutdown()
+	for _, sink := range r.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+func (r *sinkRecorder) write(eventType, reason, message string) {
+	event := Event{
+		Time:      time.Now(),
+		Component: r.delegate.ComponentName(),
+		Type:      eventType,
+		Reason:    reason,
+		Message:   message,
+	}
+	for _, sink := range r.sinks {
+		sink.Write(event)
+	}
+}
+
+// jsonSink writes events as JSON lines.
+type jsonSink struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+func newJSONSink(writer io.Writer) *jsonSink {
+	return &jsonSink{writer: writer}
+}
+
+func (s *jsonSink) Write(event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		klog.Errorf("Failed to marshal event %s: %s", event.Reason, err)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := s.writer.Write(append(data, '\n')); err != nil {
+		klog.Errorf("Failed to write event %s: %s", event.Reason, err)
+	}
+}
+
+func (s *jsonSink) Close() error {
+	if closer, ok := s.writer.(io.Closer); ok && s.writer != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
+
+// logSink writes events as structured log messages.
+type logSink struct{}
+
+func (logSink) Write(event Event) {
+	klog.InfoS("Event", "component", event.Component, "type", event.Type, "reason", event.Reason, "message", event.Message)
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// minResyncInterval is the resync interval of a controller right after its
+// sync failed.
+const minResyncInterval = 30 * time.Second
+
+var (
+	// maxResyncInterval is the resync interval of a stable controller.
+	maxResyncInterval = 20 * time.Minute
+
+	syncDurationMetric = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cluster_storage_operator_controller_sync_duration_seconds",
+			Help:    "Duration of syncs of a controller, labeled by controller name and result.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+		},
+		[]string{"controller", "result"},
+	)
+	resyncIntervalMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cluster_storage_operator_controller_resync_interval_seconds",
+			Help: "Current resync interval of a controller, short when the controller recently failed and growing while it syncs successfully.",
+		},
+		[]string{"controller"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(syncDurationMetric, resyncIntervalMetric)
+}
+
+// SetMaxResyncInterval sets the resync interval of stable controllers. It
+// should match the resync interval of the controllers.
+func SetMaxResyncInterval(interval time.Duration) {
+	lock.Lock()
+	defer lock.Unlock()
+	maxResyncInterval = interval
+}
+
+// nextResyncInterval returns the resync interval after a sync. A failed sync
+// resets it to minResyncInterval, so a controller that recently failed
+// checks its objects often. Each successful sync doubles it up to
+// maxResyncInterval, so stable controllers do not load the API server.
+func nextResyncInterval(previous time.Duration, syncErr error) time.Duration {
+	if syncErr != nil || previous == 0 {
+		return minResyncInterval
+	}
+	next := previous * 2
+	if next > maxResyncInterval {
+		next = maxResyncInterval
+	}
+	return next
+}
+
+// recordSync records metrics of a sync and requeues the controller after
+// its new resync interval. Failed syncs are requeued by the controller
+// itself with a rate limit.
+func recordSync(name string, syncCtx factory.SyncContext, duration time.Duration, record *syncRecord) {
+	result := "success"
+	if record.lastError != nil {
+		result = "error"
+	}
+	syncDurationMetric.WithLabelValues(name, result).Observe(duration.Seconds())
+	resyncIntervalMetric.WithLabelValues(name).Set(record.resyncInterval.Seconds())
+
+	if record.lastError == nil && syncCtx != nil && syncCtx.Queue() != nil {
+		syncCtx.Queue().AddAfter(syncCtx.QueueKey(), record.resyncInterval)
+	}
+}
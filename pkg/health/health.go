@@ -0,0 +1,224 @@
+// Package health records state of controllers and CSI driver managers run
+// by the operator and serves it as a JSON document, e.g. for must-gather
+// and the storage status page of the console.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/klog/v2"
+)
+
+// Path is the HTTP path where the health document is served.
+const Path = "/debug/controllers"
+
+// States of controllers and driver managers.
+const (
+	StateHealthy     = "Healthy"
+	StateDegraded    = "Degraded"
+	StateProgressing = "Progressing"
+	// StateUnknown is a controller that has not synced yet.
+	StateUnknown    = "Unknown"
+	StateRunning    = "Running"
+	StateNotRunning = "NotRunning"
+)
+
+// conditionSuffixes are suffixes of operator conditions, the rest of the
+// condition type is name of the controller that sets it.
+var conditionSuffixes = []string{
+	operatorapi.OperatorStatusTypeDegraded,
+	operatorapi.OperatorStatusTypeProgressing,
+	operatorapi.OperatorStatusTypeAvailable,
+	operatorapi.OperatorStatusTypeUpgradeable,
+}
+
+// Controller is state of a single controller.
+type Controller struct {
+	Name         string     `json:"name"`
+	State        string     `json:"state"`
+	LastSyncTime *time.Time `json:"lastSyncTime,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	// ResyncIntervalSeconds is the current resync interval of controllers
+	// that track their syncs.
+	ResyncIntervalSeconds float64                         `json:"resyncIntervalSeconds,omitempty"`
+	Conditions            []operatorapi.OperatorCondition `json:"conditions,omitempty"`
+}
+
+// DriverManager is state of the controller manager of a CSI driver
+// operator.
+type DriverManager struct {
+	Name               string    `json:"name"`
+	State              string    `json:"state"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// Report is the health document.
+type Report struct {
+	Controllers    []Controller    `json:"controllers"`
+	DriverManagers []DriverManager `json:"driverManagers"`
+}
+
+type syncRecord struct {
+	lastSyncTime   time.Time
+	lastError      error
+	resyncInterval time.Duration
+}
+
+var (
+	lock           sync.Mutex
+	syncs          = map[string]*syncRecord{}
+	driverManagers = map[string]*DriverManager{}
+)
+
+// TrackSync returns a sync function that records time, duration and error
+// of each sync of the named controller. It adapts the resync interval of
+// the controller to its health, see nextResyncInterval.
+func TrackSync(name string, syncFn factory.SyncFunc) factory.SyncFunc {
+	return func(ctx context.Context, syncCtx factory.SyncContext) error {
+		start := time.Now()
+		err := syncFn(ctx, syncCtx)
+		duration := time.Since(start)
+
+		lock.Lock()
+		var previousInterval time.Duration
+		if previous, found := syncs[name]; found {
+			previousInterval = previous.resyncInterval
+		}
+		record := &syncRecord{
+			lastSyncTime:   time.Now(),
+			lastError:      err,
+			resyncInterval: nextResyncInterval(previousInterval, err),
+		}
+		syncs[name] = record
+		lock.Unlock()
+
+		recordSync(name, syncCtx, duration, record)
+		return err
+	}
+}
+
+// SetDriverManagerRunning records whether the controller manager of the
+// named CSI driver operator runs.
+func SetDriverManagerRunning(name string, running bool) {
+	state := StateNotRunning
+	if running {
+		state = StateRunning
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if mgr, found := driverManagers[name]; found && mgr.State == state {
+		return
+	}
+	driverManagers[name] = &DriverManager{
+		Name:               name,
+		State:              state,
+		LastTransitionTime: time.Now(),
+	}
+}
+
+// NewReport returns state of all controllers that either track their syncs
+// or set operator conditions, and of all CSI driver managers. Controllers
+// from library-go do not track their syncs, their state is computed only
+// from their conditions.
+func NewReport(opStatus *operatorapi.OperatorStatus) *Report {
+	lock.Lock()
+	defer lock.Unlock()
+
+	controllers := map[string]*Controller{}
+	getController := func(name string) *Controller {
+		ctrl, found := controllers[name]
+		if !found {
+			ctrl = &Controller{Name: name}
+			controllers[name] = ctrl
+		}
+		return ctrl
+	}
+	for name, record := range syncs {
+		ctrl := getController(name)
+		lastSyncTime := record.lastSyncTime
+		ctrl.LastSyncTime = &lastSyncTime
+		if record.lastError != nil {
+			ctrl.LastError = record.lastError.Error()
+		}
+		ctrl.ResyncIntervalSeconds = record.resyncInterval.Seconds()
+	}
+	if opStatus != nil {
+		for _, cond := range opStatus.Conditions {
+			if name := controllerName(cond.Type); name != "" {
+				ctrl := getController(name)
+				ctrl.Conditions = append(ctrl.Conditions, cond)
+			}
+		}
+	}
+
+	report := &Report{
+		Controllers:    []Controller{},
+		DriverManagers: []DriverManager{},
+	}
+	for _, ctrl := range controllers {
+		ctrl.State = controllerState(ctrl)
+		report.Controllers = append(report.Controllers, *ctrl)
+	}
+	sort.Slice(report.Controllers, func(i, j int) bool {
+		return report.Controllers[i].Name < report.Controllers[j].Name
+	})
+	for _, mgr := range driverManagers {
+		report.DriverManagers = append(report.DriverManagers, *mgr)
+	}
+	sort.Slice(report.DriverManagers, func(i, j int) bool {
+		return report.DriverManagers[i].Name < report.DriverManagers[j].Name
+	})
+	return report
+}
+
+// controllerName returns name of the controller that sets condition of
+// given type or an empty string, when the type has unknown suffix.
+func controllerName(conditionType string) string {
+	for _, suffix := range conditionSuffixes {
+		if strings.HasSuffix(conditionType, suffix) {
+			return strings.TrimSuffix(conditionType, suffix)
+		}
+	}
+	return ""
+}
+
+func controllerState(ctrl *Controller) string {
+	switch {
+	case ctrl.LastError != "" || v1helpers.IsOperatorConditionTrue(ctrl.Conditions, ctrl.Name+operatorapi.OperatorStatusTypeDegraded):
+		return StateDegraded
+	case v1helpers.IsOperatorConditionTrue(ctrl.Conditions, ctrl.Name+operatorapi.OperatorStatusTypeProgressing):
+		return StateProgressing
+	case ctrl.LastSyncTime == nil && len(ctrl.Conditions) == 0:
+		return StateUnknown
+	}
+	return StateHealthy
+}
+
+// NewHandler returns HTTP handler that serves the health document. It must
+// be served by a server that authenticates and authorizes the requests.
+func NewHandler(operatorClient v1helpers.OperatorClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		_, opStatus, _, err := operatorClient.GetOperatorState()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(NewReport(opStatus)); err != nil {
+			klog.Warningf("Failed to write controller health: %s", err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+)
+
+func TestNewReport(t *testing.T) {
+	failingSync := TrackSync("FailingController", func(ctx context.Context, syncCtx factory.SyncContext) error {
+		return errors.New("test error")
+	})
+	okSync := TrackSync("OKController", func(ctx context.Context, syncCtx factory.SyncContext) error {
+		return nil
+	})
+	_ = failingSync(context.TODO(), nil)
+	_ = okSync(context.TODO(), nil)
+	SetDriverManagerRunning("AWSEBS", true)
+
+	opStatus := &operatorapi.OperatorStatus{
+		Conditions: []operatorapi.OperatorCondition{
+			{Type: "OKControllerAvailable", Status: operatorapi.ConditionTrue},
+			{Type: "StaticControllerProgressing", Status: operatorapi.ConditionTrue},
+			{Type: "UnknownSuffix", Status: operatorapi.ConditionTrue},
+		},
+	}
+	report := NewReport(opStatus)
+
+	expectedStates := map[string]string{
+		"FailingController": StateDegraded,
+		"OKController":      StateHealthy,
+		"StaticController":  StateProgressing,
+	}
+	if len(report.Controllers) != len(expectedStates) {
+		t.Fatalf("expected %d controllers, got %+v", len(expectedStates), report.Controllers)
+	}
+	for _, ctrl := range report.Controllers {
+		if ctrl.State != expectedStates[ctrl.Name] {
+			t.Errorf("expected controller %s to be %s, got %s", ctrl.Name, expectedStates[ctrl.Name], ctrl.State)
+		}
+	}
+	if report.Controllers[0].LastError != "test error" {
+		t.Errorf("expected FailingController to report its last error, got %q", report.Controllers[0].LastError)
+	}
+	if len(report.DriverManagers) != 1 || report.DriverManagers[0].State != StateRunning {
+		t.Errorf("expected running AWSEBS driver manager, got %+v", report.DriverManagers)
+	}
+}
+
+func TestNextResyncInterval(t *testing.T) {
+	interval := nextResyncInterval(0, nil)
+	if interval != minResyncInterval {
+		t.Errorf("expected first interval %s, got %s", minResyncInterval, interval)
+	}
+	for i := 0; i < 20; i++ {
+		interval = nextResyncInterval(interval, nil)
+	}
+	if interval != maxResyncInterval {
+		t.Errorf("expected stable interval %s, got %s", maxResyncInterval, interval)
+	}
+	if interval = nextResyncInterval(interval, errors.New("test error")); interval != minResyncInterval {
+		t.Errorf("expected interval %s after error, got %s", minResyncInterval, interval)
+	}
+	if interval = nextResyncInterval(interval, nil); interval != 2*minResyncInterval {
+		t.Errorf("expected interval %s after recovery, got %s", 2*minResyncInterval, interval)
+	}
+}
@@ -0,0 +1,124 @@
+package backuplabels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/cleanup"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "BackupLabelsController"
+
+	// excludeFromBackupLabel excludes objects from Velero / OADP backups.
+	excludeFromBackupLabel = "velero.io/exclude-from-backup"
+)
+
+// Resources with user configuration that must be backed up, even though
+// they're created by CSO.
+var backedUpResources = map[schema.GroupResource]bool{
+	{Group: "operator.openshift.io", Resource: "clustercsidrivers"}: true,
+}
+
+// This Controller labels objects created by CSO that CSO re-creates on its
+// own (Deployments, RBAC, StorageClasses, ...) to be excluded from Velero /
+// OADP backups, so restored backups don't conflict with objects of the
+// running operator. ClusterCSIDrivers with user configuration are kept in
+// the backups. It's enabled in the Storage CR:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    excludeFromBackup: true
+//
+// When disabled, the label is removed from all objects created by CSO.
+// It produces following Conditions:
+// BackupLabelsControllerDegraded - error labeling the objects.
+type Controller struct {
+	operatorClient v1helpers.OperatorClient
+	dynamicClient  dynamic.Interface
+	eventRecorder  events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration) factory.Controller {
+	c := &Controller{
+		operatorClient: clients.OperatorClient,
+		dynamicClient:  clients.DynamicClient,
+		eventRecorder:  eventRecorder,
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).ResyncEvery(resyncInterval).WithInformers(
+		clients.OperatorClient.Informer(),
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("BackupLabelsController sync started")
+	defer klog.V(4).Infof("BackupLabelsController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	exclude := false
+	if _, err := csoutils.GetUnsupportedConfigOverride(opSpec, "excludeFromBackup", &exclude); err != nil {
+		return err
+	}
+
+	objects, err := cleanup.List(ctx, c.dynamicClient)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := c.syncLabel(ctx, obj, exclude && !backedUpResources[obj.Resource.GroupResource()]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Controller) syncLabel(ctx context.Context, obj cleanup.Object, exclude bool) error {
+	if _, labeled := obj.Labels[excludeFromBackupLabel]; labeled == exclude {
+		return nil
+	}
+
+	// JSON merge patch, null removes the label.
+	var value interface{}
+	if exclude {
+		value = "true"
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{excludeFromBackupLabel: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("Setting %s=%t on %s", excludeFromBackupLabel, exclude, obj)
+	_, err = c.dynamicClient.Resource(obj.Resource).Namespace(obj.Namespace).Patch(ctx, obj.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to label %s: %w", obj, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package cabundle
+
+import (
+	"context"
+	"fmt"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "CustomCABundleController"
+	ownerComponent = "custom-ca-bundle"
+)
+
+// Namespaces of operands that talk to vCenter / OpenStack endpoints and get
+// the custom CA bundle.
+var targetNamespaces = []string{
+	csoclients.CSIOperatorNamespace,
+	csoclients.OperatorNamespace,
+}
+
+// This Controller syncs user provided CA bundle from a ConfigMap in
+// openshift-config namespace to namespaces of vSphere / OpenStack CSI driver
+// operators and vSphere problem detector. Deployment controllers of the
+// operands mount the synced ConfigMap and roll out their pods when it changes.
+// The synced ConfigMaps are removed when the CA bundle is not configured.
+// It produces following Conditions:
+// CustomCABundleControllerDegraded - error syncing the CA bundle.
+type Controller struct {
+	operatorClient  v1helpers.OperatorClient
+	kubeClient      kubernetes.Interface
+	configMapLister corelisters.ConfigMapLister
+	eventRecorder   events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient:  clients.OperatorClient,
+		kubeClient:      clients.KubeClient,
+		configMapLister: clients.KubeInformers.ConfigMapLister(),
+		eventRecorder:   eventRecorder.WithComponentSuffix("custom-ca-bundle"),
+	}
+	informers := []factory.Informer{
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor(csoclients.CloudConfigNamespace).Core().V1().ConfigMaps().Informer(),
+	}
+	for _, ns := range targetNamespaces {
+		informers = append(informers, clients.KubeInformers.InformersFor(ns).Core().V1().ConfigMaps().Informer())
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		informers...,
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("CustomCABundleController sync started")
+	defer klog.V(4).Infof("CustomCABundleController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	sourceName, err := csoutils.GetCustomCABundleName(opSpec)
+	if err != nil {
+		return err
+	}
+	if sourceName == "" {
+		return c.removeCABundles(ctx)
+	}
+
+	source, err := c.configMapLister.ConfigMaps(csoclients.CloudConfigNamespace).Get(sourceName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("ConfigMap %s/%s with custom CA bundle not found", csoclients.CloudConfigNamespace, sourceName)
+		}
+		return err
+	}
+	caBundle, found := source.Data[csoutils.CustomCABundleKey]
+	if !found {
+		return fmt.Errorf("ConfigMap %s/%s does not contain key %s", csoclients.CloudConfigNamespace, sourceName, csoutils.CustomCABundleKey)
+	}
+
+	for _, ns := range targetNamespaces {
+		required := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      csoutils.CustomCABundleConfigMapName,
+				Namespace: ns,
+			},
+			Data: map[string]string{
+				csoutils.CustomCABundleKey: caBundle,
+			},
+		}
+		csoutils.SetOwnedByLabel(required, ownerComponent)
+		if _, _, err := resourceapply.ApplyConfigMap(ctx, c.kubeClient.CoreV1(), c.eventRecorder, required); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Controller) removeCABundles(ctx context.Context) error {
+	for _, ns := range targetNamespaces {
+		_, err := c.configMapLister.ConfigMaps(ns).Get(csoutils.CustomCABundleConfigMapName)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = c.kubeClient.CoreV1().ConfigMaps(ns).Delete(ctx, csoutils.CustomCABundleConfigMapName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		c.eventRecorder.Eventf("CustomCABundleRemoved", "Removed ConfigMap %s/%s", ns, csoutils.CustomCABundleConfigMapName)
+	}
+	return nil
+}
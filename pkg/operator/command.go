@@ -0,0 +1,41 @@
+package operator
+
+import (
+	"context"
+
+	"github.com/openshift/library-go/pkg/controller/controllercmd"
+	"github.com/spf13/cobra"
+
+	"github.com/openshift/cluster-storage-operator/pkg/eventsink"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/cluster-storage-operator/pkg/version"
+)
+
+// NewStartCommand returns the command that starts the operator. Binaries
+// that embed CSO with additional CSI driver operators (see
+// pkg/driverregistry) register them and then run this command.
+func NewStartCommand() *cobra.Command {
+	var eventSinks []string
+	var controllerWorkers map[string]int
+	cmd := controllercmd.NewControllerCommandConfig(
+		"cluster-storage-operator",
+		version.Get(),
+		func(ctx context.Context, controllerConfig *controllercmd.ControllerContext) error {
+			sinks, err := eventsink.NewSinks(eventSinks)
+			if err != nil {
+				return err
+			}
+			controllerConfig.EventRecorder = eventsink.NewRecorder(controllerConfig.EventRecorder, sinks)
+			workers, err := csoutils.NewControllerWorkers(controllerWorkers)
+			if err != nil {
+				return err
+			}
+			return RunOperator(ctx, controllerConfig, workers)
+		},
+	).NewCommand()
+	cmd.Use = "start"
+	cmd.Short = "Start the Cluster Storage Operator"
+	cmd.Flags().StringSliceVar(&eventSinks, "event-sink", nil, "Additional sinks of operator events: stdout, log or file:<path>. Can be repeated.")
+	cmd.Flags().StringToIntVar(&controllerWorkers, "controller-workers", nil, "Number of workers of controllers as <controller name>=<workers>, e.g. CSIDriverOperatorDeployment=2. Controllers of CSI drivers are named without the driver prefix. Defaults to 1.")
+	return cmd
+}
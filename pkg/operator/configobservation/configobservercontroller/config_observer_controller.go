@@ -10,6 +10,7 @@ import (
 
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/trustedca"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
 )
 
@@ -27,6 +28,7 @@ func NewConfigObserverController(
 	informers := []factory.Informer{
 		clients.OperatorClient.Informer(),
 		clients.ConfigInformers.Config().V1().Proxies().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.CloudConfigNamespace).Core().V1().ConfigMaps().Informer(),
 	}
 
 	c := &ConfigObserverController{
@@ -34,14 +36,17 @@ func NewConfigObserverController(
 			clients.OperatorClient,
 			eventRecorder.WithComponentSuffix("config-observer-controller-"),
 			configobservation.Listers{
-				ProxyLister_: clients.ConfigInformers.Config().V1().Proxies().Lister(),
+				ProxyLister_:     clients.ConfigInformers.Config().V1().Proxies().Lister(),
+				ConfigMapLister_: clients.KubeInformers.InformersFor(csoclients.CloudConfigNamespace).Core().V1().ConfigMaps().Lister().ConfigMaps(csoclients.CloudConfigNamespace),
 				PreRunCachesSynced: append([]cache.InformerSynced{},
 					clients.OperatorClient.Informer().HasSynced,
 					clients.ConfigInformers.Config().V1().Proxies().Informer().HasSynced,
+					clients.KubeInformers.InformersFor(csoclients.CloudConfigNamespace).Core().V1().ConfigMaps().Informer().HasSynced,
 				),
 			},
 			informers,
 			proxy.NewProxyObserveFunc(util.ProxyConfigPath()),
+			trustedca.ObserveTrustedCA,
 		),
 	}
 
@@ -3,12 +3,15 @@ package configobservation
 import (
 	configlistersv1 "github.com/openshift/client-go/config/listers/config/v1"
 	"github.com/openshift/library-go/pkg/operator/resourcesynccontroller"
+	corelistersv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
 // Listers implement the configobserver.Listers interface.
 type Listers struct {
 	ProxyLister_ configlistersv1.ProxyLister
+	// ConfigMapLister_ lists ConfigMaps in openshift-config namespace.
+	ConfigMapLister_ corelistersv1.ConfigMapNamespaceLister
 
 	ResourceSync       resourcesynccontroller.ResourceSyncer
 	PreRunCachesSynced []cache.InformerSynced
@@ -0,0 +1,66 @@
+package trustedca
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/openshift/library-go/pkg/operator/configobserver"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
+)
+
+const (
+	proxyConfigName = "cluster"
+	caBundleKey     = "ca-bundle.crt"
+)
+
+// ObserveTrustedCA observes the ConfigMap with additional trusted CAs referenced
+// by proxy.config.openshift.io/cluster and writes hash of its content to the
+// observed config. The hash is then used to roll out Deployments of operands
+// when the trusted CAs change, even though the proxy env. vars stay the same.
+func ObserveTrustedCA(genericListers configobserver.Listers, recorder events.Recorder, existingConfig map[string]interface{}) (ret map[string]interface{}, _ []error) {
+	configPath := util.ProxyTrustedCAHashPath()
+	defer func() {
+		ret = configobserver.Pruned(ret, configPath)
+	}()
+
+	listers := genericListers.(configobservation.Listers)
+	errs := []error{}
+	observedConfig := map[string]interface{}{}
+
+	proxyConfig, err := listers.ProxyLister().Get(proxyConfigName)
+	if errors.IsNotFound(err) {
+		return observedConfig, errs
+	}
+	if err != nil {
+		return existingConfig, append(errs, err)
+	}
+	if proxyConfig.Spec.TrustedCA.Name == "" {
+		return observedConfig, errs
+	}
+
+	cm, err := listers.ConfigMapLister_.Get(proxyConfig.Spec.TrustedCA.Name)
+	if errors.IsNotFound(err) {
+		recorder.Warningf("ObserveTrustedCA", "ConfigMap %s with trusted CA bundle not found", proxyConfig.Spec.TrustedCA.Name)
+		return observedConfig, errs
+	}
+	if err != nil {
+		return existingConfig, append(errs, err)
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(cm.Data[caBundleKey])))
+	if err := unstructured.SetNestedField(observedConfig, hash, configPath...); err != nil {
+		return existingConfig, append(errs, err)
+	}
+
+	currentHash, _, _ := unstructured.NestedString(existingConfig, configPath...)
+	if currentHash != hash {
+		recorder.Eventf("ObserveTrustedCA", "proxy trusted CA bundle %s changed", proxyConfig.Spec.TrustedCA.Name)
+	}
+	return observedConfig, errs
+}
@@ -1,32 +1,110 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	operatorapi "github.com/openshift/api/operator/v1"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 )
 
+const (
+	// Pod template annotation with hash of the observed proxy config and
+	// trusted CA bundle. Any change of the proxy config rolls out the pods.
+	proxyHashAnnotation = "operator.openshift.io/proxy-hash"
+	// Deployment annotation with comma-separated names of containers that
+	// get the proxy config.
+	injectProxyAnnotation = "config.openshift.io/inject-proxy"
+)
+
 // InjectObservedProxyInDeploymentContainers takes an observed proxy config and returns a patched Deployment with proxy env vars set.
+// The Deployment pod template is annotated with hash of the proxy config, including the trusted CA bundle.
 func InjectObservedProxyInDeploymentContainers(deployment *appsv1.Deployment, opSpec *operatorapi.OperatorSpec) (*appsv1.Deployment, error) {
+	containerNamesString := deployment.Annotations[injectProxyAnnotation]
+	return injectObservedProxy(deployment, strings.Split(containerNamesString, ","), opSpec)
+}
+
+// InjectObservedProxyInAllDeploymentContainers is like
+// InjectObservedProxyInDeploymentContainers, but it injects the proxy config
+// into all containers of Deployments without the inject-proxy annotation.
+func InjectObservedProxyInAllDeploymentContainers(deployment *appsv1.Deployment, opSpec *operatorapi.OperatorSpec) (*appsv1.Deployment, error) {
+	if _, found := deployment.Annotations[injectProxyAnnotation]; found {
+		return InjectObservedProxyInDeploymentContainers(deployment, opSpec)
+	}
+	var containerNames []string
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		containerNames = append(containerNames, container.Name)
+	}
+	return injectObservedProxy(deployment, containerNames, opSpec)
+}
+
+func injectObservedProxy(deployment *appsv1.Deployment, containerNames []string, opSpec *operatorapi.OperatorSpec) (*appsv1.Deployment, error) {
 	deploymentCopy := deployment.DeepCopy()
-	containerNamesString := deploymentCopy.Annotations["config.openshift.io/inject-proxy"]
 	err := v1helpers.InjectObservedProxyIntoContainers(
 		&deploymentCopy.Spec.Template.Spec,
-		strings.Split(containerNamesString, ","),
+		containerNames,
 		opSpec.ObservedConfig.Raw,
 		ProxyConfigPath()...,
 	)
 	if err != nil {
 		return nil, err
 	}
+
+	hash, err := observedProxyHash(opSpec)
+	if err != nil {
+		return nil, err
+	}
+	if hash != "" {
+		if deploymentCopy.Spec.Template.Annotations == nil {
+			deploymentCopy.Spec.Template.Annotations = map[string]string{}
+		}
+		deploymentCopy.Spec.Template.Annotations[proxyHashAnnotation] = hash
+	}
 	return deploymentCopy, nil
 }
 
+// observedProxyHash returns hash of the observed proxy config and trusted CA
+// bundle. Empty string is returned when there is no proxy config.
+func observedProxyHash(opSpec *operatorapi.OperatorSpec) (string, error) {
+	if len(opSpec.ObservedConfig.Raw) == 0 {
+		return "", nil
+	}
+	observedConfig := map[string]interface{}{}
+	if err := json.Unmarshal(opSpec.ObservedConfig.Raw, &observedConfig); err != nil {
+		return "", fmt.Errorf("failed to unmarshal the observedConfig: %w", err)
+	}
+	proxyConfig, _, err := unstructured.NestedStringMap(observedConfig, ProxyConfigPath()...)
+	if err != nil {
+		return "", err
+	}
+	caHash, _, err := unstructured.NestedString(observedConfig, ProxyTrustedCAHashPath()...)
+	if err != nil {
+		return "", err
+	}
+	if len(proxyConfig) == 0 && caHash == "" {
+		return "", nil
+	}
+	// json.Marshal sorts map keys, the result is stable.
+	data, err := json.Marshal([]interface{}{proxyConfig, caHash})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
 // ProxyConfigPath returns the path for the observed proxy config. This is a
 // function to avoid exposing a slice that could potentially be appended.
 func ProxyConfigPath() []string {
 	return []string{"targetconfig", "proxy"}
 }
+
+// ProxyTrustedCAHashPath returns the path for hash of the observed proxy
+// trusted CA bundle.
+func ProxyTrustedCAHashPath() []string {
+	return []string{"targetconfig", "proxyTrustedCAHash"}
+}
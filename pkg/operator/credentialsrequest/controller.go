@@ -0,0 +1,361 @@
+package credentialsrequest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	configlisters "github.com/openshift/client-go/config/listers/config/v1"
+	oplisters "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "CredentialsRequestController"
+	ownerComponent = "credentials-request"
+
+	// secretConditionType is suffix of the condition with state of the
+	// Secret minted for the CredentialsRequest.
+	secretConditionType = "CredentialsSecretDegraded"
+	// secretTimeout is how long CCO may take to mint the Secret before the
+	// controller reports it.
+	secretTimeout = 10 * time.Minute
+
+	credentialsRequestKind = "CredentialsRequest"
+)
+
+var credentialsRequestResource = schema.GroupVersionResource{
+	Group:    resourceapply.CredentialsRequestGroup,
+	Version:  resourceapply.CredentialsRequestVersion,
+	Resource: resourceapply.CredentialsRequestResource,
+}
+
+// This Controller creates and syncs CredentialsRequest of a CSI driver
+// operator (or any other operand) from a bindata asset. It is meant to run
+// in the same ControllerManager as the operand, so the CredentialsRequest is
+// created only when the operand is actually started.
+// When the cluster uses short-lived tokens (AWS STS, Azure / GCP Workload
+// Identity), CCO does not provision the credentials Secret. The controller
+// then sets cloudTokenPath in the CredentialsRequest and waits for the admin
+// to create the Secret.
+// Otherwise it watches the Secret minted by CCO and reports when it does
+// not appear in time or when it has no data.
+// In audit-only mode (see csoutils.IsAuditOnly) drift of the
+// CredentialsRequest is only reported.
+// It produces following Conditions:
+// <name>CredentialsRequestControllerDegraded - error applying the CredentialsRequest.
+// <name>CredentialsRequestControllerProgressing - waiting for the admin to
+// provide credentials Secret in the short-lived token mode.
+// <name>CredentialsSecretDegraded - the Secret was not minted in time or it's
+// not valid.
+type Controller struct {
+	name            string
+	assetFunc       resourceapply.AssetFunc
+	asset           string
+	operatorClient  v1helpers.OperatorClient
+	dynamicClient   dynamic.Interface
+	authLister      configlisters.AuthenticationLister
+	cloudCredLister oplisters.CloudCredentialLister
+	secretLister    corelisters.SecretLister
+	eventRecorder   events.Recorder
+	factory         *factory.Factory
+}
+
+var _ factory.Controller = &Controller{}
+
+func NewController(
+	name string,
+	assetFunc resourceapply.AssetFunc,
+	asset string,
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration,
+) (factory.Controller, error) {
+	f := factory.New()
+	f = f.ResyncEvery(resyncInterval)
+	f = f.WithSyncDegradedOnError(clients.OperatorClient)
+	// Necessary to do initial Sync after the controller starts.
+	f = f.WithPostStartHooks(initialSync)
+	// Add informers to the factory now, but the actual event handlers
+	// are added later in Controller.Run(), when we're 100% sure the
+	// controller is going to start.
+	cr, err := readCredentialsRequest(assetFunc, asset)
+	if err != nil {
+		return nil, err
+	}
+	secretNamespace, _, _ := unstructured.NestedString(cr.Object, "spec", "secretRef", "namespace")
+	secretInformer := clients.KubeInformers.InformersFor(secretNamespace).Core().V1().Secrets()
+	f = f.WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().Authentications().Informer(),
+		clients.OperatorInformers.Operator().V1().CloudCredentials().Informer(),
+		secretInformer.Informer())
+
+	c := &Controller{
+		name:            name,
+		assetFunc:       assetFunc,
+		asset:           asset,
+		operatorClient:  clients.OperatorClient,
+		dynamicClient:   clients.DynamicClient,
+		authLister:      clients.ConfigInformers.Config().V1().Authentications().Lister(),
+		cloudCredLister: clients.OperatorInformers.Operator().V1().CloudCredentials().Lister(),
+		secretLister:    secretInformer.Lister(),
+		eventRecorder:   eventRecorder.WithComponentSuffix(name),
+		factory:         f,
+	}
+	return c, nil
+}
+
+func (c *Controller) Sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("CredentialsRequestController sync started")
+	defer klog.V(4).Infof("CredentialsRequestController sync finished")
+
+	opSpec, opStatus, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	required, err := readCredentialsRequest(c.assetFunc, c.asset)
+	if err != nil {
+		return err
+	}
+	csoutils.SetOwnedByLabel(required, ownerComponent)
+
+	shortLivedTokens, err := csoutils.IsShortLivedTokenMode(c.authLister, c.cloudCredLister)
+	if err != nil {
+		return err
+	}
+	if shortLivedTokens {
+		if err := unstructured.SetNestedField(required.Object, csoutils.BoundSATokenPath, "spec", "cloudTokenPath"); err != nil {
+			return err
+		}
+	}
+
+	var expectedGeneration int64 = -1
+	generation := resourcemerge.GenerationFor(opStatus.Generations, credentialsRequestResource.GroupResource(), required.GetNamespace(), required.GetName())
+	if generation != nil {
+		expectedGeneration = generation.LastGeneration
+	}
+
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		return err
+	}
+	var cr *unstructured.Unstructured
+	if auditOnly {
+		// Check the Secret of the existing CredentialsRequest, but don't
+		// revert it.
+		cr, err = c.dynamicClient.Resource(credentialsRequestResource).Namespace(required.GetNamespace()).Get(ctx, required.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return csoutils.ReportObjectDrift(c.eventRecorder, required.GetKind(), required, nil)
+		}
+		if err != nil {
+			return err
+		}
+		if err := csoutils.ReportObjectDrift(c.eventRecorder, required.GetKind(), required, cr); err != nil {
+			return err
+		}
+	} else {
+		cr, _, err = resourceapply.ApplyCredentialsRequest(ctx, c.dynamicClient, c.eventRecorder, required, expectedGeneration)
+		if err != nil {
+			return fmt.Errorf("failed to apply CredentialsRequest %s: %w", required.GetName(), err)
+		}
+	}
+
+	progressing := operatorapi.OperatorCondition{
+		Type:   c.Name() + operatorapi.OperatorStatusTypeProgressing,
+		Status: operatorapi.ConditionFalse,
+	}
+	secretDegraded := operatorapi.OperatorCondition{
+		Type:   c.name + secretConditionType,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	if shortLivedTokens {
+		missing, msg, err := c.missingSecret(required)
+		if err != nil {
+			return err
+		}
+		if missing {
+			progressing.Status = operatorapi.ConditionTrue
+			progressing.Reason = "WaitingForCredentials"
+			progressing.Message = msg
+		}
+	} else {
+		reason, msg, err := c.checkMintedSecret(cr)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			secretDegraded.Status = operatorapi.ConditionTrue
+			secretDegraded.Reason = reason
+			secretDegraded.Message = msg
+		}
+	}
+
+	_, _, err = v1helpers.UpdateStatus(c.operatorClient,
+		v1helpers.UpdateConditionFn(progressing),
+		v1helpers.UpdateConditionFn(secretDegraded),
+		func(status *operatorapi.OperatorStatus) error {
+			if auditOnly {
+				// The generation was not applied by CSO.
+				return nil
+			}
+			resourcemerge.SetGeneration(&status.Generations, operatorapi.GenerationStatus{
+				Group:          credentialsRequestResource.Group,
+				Resource:       credentialsRequestResource.Resource,
+				Namespace:      cr.GetNamespace(),
+				Name:           cr.GetName(),
+				LastGeneration: cr.GetGeneration(),
+			})
+			return nil
+		})
+	return err
+}
+
+// missingSecret checks that the Secret referenced by the CredentialsRequest
+// exists. In the short-lived token mode it must be created by the cluster
+// admin (typically using ccoctl), CCO does not create it.
+func (c *Controller) missingSecret(cr *unstructured.Unstructured) (bool, string, error) {
+	namespace, _, _ := unstructured.NestedString(cr.Object, "spec", "secretRef", "namespace")
+	name, _, _ := unstructured.NestedString(cr.Object, "spec", "secretRef", "name")
+	_, err := c.secretLister.Secrets(namespace).Get(name)
+	if err == nil {
+		return false, "", nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return false, "", err
+	}
+	msg := fmt.Sprintf("The cluster uses short-lived cloud credentials. Create Secret %s/%s with the cloud role / identity for CredentialsRequest %s, e.g. using ccoctl", namespace, name, cr.GetName())
+	return true, msg, nil
+}
+
+// checkMintedSecret checks the Secret minted by CCO for the CredentialsRequest.
+// It returns reason and message of the problem, when the Secret is not valid
+// or it was not minted in secretTimeout after the CredentialsRequest was
+// created. The message includes CCO failure conditions of the
+// CredentialsRequest, they usually say why the Secret is missing.
+func (c *Controller) checkMintedSecret(cr *unstructured.Unstructured) (string, string, error) {
+	namespace, _, _ := unstructured.NestedString(cr.Object, "spec", "secretRef", "namespace")
+	name, _, _ := unstructured.NestedString(cr.Object, "spec", "secretRef", "name")
+	secret, err := c.secretLister.Secrets(namespace).Get(name)
+	if err == nil {
+		if len(secret.Data) == 0 {
+			return "InvalidCredentialsSecret", fmt.Sprintf("Secret %s/%s of CredentialsRequest %s has no data", namespace, name, cr.GetName()), nil
+		}
+		return "", "", nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return "", "", err
+	}
+
+	if time.Since(cr.GetCreationTimestamp().Time) < secretTimeout {
+		klog.V(4).Infof("Waiting for Secret %s/%s of CredentialsRequest %s", namespace, name, cr.GetName())
+		return "", "", nil
+	}
+	// The message must not change on each sync, it's copied to a condition.
+	msg := fmt.Sprintf("Secret %s/%s of CredentialsRequest %s was not created in %s", namespace, name, cr.GetName(), secretTimeout)
+	conditions, _, _ := unstructured.NestedSlice(cr.Object, "status", "conditions")
+	for _, item := range conditions {
+		cnd, ok := item.(map[string]interface{})
+		if !ok || cnd["status"] != "True" {
+			continue
+		}
+		msg += fmt.Sprintf(": %s: %s", cnd["type"], cnd["message"])
+	}
+	return "CredentialsSecretMissing", msg, nil
+}
+
+func (c *Controller) Run(ctx context.Context, workers int) {
+	// This adds event handlers to informers.
+	ctrl := c.factory.WithSync(health.TrackSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
+	ctrl.Run(ctx, workers)
+}
+
+func (c *Controller) Name() string {
+	return c.name + controllerName
+}
+
+// DeleteCredentialsRequest removes CredentialsRequest defined in given asset
+// from the cluster, so CCO stops provisioning credentials for it. In
+// audit-only mode an existing CredentialsRequest is only reported as drifted
+// and an error is returned, so the caller retries.
+func DeleteCredentialsRequest(ctx context.Context, dynamicClient dynamic.Interface, opSpec *operatorapi.OperatorSpec, assetFunc resourceapply.AssetFunc, asset string, recorder events.Recorder) error {
+	cr, err := readCredentialsRequest(assetFunc, asset)
+	if err != nil {
+		return err
+	}
+	client := dynamicClient.Resource(credentialsRequestResource).Namespace(cr.GetNamespace())
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		return err
+	}
+	if auditOnly {
+		_, err := client.Get(ctx, cr.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		obj := csoutils.DriftedObject{Kind: cr.GetKind(), Namespace: cr.GetNamespace(), Name: cr.GetName()}
+		csoutils.ReportDrift(recorder, obj, true)
+		return fmt.Errorf("%s is not deleted in audit-only mode", obj)
+	}
+	err = client.Delete(ctx, cr.GetName(), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete CredentialsRequest %s: %w", cr.GetName(), err)
+	}
+	recorder.Eventf("CredentialsRequestDeleted", "Deleted CredentialsRequest %s/%s", cr.GetNamespace(), cr.GetName())
+	return nil
+}
+
+// SecretRef returns namespace and name of the Secret with cloud credentials
+// referenced by CredentialsRequest defined in given asset.
+func SecretRef(assetFunc resourceapply.AssetFunc, asset string) (string, string, error) {
+	cr, err := readCredentialsRequest(assetFunc, asset)
+	if err != nil {
+		return "", "", err
+	}
+	namespace, _, _ := unstructured.NestedString(cr.Object, "spec", "secretRef", "namespace")
+	name, _, _ := unstructured.NestedString(cr.Object, "spec", "secretRef", "name")
+	return namespace, name, nil
+}
+
+func readCredentialsRequest(assetFunc resourceapply.AssetFunc, asset string) (*unstructured.Unstructured, error) {
+	cr, err := csoutils.ReadUnstructuredAsset(assetFunc, asset)
+	if err != nil {
+		return nil, err
+	}
+	if cr.GetKind() != credentialsRequestKind {
+		return nil, fmt.Errorf("asset %s is %s, not %s", asset, cr.GetKind(), credentialsRequestKind)
+	}
+	return cr, nil
+}
+
+// factory.PostStartHook to poke newly started controller to resync.
+func initialSync(ctx context.Context, syncContext factory.SyncContext) error {
+	syncContext.Queue().Add(factory.DefaultQueueKey)
+	return nil
+}
@@ -0,0 +1,319 @@
+package credentialsrequest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/testharness"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const (
+	testAsset     = "credentials_request.yaml"
+	testCRName    = "test-csi-driver-operator"
+	testNamespace = "openshift-cloud-credential-operator"
+)
+
+var testCredentialsRequest = []byte(`
+apiVersion: cloudcredential.openshift.io/v1
+kind: CredentialsRequest
+metadata:
+  name: test-csi-driver-operator
+  namespace: openshift-cloud-credential-operator
+spec:
+  secretRef:
+    name: test-cloud-credentials
+    namespace: openshift-cluster-csi-drivers
+  providerSpec:
+    apiVersion: cloudcredential.openshift.io/v1
+    kind: AWSProviderSpec
+`)
+
+func testAssetFunc(name string) ([]byte, error) {
+	return testCredentialsRequest, nil
+}
+
+func newTestController(t *testing.T, objects *csoclients.FakeTestObjects) (*testharness.Harness, *Controller) {
+	h := testharness.New(t, objects)
+	h.Clients.DynamicClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		credentialsRequestResource: "CredentialsRequestList",
+	})
+	ctrl, err := NewController("Test", testAssetFunc, testAsset, h.Clients, h.Recorder, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create controller: %s", err)
+	}
+	h.Start()
+	return h, ctrl.(*Controller)
+}
+
+func TestNewControllerInvalidAsset(t *testing.T) {
+	tests := []struct {
+		name  string
+		asset []byte
+		err   error
+	}{
+		{
+			name: "missing asset",
+			err:  fmt.Errorf("asset not found"),
+		},
+		{
+			name:  "not an object",
+			asset: []byte("not an object"),
+		},
+		{
+			name:  "wrong kind",
+			asset: bytes.Replace(testCredentialsRequest, []byte("kind: CredentialsRequest"), []byte("kind: Secret"), 1),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{})
+			assetFunc := func(name string) ([]byte, error) {
+				return test.asset, test.err
+			}
+			if _, err := NewController("Test", assetFunc, testAsset, clients, events.NewInMemoryRecorder("test"), time.Minute); err == nil {
+				t.Errorf("expected error, got none")
+			}
+		})
+	}
+}
+
+func getCredentialsRequest(t *testing.T, h *testharness.Harness) *unstructured.Unstructured {
+	t.Helper()
+	cr, err := h.Clients.DynamicClient.Resource(credentialsRequestResource).Namespace(testNamespace).Get(context.TODO(), testCRName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get CredentialsRequest: %s", err)
+	}
+	return cr
+}
+
+func TestSyncAppliesCredentialsRequest(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cloud-credentials", Namespace: csoclients.CSIOperatorNamespace},
+		Data:       map[string][]byte{"credentials": []byte("secret")},
+	}
+	h, ctrl := newTestController(t, &csoclients.FakeTestObjects{
+		CoreObjects:     []runtime.Object{secret},
+		OperatorObjects: []runtime.Object{testharness.NewStorage()},
+	})
+	if err := ctrl.Sync(context.TODO(), factory.NewSyncContext(ctrl.Name(), h.Recorder)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cr := getCredentialsRequest(t, h)
+	if cr.GetLabels()[csoutils.ComponentLabel] != ownerComponent {
+		t.Errorf("expected CredentialsRequest to be labeled as owned by %s, got labels %v", ownerComponent, cr.GetLabels())
+	}
+	if _, found, _ := unstructured.NestedString(cr.Object, "spec", "cloudTokenPath"); found {
+		t.Errorf("expected no cloudTokenPath outside of the short-lived token mode")
+	}
+	h.AssertCondition("TestCredentialsSecretDegraded", operatorapi.ConditionFalse)
+	h.AssertCondition("TestCredentialsRequestControllerProgressing", operatorapi.ConditionFalse)
+}
+
+func TestSyncTracksGeneration(t *testing.T) {
+	h, ctrl := newTestController(t, &csoclients.FakeTestObjects{
+		OperatorObjects: []runtime.Object{testharness.NewStorage()},
+	})
+	syncCtx := factory.NewSyncContext(ctrl.Name(), h.Recorder)
+	if err := ctrl.Sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	storage, err := h.Clients.OperatorClientSet.OperatorV1().Storages().Get(context.TODO(), operatorclient.GlobalConfigName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get Storage: %s", err)
+	}
+	generation := resourcemerge.GenerationFor(storage.Status.Generations, credentialsRequestResource.GroupResource(), testNamespace, testCRName)
+	if generation == nil {
+		t.Fatalf("expected generation of CredentialsRequest %s in Storage status", testCRName)
+	}
+
+	// Someone edits the CredentialsRequest, the next sync overwrites it.
+	cr := getCredentialsRequest(t, h)
+	if err := unstructured.SetNestedField(cr.Object, "other-secret", "spec", "secretRef", "name"); err != nil {
+		t.Fatal(err)
+	}
+	cr.SetGeneration(cr.GetGeneration() + 1)
+	if _, err := h.Clients.DynamicClient.Resource(credentialsRequestResource).Namespace(testNamespace).Update(context.TODO(), cr, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update CredentialsRequest: %s", err)
+	}
+	if err := ctrl.Sync(context.TODO(), syncCtx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cr = getCredentialsRequest(t, h)
+	if name, _, _ := unstructured.NestedString(cr.Object, "spec", "secretRef", "name"); name != "test-cloud-credentials" {
+		t.Errorf("expected the CredentialsRequest to be reverted to the asset, got secretRef %s", name)
+	}
+}
+
+func TestDeleteCredentialsRequest(t *testing.T) {
+	h, ctrl := newTestController(t, &csoclients.FakeTestObjects{
+		OperatorObjects: []runtime.Object{testharness.NewStorage()},
+	})
+	if err := ctrl.Sync(context.TODO(), factory.NewSyncContext(ctrl.Name(), h.Recorder)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		// The second delete finds nothing and succeeds.
+		if err := DeleteCredentialsRequest(context.TODO(), h.Clients.DynamicClient, &testharness.NewStorage().Spec.OperatorSpec, testAssetFunc, testAsset, h.Recorder); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	_, err := h.Clients.DynamicClient.Resource(credentialsRequestResource).Namespace(testNamespace).Get(context.TODO(), testCRName, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected CredentialsRequest to be deleted, got %v", err)
+	}
+}
+
+func TestDeleteCredentialsRequestAuditOnly(t *testing.T) {
+	defer csoutils.ResetDrift()
+	storage := testharness.NewStorage()
+	h, ctrl := newTestController(t, &csoclients.FakeTestObjects{
+		OperatorObjects: []runtime.Object{storage},
+	})
+	if err := ctrl.Sync(context.TODO(), factory.NewSyncContext(ctrl.Name(), h.Recorder)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	storage.Spec.UnsupportedConfigOverrides.Raw = []byte(`{"auditOnly":true}`)
+	if err := DeleteCredentialsRequest(context.TODO(), h.Clients.DynamicClient, &storage.Spec.OperatorSpec, testAssetFunc, testAsset, h.Recorder); err == nil {
+		t.Errorf("expected error in audit-only mode, got none")
+	}
+	_, err := h.Clients.DynamicClient.Resource(credentialsRequestResource).Namespace(testNamespace).Get(context.TODO(), testCRName, metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("expected CredentialsRequest to be kept, got %v", err)
+	}
+	if drifted := csoutils.GetDriftedObjects(); len(drifted) != 1 || drifted[0].Name != testCRName {
+		t.Errorf("expected CredentialsRequest %s to be drifted, got %v", testCRName, drifted)
+	}
+}
+
+func TestSyncShortLivedTokens(t *testing.T) {
+	authentication := &configv1.Authentication{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Spec:       configv1.AuthenticationSpec{ServiceAccountIssuer: "https://oidc.example.com"},
+	}
+	cloudCredential := &operatorapi.CloudCredential{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Spec:       operatorapi.CloudCredentialSpec{CredentialsMode: operatorapi.CloudCredentialsModeManual},
+	}
+	h, ctrl := newTestController(t, &csoclients.FakeTestObjects{
+		OperatorObjects: []runtime.Object{testharness.NewStorage(), cloudCredential},
+		ConfigObjects:   []runtime.Object{authentication},
+	})
+	if err := ctrl.Sync(context.TODO(), factory.NewSyncContext(ctrl.Name(), h.Recorder)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cr := getCredentialsRequest(t, h)
+	if path, _, _ := unstructured.NestedString(cr.Object, "spec", "cloudTokenPath"); path != csoutils.BoundSATokenPath {
+		t.Errorf("expected cloudTokenPath %s, got %q", csoutils.BoundSATokenPath, path)
+	}
+	// The admin must create the Secret, CCO does not mint it.
+	h.AssertCondition("TestCredentialsRequestControllerProgressing", operatorapi.ConditionTrue)
+	h.AssertCondition("TestCredentialsSecretDegraded", operatorapi.ConditionFalse)
+}
+
+func TestCheckMintedSecret(t *testing.T) {
+	failedCondition := map[string]interface{}{
+		"type":    "CredentialsProvisionFailure",
+		"status":  "True",
+		"message": "failed to grant creds",
+	}
+	tests := []struct {
+		name            string
+		secret          *corev1.Secret
+		age             time.Duration
+		conditions      []interface{}
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:   "valid Secret",
+			secret: testSecret(map[string][]byte{"credentials": []byte("secret")}),
+			age:    time.Hour,
+		},
+		{
+			name:            "Secret with no data",
+			secret:          testSecret(nil),
+			expectedReason:  "InvalidCredentialsSecret",
+			expectedMessage: "Secret openshift-cluster-csi-drivers/test-cloud-credentials of CredentialsRequest test-csi-driver-operator has no data",
+		},
+		{
+			name: "missing Secret before timeout",
+			age:  time.Minute,
+		},
+		{
+			name:            "missing Secret after timeout",
+			age:             time.Hour,
+			expectedReason:  "CredentialsSecretMissing",
+			expectedMessage: "Secret openshift-cluster-csi-drivers/test-cloud-credentials of CredentialsRequest test-csi-driver-operator was not created in 10m0s",
+		},
+		{
+			name: "missing Secret with CCO conditions",
+			age:  time.Hour,
+			conditions: []interface{}{
+				failedCondition,
+				map[string]interface{}{"type": "Ignored", "status": "False", "message": "ignored"},
+			},
+			expectedReason:  "CredentialsSecretMissing",
+			expectedMessage: "Secret openshift-cluster-csi-drivers/test-cloud-credentials of CredentialsRequest test-csi-driver-operator was not created in 10m0s: CredentialsProvisionFailure: failed to grant creds",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects := &csoclients.FakeTestObjects{}
+			if test.secret != nil {
+				objects.CoreObjects = []runtime.Object{test.secret}
+			}
+			_, ctrl := newTestController(t, objects)
+			cr, err := readCredentialsRequest(testAssetFunc, testAsset)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			cr.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-test.age)))
+			if test.conditions != nil {
+				if err := unstructured.SetNestedSlice(cr.Object, test.conditions, "status", "conditions"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reason, msg, err := ctrl.checkMintedSecret(cr)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if reason != test.expectedReason {
+				t.Errorf("expected reason %q, got %q", test.expectedReason, reason)
+			}
+			if msg != test.expectedMessage {
+				t.Errorf("expected message %q, got %q", test.expectedMessage, msg)
+			}
+		})
+	}
+}
+
+func testSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cloud-credentials", Namespace: csoclients.CSIOperatorNamespace},
+		Data:       data,
+	}
+}
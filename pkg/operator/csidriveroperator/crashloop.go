@@ -0,0 +1,157 @@
+package csidriveroperator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	crashLoopConditionType = "CrashLoopDegraded"
+
+	// crashLoopRestartThreshold is number of restarts of a container of the
+	// CSI driver operator after which the circuit breaker trips.
+	crashLoopRestartThreshold = 10
+
+	// Annotation of ClusterCSIDriver that resumes syncing of the CSI driver
+	// operator Deployment after the circuit breaker tripped. Any change of
+	// its value resumes it. The value is copied to the pod template, so the
+	// crash-looping pods are replaced.
+	crashLoopRetryAnnotation = "storage.openshift.io/crash-loop-retry"
+)
+
+// crashLoopBreaker is state of the crash-loop circuit breaker of a CSI driver
+// operator Deployment. When pods of the current Deployment crash-loop, the
+// breaker trips and the controller stops applying the Deployment, so e.g.
+// rotated credentials or CA bundles do not restart the pods again and
+// again. It resumes when spec or crashLoopRetryAnnotation of ClusterCSIDriver
+// changes. The state is not persisted, CSO restart resumes the controller,
+// which trips again if the pods still crash-loop.
+type crashLoopBreaker struct {
+	tripped bool
+	// generation of ClusterCSIDriver when the breaker tripped.
+	generation int64
+	// retry is value of crashLoopRetryAnnotation when the breaker tripped.
+	retry string
+	// condition reported while the breaker is tripped.
+	condition operatorv1.OperatorCondition
+}
+
+// getCrashLoopRetry returns generation and crashLoopRetryAnnotation of
+// ClusterCSIDriver.
+func (c *CSIDriverOperatorDeploymentController) getCrashLoopRetry() (int64, string, error) {
+	cr, err := c.clusterCSIDriverLister.Get(c.csiOperatorConfig.CSIDriverName)
+	if apierrors.IsNotFound(err) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return cr.Generation, cr.Annotations[crashLoopRetryAnnotation], nil
+}
+
+// injectCrashLoopRetry copies crashLoopRetryAnnotation to the pod template.
+func injectCrashLoopRetry(deployment *appsv1.Deployment, retry string) *appsv1.Deployment {
+	if retry == "" {
+		return deployment
+	}
+	deploymentCopy := deployment.DeepCopy()
+	if deploymentCopy.Spec.Template.Annotations == nil {
+		deploymentCopy.Spec.Template.Annotations = map[string]string{}
+	}
+	deploymentCopy.Spec.Template.Annotations[crashLoopRetryAnnotation] = retry
+	return deploymentCopy
+}
+
+// isCrashLoopBreakerTripped returns true when the Deployment should not be
+// applied. It resets the breaker when ClusterCSIDriver changed since it
+// tripped.
+func (c *CSIDriverOperatorDeploymentController) isCrashLoopBreakerTripped(generation int64, retry string) bool {
+	if !c.crashLoop.tripped {
+		return false
+	}
+	if c.crashLoop.generation == generation && c.crashLoop.retry == retry {
+		return true
+	}
+	klog.V(2).Infof("ClusterCSIDriver %s changed, resuming sync of crash-looping CSI driver operator", c.csiOperatorConfig.CSIDriverName)
+	c.eventRecorder.Eventf("CrashLoopRetry", "Resuming sync of crash-looping CSI driver operator %s", c.csiOperatorConfig.CSIDriverName)
+	c.crashLoop = crashLoopBreaker{}
+	return false
+}
+
+// checkCrashLoop checks pods of the current Deployment configuration and
+// trips the circuit breaker when any of its containers crash-loops. It
+// returns <name>CrashLoopDegraded condition with last termination messages
+// of the containers.
+func (c *CSIDriverOperatorDeploymentController) checkCrashLoop(deployment *appsv1.Deployment, generation int64, retry string) (operatorv1.OperatorCondition, error) {
+	cnd := operatorv1.OperatorCondition{
+		Type:   c.Name() + crashLoopConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return cnd, err
+	}
+	pods, err := c.podLister.Pods(deployment.Namespace).List(selector)
+	if err != nil {
+		return cnd, err
+	}
+
+	var failures []string
+	for _, pod := range pods {
+		// Old pods are replaced by the rollout.
+		if pod.Annotations[csoutils.ConfigHashAnnotation] != deployment.Annotations[csoutils.ConfigHashAnnotation] {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if isCrashLooping(status) {
+				failures = append(failures, fmt.Sprintf("container %s of pod %s restarted %d times: %s", status.Name, pod.Name, status.RestartCount, lastTerminationMessage(status)))
+			}
+		}
+	}
+	if len(failures) == 0 {
+		return cnd, nil
+	}
+
+	sort.Strings(failures)
+	cnd.Status = operatorv1.ConditionTrue
+	cnd.Reason = "CrashLooping"
+	cnd.Message = fmt.Sprintf("The CSI driver operator is crash-looping and it's not updated anymore, fix the issue and change annotation %s of ClusterCSIDriver %s to retry: %s", crashLoopRetryAnnotation, c.csiOperatorConfig.CSIDriverName, strings.Join(failures, "\n"))
+	c.crashLoop = crashLoopBreaker{
+		tripped:    true,
+		generation: generation,
+		retry:      retry,
+		condition:  cnd,
+	}
+	c.eventRecorder.Warningf("CrashLoopDetected", "CSI driver operator %s is crash-looping, stopped syncing its Deployment", c.csiOperatorConfig.CSIDriverName)
+	return cnd, nil
+}
+
+func isCrashLooping(status corev1.ContainerStatus) bool {
+	return status.RestartCount >= crashLoopRestartThreshold &&
+		status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff"
+}
+
+func lastTerminationMessage(status corev1.ContainerStatus) string {
+	terminated := status.LastTerminationState.Terminated
+	if terminated == nil {
+		return "no termination message"
+	}
+	msg := fmt.Sprintf("exit code %d", terminated.ExitCode)
+	if terminated.Reason != "" {
+		msg += ", reason " + terminated.Reason
+	}
+	if terminated.Message != "" {
+		msg += ": " + strings.TrimSpace(terminated.Message)
+	}
+	return msg
+}
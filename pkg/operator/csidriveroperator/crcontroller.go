@@ -9,11 +9,14 @@ import (
 	operatorapi "github.com/openshift/api/operator/v1"
 	opclient "github.com/openshift/client-go/operator/clientset/versioned"
 	oplisters "github.com/openshift/client-go/operator/listers/operator/v1"
-	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 	"github.com/openshift/library-go/pkg/operator/status"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
@@ -42,8 +45,10 @@ type CSIDriverOperatorCRController struct {
 	eventRecorder          events.Recorder
 	factory                *factory.Factory
 	csiDriverName          string
+	assetFunc              resourceapply.AssetFunc
 	csiDriverAsset         string
 	allowDisabled          bool
+	optionalDriver         bool
 }
 
 var _ factory.Controller = &CSIDriverOperatorCRController{}
@@ -97,8 +102,10 @@ func NewCSIDriverOperatorCRController(
 		eventRecorder:          eventRecorder.WithComponentSuffix(name),
 		factory:                f,
 		csiDriverName:          csiOperatorConfig.CSIDriverName,
+		assetFunc:              assettemplate.AssetFunc(csiOperatorConfig.GetAssetFunc(), assettemplate.ClusterValuesFunc(clients.OperatorClient, clients.InfrastructureLister(), clients.ConfigInformers.Config().V1().Networks().Lister(), getImages(csiOperatorConfig))),
 		csiDriverAsset:         csiOperatorConfig.CRAsset,
 		allowDisabled:          csiOperatorConfig.AllowDisabled,
+		optionalDriver:         csiOperatorConfig.OptionalDriver,
 	}
 	return c
 }
@@ -117,8 +124,16 @@ func (c *CSIDriverOperatorCRController) Sync(ctx context.Context, syncCtx factor
 	}
 
 	// Sync CSIDriver CR
-	requiredCR := c.getRequestedClusterCSIDriver(opSpec.LogLevel)
+	requiredCR, err := c.getRequestedClusterCSIDriver(opSpec.LogLevel)
+	if err != nil {
+		return err
+	}
 	cr, _, err := c.applyClusterCSIDriver(requiredCR)
+	if apierrors.IsNotFound(err) && c.optionalDriver {
+		// ClusterCSIDriver of an optional CSI driver was deleted, the
+		// starter stops this controller.
+		return nil
+	}
 	if err != nil {
 		// This will set Degraded condition
 		return err
@@ -142,24 +157,25 @@ func (c *CSIDriverOperatorCRController) Sync(ctx context.Context, syncCtx factor
 	return errors.NewAggregate(errs)
 }
 
-func (c *CSIDriverOperatorCRController) getRequestedClusterCSIDriver(logLevel operatorapi.LogLevel) *operatorapi.ClusterCSIDriver {
+func (c *CSIDriverOperatorCRController) getRequestedClusterCSIDriver(logLevel operatorapi.LogLevel) (*operatorapi.ClusterCSIDriver, error) {
 	if logLevel == "" {
 		logLevel = operatorapi.Normal
 	}
-	assetBytes, err := assets.ReadFile(c.csiDriverAsset)
+	assetBytes, err := c.assetFunc(c.csiDriverAsset)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	cr := readClusterCSIDriverOrDie(assetBytes)
 	cr.Spec.LogLevel = logLevel
 	cr.Spec.OperatorLogLevel = logLevel
 	cr.Spec.ManagementState = operatorapi.Managed
-	return cr
+	csoutils.SetOwnedByLabel(cr, OwnerComponent)
+	return cr, nil
 }
 
 func (c *CSIDriverOperatorCRController) Run(ctx context.Context, workers int) {
 	// This adds event handlers to informers.
-	ctrl := c.factory.WithSync(c.Sync).ToController(c.Name(), c.eventRecorder)
+	ctrl := c.factory.WithSync(health.TrackSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
 	ctrl.Run(ctx, workers)
 }
 
@@ -173,6 +189,11 @@ func (c *CSIDriverOperatorCRController) crConditionName(cndType string) string {
 
 func (c *CSIDriverOperatorCRController) applyClusterCSIDriver(required *operatorapi.ClusterCSIDriver) (*operatorapi.ClusterCSIDriver, bool, error) {
 	existing, err := c.operatorClientSet.OperatorV1().ClusterCSIDrivers().Get(context.TODO(), required.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) && c.optionalDriver {
+		// ClusterCSIDriver of an optional CSI driver is created by the
+		// cluster admin.
+		return nil, false, err
+	}
 	if apierrors.IsNotFound(err) {
 		actual, err := c.operatorClientSet.OperatorV1().ClusterCSIDrivers().Create(context.TODO(), required, metav1.CreateOptions{})
 		reportCreateEvent(c.eventRecorder, required, err)
@@ -182,6 +203,16 @@ func (c *CSIDriverOperatorCRController) applyClusterCSIDriver(required *operator
 		return nil, false, err
 	}
 
+	if existing.Labels[csoutils.OwnedByLabel] != csoutils.OwnedByLabelValue {
+		// Label ClusterCSIDrivers created by older CSO versions.
+		existingCopy := existing.DeepCopy()
+		csoutils.SetOwnedByLabel(existingCopy, OwnerComponent)
+		actual, err := c.operatorClientSet.OperatorV1().ClusterCSIDrivers().Update(context.TODO(), existingCopy, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, false, err
+		}
+		return actual.DeepCopy(), true, nil
+	}
 	return existing.DeepCopy(), false, nil
 }
 
@@ -0,0 +1,40 @@
+package csioperatorclient
+
+import (
+	"os"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
+)
+
+const (
+	AlibabaDiskCSIDriverName          = "diskplugin.csi.alibabacloud.com"
+	envAlibabaDiskDriverOperatorImage = "ALIBABA_DISK_DRIVER_OPERATOR_IMAGE"
+	envAlibabaDiskDriverImage         = "ALIBABA_DISK_DRIVER_IMAGE"
+)
+
+func GetAlibabaDiskCSIOperatorConfig() CSIOperatorConfig {
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envAlibabaDiskDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envAlibabaDiskDriverImage),
+	}
+
+	return CSIOperatorConfig{
+		CSIDriverName:   AlibabaDiskCSIDriverName,
+		ConditionPrefix: "AlibabaDisk",
+		Platform:        configv1.AlibabaCloudPlatformType,
+		StaticAssets: []string{
+			"csidriveroperators/alibaba-disk/02_sa.yaml",
+			"csidriveroperators/alibaba-disk/03_role.yaml",
+			"csidriveroperators/alibaba-disk/04_rolebinding.yaml",
+			"csidriveroperators/alibaba-disk/05_clusterrole.yaml",
+			"csidriveroperators/alibaba-disk/06_clusterrolebinding.yaml",
+		},
+		CredentialsRequestAsset: "csidriveroperators/alibaba-disk/01_credentials_request.yaml",
+		CRAsset:                 "csidriveroperators/alibaba-disk/08_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/alibaba-disk/07_deployment.yaml",
+		Images:                  images,
+		VolumeCloning:           true,
+		AllowDisabled:           false,
+	}
+}
@@ -2,21 +2,30 @@ package csioperatorclient
 
 import (
 	"os"
+	"sort"
 	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 )
 
 const (
 	AWSEBSCSIDriverName          = "ebs.csi.aws.com"
 	envAWSEBSDriverOperatorImage = "AWS_EBS_DRIVER_OPERATOR_IMAGE"
 	envAWSEBSDriverImage         = "AWS_EBS_DRIVER_IMAGE"
+
+	// AWSServiceEndpointsEnv is env. var of AWS CSI driver operators with
+	// custom AWS service endpoints from Infrastructure, as comma-separated
+	// <service name>=<URL> pairs sorted by the service name, e.g.
+	// "ec2=https://ec2.example.com,sts=https://sts.example.com". They're
+	// used in C2S, GovCloud and PrivateLink clusters.
+	AWSServiceEndpointsEnv = "AWS_SERVICE_ENDPOINTS"
 )
 
 func GetAWSEBSCSIOperatorConfig() CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envAWSEBSDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envAWSEBSDriverImage),
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envAWSEBSDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envAWSEBSDriverImage),
 	}
 
 	return CSIOperatorConfig{
@@ -32,10 +41,18 @@ func GetAWSEBSCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/aws-ebs/07_role_aws_config.yaml",
 			"csidriveroperators/aws-ebs/08_rolebinding_aws_config.yaml",
 		},
-		CRAsset:         "csidriveroperators/aws-ebs/10_cr.yaml",
-		DeploymentAsset: "csidriveroperators/aws-ebs/09_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CredentialsRequestAsset: "csidriveroperators/aws-ebs/01_credentials_request.yaml",
+		BoundSAToken:            &BoundSATokenConfig{Audience: shortLivedTokenAudience, CloudIdentity: awsSTSIdentity},
+		CRAsset:                 "csidriveroperators/aws-ebs/10_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/aws-ebs/09_deployment.yaml",
+		Images:                  images,
+		StorageClassEncryption:  true,
+		NonGracefulShutdown:     true,
+		ReadWriteOncePod:        true,
+		InfrastructureEnv:       awsServiceEndpointsEnv,
+		PreflightChecks:         []PreflightCheck{awsRegionCheck},
+		VolumeSnapshotClass:     &VolumeSnapshotClassConfig{Name: "csi-aws-vsc"},
+		AllowDisabled:           false,
 		/* For reference / experiments only. OpenShift does not support
 		   update from OLM-based AWS EBS operator to CVO/CSO one.
 		OLMOptions: &OLMOptions{
@@ -50,3 +67,18 @@ func GetAWSEBSCSIOperatorConfig() CSIOperatorConfig {
 		*/
 	}
 }
+
+// awsServiceEndpointsEnv returns AWSServiceEndpointsEnv with custom AWS
+// service endpoints of the cluster, if any.
+func awsServiceEndpointsEnv(infra *configv1.Infrastructure) map[string]string {
+	status := infra.Status.PlatformStatus
+	if status == nil || status.AWS == nil || len(status.AWS.ServiceEndpoints) == 0 {
+		return nil
+	}
+	var endpoints []string
+	for _, endpoint := range status.AWS.ServiceEndpoints {
+		endpoints = append(endpoints, endpoint.Name+"="+endpoint.URL)
+	}
+	sort.Strings(endpoints)
+	return map[string]string{AWSServiceEndpointsEnv: strings.Join(endpoints, ",")}
+}
@@ -2,9 +2,9 @@ package csioperatorclient
 
 import (
 	"os"
-	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 )
 
 const (
@@ -12,13 +12,19 @@ const (
 	envAzureDiskDriverOperatorImage = "AZURE_DISK_DRIVER_OPERATOR_IMAGE"
 	envAzureDiskDriverImage         = "AZURE_DISK_DRIVER_IMAGE"
 	envCCMOperatorImage             = "CLUSTER_CLOUD_CONTROLLER_MANAGER_OPERATOR_IMAGE"
+
+	// AzureEnvironmentEnv is env. var of Azure CSI driver operators with
+	// name of the Azure cloud environment of the cluster, e.g.
+	// AzureUSGovernmentCloud or AzureStackCloud. The operators configure
+	// cloud API endpoints of the CSI driver from it.
+	AzureEnvironmentEnv = "AZURE_ENVIRONMENT"
 )
 
 func GetAzureDiskCSIOperatorConfig() CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envAzureDiskDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envAzureDiskDriverImage),
-		"${CLUSTER_CLOUD_CONTROLLER_MANAGER_OPERATOR_IMAGE}", os.Getenv(envCCMOperatorImage),
+	images := map[string]string{
+		assettemplate.ImageOperator:             os.Getenv(envAzureDiskDriverOperatorImage),
+		assettemplate.ImageDriver:               os.Getenv(envAzureDiskDriverImage),
+		"ClusterCloudControllerManagerOperator": os.Getenv(envCCMOperatorImage),
 	}
 
 	return CSIOperatorConfig{
@@ -32,9 +38,38 @@ func GetAzureDiskCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/azure-disk/06_clusterrole.yaml",
 			"csidriveroperators/azure-disk/07_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/azure-disk/09_cr.yaml",
-		DeploymentAsset: "csidriveroperators/azure-disk/08_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CredentialsRequestAsset: "csidriveroperators/azure-disk/02_credentials_request.yaml",
+		BoundSAToken:            &BoundSATokenConfig{Audience: shortLivedTokenAudience, CloudIdentity: azureWorkloadIdentity},
+		CRAsset:                 "csidriveroperators/azure-disk/09_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/azure-disk/08_deployment.yaml",
+		Images:                  images,
+		StorageClassEncryption:  true,
+		StorageClassTopology:    true,
+		NonGracefulShutdown:     true,
+		ReadWriteOncePod:        true,
+		VolumeCloning:           true,
+		InfrastructureEnv:       azureEnvironmentEnv,
+		PreflightChecks:         []PreflightCheck{azureResourceGroupCheck},
+		AllowDisabled:           false,
+		VolumeSnapshotClass: &VolumeSnapshotClassConfig{
+			Name:       "csi-azuredisk-vsc",
+			Parameters: map[string]string{"incremental": "true"},
+		},
 	}
 }
+
+// getAzureCloudName returns Azure cloud environment of the cluster. Clusters
+// installed before the cloud name was reported run in the public cloud.
+func getAzureCloudName(infrastructure *configv1.Infrastructure) configv1.AzureCloudEnvironment {
+	platformStatus := infrastructure.Status.PlatformStatus
+	if platformStatus == nil || platformStatus.Azure == nil || platformStatus.Azure.CloudName == "" {
+		return configv1.AzurePublicCloud
+	}
+	return platformStatus.Azure.CloudName
+}
+
+// azureEnvironmentEnv returns AzureEnvironmentEnv with the cloud environment
+// of the cluster.
+func azureEnvironmentEnv(infrastructure *configv1.Infrastructure) map[string]string {
+	return map[string]string{AzureEnvironmentEnv: string(getAzureCloudName(infrastructure))}
+}
@@ -2,9 +2,9 @@ package csioperatorclient
 
 import (
 	"os"
-	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 )
 
 const (
@@ -14,10 +14,10 @@ const (
 )
 
 func GetAzureFileCSIOperatorConfig() CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envAzureFileDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envAzureFileDriverImage),
-		"${CLUSTER_CLOUD_CONTROLLER_MANAGER_OPERATOR_IMAGE}", os.Getenv(envCCMOperatorImage),
+	images := map[string]string{
+		assettemplate.ImageOperator:             os.Getenv(envAzureFileDriverOperatorImage),
+		assettemplate.ImageDriver:               os.Getenv(envAzureFileDriverImage),
+		"ClusterCloudControllerManagerOperator": os.Getenv(envCCMOperatorImage),
 	}
 
 	return CSIOperatorConfig{
@@ -31,10 +31,22 @@ func GetAzureFileCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/azure-file/06_clusterrole.yaml",
 			"csidriveroperators/azure-file/07_clusterrolebinding.yaml",
 		},
-		CRAsset:            "csidriveroperators/azure-file/09_cr.yaml",
-		DeploymentAsset:    "csidriveroperators/azure-file/08_deployment.yaml",
-		ImageReplacer:      strings.NewReplacer(pairs...),
-		AllowDisabled:      false,
-		RequireFeatureGate: "CSIDriverAzureFile",
+		CredentialsRequestAsset: "csidriveroperators/azure-file/02_credentials_request.yaml",
+		BoundSAToken:            &BoundSATokenConfig{Audience: shortLivedTokenAudience, CloudIdentity: azureWorkloadIdentity},
+		CRAsset:                 "csidriveroperators/azure-file/09_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/azure-file/08_deployment.yaml",
+		Images:                  images,
+		ReadWriteOncePod:        true,
+		AllowDisabled:           false,
+		RequireFeatureGates:     []string{"CSIDriverAzureFile"},
+		StatusFilter:            isNotAzureStackHub,
+		InfrastructureEnv:       azureEnvironmentEnv,
+		PreflightChecks:         []PreflightCheck{azureResourceGroupCheck},
 	}
 }
+
+// isNotAzureStackHub returns false on Azure Stack Hub, it does not provide
+// Azure File service.
+func isNotAzureStackHub(infrastructure *configv1.Infrastructure, fg *configv1.FeatureGate) bool {
+	return getAzureCloudName(infrastructure) != configv1.AzureStackCloud
+}
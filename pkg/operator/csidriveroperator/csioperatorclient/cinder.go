@@ -2,9 +2,9 @@ package csioperatorclient
 
 import (
 	"os"
-	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/library-go/pkg/operator/events"
 )
@@ -16,9 +16,9 @@ const (
 )
 
 func GetOpenStackCinderCSIOperatorConfig(clients *csoclients.Clients, recorder events.Recorder) CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envOpenStackCinderDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envOpenStackCinderDriverImage),
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envOpenStackCinderDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envOpenStackCinderDriverImage),
 	}
 
 	return CSIOperatorConfig{
@@ -32,9 +32,15 @@ func GetOpenStackCinderCSIOperatorConfig(clients *csoclients.Clients, recorder e
 			"csidriveroperators/openstack-cinder/05_clusterrole.yaml",
 			"csidriveroperators/openstack-cinder/06_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/openstack-cinder/08_cr.yaml",
-		DeploymentAsset: "csidriveroperators/openstack-cinder/07_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CredentialsRequestAsset: "csidriveroperators/openstack-cinder/01_credentials_request.yaml",
+		CRAsset:                 "csidriveroperators/openstack-cinder/08_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/openstack-cinder/07_deployment.yaml",
+		Images:                  images,
+		NonGracefulShutdown:     true,
+		ReadWriteOncePod:        true,
+		VolumeCloning:           true,
+		VolumeSnapshotClass:     &VolumeSnapshotClassConfig{Name: "standard-csi"},
+		AllowDisabled:           false,
+		CustomCABundle:          true,
 	}
 }
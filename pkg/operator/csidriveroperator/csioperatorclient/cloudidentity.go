@@ -0,0 +1,88 @@
+package csioperatorclient
+
+import (
+	"encoding/json"
+	"fmt"
+
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+)
+
+// CloudIdentityConfig is cloud identity of a CSI driver on clusters with
+// short-lived credentials. The cluster admin sets it in ClusterCSIDriver,
+// see csoutils.GetCloudIdentity.
+type CloudIdentityConfig struct {
+	// Mode is human readable name of the credentials mode, used in
+	// conditions, e.g. "AWS STS".
+	Mode string
+	// Keys are all required keys of the identity.
+	Keys []string
+	// SecretDataFunc returns data of the cloud credentials Secret for the
+	// identity. The identity has all Keys.
+	SecretDataFunc func(identity map[string]string) (map[string][]byte, error)
+}
+
+const (
+	AWSRoleARNKey = "roleARN"
+
+	AzureClientIDKey       = "clientID"
+	AzureTenantIDKey       = "tenantID"
+	AzureSubscriptionIDKey = "subscriptionID"
+	AzureRegionKey         = "region"
+
+	GCPWorkloadIdentityProviderKey = "workloadIdentityProvider"
+	GCPServiceAccountEmailKey      = "serviceAccountEmail"
+)
+
+// awsSTSIdentity is the same credentials file as ccoctl creates for AWS
+// STS.
+var awsSTSIdentity = &CloudIdentityConfig{
+	Mode: "AWS STS",
+	Keys: []string{AWSRoleARNKey},
+	SecretDataFunc: func(identity map[string]string) (map[string][]byte, error) {
+		credentials := fmt.Sprintf("[default]\nsts_regional_endpoints = regional\nrole_arn = %s\nweb_identity_token_file = %s\n", identity[AWSRoleARNKey], csoutils.BoundSATokenPath)
+		return map[string][]byte{"credentials": []byte(credentials)}, nil
+	},
+}
+
+// azureWorkloadIdentity are the same Secret keys as ccoctl creates for
+// Azure Workload Identity.
+var azureWorkloadIdentity = &CloudIdentityConfig{
+	Mode: "Azure Workload Identity",
+	Keys: []string{AzureClientIDKey, AzureTenantIDKey, AzureSubscriptionIDKey, AzureRegionKey},
+	SecretDataFunc: func(identity map[string]string) (map[string][]byte, error) {
+		return map[string][]byte{
+			"azure_client_id":            []byte(identity[AzureClientIDKey]),
+			"azure_tenant_id":            []byte(identity[AzureTenantIDKey]),
+			"azure_subscription_id":      []byte(identity[AzureSubscriptionIDKey]),
+			"azure_region":               []byte(identity[AzureRegionKey]),
+			"azure_federated_token_file": []byte(csoutils.BoundSATokenPath),
+		}, nil
+	},
+}
+
+// gcpWorkloadIdentity is external_account credentials config of GCP
+// Workload Identity Federation, the same as ccoctl creates.
+var gcpWorkloadIdentity = &CloudIdentityConfig{
+	Mode: "GCP Workload Identity",
+	Keys: []string{GCPWorkloadIdentityProviderKey, GCPServiceAccountEmailKey},
+	SecretDataFunc: func(identity map[string]string) (map[string][]byte, error) {
+		config := map[string]interface{}{
+			"type":                              "external_account",
+			"audience":                          "//iam.googleapis.com/" + identity[GCPWorkloadIdentityProviderKey],
+			"subject_token_type":                "urn:ietf:params:oauth:token-type:jwt",
+			"token_url":                         "https://sts.googleapis.com/v1/token",
+			"service_account_impersonation_url": fmt.Sprintf("https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken", identity[GCPServiceAccountEmailKey]),
+			"credential_source": map[string]interface{}{
+				"file": csoutils.BoundSATokenPath,
+				"format": map[string]string{
+					"type": "text",
+				},
+			},
+		}
+		data, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{"service_account.json": data}, nil
+	},
+}
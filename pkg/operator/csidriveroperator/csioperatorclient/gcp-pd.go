@@ -2,9 +2,9 @@ package csioperatorclient
 
 import (
 	"os"
-	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 )
 
 const (
@@ -14,9 +14,9 @@ const (
 )
 
 func GetGCPPDCSIOperatorConfig() CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envGCPPDDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envGCPPDDriverImage),
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envGCPPDDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envGCPPDDriverImage),
 	}
 
 	return CSIOperatorConfig{
@@ -30,9 +30,17 @@ func GetGCPPDCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/gcp-pd/05_clusterrole.yaml",
 			"csidriveroperators/gcp-pd/06_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/gcp-pd/08_cr.yaml",
-		DeploymentAsset: "csidriveroperators/gcp-pd/07_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CredentialsRequestAsset: "csidriveroperators/gcp-pd/01_credentials_request.yaml",
+		BoundSAToken:            &BoundSATokenConfig{Audience: shortLivedTokenAudience, CloudIdentity: gcpWorkloadIdentity},
+		CRAsset:                 "csidriveroperators/gcp-pd/08_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/gcp-pd/07_deployment.yaml",
+		Images:                  images,
+		StorageClassEncryption:  true,
+		NonGracefulShutdown:     true,
+		ReadWriteOncePod:        true,
+		VolumeCloning:           true,
+		PreflightChecks:         []PreflightCheck{gcpProjectCheck},
+		VolumeSnapshotClass:     &VolumeSnapshotClassConfig{Name: "csi-gce-pd-vsc"},
+		AllowDisabled:           false,
 	}
 }
@@ -0,0 +1,45 @@
+package csioperatorclient
+
+import (
+	"os"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
+)
+
+const (
+	HostPathCSIDriverName          = "kubevirt.io.hostpath-provisioner"
+	envHostPathDriverOperatorImage = "HOSTPATH_DRIVER_OPERATOR_IMAGE"
+	envHostPathDriverImage         = "HOSTPATH_DRIVER_IMAGE"
+
+	// HostPathFeatureGate enables the reference hostpath CSI driver on
+	// clusters without a cloud platform. Volumes of the driver are local
+	// directories of a node, it's meant for small clusters that have no
+	// other dynamic storage.
+	HostPathFeatureGate = "CSIDriverHostPath"
+)
+
+func GetHostPathCSIOperatorConfig() CSIOperatorConfig {
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envHostPathDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envHostPathDriverImage),
+	}
+
+	return CSIOperatorConfig{
+		CSIDriverName:   HostPathCSIDriverName,
+		ConditionPrefix: "HostPath",
+		Platform:        configv1.NonePlatformType,
+		StaticAssets: []string{
+			"csidriveroperators/hostpath/02_sa.yaml",
+			"csidriveroperators/hostpath/03_role.yaml",
+			"csidriveroperators/hostpath/04_rolebinding.yaml",
+			"csidriveroperators/hostpath/05_clusterrole.yaml",
+			"csidriveroperators/hostpath/06_clusterrolebinding.yaml",
+		},
+		CRAsset:             "csidriveroperators/hostpath/08_cr.yaml",
+		DeploymentAsset:     "csidriveroperators/hostpath/07_deployment.yaml",
+		Images:              images,
+		AllowDisabled:       false,
+		RequireFeatureGates: []string{HostPathFeatureGate},
+	}
+}
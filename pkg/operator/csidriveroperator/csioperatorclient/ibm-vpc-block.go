@@ -0,0 +1,71 @@
+package csioperatorclient
+
+import (
+	"fmt"
+	"os"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
+)
+
+const (
+	IBMVPCBlockCSIDriverName          = "vpc.block.csi.ibm.io"
+	envIBMVPCBlockDriverOperatorImage = "IBM_VPC_BLOCK_DRIVER_OPERATOR_IMAGE"
+	envIBMVPCBlockDriverImage         = "IBM_VPC_BLOCK_DRIVER_IMAGE"
+)
+
+func GetIBMVPCBlockCSIOperatorConfig() CSIOperatorConfig {
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envIBMVPCBlockDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envIBMVPCBlockDriverImage),
+	}
+
+	return CSIOperatorConfig{
+		CSIDriverName:   IBMVPCBlockCSIDriverName,
+		ConditionPrefix: "IBMVPCBlock",
+		Platform:        configv1.IBMCloudPlatformType,
+		StaticAssets: []string{
+			"csidriveroperators/ibm-vpc-block/02_sa.yaml",
+			"csidriveroperators/ibm-vpc-block/03_role.yaml",
+			"csidriveroperators/ibm-vpc-block/04_rolebinding.yaml",
+			"csidriveroperators/ibm-vpc-block/05_clusterrole.yaml",
+			"csidriveroperators/ibm-vpc-block/06_clusterrolebinding.yaml",
+		},
+		CredentialsRequestAsset: "csidriveroperators/ibm-vpc-block/01_credentials_request.yaml",
+		CRAsset:                 "csidriveroperators/ibm-vpc-block/08_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/ibm-vpc-block/07_deployment.yaml",
+		Images:                  images,
+		StorageClassEncryption:  true,
+		StatusFilter:            isIBMCloudVPC,
+		RolloutSecrets:          []string{"ibm-cloud-credentials"},
+		VolumeSnapshotClass:     &VolumeSnapshotClassConfig{Name: "vpc-block-snapshot"},
+		AllowDisabled:           false,
+	}
+}
+
+// isIBMCloudVPC returns true on IBM Cloud VPC (Gen2) clusters. Classic
+// infrastructure has no CSI driver shipped with OpenShift.
+func isIBMCloudVPC(infrastructure *configv1.Infrastructure, fg *configv1.FeatureGate) bool {
+	return getIBMCloudProviderType(infrastructure) == configv1.IBMCloudProviderTypeVPC
+}
+
+func getIBMCloudProviderType(infrastructure *configv1.Infrastructure) configv1.IBMCloudProviderType {
+	platformStatus := infrastructure.Status.PlatformStatus
+	if platformStatus == nil || platformStatus.IBMCloud == nil {
+		return ""
+	}
+	return platformStatus.IBMCloud.ProviderType
+}
+
+// checkIBMCloudVariant returns an error on IBM Cloud clusters that are
+// neither VPC nor Classic.
+func checkIBMCloudVariant(infrastructure *configv1.Infrastructure) error {
+	switch providerType := getIBMCloudProviderType(infrastructure); providerType {
+	case configv1.IBMCloudProviderTypeVPC, configv1.IBMCloudProviderTypeClassic:
+		return nil
+	case "":
+		return fmt.Errorf("IBM Cloud infrastructure does not report its provider type, expected %s or %s", configv1.IBMCloudProviderTypeVPC, configv1.IBMCloudProviderTypeClassic)
+	default:
+		return fmt.Errorf("IBM Cloud provider type %s is not supported, expected %s or %s", providerType, configv1.IBMCloudProviderTypeVPC, configv1.IBMCloudProviderTypeClassic)
+	}
+}
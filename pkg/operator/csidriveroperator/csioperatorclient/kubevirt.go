@@ -0,0 +1,44 @@
+package csioperatorclient
+
+import (
+	"os"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
+)
+
+const (
+	KubeVirtCSIDriverName          = "csi.kubevirt.io"
+	envKubeVirtDriverOperatorImage = "KUBEVIRT_DRIVER_OPERATOR_IMAGE"
+	envKubeVirtDriverImage         = "KUBEVIRT_DRIVER_IMAGE"
+
+	// Env. vars of the KubeVirt CSI driver operator with path to kubeconfig
+	// of the infra cluster and namespace of the VMs there.
+	InfraClusterKubeconfigEnv = "INFRA_CLUSTER_KUBECONFIG"
+	InfraClusterNamespaceEnv  = "INFRA_CLUSTER_NAMESPACE"
+)
+
+func GetKubeVirtCSIOperatorConfig() CSIOperatorConfig {
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envKubeVirtDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envKubeVirtDriverImage),
+	}
+
+	return CSIOperatorConfig{
+		CSIDriverName:   KubeVirtCSIDriverName,
+		ConditionPrefix: "KubeVirt",
+		Platform:        configv1.KubevirtPlatformType,
+		StaticAssets: []string{
+			"csidriveroperators/kubevirt/02_sa.yaml",
+			"csidriveroperators/kubevirt/03_role.yaml",
+			"csidriveroperators/kubevirt/04_rolebinding.yaml",
+			"csidriveroperators/kubevirt/05_clusterrole.yaml",
+			"csidriveroperators/kubevirt/06_clusterrolebinding.yaml",
+		},
+		CRAsset:              "csidriveroperators/kubevirt/08_cr.yaml",
+		DeploymentAsset:      "csidriveroperators/kubevirt/07_deployment.yaml",
+		Images:               images,
+		KubeVirtInfraCluster: true,
+		AllowDisabled:        false,
+	}
+}
@@ -2,9 +2,9 @@ package csioperatorclient
 
 import (
 	"os"
-	"strings"
 
 	v1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -16,16 +16,18 @@ import (
 const (
 	CloudConfigName = "cloud-provider-config"
 
+	manilaNamespace = "openshift-manila-csi-driver"
+
 	envManilaDriverOperatorImage = "MANILA_DRIVER_OPERATOR_IMAGE"
 	envManilaDriverImage         = "MANILA_DRIVER_IMAGE"
 	envNFSDriverImage            = "MANILA_NFS_DRIVER_IMAGE"
 )
 
 func GetManilaOperatorConfig(clients *csoclients.Clients, recorder events.Recorder) CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envManilaDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envManilaDriverImage),
-		"${NFS_DRIVER_IMAGE}", os.Getenv(envNFSDriverImage),
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envManilaDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envManilaDriverImage),
+		"NFSDriver":                 os.Getenv(envNFSDriverImage),
 	}
 
 	return CSIOperatorConfig{
@@ -40,13 +42,20 @@ func GetManilaOperatorConfig(clients *csoclients.Clients, recorder events.Record
 			"csidriveroperators/manila/05_clusterrole.yaml",
 			"csidriveroperators/manila/06_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/manila/08_cr.yaml",
-		DeploymentAsset: "csidriveroperators/manila/07_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
+		CredentialsRequestAsset: "csidriveroperators/manila/00_credentials_request.yaml",
+		CRAsset:                 "csidriveroperators/manila/08_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/manila/07_deployment.yaml",
+		Images:                  images,
 		ExtraControllers: []factory.Controller{
 			newCertificateSyncerOrDie(clients, recorder),
 		},
-		AllowDisabled: true,
+		AllowDisabled:     true,
+		RolloutConfigMaps: []string{CloudConfigName},
+		CustomCABundle:    true,
+		OperandNamespaces: []string{
+			csoclients.CSIOperatorNamespace,
+			manilaNamespace,
+		},
 		OLMOptions: &OLMOptions{
 			OLMOperatorDeploymentName: "csi-driver-manila-operator",
 
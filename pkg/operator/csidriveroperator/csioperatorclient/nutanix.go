@@ -0,0 +1,43 @@
+package csioperatorclient
+
+import (
+	"os"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
+)
+
+const (
+	NutanixCSIDriverName          = "csi.nutanix.com"
+	envNutanixDriverOperatorImage = "NUTANIX_DRIVER_OPERATOR_IMAGE"
+	envNutanixDriverImage         = "NUTANIX_DRIVER_IMAGE"
+
+	// NutanixPlatformType is PlatformType of Nutanix clusters. It's not
+	// available in the vendored openshift/api yet.
+	NutanixPlatformType configv1.PlatformType = "Nutanix"
+)
+
+func GetNutanixCSIOperatorConfig() CSIOperatorConfig {
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envNutanixDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envNutanixDriverImage),
+	}
+
+	return CSIOperatorConfig{
+		CSIDriverName:   NutanixCSIDriverName,
+		ConditionPrefix: "Nutanix",
+		Platform:        NutanixPlatformType,
+		StaticAssets: []string{
+			"csidriveroperators/nutanix/02_sa.yaml",
+			"csidriveroperators/nutanix/03_role.yaml",
+			"csidriveroperators/nutanix/04_rolebinding.yaml",
+			"csidriveroperators/nutanix/05_clusterrole.yaml",
+			"csidriveroperators/nutanix/06_clusterrolebinding.yaml",
+		},
+		CredentialsRequestAsset: "csidriveroperators/nutanix/01_credentials_request.yaml",
+		CRAsset:                 "csidriveroperators/nutanix/08_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/nutanix/07_deployment.yaml",
+		Images:                  images,
+		AllowDisabled:           false,
+	}
+}
@@ -2,9 +2,9 @@ package csioperatorclient
 
 import (
 	"os"
-	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/library-go/pkg/operator/events"
 )
@@ -16,9 +16,9 @@ const (
 )
 
 func GetOVirtCSIOperatorConfig(clients *csoclients.Clients, recorder events.Recorder) CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envOVirtDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envOVirtDriverImage),
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envOVirtDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envOVirtDriverImage),
 	}
 
 	return CSIOperatorConfig{
@@ -32,9 +32,11 @@ func GetOVirtCSIOperatorConfig(clients *csoclients.Clients, recorder events.Reco
 			"csidriveroperators/ovirt/05_clusterrole.yaml",
 			"csidriveroperators/ovirt/06_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/ovirt/08_cr.yaml",
-		DeploymentAsset: "csidriveroperators/ovirt/07_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CredentialsRequestAsset: "csidriveroperators/ovirt/01_credentials_request.yaml",
+		CRAsset:                 "csidriveroperators/ovirt/08_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/ovirt/07_deployment.yaml",
+		Images:                  images,
+		NonGracefulShutdown:     true,
+		AllowDisabled:           false,
 	}
 }
@@ -0,0 +1,92 @@
+package csioperatorclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PreflightCheck verifies that a cluster can run a CSI driver before CSO
+// starts its operator. A failed check is reported in Degraded condition and
+// the operator is not started until the check passes, instead of letting
+// the CSI driver crash-loop. Errors should tell the cluster admin how to
+// fix the cluster.
+type PreflightCheck struct {
+	// Name of the check, used in conditions.
+	Name string
+	// Run runs the check.
+	Run func(ctx context.Context, clients *csoclients.Clients, infrastructure *configv1.Infrastructure) error
+}
+
+var awsRegionCheck = PreflightCheck{
+	Name: "AWSRegion",
+	Run: func(ctx context.Context, clients *csoclients.Clients, infrastructure *configv1.Infrastructure) error {
+		platformStatus := infrastructure.Status.PlatformStatus
+		if platformStatus == nil || platformStatus.AWS == nil || platformStatus.AWS.Region == "" {
+			return fmt.Errorf("Infrastructure %s does not report AWS region in status.platformStatus.aws.region, the CSI driver can't reach EC2 API", infrastructure.Name)
+		}
+		return nil
+	},
+}
+
+var gcpProjectCheck = PreflightCheck{
+	Name: "GCPProject",
+	Run: func(ctx context.Context, clients *csoclients.Clients, infrastructure *configv1.Infrastructure) error {
+		platformStatus := infrastructure.Status.PlatformStatus
+		if platformStatus == nil || platformStatus.GCP == nil || platformStatus.GCP.ProjectID == "" {
+			return fmt.Errorf("Infrastructure %s does not report GCP project in status.platformStatus.gcp.projectID, the CSI driver can't reach Compute Engine API", infrastructure.Name)
+		}
+		return nil
+	},
+}
+
+var azureResourceGroupCheck = PreflightCheck{
+	Name: "AzureResourceGroup",
+	Run: func(ctx context.Context, clients *csoclients.Clients, infrastructure *configv1.Infrastructure) error {
+		platformStatus := infrastructure.Status.PlatformStatus
+		if platformStatus == nil || platformStatus.Azure == nil || platformStatus.Azure.ResourceGroupName == "" {
+			return fmt.Errorf("Infrastructure %s does not report Azure resource group in status.platformStatus.azure.resourceGroupName, the CSI driver can't create disks", infrastructure.Name)
+		}
+		return nil
+	},
+}
+
+// vSphereCloudConfigCheck verifies that the vSphere cloud config lists all
+// vCenters and datacenters of the cluster. When the cluster spans several of
+// them, the CSI driver provisions volumes only with topology and all nodes
+// must be labeled with their zone.
+var vSphereCloudConfigCheck = PreflightCheck{
+	Name: "VSphereCloudConfig",
+	Run: func(ctx context.Context, clients *csoclients.Clients, infrastructure *configv1.Infrastructure) error {
+		configMapLister := clients.KubeInformers.InformersFor(csoclients.CloudConfigNamespace).Core().V1().ConfigMaps().Lister()
+		cloudConfig, err := csoutils.GetVSphereCloudConfig(configMapLister, infrastructure)
+		if err != nil {
+			return fmt.Errorf("failed to read vSphere cloud config: %w", err)
+		}
+		if !cloudConfig.IsMultiZone() {
+			return nil
+		}
+		nodes, err := clients.KubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		var unlabeled []string
+		for _, node := range nodes.Items {
+			if node.Labels[corev1.LabelTopologyZone] == "" {
+				unlabeled = append(unlabeled, node.Name)
+			}
+		}
+		if len(unlabeled) > 0 {
+			sort.Strings(unlabeled)
+			return fmt.Errorf("the cluster spans vCenters %s, but nodes %s do not have label %s", strings.Join(cloudConfig.Servers(), ", "), strings.Join(unlabeled, ", "), corev1.LabelTopologyZone)
+		}
+		return nil
+	},
+}
@@ -2,7 +2,8 @@ package csioperatorclient
 
 import (
 	"os"
-	"strings"
+
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 )
 
 const (
@@ -12,9 +13,9 @@ const (
 )
 
 func GetSharedResourceCSIOperatorConfig() CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envSharedResourceDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envSharedResourceDriverImage),
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envSharedResourceDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envSharedResourceDriverImage),
 	}
 
 	return CSIOperatorConfig{
@@ -30,10 +31,10 @@ func GetSharedResourceCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/shared-resource/07_role_config.yaml",
 			"csidriveroperators/shared-resource/08_rolebinding_config.yaml",
 		},
-		CRAsset:            "csidriveroperators/shared-resource/10_cr.yaml",
-		DeploymentAsset:    "csidriveroperators/shared-resource/09_deployment.yaml",
-		ImageReplacer:      strings.NewReplacer(pairs...),
-		AllowDisabled:      false,
-		RequireFeatureGate: "CSIDriverSharedResource",
+		CRAsset:             "csidriveroperators/shared-resource/10_cr.yaml",
+		DeploymentAsset:     "csidriveroperators/shared-resource/09_deployment.yaml",
+		Images:              images,
+		AllowDisabled:       false,
+		RequireFeatureGates: []string{"CSIDriverSharedResource"},
 	}
 }
@@ -1,10 +1,19 @@
 package csioperatorclient
 
 import (
-	"strings"
+	"context"
+	"sort"
+	"strconv"
+	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -12,6 +21,97 @@ const (
 	// AllPlatforms is a special PlatformType that indicates a CSI driver is installable on any cloud provider.
 	// It is only meant to be used by the CSIOperatorConfig, and does not represent a real OpenShift platform type.
 	AllPlatforms configv1.PlatformType = "AllPlatforms"
+
+	// ExternalPlatformType is PlatformType of clusters on third-party clouds
+	// integrated through the External platform. It's not available in the
+	// vendored openshift/api yet.
+	ExternalPlatformType configv1.PlatformType = "External"
+
+	// StorageCapacityEnv is env. var of the CSI driver operator that enables
+	// CSIStorageCapacity tracking. The operator then sets storageCapacity
+	// field of its CSIDriver and runs external-provisioner with
+	// --enable-capacity.
+	StorageCapacityEnv = "STORAGE_CAPACITY_TRACKING"
+
+	// NonGracefulShutdownFeatureGate is the feature gate that enables
+	// non-graceful node shutdown handling, i.e. force detach of volumes
+	// from nodes tainted with node.kubernetes.io/out-of-service.
+	NonGracefulShutdownFeatureGate = "NodeOutOfServiceVolumeDetach"
+	// NonGracefulShutdownEnv is env. var of the CSI driver operator that
+	// configures its operands to support force detach of volumes from
+	// out-of-service nodes.
+	NonGracefulShutdownEnv = "NODE_OUT_OF_SERVICE_VOLUME_DETACH"
+
+	// ReadWriteOncePodFeatureGate is the feature gate that enables
+	// ReadWriteOncePod access mode of PersistentVolumes.
+	ReadWriteOncePodFeatureGate = "ReadWriteOncePod"
+	// ReadWriteOncePodEnv is env. var of the CSI driver operator that
+	// configures its operands to support ReadWriteOncePod volumes, i.e.
+	// the driver reports SINGLE_NODE_MULTI_WRITER capability and runs CSI
+	// sidecars with the feature enabled.
+	ReadWriteOncePodEnv = "READ_WRITE_ONCE_POD"
+
+	// Env. vars of the CSI driver operator with configuration of
+	// liveness-probe sidecar of the CSI driver, see LivenessProbeConfig.
+	LivenessProbeEnabledEnv          = "LIVENESS_PROBE_ENABLED"
+	LivenessProbePortEnv             = "LIVENESS_PROBE_PORT"
+	LivenessProbeTimeoutEnv          = "LIVENESS_PROBE_TIMEOUT"
+	LivenessProbeTimeoutSecondsEnv   = "LIVENESS_PROBE_TIMEOUT_SECONDS"
+	LivenessProbePeriodSecondsEnv    = "LIVENESS_PROBE_PERIOD_SECONDS"
+	LivenessProbeFailureThresholdEnv = "LIVENESS_PROBE_FAILURE_THRESHOLD"
+
+	// VolumeCloningEnv is env. var of the CSI driver operator that enables
+	// or disables cloning of volumes ("true" / "false"). The operator then
+	// sets CLONE_VOLUME capability of the CSI driver and runs its sidecars
+	// accordingly. CSO sets it only for CSI drivers with
+	// CSIOperatorConfig.VolumeCloning, cloning is enabled unless it's
+	// disabled in ClusterCSIDriver spec.unsupportedConfigOverrides:
+	//
+	//	spec:
+	//	  unsupportedConfigOverrides:
+	//	    volumeCloning: false
+	VolumeCloningEnv = "VOLUME_CLONING"
+
+	// StorageClassParametersEnv is env. var of the CSI driver operator with
+	// extra parameters of StorageClasses it creates, as comma-separated
+	// <name>=<value> pairs sorted by the name. CSO sets it to the
+	// storageClassParameters overlay of the ClusterCSIDriver and encryption
+	// parameters of the customer managed key in its driverConfig, see
+	// csoutils.DriverConfig.
+	StorageClassParametersEnv = "STORAGECLASS_PARAMETERS"
+
+	// StorageClassStateEnv is env. var of the CSI driver operator with state
+	// of StorageClasses it provides: "Managed" (the default), "Unmanaged" -
+	// the operator creates missing StorageClasses but does not reconcile
+	// them, or "Removed" - the operator deletes them. CSO reads it from
+	// ClusterCSIDriver.
+	StorageClassStateEnv = "STORAGECLASS_STATE"
+
+	// StorageClassZonesEnv is env. var of the CSI driver operator with
+	// comma-separated, sorted zones of the cluster: zones of vSphere
+	// failure domains in Infrastructure and zones of the nodes (their
+	// topology.kubernetes.io/zone label). The operator sets allowedTopologies
+	// of StorageClasses it creates to these zones, so volumes are
+	// provisioned only in zones of the cluster. CSO sets it only for CSI drivers
+	// with CSIOperatorConfig.StorageClassTopology and only in zonal
+	// clusters.
+	StorageClassZonesEnv = "STORAGECLASS_ZONES"
+
+	// NodeMaxUnavailableEnv is env. var of the CSI driver operator with
+	// maxUnavailable of its node DaemonSet, an absolute number or
+	// a percentage of nodes.
+	NodeMaxUnavailableEnv = "NODE_DAEMONSET_MAX_UNAVAILABLE"
+
+	// IPFamiliesEnv is env. var of the CSI driver operator with
+	// comma-separated IP families of the cluster, the primary one first
+	// (e.g. "IPv4,IPv6" in dual-stack clusters). The operator configures
+	// listen addresses and Services of its operands (metrics, webhooks)
+	// accordingly. Set only in operators with CSIOperatorConfig.IPFamilies.
+	IPFamiliesEnv = "IP_FAMILIES"
+
+	// shortLivedTokenAudience is the audience of ServiceAccount tokens
+	// accepted by cloud identity providers configured by ccoctl.
+	shortLivedTokenAudience = "openshift"
 )
 
 // CSIOperatorConfig is configuration of a CSI driver operator.
@@ -21,32 +121,382 @@ type CSIOperatorConfig struct {
 	CSIDriverName string
 	// Short name of the driver, used to prefix conditions.
 	ConditionPrefix string
+	// FormerConditionPrefixes are condition prefixes the driver used in
+	// previous releases, e.g. while it was tech preview. CSO removes their
+	// conditions when it starts the CSI driver operator.
+	FormerConditionPrefixes []string
 	// Platform where the driver should run.
 	Platform configv1.PlatformType
+	// ExternalPlatformName is name of the cloud provider of External
+	// platform clusters where the driver should run, as set in Infrastructure
+	// spec.platformSpec.external.platformName. Platform must be
+	// ExternalPlatformType then.
+	ExternalPlatformName string
 	// StaticAssets is list of bindata assets to create when starting the CSI
 	// driver operator.
 	StaticAssets []string
+	// ProfileStaticAssets are static assets created instead of StaticAssets
+	// on clusters with given profile, e.g. without PodDisruptionBudgets on
+	// single node clusters. Clusters with profiles that are not listed use
+	// StaticAssets.
+	ProfileStaticAssets map[ClusterProfile][]string
+	// CredentialsRequestAsset is name of the bindata asset with
+	// CredentialsRequest of the operator. It is created only when the CSI
+	// driver operator is started and removed when the operator should not
+	// run on the cluster.
+	CredentialsRequestAsset string
+	// RolloutConfigMaps are names of ConfigMaps in the CSI driver operator
+	// namespace that are consumed by the operator (e.g. CA bundles). The
+	// operator Deployment is rolled out when any of them changes, the same
+	// way as when Secret of CredentialsRequestAsset changes.
+	RolloutConfigMaps []string
+	// RolloutSecrets are names of Secrets in the CSI driver operator
+	// namespace with cloud credentials that are not provisioned through
+	// CredentialsRequestAsset. The operator Deployment is rolled out when
+	// any of them is rotated.
+	RolloutSecrets []string
+	// CustomCABundle enables mounting of user provided CA bundle for cloud
+	// API endpoints (vCenter, OpenStack) to the CSI driver operator.
+	CustomCABundle bool
+	// BoundSAToken is configuration of projected ServiceAccount token mounted
+	// to the CSI driver operator when the cluster uses short-lived cloud
+	// credentials (AWS STS, Azure / GCP Workload Identity). The token is then
+	// used to access the cloud API instead of long-lived credentials. Nil
+	// means the driver does not support short-lived tokens.
+	BoundSAToken *BoundSATokenConfig
 	// CRAsset is name of the bindata asset with ClusterCSIDriver of the
 	// operator. Its logLevel & operatorLoglevel will be set by CSO.
 	CRAsset string
 	// DeploymentAsset is name of the bindata asset with Deployment of the
-	// operator. It will get updated by OCS in this way:
-	// - ImageReplacer this CSIOperatorConfig is run.
-	// - SidecarReplacer is run (see util.go)
+	// operator. It's rendered as a template (see pkg/assettemplate) with
+	// Images of this CSIOperatorConfig and images of CSI sidecars.
 	DeploymentAsset string
-	// ImageReplacer is a replacer that's replaces CSI driver + operator image
-	// names in the Deployment.
-	ImageReplacer *strings.Replacer
+	// Images are CSI driver + operator images available to the assets as
+	// {{.Images.<name>}}.
+	Images map[string]string
 	// Whether the CSI driver can set Disabled condition (i.e. the cloud may not support it) and it's OK.
 	// In this case, the CSO's overall Available / Progressing conditions will not be affected by Disabled
 	// ClusterCSIDriver.
 	AllowDisabled bool
 	// Extra controllers to start with the CSI driver operator
 	ExtraControllers []factory.Controller
+	// ExtraControllersFunc returns extra controllers to start with the CSI
+	// driver operator. Unlike ExtraControllers, it's called each time the
+	// ControllerManager of the CSI driver is created, so the controllers can
+	// use clients of CSO and they are created again after the CSI driver
+	// operator was stopped.
+	ExtraControllersFunc ExtraControllersFunc
+	// FIPSUnsupported marks CSI drivers that can't run with FIPS validated
+	// crypto. CSO refuses to install them on clusters in FIPS mode.
+	FIPSUnsupported bool
+	// PodSecurityLevel is PodSecurity admission level required by the CSI
+	// driver operator and its operands. Defaults to privileged, CSI driver
+	// node pods need privileged access to the host.
+	PodSecurityLevel PodSecurityLevel
+	// OperandNamespaces are namespaces where the CSI driver operator and its
+	// operands run. Defaults to openshift-cluster-csi-drivers.
+	OperandNamespaces []string
 	// OLMOptions configuration of migration from OLM to CSO
 	OLMOptions *OLMOptions
-	// Run the CSI driver operator only when given FeatureGate is enabled
-	RequireFeatureGate string
+	// Run the CSI driver operator only when given cluster capability is
+	// enabled in ClusterVersion.
+	RequireCapability string
+	// Run the CSI driver operator only when all given FeatureGates are
+	// enabled.
+	RequireFeatureGates []string
+	// Do not run the CSI driver operator when any of given FeatureGates is
+	// enabled, e.g. when another CSI driver replaces it.
+	DisabledByFeatureGates []string
+	// StatusFilter decides whether the CSI driver can run on a cluster of
+	// its Platform, e.g. when the driver does not support some cloud
+	// environments of the platform. Nil means the driver runs on all
+	// clusters of the platform.
+	StatusFilter StatusFilterFunc
+	// OptionalDriver marks CSI drivers that are not installed by default,
+	// typically a second CSI driver of a platform (e.g. AWS EFS next to AWS
+	// EBS). CSO runs the CSI driver operator only when the cluster admin
+	// creates its ClusterCSIDriver, CSO does not create it from CRAsset.
+	// When the admin deletes the ClusterCSIDriver, CSO uninstalls the CSI
+	// driver operator.
+	OptionalDriver bool
+	// StorageCapacity enables CSIStorageCapacity tracking of the CSI driver.
+	// CSO creates RBAC for CSIStorageCapacity objects and sets
+	// StorageCapacityEnv in the CSI driver operator Deployment.
+	StorageCapacity bool
+	// NonGracefulShutdown marks CSI drivers that support force detach of
+	// volumes from out-of-service nodes. CSO sets NonGracefulShutdownEnv in
+	// the CSI driver operator Deployment when
+	// NonGracefulShutdownFeatureGate is enabled.
+	NonGracefulShutdown bool
+	// ReadWriteOncePod marks CSI drivers that support ReadWriteOncePod
+	// access mode. CSO sets ReadWriteOncePodEnv in the CSI driver operator
+	// Deployment when ReadWriteOncePodFeatureGate is enabled.
+	ReadWriteOncePod bool
+	// LivenessProbe is configuration of liveness-probe sidecar of the CSI
+	// driver, passed to the CSI driver operator as env. vars. Nil means the
+	// operator uses its defaults.
+	LivenessProbe *LivenessProbeConfig
+	// VolumeCloning marks CSI drivers that support cloning of volumes. It
+	// can be disabled in the ClusterCSIDriver, see VolumeCloningEnv.
+	VolumeCloning bool
+	// StorageClassEncryption marks CSI drivers whose StorageClasses can be
+	// encrypted with a customer managed key, see StorageClassParametersEnv.
+	StorageClassEncryption bool
+	// StorageClassTopology marks CSI drivers whose StorageClasses are
+	// restricted to zones of the cluster, see StorageClassZonesEnv.
+	StorageClassTopology bool
+	// IPFamilies marks CSI driver operators that configure their operands
+	// for the cluster IP stack, see IPFamiliesEnv.
+	IPFamilies bool
+	// VolumeSnapshotClass is the default VolumeSnapshotClass of the CSI
+	// driver, created by CSO when enabled in the Storage CR. Nil for drivers
+	// without snapshot support.
+	VolumeSnapshotClass *VolumeSnapshotClassConfig
+	// InfrastructureEnv returns env. vars of the CSI driver operator with
+	// platform specific configuration from Infrastructure, e.g. custom cloud
+	// API endpoints. The Deployment is rolled out when they change.
+	InfrastructureEnv InfrastructureEnvFunc
+	// KubeVirtInfraCluster marks CSI drivers that provision volumes in the
+	// KubeVirt infra cluster, see csoclients.InfraKubeconfigEnv. CSO passes
+	// kubeconfig and namespace of the infra cluster to the CSI driver
+	// operator and checks that KubeVirt there can hot-plug volumes to VMs.
+	KubeVirtInfraCluster bool
+	// AssetFunc returns content of StaticAssets, CredentialsRequestAsset,
+	// CRAsset and DeploymentAsset. Defaults to assets shipped with CSO,
+	// drivers registered outside of CSO (see pkg/driverregistry) provide
+	// their own.
+	AssetFunc resourceapply.AssetFunc
+	// PreflightChecks run before CSO starts the CSI driver operator, in the
+	// given order. Cloud credentials in Manual mode are always checked.
+	PreflightChecks []PreflightCheck
+	// DeploymentHooks are called on Deployment of the CSI driver operator
+	// after CSO rendered it and before it's applied, in the given order.
+	DeploymentHooks []DeploymentHookFunc
+	// PreUpgradeHooks run in the given order when the CSI driver operator
+	// is updated to a new version, before its new Deployment is applied.
+	PreUpgradeHooks []UpgradeHook
+	// PostUpgradeHooks run in the given order after the new Deployment of
+	// the CSI driver operator is rolled out.
+	PostUpgradeHooks []UpgradeHook
+}
+
+// StatusFilterFunc returns false when a CSI driver should not run on a
+// cluster with given Infrastructure and FeatureGate.
+type StatusFilterFunc func(infrastructure *configv1.Infrastructure, fg *configv1.FeatureGate) bool
+
+// ExtraControllersFunc returns controllers that run together with a CSI
+// driver operator.
+type ExtraControllersFunc func(clients *csoclients.Clients, recorder events.Recorder, resyncInterval time.Duration) []factory.Controller
+
+// InfrastructureEnvFunc returns env. vars of a CSI driver operator computed
+// from Infrastructure.
+type InfrastructureEnvFunc func(infra *configv1.Infrastructure) map[string]string
+
+// DeploymentHookFunc modifies Deployment of a CSI driver operator before
+// it's applied. An error is reported as Degraded condition.
+type DeploymentHookFunc func(opSpec *operatorv1.OperatorSpec, deployment *appsv1.Deployment) error
+
+// UpgradeHook is a step of an update of a CSI driver operator to a new
+// version, e.g. a check of CRD versions or removal of an old webhook. The
+// update does not continue until the hook succeeds, its error is reported in
+// Progressing and Degraded conditions. Hooks are retried, they must be
+// idempotent.
+type UpgradeHook struct {
+	// Name of the hook, used in conditions and events.
+	Name string
+	// Run runs the hook. fromVersion and toVersion are release versions of
+	// the CSI driver operator.
+	Run func(ctx context.Context, fromVersion, toVersion string) error
+}
+
+// BoundSATokenConfig is configuration of projected ServiceAccount token used
+// to access cloud API.
+type BoundSATokenConfig struct {
+	// Audience of the token. The cluster admin can override it in the Storage
+	// CR, e.g. when the cloud identity provider was configured with
+	// a different audience than ccoctl uses by default.
+	Audience string
+	// ExpirationSeconds is lifetime of the token. Defaults to one hour.
+	ExpirationSeconds int64
+	// CloudIdentity describes cloud identity that the token is exchanged
+	// for. When set and the cloud credentials Secret does not exist, CSO
+	// creates it from the identity set by the cluster admin in
+	// ClusterCSIDriver instead of waiting for it.
+	CloudIdentity *CloudIdentityConfig
+}
+
+// VolumeSnapshotClassConfig is the default VolumeSnapshotClass of a CSI driver.
+type VolumeSnapshotClassConfig struct {
+	Name string
+	// DeletionPolicy of the class, defaults to Delete.
+	DeletionPolicy string
+	Parameters     map[string]string
+}
+
+// LivenessProbeConfig is configuration of liveness-probe sidecar of a CSI
+// driver and of the liveness probe of the driver container that calls it.
+// Zero values are not passed to the CSI driver operator, it uses its
+// defaults for them.
+type LivenessProbeConfig struct {
+	// Disabled disables the liveness-probe sidecar and the liveness probe.
+	Disabled bool
+	// Port where the sidecar listens.
+	Port int32
+	// ProbeTimeout is timeout of the CSI Probe call made by the sidecar.
+	// Slow clouds may need more than the default 1s.
+	ProbeTimeout time.Duration
+	// TimeoutSeconds, PeriodSeconds and FailureThreshold of the liveness
+	// probe of the CSI driver container.
+	TimeoutSeconds   int32
+	PeriodSeconds    int32
+	FailureThreshold int32
+}
+
+// Env returns env. vars of the CSI driver operator with the configuration.
+func (c *LivenessProbeConfig) Env() map[string]string {
+	if c.Disabled {
+		return map[string]string{LivenessProbeEnabledEnv: "false"}
+	}
+	env := map[string]string{}
+	setInt := func(name string, value int32) {
+		if value != 0 {
+			env[name] = strconv.Itoa(int(value))
+		}
+	}
+	setInt(LivenessProbePortEnv, c.Port)
+	setInt(LivenessProbeTimeoutSecondsEnv, c.TimeoutSeconds)
+	setInt(LivenessProbePeriodSecondsEnv, c.PeriodSeconds)
+	setInt(LivenessProbeFailureThresholdEnv, c.FailureThreshold)
+	if c.ProbeTimeout != 0 {
+		env[LivenessProbeTimeoutEnv] = c.ProbeTimeout.String()
+	}
+	return env
+}
+
+// PodSecurityLevel is a PodSecurity admission level.
+type PodSecurityLevel string
+
+const (
+	PodSecurityPrivileged PodSecurityLevel = "privileged"
+	PodSecurityBaseline   PodSecurityLevel = "baseline"
+	PodSecurityRestricted PodSecurityLevel = "restricted"
+)
+
+// GetPodSecurityLevel returns PodSecurity admission level required by the
+// CSI driver operator.
+func (c *CSIOperatorConfig) GetPodSecurityLevel() PodSecurityLevel {
+	if c.PodSecurityLevel == "" {
+		return PodSecurityPrivileged
+	}
+	return c.PodSecurityLevel
+}
+
+// Assets with RBAC for CSIStorageCapacity objects, shared by all CSI drivers
+// with StorageCapacity.
+var storageCapacityAssets = []string{
+	"csidriveroperators/storage-capacity/01_clusterrole.yaml",
+	"csidriveroperators/storage-capacity/02_clusterrolebinding.yaml",
+}
+
+// TrustedCABundleAsset is asset with ConfigMap with the cluster trusted CA
+// bundle, mounted to all CSI driver operators in its namespace.
+const TrustedCABundleAsset = "csidriveroperators/trusted-ca-bundle/01_configmap.yaml"
+
+// GetAssetFunc returns AssetFunc of the CSI driver operator assets.
+func (c *CSIOperatorConfig) GetAssetFunc() resourceapply.AssetFunc {
+	assetFunc := c.AssetFunc
+	if assetFunc == nil {
+		return assets.ReadFile
+	}
+	return func(name string) ([]byte, error) {
+		// Shared assets are always shipped with CSO.
+		for _, asset := range storageCapacityAssets {
+			if name == asset {
+				return assets.ReadFile(name)
+			}
+		}
+		return assetFunc(name)
+	}
+}
+
+// ClusterProfile is profile of a cluster, see GetClusterProfile.
+type ClusterProfile string
+
+const (
+	SelfManagedHAProfile ClusterProfile = "self-managed-high-availability"
+	SingleNodeProfile    ClusterProfile = "single-node-developer"
+	HyperShiftProfile    ClusterProfile = "hypershift"
+)
+
+// GetClusterProfile returns profile of the cluster from topology of its
+// Infrastructure.
+func GetClusterProfile(infra *configv1.Infrastructure) ClusterProfile {
+	switch {
+	case infra.Status.ControlPlaneTopology == configv1.ExternalTopologyMode:
+		return HyperShiftProfile
+	case infra.Status.ControlPlaneTopology == configv1.SingleReplicaTopologyMode:
+		return SingleNodeProfile
+	}
+	return SelfManagedHAProfile
+}
+
+// GetStaticAssets returns static assets of the CSI driver operator for
+// clusters with given profile, including RBAC for CSIStorageCapacity objects
+// when StorageCapacity is enabled.
+func (c *CSIOperatorConfig) GetStaticAssets(profile ClusterProfile) []string {
+	staticAssets, found := c.ProfileStaticAssets[profile]
+	if !found {
+		staticAssets = c.StaticAssets
+	}
+	staticAssets = append([]string{}, staticAssets...)
+	if c.StorageCapacity {
+		staticAssets = append(staticAssets, storageCapacityAssets...)
+	}
+	return staticAssets
+}
+
+// GetOwnStaticAssets returns static assets of the CSI driver operator for
+// all cluster profiles, without assets shared with other CSI driver
+// operators.
+func (c *CSIOperatorConfig) GetOwnStaticAssets() []string {
+	profiles := make([]string, 0, len(c.ProfileStaticAssets))
+	for profile := range c.ProfileStaticAssets {
+		profiles = append(profiles, string(profile))
+	}
+	sort.Strings(profiles)
+	staticAssets := append([]string{}, c.StaticAssets...)
+	for _, profile := range profiles {
+		staticAssets = append(staticAssets, c.ProfileStaticAssets[ClusterProfile(profile)]...)
+	}
+	seen := map[string]bool{}
+	var deduped []string
+	for _, asset := range staticAssets {
+		if !seen[asset] {
+			seen[asset] = true
+			deduped = append(deduped, asset)
+		}
+	}
+	return deduped
+}
+
+// GetAllStaticAssets returns static assets of the CSI driver operator for
+// all cluster profiles, including RBAC for CSIStorageCapacity objects when
+// StorageCapacity is enabled.
+func (c *CSIOperatorConfig) GetAllStaticAssets() []string {
+	staticAssets := c.GetOwnStaticAssets()
+	if c.StorageCapacity {
+		staticAssets = append(staticAssets, storageCapacityAssets...)
+	}
+	return staticAssets
+}
+
+// GetOperandNamespaces returns namespaces where the CSI driver operator and
+// its operands run.
+func (c *CSIOperatorConfig) GetOperandNamespaces() []string {
+	if len(c.OperandNamespaces) == 0 {
+		return []string{csoclients.CSIOperatorNamespace}
+	}
+	return c.OperandNamespaces
 }
 
 // OLMOptions contains information that is necessary to remove old CSI driver
@@ -59,3 +509,24 @@ type OLMOptions struct {
 	// Resource of the old operator CR
 	CRResource schema.GroupVersionResource
 }
+
+// platformVariantChecks return an error for variants of a platform that CSO
+// does not know, e.g. a new infrastructure type of a cloud, to tell them
+// from variants that intentionally have no CSI driver.
+var platformVariantChecks = map[configv1.PlatformType]func(infrastructure *configv1.Infrastructure) error{
+	configv1.IBMCloudPlatformType: checkIBMCloudVariant,
+}
+
+// CheckPlatformVariant returns an error when the cluster runs on an
+// unsupported variant of its platform.
+func CheckPlatformVariant(infrastructure *configv1.Infrastructure) error {
+	platformStatus := infrastructure.Status.PlatformStatus
+	if platformStatus == nil {
+		return nil
+	}
+	check, found := platformVariantChecks[platformStatus.Type]
+	if !found {
+		return nil
+	}
+	return check(infrastructure)
+}
@@ -2,9 +2,9 @@ package csioperatorclient
 
 import (
 	"os"
-	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 )
 
 const (
@@ -15,10 +15,10 @@ const (
 )
 
 func GetVMwareVSphereCSIOperatorConfig() CSIOperatorConfig {
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(envVMwareVSphereDriverOperatorImage),
-		"${DRIVER_IMAGE}", os.Getenv(envVMwareVSphereDriverImage),
-		"${VMWARE_VSPHERE_SYNCER_IMAGE}", os.Getenv(envVMWareVsphereDriverSyncerImage),
+	images := map[string]string{
+		assettemplate.ImageOperator: os.Getenv(envVMwareVSphereDriverOperatorImage),
+		assettemplate.ImageDriver:   os.Getenv(envVMwareVSphereDriverImage),
+		"Syncer":                    os.Getenv(envVMWareVsphereDriverSyncerImage),
 	}
 
 	return CSIOperatorConfig{
@@ -33,9 +33,17 @@ func GetVMwareVSphereCSIOperatorConfig() CSIOperatorConfig {
 			"csidriveroperators/vsphere/06_clusterrole.yaml",
 			"csidriveroperators/vsphere/07_clusterrolebinding.yaml",
 		},
-		CRAsset:         "csidriveroperators/vsphere/09_cr.yaml",
-		DeploymentAsset: "csidriveroperators/vsphere/08_deployment.yaml",
-		ImageReplacer:   strings.NewReplacer(pairs...),
-		AllowDisabled:   false,
+		CredentialsRequestAsset: "csidriveroperators/vsphere/01_credentials_request.yaml",
+		CRAsset:                 "csidriveroperators/vsphere/09_cr.yaml",
+		DeploymentAsset:         "csidriveroperators/vsphere/08_deployment.yaml",
+		Images:                  images,
+		PreflightChecks:         []PreflightCheck{vSphereCloudConfigCheck},
+		NonGracefulShutdown:     true,
+		ReadWriteOncePod:        true,
+		StorageClassTopology:    true,
+		IPFamilies:              true,
+		VolumeSnapshotClass:     &VolumeSnapshotClassConfig{Name: "csi-vsphere-vsc"},
+		AllowDisabled:           false,
+		CustomCABundle:          true,
 	}
 }
@@ -0,0 +1,210 @@
+// Package decision decides which CSI driver operators CSO runs on a
+// cluster. It's used by CSO itself and it can be used by other tools to
+// predict which CSI drivers CSO will manage.
+package decision
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnnOpenShiftManaged is annotation of CSIDriver objects installed by
+// OpenShift. A CSIDriver without it was installed by someone else.
+const AnnOpenShiftManaged = "csi.openshift.io/managed"
+
+// Reason is a machine readable reason of a Decision.
+type Reason string
+
+const (
+	// ReasonPlatformMismatch is a CSI driver for another platform.
+	ReasonPlatformMismatch Reason = "PlatformMismatch"
+	// ReasonCapabilityDisabled is a CSI driver for the platform whose cluster
+	// capability is disabled.
+	ReasonCapabilityDisabled Reason = "CapabilityDisabled"
+	// ReasonFilteredOut is a CSI driver for the platform that does not
+	// support the cluster, see CSIOperatorConfig.StatusFilter.
+	ReasonFilteredOut Reason = "FilteredOut"
+	// ReasonGA is a GA CSI driver for the platform.
+	ReasonGA Reason = "GA"
+	// ReasonDisabledByFeatureGate is a CSI driver turned off by an enabled
+	// feature gate, see CSIOperatorConfig.DisabledByFeatureGates.
+	ReasonDisabledByFeatureGate Reason = "DisabledByFeatureGate"
+	// ReasonFeatureGateDisabled is a tech preview CSI driver whose feature
+	// gates are not all enabled.
+	ReasonFeatureGateDisabled Reason = "FeatureGateDisabled"
+	// ReasonFeatureGateEnabled is a tech preview CSI driver whose feature
+	// gate is enabled.
+	ReasonFeatureGateEnabled Reason = "FeatureGateEnabled"
+	// ReasonUnsupportedCSIDriverInstalled is a tech preview CSI driver whose
+	// feature gate is enabled, but the same CSI driver was already
+	// installed by someone else than OpenShift.
+	ReasonUnsupportedCSIDriverInstalled Reason = "UnsupportedCSIDriverInstalled"
+	// ReasonNotRequested is an optional CSI driver that could run, but the
+	// cluster admin did not create its ClusterCSIDriver.
+	ReasonNotRequested Reason = "NotRequested"
+	// ReasonExternalPlatformMismatch is a CSI driver for another cloud
+	// provider of the External platform.
+	ReasonExternalPlatformMismatch Reason = "ExternalPlatformMismatch"
+	// ReasonLegacyInTreeDriver is a CSI driver replaced by the in-tree volume
+	// plugin selected by the cluster admin.
+	ReasonLegacyInTreeDriver Reason = "LegacyInTreeDriver"
+)
+
+// Decision is the result of ShouldRun.
+type Decision struct {
+	// CSIDriverName is name of the CSI driver.
+	CSIDriverName string `json:"csiDriverName"`
+	// Run is true when CSO runs the CSI driver operator.
+	Run bool `json:"run"`
+	// Reason is a machine readable reason of the decision.
+	Reason Reason `json:"reason"`
+	// Message is a human readable explanation of the decision.
+	Message string `json:"message"`
+}
+
+// ShouldRun decides whether CSO runs the CSI driver operator described by
+// cfg on a cluster with given Infrastructure, FeatureGate and enabled
+// capabilities. csiDriver and
+// clusterCSIDriver are the CSIDriver and ClusterCSIDriver objects of the
+// driver or nil, if they do not exist. An error is returned together with
+// the decision when the cluster can't run the driver and should be marked
+// as degraded.
+//
+// Several CSI drivers can run on the same platform, each of them is decided
+// independently.
+func ShouldRun(cfg csioperatorclient.CSIOperatorConfig, infrastructure *configv1.Infrastructure, fg *configv1.FeatureGate, capabilities *csoutils.Capabilities, csiDriver *storagev1.CSIDriver, clusterCSIDriver *operatorv1.ClusterCSIDriver) (Decision, error) {
+	decision, err := shouldRun(cfg, infrastructure, fg, capabilities, csiDriver)
+	if err != nil || !decision.Run {
+		return decision, err
+	}
+	if cfg.OptionalDriver && clusterCSIDriver == nil {
+		decision.Run = false
+		decision.Reason = ReasonNotRequested
+		decision.Message = fmt.Sprintf("the CSI driver is optional, create ClusterCSIDriver %s to install it", cfg.CSIDriverName)
+	}
+	if cfg.OptionalDriver && clusterCSIDriver != nil && clusterCSIDriver.DeletionTimestamp != nil {
+		decision.Run = false
+		decision.Reason = ReasonNotRequested
+		decision.Message = fmt.Sprintf("the CSI driver is optional and its ClusterCSIDriver %s is being deleted", cfg.CSIDriverName)
+	}
+	return decision, nil
+}
+
+func shouldRun(cfg csioperatorclient.CSIOperatorConfig, infrastructure *configv1.Infrastructure, fg *configv1.FeatureGate, capabilities *csoutils.Capabilities, csiDriver *storagev1.CSIDriver) (Decision, error) {
+	decision := Decision{CSIDriverName: cfg.CSIDriverName}
+
+	// Check the correct platform first, it will filter out most CSI driver operators
+	var platform configv1.PlatformType
+	if infrastructure.Status.PlatformStatus != nil {
+		platform = infrastructure.Status.PlatformStatus.Type
+	}
+	if cfg.Platform != csioperatorclient.AllPlatforms && cfg.Platform != platform {
+		decision.Reason = ReasonPlatformMismatch
+		decision.Message = fmt.Sprintf("the CSI driver runs on platform %s, the cluster runs on %s", cfg.Platform, platform)
+		return decision, nil
+	}
+
+	if cfg.RequireCapability != "" && !capabilities.Enabled(cfg.RequireCapability) {
+		decision.Reason = ReasonCapabilityDisabled
+		decision.Message = fmt.Sprintf("capability %s is disabled", cfg.RequireCapability)
+		return decision, nil
+	}
+
+	if cfg.StatusFilter != nil && !cfg.StatusFilter(infrastructure, fg) {
+		decision.Reason = ReasonFilteredOut
+		decision.Message = fmt.Sprintf("the CSI driver does not support this %s cluster", platform)
+		return decision, nil
+	}
+
+	for _, feature := range cfg.DisabledByFeatureGates {
+		if csoutils.FeatureGateEnabled(fg, feature) {
+			decision.Reason = ReasonDisabledByFeatureGate
+			decision.Message = fmt.Sprintf("feature %s is enabled", feature)
+			return decision, nil
+		}
+	}
+
+	if len(cfg.RequireFeatureGates) == 0 {
+		// This is GA / always enabled operator, always run
+		decision.Run = true
+		decision.Reason = ReasonGA
+		decision.Message = "the CSI driver is GA"
+		return decision, nil
+	}
+
+	// All features must be enabled
+	var disabled []string
+	for _, feature := range cfg.RequireFeatureGates {
+		if !csoutils.FeatureGateEnabled(fg, feature) {
+			disabled = append(disabled, feature)
+		}
+	}
+	if len(disabled) > 0 {
+		decision.Reason = ReasonFeatureGateDisabled
+		decision.Message = fmt.Sprintf("feature %s is not enabled", strings.Join(disabled, ", "))
+		return decision, nil
+	}
+
+	if IsUnsupportedCSIDriverRunning(csiDriver) {
+		// Some other version of the CSI driver is running, degrade the whole cluster
+		decision.Reason = ReasonUnsupportedCSIDriverInstalled
+		decision.Message = fmt.Sprintf("detected CSI driver %s that is not provided by OpenShift - please remove it before enabling the OpenShift one", cfg.CSIDriverName)
+		return decision, errors.New(decision.Message)
+	}
+
+	// Tech preview operator and tech preview is enabled
+	decision.Run = true
+	decision.Reason = ReasonFeatureGateEnabled
+	decision.Message = fmt.Sprintf("feature %s is enabled", strings.Join(cfg.RequireFeatureGates, ", "))
+	return decision, nil
+}
+
+// FilterExternalPlatform refines decision of ShouldRun for clusters with
+// External platform. The cloud provider name of the platform,
+// externalPlatformName, is not available in the vendored Infrastructure
+// type and it must be read separately. A CSI driver of another cloud
+// provider does not run.
+func FilterExternalPlatform(decision Decision, cfg csioperatorclient.CSIOperatorConfig, externalPlatformName string) Decision {
+	if !decision.Run || cfg.ExternalPlatformName == "" || cfg.ExternalPlatformName == externalPlatformName {
+		return decision
+	}
+	decision.Run = false
+	decision.Reason = ReasonExternalPlatformMismatch
+	decision.Message = fmt.Sprintf("the CSI driver runs on External platform %s, the cluster runs on %q", cfg.ExternalPlatformName, externalPlatformName)
+	return decision
+}
+
+// FilterLegacyInTreeDriver returns the decision adjusted for clusters where
+// the cluster admin selected the legacy in-tree volume plugin instead of the
+// CSI driver, e.g. by vsphereStorageDriver in the Storage CR.
+func FilterLegacyInTreeDriver(decision Decision, legacyInTreeDriver bool) Decision {
+	if !decision.Run || !legacyInTreeDriver {
+		return decision
+	}
+	decision.Run = false
+	decision.Reason = ReasonLegacyInTreeDriver
+	decision.Message = "the cluster admin selected the legacy in-tree volume plugin instead of the CSI driver"
+	return decision
+}
+
+// IsUnsupportedCSIDriverRunning returns true when the CSIDriver object
+// exists and it was not installed by OpenShift.
+func IsUnsupportedCSIDriverRunning(csiDriver *storagev1.CSIDriver) bool {
+	if csiDriver == nil {
+		return false
+	}
+
+	if metav1.HasAnnotation(csiDriver.ObjectMeta, AnnOpenShiftManaged) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,435 @@
+package decision
+
+import (
+	"testing"
+
+	v1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestShouldRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		platform    v1.PlatformType
+		featureGate *v1.FeatureGate
+		csiDriver   *storagev1.CSIDriver
+		config      csioperatorclient.CSIOperatorConfig
+		expectRun   bool
+		expectError bool
+	}{
+		{
+			"tech preview Shared Resource driver on AllPlatforms type",
+			v1.AWSPlatformType,
+			featureSet("TechPreviewNoUpgrade"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "csi.sharedresource.openshift.io",
+				Platform:            csioperatorclient.AllPlatforms,
+				RequireFeatureGates: []string{"CSIDriverSharedResource"},
+			},
+			true,
+			false,
+		},
+		{
+			"tech preview Shared Resource driver on AWSPlatformType",
+			v1.AWSPlatformType,
+			featureSet("TechPreviewNoUpgrade"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "csi.sharedresource.openshift.io",
+				Platform:            v1.AWSPlatformType,
+				RequireFeatureGates: []string{"CSIDriverSharedResource"},
+			},
+			true,
+			false,
+		},
+		{
+			"tech preview Shared Resource driver on GCPPlatformType",
+			v1.GCPPlatformType,
+			featureSet("TechPreviewNoUpgrade"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "csi.sharedresource.openshift.io",
+				Platform:            v1.GCPPlatformType,
+				RequireFeatureGates: []string{"CSIDriverSharedResource"},
+			},
+			true,
+			false,
+		},
+		{
+			"tech preview Shared Resource driver on GCPPlatformType",
+			v1.VSpherePlatformType,
+			featureSet("TechPreviewNoUpgrade"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "csi.sharedresource.openshift.io",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverSharedResource"},
+			},
+			true,
+			false,
+		},
+		{
+			"GA CSI driver on matching platform",
+			v1.AWSPlatformType,
+			featureSet(""),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "ebs.csi.aws.com",
+				Platform:            v1.AWSPlatformType,
+				RequireFeatureGates: nil,
+			},
+			true,
+			false,
+		},
+		{
+			"GA CSI driver on non-matching platform",
+			v1.GCPPlatformType,
+			featureSet(""),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "ebs.csi.aws.com",
+				Platform:            v1.AWSPlatformType,
+				RequireFeatureGates: nil,
+			},
+			false,
+			false,
+		},
+		{
+			"tech preview driver on non-matching platform",
+			v1.VSpherePlatformType,
+			featureSet("TechPreviewNoUpgrade"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.AWSPlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere"},
+			},
+			false,
+			false,
+		},
+		{
+			"tech preview driver with enabled TechPreviewNoUpgrade FeatureSet",
+			v1.VSpherePlatformType,
+			featureSet("TechPreviewNoUpgrade"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere"},
+			},
+			true,
+			false,
+		},
+		{
+			"tech preview driver with disabled TechPreviewNoUpgrade FeatureSet",
+			v1.VSpherePlatformType,
+			featureSet(""),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere"},
+			},
+			false,
+			false,
+		},
+		{
+			"tech preview driver with correct CustomNoUpgrade FeatureSet",
+			v1.VSpherePlatformType,
+			customSet("foo", "bar", "baz", "CSIDriverVSphere"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere"},
+			},
+			true,
+			false,
+		},
+		{
+			"tech preview driver with wrong CustomNoUpgrade FeatureSet",
+			v1.VSpherePlatformType,
+			customSet("foo", "bar", "baz"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere"},
+			},
+			false,
+			false,
+		},
+		{
+			"tech preview driver with all required CustomNoUpgrade features",
+			v1.VSpherePlatformType,
+			customSet("foo", "CSIDriverVSphere", "CSIDriverVSphereExtra"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere", "CSIDriverVSphereExtra"},
+			},
+			true,
+			false,
+		},
+		{
+			"tech preview driver with some required CustomNoUpgrade features",
+			v1.VSpherePlatformType,
+			customSet("foo", "CSIDriverVSphere"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere", "CSIDriverVSphereExtra"},
+			},
+			false,
+			false,
+		},
+		{
+			"GA driver disabled by CustomNoUpgrade feature",
+			v1.VSpherePlatformType,
+			customSet("foo", "CSIDriverVSphereReplacement"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:          "vsphere",
+				Platform:               v1.VSpherePlatformType,
+				DisabledByFeatureGates: []string{"CSIDriverVSphereReplacement"},
+			},
+			false,
+			false,
+		},
+		{
+			"tech preview driver with required and disabling CustomNoUpgrade features",
+			v1.VSpherePlatformType,
+			customSet("CSIDriverVSphere", "CSIDriverVSphereReplacement"),
+			nil,
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:          "vsphere",
+				Platform:               v1.VSpherePlatformType,
+				RequireFeatureGates:    []string{"CSIDriverVSphere"},
+				DisabledByFeatureGates: []string{"CSIDriverVSphereReplacement"},
+			},
+			false,
+			false,
+		},
+		{
+			"tech preview driver with existing OpenShift CSIDriver",
+			v1.VSpherePlatformType,
+			customSet("CSIDriverVSphere"),
+			csiDriver("vsphere", map[string]string{AnnOpenShiftManaged: "true"}),
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere"},
+			},
+			true,
+			false,
+		},
+		{
+			"tech preview driver with existing community CSIDriver",
+			v1.VSpherePlatformType,
+			customSet("CSIDriverVSphere"),
+			csiDriver("vsphere", nil),
+			csioperatorclient.CSIOperatorConfig{
+				CSIDriverName:       "vsphere",
+				Platform:            v1.VSpherePlatformType,
+				RequireFeatureGates: []string{"CSIDriverVSphere"},
+			},
+			false,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			infra := &v1.Infrastructure{
+				Status: v1.InfrastructureStatus{
+					PlatformStatus: &v1.PlatformStatus{
+						Type: test.platform,
+					},
+				},
+			}
+			res, err := ShouldRun(test.config, infra, test.featureGate, nil, test.csiDriver, nil)
+			if res.Run != test.expectRun {
+				t.Errorf("Expected run %t, got %t", test.expectRun, res.Run)
+			}
+			gotError := err != nil
+			if gotError != test.expectError {
+				t.Errorf("Expected error %t, got %t: %s", test.expectError, gotError, err)
+			}
+		})
+	}
+}
+
+func TestShouldRunReason(t *testing.T) {
+	infra := &v1.Infrastructure{
+		Status: v1.InfrastructureStatus{
+			PlatformStatus: &v1.PlatformStatus{
+				Type: v1.AWSPlatformType,
+			},
+		},
+	}
+	techPreview := csioperatorclient.CSIOperatorConfig{
+		CSIDriverName:       "csi.sharedresource.openshift.io",
+		Platform:            csioperatorclient.AllPlatforms,
+		RequireFeatureGates: []string{"CSIDriverSharedResource"},
+	}
+	tests := []struct {
+		name             string
+		config           csioperatorclient.CSIOperatorConfig
+		featureGate      *v1.FeatureGate
+		capabilities     *csoutils.Capabilities
+		csiDriver        *storagev1.CSIDriver
+		clusterCSIDriver *operatorv1.ClusterCSIDriver
+		expectedReason   Reason
+	}{
+		{
+			name:           "wrong platform",
+			config:         csioperatorclient.CSIOperatorConfig{CSIDriverName: "pd.csi.storage.gke.io", Platform: v1.GCPPlatformType},
+			featureGate:    featureSet(""),
+			expectedReason: ReasonPlatformMismatch,
+		},
+		{
+			name:           "capability disabled",
+			config:         csioperatorclient.CSIOperatorConfig{CSIDriverName: "ebs.csi.aws.com", Platform: v1.AWSPlatformType, RequireCapability: "Storage"},
+			featureGate:    featureSet(""),
+			capabilities:   &csoutils.Capabilities{},
+			expectedReason: ReasonCapabilityDisabled,
+		},
+		{
+			name: "filtered out",
+			config: csioperatorclient.CSIOperatorConfig{
+				CSIDriverName: "ebs.csi.aws.com",
+				Platform:      v1.AWSPlatformType,
+				StatusFilter: func(*v1.Infrastructure, *v1.FeatureGate) bool {
+					return false
+				},
+			},
+			featureGate:    featureSet(""),
+			expectedReason: ReasonFilteredOut,
+		},
+		{
+			name:           "GA",
+			config:         csioperatorclient.CSIOperatorConfig{CSIDriverName: "ebs.csi.aws.com", Platform: v1.AWSPlatformType},
+			featureGate:    featureSet(""),
+			expectedReason: ReasonGA,
+		},
+		{
+			name:           "feature gate disabled",
+			config:         techPreview,
+			featureGate:    featureSet(""),
+			expectedReason: ReasonFeatureGateDisabled,
+		},
+		{
+			name:           "feature gate enabled",
+			config:         techPreview,
+			featureGate:    featureSet("TechPreviewNoUpgrade"),
+			expectedReason: ReasonFeatureGateEnabled,
+		},
+		{
+			name:           "unsupported CSI driver",
+			config:         techPreview,
+			featureGate:    featureSet("TechPreviewNoUpgrade"),
+			csiDriver:      csiDriver("csi.sharedresource.openshift.io", nil),
+			expectedReason: ReasonUnsupportedCSIDriverInstalled,
+		},
+		{
+			name:           "optional CSI driver without ClusterCSIDriver",
+			config:         csioperatorclient.CSIOperatorConfig{CSIDriverName: "efs.csi.aws.com", Platform: v1.AWSPlatformType, OptionalDriver: true},
+			featureGate:    featureSet(""),
+			expectedReason: ReasonNotRequested,
+		},
+		{
+			name:             "optional CSI driver with ClusterCSIDriver",
+			config:           csioperatorclient.CSIOperatorConfig{CSIDriverName: "efs.csi.aws.com", Platform: v1.AWSPlatformType, OptionalDriver: true},
+			featureGate:      featureSet(""),
+			clusterCSIDriver: &operatorv1.ClusterCSIDriver{ObjectMeta: metav1.ObjectMeta{Name: "efs.csi.aws.com"}},
+			expectedReason:   ReasonGA,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, _ := ShouldRun(test.config, infra, test.featureGate, test.capabilities, test.csiDriver, test.clusterCSIDriver)
+			if res.Reason != test.expectedReason {
+				t.Errorf("Expected reason %s, got %s", test.expectedReason, res.Reason)
+			}
+			if res.CSIDriverName != test.config.CSIDriverName || res.Message == "" {
+				t.Errorf("Expected CSI driver name and message, got %+v", res)
+			}
+		})
+	}
+}
+
+func featureSet(set v1.FeatureSet) *v1.FeatureGate {
+	return &v1.FeatureGate{
+		Spec: v1.FeatureGateSpec{
+			FeatureGateSelection: v1.FeatureGateSelection{
+				FeatureSet: set,
+			},
+		},
+	}
+}
+
+func customSet(gates ...string) *v1.FeatureGate {
+	return &v1.FeatureGate{
+		Spec: v1.FeatureGateSpec{
+			FeatureGateSelection: v1.FeatureGateSelection{
+				FeatureSet: v1.CustomNoUpgrade,
+				CustomNoUpgrade: &v1.CustomFeatureGates{
+					Enabled: gates,
+				},
+			},
+		},
+	}
+}
+
+func TestFilterExternalPlatform(t *testing.T) {
+	infra := &v1.Infrastructure{
+		Status: v1.InfrastructureStatus{
+			PlatformStatus: &v1.PlatformStatus{
+				Type: csioperatorclient.ExternalPlatformType,
+			},
+		},
+	}
+	cfg := csioperatorclient.CSIOperatorConfig{
+		CSIDriverName:        "csi.example.com",
+		Platform:             csioperatorclient.ExternalPlatformType,
+		ExternalPlatformName: "example-cloud",
+	}
+	res, err := ShouldRun(cfg, infra, featureSet(""), nil, nil, nil)
+	if err != nil || !res.Run {
+		t.Fatalf("expected the CSI driver to run on External platform, got %+v, %v", res, err)
+	}
+	if res := FilterExternalPlatform(res, cfg, "example-cloud"); !res.Run {
+		t.Errorf("expected the CSI driver to run on its cloud provider, got %+v", res)
+	}
+	if res := FilterExternalPlatform(res, cfg, "other-cloud"); res.Run || res.Reason != ReasonExternalPlatformMismatch {
+		t.Errorf("expected %s on another cloud provider, got %+v", ReasonExternalPlatformMismatch, res)
+	}
+}
+
+func TestFilterLegacyInTreeDriver(t *testing.T) {
+	res := Decision{Run: true, Reason: ReasonGA}
+	if res := FilterLegacyInTreeDriver(res, false); !res.Run {
+		t.Errorf("expected the CSI driver to run, got %+v", res)
+	}
+	if res := FilterLegacyInTreeDriver(res, true); res.Run || res.Reason != ReasonLegacyInTreeDriver {
+		t.Errorf("expected %s with legacy in-tree driver, got %+v", ReasonLegacyInTreeDriver, res)
+	}
+}
+
+func csiDriver(csiDriverName string, annotations map[string]string) *storagev1.CSIDriver {
+	return &storagev1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        csiDriverName,
+			Annotations: annotations,
+		},
+		Spec: storagev1.CSIDriverSpec{},
+	}
+}
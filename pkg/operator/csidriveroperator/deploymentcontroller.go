@@ -3,35 +3,54 @@ package csidriveroperator
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
 	configv1 "github.com/openshift/api/config/v1"
 	operatorv1 "github.com/openshift/api/operator/v1"
 	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	oplisters "github.com/openshift/client-go/operator/listers/operator/v1"
+	opv1alpha1listers "github.com/openshift/client-go/operator/listers/operator/v1alpha1"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
-	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 	"github.com/openshift/library-go/pkg/operator/status"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/credentialsrequest"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
 	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 )
 
 // This CSIDriverStarterController installs and syncs CSI driver operator Deployment.
-// It replace ${LOG_LEVEL} in the Deployment with current log level.
-// It replaces images in the Deployment using  CSIOperatorConfig.ImageReplacer.
+// It renders the Deployment with per-cluster values and CSIOperatorConfig
+// features, see the inject* and apply* helpers for details.
 // It produces following Conditions:
 // <CSI driver name>CSIDriverOperatorDeploymentProgressing
 // <CSI driver name>CSIDriverOperatorDeploymentDegraded
+// <CSI driver name>CSIDriverOperatorDeploymentFIPS - the operator runs in FIPS mode
+// <CSI driver name>CSIDriverOperatorDeploymentImagePullDegraded - the operator image can't be pulled
+// <CSI driver name>CSIDriverOperatorDeploymentCrashLoopDegraded - the operator crash-loops
+// <CSI driver name>CSIDriverOperatorDeploymentUpgradeable - false when the operator image is overridden
+// <CSI driver name>CSIDriverOperatorDeploymentNonGracefulShutdown - the driver handles non-graceful node shutdown
+// <CSI driver name>CSIDriverOperatorDeploymentReadWriteOncePodRequested - the operator was asked to enable ReadWriteOncePod volumes
+// <CSI driver name>CSIDriverOperatorDeploymentStorageClassManaged - StorageClasses of the driver are managed
+// <CSI driver name>CSIDriverOperatorDeploymentVolumeCloning - the CSI driver clones volumes
 // This controller doesn't set the Available condition to avoid prematurely cascading
 // up to the clusteroperator CR a potential Available=false. On the other hand it
 // does a better in making sure the Degraded condition is properly set if the
@@ -40,18 +59,49 @@ type CSIDriverOperatorDeploymentController struct {
 	name              string
 	operatorClient    v1helpers.OperatorClient
 	csiOperatorConfig csioperatorclient.CSIOperatorConfig
-	kubeClient        kubernetes.Interface
-	versionGetter     status.VersionGetter
-	targetVersion     string
-	eventRecorder     events.Recorder
-	infraLister       configv1listers.InfrastructureLister
-	factory           *factory.Factory
+	// kubeClient is client of the cluster where the Deployment runs.
+	kubeClient             kubernetes.Interface
+	splitClients           bool
+	controlPlaneNamespace  string
+	infraCluster           *csoclients.InfraCluster
+	versionGetter          status.VersionGetter
+	targetVersion          string
+	eventRecorder          events.Recorder
+	infraLister            configv1listers.InfrastructureLister
+	dynamicInfraLister     cache.GenericLister
+	networkLister          configv1listers.NetworkLister
+	authLister             configv1listers.AuthenticationLister
+	featureGateLister      configv1listers.FeatureGateLister
+	dynamicFGLister        cache.GenericLister
+	cloudCredLister        oplisters.CloudCredentialLister
+	secretLister           corelisters.SecretLister
+	configMapLister        corelisters.ConfigMapLister
+	podLister              corelisters.PodLister
+	deploymentLister       appslisters.DeploymentLister
+	nodeLister             corelisters.NodeLister
+	icspLister             opv1alpha1listers.ImageContentSourcePolicyLister
+	clusterCSIDriverLister oplisters.ClusterCSIDriverLister
+	factory                *factory.Factory
+	crashLoop              crashLoopBreaker
+	// issuerRollout is true while the Deployment is rolled out because of
+	// a changed ServiceAccount issuer.
+	issuerRollout bool
 }
 
 var _ factory.Controller = &CSIDriverOperatorDeploymentController{}
 
 const (
 	deploymentControllerName = "CSIDriverOperatorDeployment"
+	fipsConditionType        = "FIPS"
+	nonGracefulShutdownType  = "NonGracefulShutdown"
+	readWriteOncePodType     = "ReadWriteOncePodRequested"
+	volumeCloningType        = "VolumeCloning"
+
+	// Annotation of ClusterCSIDriver with image of the CSI driver operator
+	// to use instead of the one shipped in the release payload. It's meant
+	// for debugging and hotfixes only, the cluster can't be upgraded while
+	// it's set.
+	operandImageOverrideAnnotation = "storage.openshift.io/operand-image-override"
 )
 
 func NewCSIDriverOperatorDeploymentController(
@@ -75,19 +125,54 @@ func NewCSIDriverOperatorDeploymentController(
 	// controller queue, without anything reading it.
 	f = f.WithInformers(
 		clients.OperatorClient.Informer(),
-		clients.KubeInformers.InformersFor(csoclients.CSIOperatorNamespace).Apps().V1().Deployments().Informer(),
-		clients.ConfigInformers.Config().V1().Infrastructures().Informer())
+		clients.ControlPlaneKubeInformers.InformersFor(clients.ControlPlaneNamespace).Apps().V1().Deployments().Informer(),
+		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
+		clients.DynamicInformers.ForResource(csoutils.InfrastructureResource).Informer(),
+		clients.ConfigInformers.Config().V1().Networks().Informer(),
+		clients.ConfigInformers.Config().V1().Authentications().Informer(),
+		clients.ConfigInformers.Config().V1().FeatureGates().Informer(),
+		clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Informer(),
+		clients.OperatorInformers.Operator().V1().CloudCredentials().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.CSIOperatorNamespace).Core().V1().Secrets().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.CSIOperatorNamespace).Core().V1().ConfigMaps().Informer(),
+		clients.ControlPlaneKubeInformers.InformersFor(clients.ControlPlaneNamespace).Core().V1().Pods().Informer(),
+		clients.OperatorInformers.Operator().V1alpha1().ImageContentSourcePolicies().Informer(),
+		clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Informer())
+	if csiOperatorConfig.StorageClassTopology {
+		// Zones of StorageClasses are rendered from the nodes. Nodes are
+		// updated every few seconds, the controller is synced only when
+		// their zones change.
+		nodeInformer := clients.KubeInformers.InformersFor("").Core().V1().Nodes().Informer()
+		f = f.WithBareInformers(nodeInformer)
+		f = f.WithPostStartHooks(nodeZoneChangeHook(nodeInformer))
+	}
 
 	c := &CSIDriverOperatorDeploymentController{
-		name:              csiOperatorConfig.ConditionPrefix,
-		operatorClient:    clients.OperatorClient,
-		csiOperatorConfig: csiOperatorConfig,
-		kubeClient:        clients.KubeClient,
-		versionGetter:     versionGetter,
-		targetVersion:     targetVersion,
-		eventRecorder:     eventRecorder.WithComponentSuffix(csiOperatorConfig.ConditionPrefix),
-		factory:           f,
-		infraLister:       clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
+		name:                   csiOperatorConfig.ConditionPrefix,
+		operatorClient:         clients.OperatorClient,
+		csiOperatorConfig:      csiOperatorConfig,
+		kubeClient:             clients.ControlPlaneKubeClient,
+		splitClients:           clients.SplitClients,
+		controlPlaneNamespace:  clients.ControlPlaneNamespace,
+		infraCluster:           clients.InfraCluster,
+		versionGetter:          versionGetter,
+		targetVersion:          targetVersion,
+		eventRecorder:          eventRecorder.WithComponentSuffix(csiOperatorConfig.ConditionPrefix),
+		factory:                f,
+		infraLister:            clients.InfrastructureLister(),
+		dynamicInfraLister:     clients.DynamicInformers.ForResource(csoutils.InfrastructureResource).Lister(),
+		networkLister:          clients.ConfigInformers.Config().V1().Networks().Lister(),
+		authLister:             clients.ConfigInformers.Config().V1().Authentications().Lister(),
+		featureGateLister:      clients.ConfigInformers.Config().V1().FeatureGates().Lister(),
+		dynamicFGLister:        clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Lister(),
+		cloudCredLister:        clients.OperatorInformers.Operator().V1().CloudCredentials().Lister(),
+		secretLister:           clients.KubeInformers.InformersFor(csoclients.CSIOperatorNamespace).Core().V1().Secrets().Lister(),
+		configMapLister:        clients.KubeInformers.InformersFor(csoclients.CSIOperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		podLister:              clients.ControlPlaneKubeInformers.InformersFor(clients.ControlPlaneNamespace).Core().V1().Pods().Lister(),
+		deploymentLister:       clients.ControlPlaneKubeInformers.InformersFor(clients.ControlPlaneNamespace).Apps().V1().Deployments().Lister(),
+		nodeLister:             clients.KubeInformers.InformersFor("").Core().V1().Nodes().Lister(),
+		icspLister:             clients.OperatorInformers.Operator().V1alpha1().ImageContentSourcePolicies().Lister(),
+		clusterCSIDriverLister: clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Lister(),
 	}
 	return c
 }
@@ -111,32 +196,221 @@ func (c *CSIDriverOperatorDeploymentController) Sync(ctx context.Context, syncCt
 		return nil
 	}
 
-	replacers := []*strings.Replacer{sidecarReplacer}
-	// Replace images
-	if c.csiOperatorConfig.ImageReplacer != nil {
-		replacers = append(replacers, c.csiOperatorConfig.ImageReplacer)
+	imageOverride, err := c.getOperandImageOverride()
+	if err != nil {
+		return err
+	}
+	crGeneration, crashLoopRetry, err := c.getCrashLoopRetry()
+	if err != nil {
+		return err
 	}
 
-	required, err := csoutils.GetRequiredDeployment(c.csiOperatorConfig.DeploymentAsset, opSpec, replacers...)
+	infra, err := c.infraLister.Get(infraConfigName)
 	if err != nil {
-		return fmt.Errorf("failed to generate required Deployment: %s", err)
+		return fmt.Errorf("failed to get infrastructure resource: %w", err)
 	}
 
-	requiredCopy, err := util.InjectObservedProxyInDeploymentContainers(required, opSpec)
+	images := getImages(c.csiOperatorConfig)
+	if imageOverride != "" {
+		images[assettemplate.ImageOperator] = imageOverride
+	}
+	network, err := assettemplate.GetNetwork(c.networkLister)
 	if err != nil {
-		return fmt.Errorf("failed to inject proxy data into deployment: %w", err)
+		return err
+	}
+	values, err := assettemplate.NewValues(infra, network, opSpec, images)
+	if err != nil {
+		return err
+	}
+	required, err := csoutils.GetRequiredDeployment(c.csiOperatorConfig.GetAssetFunc(), c.csiOperatorConfig.DeploymentAsset, values)
+	if err != nil {
+		return fmt.Errorf("failed to generate required Deployment: %s", err)
 	}
 
-	infra, err := c.infraLister.Get(infraConfigName)
+	requiredCopy, err := util.InjectObservedProxyInAllDeploymentContainers(required, opSpec)
 	if err != nil {
-		return fmt.Errorf("failed to get infrastructure resource: %w", err)
+		return fmt.Errorf("failed to inject proxy data into deployment: %w", err)
 	}
+
+	csoutils.SetOwnedByLabel(requiredCopy, OwnerComponent)
+
 	if infra.Status.ControlPlaneTopology == configv1.ExternalTopologyMode {
 		requiredCopy.Spec.Template.Spec.NodeSelector = map[string]string{}
 	}
+	if c.splitClients {
+		requiredCopy = csoutils.InjectGuestKubeconfig(requiredCopy, c.controlPlaneNamespace)
+	}
+	if csoutils.RunsOnSingleReplica(infra, requiredCopy) {
+		requiredCopy = csoutils.InjectSingleReplica(requiredCopy)
+	}
+	cpuPartitioning, err := csoutils.IsCPUPartitioningEnabled(c.dynamicInfraLister)
+	if err != nil {
+		return err
+	}
+	if cpuPartitioning {
+		requiredCopy = csoutils.InjectWorkloadManagementAnnotation(requiredCopy)
+	}
+
+	if tokenConfig := c.csiOperatorConfig.BoundSAToken; tokenConfig != nil {
+		shortLivedTokens, err := csoutils.IsShortLivedTokenMode(c.authLister, c.cloudCredLister)
+		if err != nil {
+			return err
+		}
+		if shortLivedTokens {
+			audience, err := csoutils.GetBoundSATokenAudience(opSpec, c.csiOperatorConfig.CSIDriverName)
+			if err != nil {
+				return err
+			}
+			if audience == "" {
+				audience = tokenConfig.Audience
+			}
+			requiredCopy = csoutils.InjectBoundSATokenVolume(requiredCopy, audience, tokenConfig.ExpirationSeconds)
+			requiredCopy, err = c.injectServiceAccountIssuer(requiredCopy)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	requiredCopy = csoutils.InjectStorageDefaults(requiredCopy)
+
+	if c.csiOperatorConfig.CustomCABundle {
+		requiredCopy = csoutils.InjectCustomCABundle(requiredCopy)
+	}
+	// Hosted control planes do not get the trusted CA bundle of the guest
+	// cluster.
+	if !c.splitClients {
+		requiredCopy, err = c.applyTrustedCABundle(ctx, requiredCopy)
+		if err != nil {
+			return err
+		}
+	}
+
+	fipsEnabled, err := csoutils.IsFIPSEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to detect FIPS mode: %w", err)
+	}
+	// In FIPS mode the operator must use FIPS validated crypto, drivers that
+	// cannot are not installed.
+	if fipsEnabled {
+		if c.csiOperatorConfig.FIPSUnsupported {
+			// This will set Degraded condition
+			return fmt.Errorf("the cluster runs in FIPS mode and CSI driver %s does not support FIPS validated crypto", c.csiOperatorConfig.CSIDriverName)
+		}
+		requiredCopy = csoutils.InjectFIPSEnv(requiredCopy)
+	}
+
+	if c.csiOperatorConfig.IPFamilies {
+		var ipFamilies []string
+		for _, family := range values.Network.IPFamilies {
+			ipFamilies = append(ipFamilies, string(family))
+		}
+		requiredCopy = csoutils.InjectEnv(requiredCopy, csioperatorclient.IPFamiliesEnv, strings.Join(ipFamilies, ","))
+	}
+
+	maxUnavailable, err := getNodeMaxUnavailable(opSpec)
+	if err != nil {
+		return err
+	}
+	requiredCopy = csoutils.InjectEnv(requiredCopy, csioperatorclient.NodeMaxUnavailableEnv, maxUnavailable)
+	if c.csiOperatorConfig.StorageClassTopology {
+		requiredCopy, err = c.injectStorageClassZones(requiredCopy)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.csiOperatorConfig.StorageCapacity {
+		requiredCopy = csoutils.InjectEnv(requiredCopy, csioperatorclient.StorageCapacityEnv, "true")
+	}
+
+	featureGate, err := c.featureGateLister.Get(featureGateConfigName)
+	if err != nil {
+		return fmt.Errorf("failed to get FeatureGate: %w", err)
+	}
+	// Use features rendered for this release in the FeatureGate status.
+	featureGate, err = csoutils.GetFeatureGateWithStatus(c.dynamicFGLister, featureGate, c.targetVersion)
+	if err != nil {
+		return err
+	}
+	// Features that are still behind feature gates are enabled only in
+	// operators that support them.
+	nonGracefulShutdown := csoutils.FeatureGateEnabled(featureGate, csioperatorclient.NonGracefulShutdownFeatureGate)
+	if nonGracefulShutdown && c.csiOperatorConfig.NonGracefulShutdown {
+		requiredCopy = csoutils.InjectEnv(requiredCopy, csioperatorclient.NonGracefulShutdownEnv, "true")
+	}
+	readWriteOncePod := csoutils.FeatureGateEnabled(featureGate, csioperatorclient.ReadWriteOncePodFeatureGate)
+	if readWriteOncePod && c.csiOperatorConfig.ReadWriteOncePod {
+		requiredCopy = csoutils.InjectEnv(requiredCopy, csioperatorclient.ReadWriteOncePodEnv, "true")
+	}
+
+	volumeCloning := false
+	if c.csiOperatorConfig.VolumeCloning {
+		volumeCloning, err = c.isVolumeCloningEnabled()
+		if err != nil {
+			return err
+		}
+		requiredCopy = csoutils.InjectEnv(requiredCopy, csioperatorclient.VolumeCloningEnv, strconv.FormatBool(volumeCloning))
+	}
+
+	scState, err := c.getStorageClassState()
+	if err != nil {
+		// This will set Degraded condition
+		return err
+	}
+	requiredCopy = csoutils.InjectEnv(requiredCopy, csioperatorclient.StorageClassStateEnv, string(scState))
+
+	scParams, err := c.getStorageClassParameters(infra)
+	if err != nil {
+		// This will set Degraded condition
+		return err
+	}
+	if scParams != "" {
+		requiredCopy = csoutils.InjectEnv(requiredCopy, csioperatorclient.StorageClassParametersEnv, scParams)
+	}
+
+	if probe := c.csiOperatorConfig.LivenessProbe; probe != nil {
+		requiredCopy = injectSortedEnv(requiredCopy, probe.Env())
+	}
+	if envFunc := c.csiOperatorConfig.InfrastructureEnv; envFunc != nil {
+		requiredCopy = injectSortedEnv(requiredCopy, envFunc(infra))
+	}
+	if c.csiOperatorConfig.KubeVirtInfraCluster {
+		requiredCopy, err = c.applyInfraCluster(ctx, requiredCopy)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, hook := range c.csiOperatorConfig.DeploymentHooks {
+		if err := hook(opSpec, requiredCopy); err != nil {
+			return fmt.Errorf("failed to run Deployment hook: %w", err)
+		}
+	}
+
+	requiredCopy = injectCrashLoopRetry(requiredCopy, crashLoopRetry)
+	requiredCopy, err = c.injectDependencyHashes(requiredCopy)
+	if err != nil {
+		return err
+	}
+	requiredCopy, err = csoutils.InjectConfigHash(requiredCopy)
+	if err != nil {
+		return err
+	}
+
+	if c.isCrashLoopBreakerTripped(crGeneration, crashLoopRetry) {
+		// Keep the crash-looping pods, don't roll them out again and again.
+		_, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(c.crashLoop.condition))
+		return err
+	}
+
+	requiredCopy, err = c.preUpgrade(ctx, requiredCopy)
+	if err != nil {
+		return err
+	}
 
 	lastGeneration := resourcemerge.ExpectedDeploymentGeneration(requiredCopy, opStatus.Generations)
-	deployment, _, err := resourceapply.ApplyDeployment(ctx, c.kubeClient.AppsV1(), c.eventRecorder, requiredCopy, lastGeneration)
+	deployment, err := csoutils.ApplyDeploymentUnlessPaused(ctx, c.kubeClient.AppsV1(), c.eventRecorder, opSpec, requiredCopy, lastGeneration)
 	if err != nil {
 		return err
 	}
@@ -150,6 +424,12 @@ func (c *CSIDriverOperatorDeploymentController) Sync(ctx context.Context, syncCt
 		progressingCondition.Status = operatorv1.ConditionTrue
 		progressingCondition.Message = msg
 		progressingCondition.Reason = "Deploying"
+		if c.issuerRollout {
+			progressingCondition.Reason = "ServiceAccountIssuerChanged"
+			progressingCondition.Message = "Restarting the operator with tokens of the new ServiceAccount issuer: " + msg
+		}
+	} else {
+		c.issuerRollout = false
 	}
 
 	updateStatusFn := func(newStatus *operatorv1.OperatorStatus) error {
@@ -157,18 +437,296 @@ func (c *CSIDriverOperatorDeploymentController) Sync(ctx context.Context, syncCt
 		return nil
 	}
 
+	fipsCondition := operatorv1.OperatorCondition{
+		Type:   c.Name() + fipsConditionType,
+		Status: operatorv1.ConditionFalse,
+		Reason: "FIPSDisabled",
+	}
+	if fipsEnabled {
+		fipsCondition.Status = operatorv1.ConditionTrue
+		fipsCondition.Reason = "FIPSEnabled"
+		fipsCondition.Message = "The cluster runs in FIPS mode, the CSI driver operator uses FIPS validated crypto"
+	}
+
+	imagePullCondition, err := c.checkImagePull(deployment)
+	if err != nil {
+		return err
+	}
+	crashLoopCondition, err := c.checkCrashLoop(deployment, crGeneration, crashLoopRetry)
+	if err != nil {
+		return err
+	}
+
+	upgradeableCondition := operatorv1.OperatorCondition{
+		Type:   c.Name() + operatorv1.OperatorStatusTypeUpgradeable,
+		Status: operatorv1.ConditionTrue,
+	}
+	if imageOverride != "" {
+		upgradeableCondition.Status = operatorv1.ConditionFalse
+		upgradeableCondition.Reason = "OperandImageOverridden"
+		upgradeableCondition.Message = fmt.Sprintf("ClusterCSIDriver %s overrides the CSI driver operator image with %s using annotation %s, remove the annotation to allow upgrades", c.csiOperatorConfig.CSIDriverName, imageOverride, operandImageOverrideAnnotation)
+		if !v1helpers.IsOperatorConditionFalse(opStatus.Conditions, upgradeableCondition.Type) {
+			c.eventRecorder.Warningf("OperandImageOverridden", "CSI driver operator image overridden with %s, upgrades are blocked", imageOverride)
+		}
+	}
+
+	progressing := progressingCondition.Status == operatorv1.ConditionTrue
+	nonGracefulShutdownCondition := c.featureCondition(nonGracefulShutdownType, csioperatorclient.NonGracefulShutdownFeatureGate,
+		nonGracefulShutdown, c.csiOperatorConfig.NonGracefulShutdown, progressing,
+		"Volumes are force detached from nodes tainted with node.kubernetes.io/out-of-service")
+	// The driver does not report whether RWOP works, the condition only
+	// says the operator was asked to enable it.
+	readWriteOncePodCondition := c.featureCondition(readWriteOncePodType, csioperatorclient.ReadWriteOncePodFeatureGate,
+		readWriteOncePod, c.csiOperatorConfig.ReadWriteOncePod, progressing,
+		"The CSI driver operator was asked to enable ReadWriteOncePod access mode")
+	volumeCloningCondition := c.volumeCloningCondition(volumeCloning, progressing)
+	scStateCondition := c.storageClassStateCondition(scState, progressing)
+
 	_, _, err = v1helpers.UpdateStatus(
 		c.operatorClient,
 		updateStatusFn,
 		v1helpers.UpdateConditionFn(progressingCondition),
+		v1helpers.UpdateConditionFn(fipsCondition),
+		v1helpers.UpdateConditionFn(imagePullCondition),
+		v1helpers.UpdateConditionFn(crashLoopCondition),
+		v1helpers.UpdateConditionFn(upgradeableCondition),
+		v1helpers.UpdateConditionFn(nonGracefulShutdownCondition),
+		v1helpers.UpdateConditionFn(readWriteOncePodCondition),
+		v1helpers.UpdateConditionFn(volumeCloningCondition),
+		v1helpers.UpdateConditionFn(scStateCondition),
 	)
 
+	if err != nil {
+		return err
+	}
+	if err := c.postUpgrade(ctx, deployment, progressing); err != nil {
+		return err
+	}
+	if imagePullCondition.Status == operatorv1.ConditionTrue || crashLoopCondition.Status == operatorv1.ConditionTrue {
+		// The specific ImagePullDegraded or CrashLoopDegraded condition is
+		// already set, don't mask it with a generic error about unhealthy
+		// Deployment.
+		return nil
+	}
 	return checkDeploymentHealth(ctx, c.kubeClient.AppsV1(), deployment)
 }
 
+// injectSortedEnv returns a copy of the Deployment with the env. vars set in
+// all its containers, in a stable order, so the Deployment does not change
+// on each sync.
+func injectSortedEnv(deployment *appsv1.Deployment, env map[string]string) *appsv1.Deployment {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		deployment = csoutils.InjectEnv(deployment, name, env[name])
+	}
+	return deployment
+}
+
+// featureCondition returns condition that reports whether an optional
+// feature of the CSI driver, enabled by the feature gate, is ready to use.
+func (c *CSIDriverOperatorDeploymentController) featureCondition(conditionType, featureGate string, featureEnabled, supported, progressing bool, enabledMessage string) operatorv1.OperatorCondition {
+	cnd := operatorv1.OperatorCondition{
+		Type:   c.Name() + conditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+	switch {
+	case !featureEnabled:
+		cnd.Reason = "FeatureGateDisabled"
+		cnd.Message = fmt.Sprintf("Feature gate %s is not enabled", featureGate)
+	case !supported:
+		cnd.Reason = "NotSupported"
+		cnd.Message = fmt.Sprintf("CSI driver %s does not support %s", c.csiOperatorConfig.CSIDriverName, featureGate)
+	case progressing:
+		cnd.Reason = "Deploying"
+		cnd.Message = fmt.Sprintf("Waiting for the CSI driver operator to enable %s", featureGate)
+	default:
+		cnd.Status = operatorv1.ConditionTrue
+		cnd.Reason = "Enabled"
+		cnd.Message = enabledMessage
+	}
+	return cnd
+}
+
+// volumeCloningCondition reports whether the CSI driver clones volumes.
+func (c *CSIDriverOperatorDeploymentController) volumeCloningCondition(enabled, progressing bool) operatorv1.OperatorCondition {
+	cnd := operatorv1.OperatorCondition{
+		Type:   c.Name() + volumeCloningType,
+		Status: operatorv1.ConditionFalse,
+	}
+	switch {
+	case !c.csiOperatorConfig.VolumeCloning:
+		cnd.Reason = "NotSupported"
+		cnd.Message = fmt.Sprintf("CSI driver %s does not support volume cloning", c.csiOperatorConfig.CSIDriverName)
+	case !enabled:
+		cnd.Reason = "Disabled"
+		cnd.Message = fmt.Sprintf("Volume cloning is disabled in ClusterCSIDriver %s", c.csiOperatorConfig.CSIDriverName)
+	case progressing:
+		cnd.Reason = "Deploying"
+		cnd.Message = "Waiting for the CSI driver operator to apply volume cloning configuration"
+	default:
+		cnd.Status = operatorv1.ConditionTrue
+		cnd.Reason = "Enabled"
+		cnd.Message = "Volumes can be cloned"
+	}
+	return cnd
+}
+
+// isVolumeCloningEnabled returns false when volume cloning is disabled in
+// ClusterCSIDriver, see csioperatorclient.VolumeCloningEnv.
+func (c *CSIDriverOperatorDeploymentController) isVolumeCloningEnabled() (bool, error) {
+	cr, err := c.clusterCSIDriverLister.Get(c.csiOperatorConfig.CSIDriverName)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	enabled := true
+	if _, err := csoutils.GetUnsupportedConfigOverride(&cr.Spec.OperatorSpec, "volumeCloning", &enabled); err != nil {
+		return false, fmt.Errorf("invalid ClusterCSIDriver %s: %w", cr.Name, err)
+	}
+	return enabled, nil
+}
+
+// getStorageClassParameters returns parameters of StorageClasses of the CSI
+// driver from the ClusterCSIDriver, in the format of
+// StorageClassParametersEnv. They're the admin's storageClassParameters
+// overlay (see storageClassParameters) and, for operators with
+// CSIOperatorConfig.StorageClassEncryption, the customer managed encryption
+// key from driverConfig.
+func (c *CSIDriverOperatorDeploymentController) getStorageClassParameters(infra *configv1.Infrastructure) (string, error) {
+	cr, err := c.clusterCSIDriverLister.Get(c.csiOperatorConfig.CSIDriverName)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	params, err := getStorageClassParametersOverlay(&cr.Spec.OperatorSpec)
+	if err != nil {
+		return "", fmt.Errorf("invalid ClusterCSIDriver %s: %w", cr.Name, err)
+	}
+	if c.csiOperatorConfig.StorageClassEncryption && infra.Status.PlatformStatus != nil {
+		cfg, err := csoutils.GetDriverConfig(&cr.Spec.OperatorSpec)
+		if err != nil {
+			return "", fmt.Errorf("invalid ClusterCSIDriver %s: %w", cr.Name, err)
+		}
+		encryptionParams, err := csoutils.GetEncryptionParameters(infra.Status.PlatformStatus.Type, cfg)
+		if err != nil {
+			return "", fmt.Errorf("invalid ClusterCSIDriver %s: %w", cr.Name, err)
+		}
+		for name, value := range encryptionParams {
+			if overlay, found := params[name]; found && overlay != value {
+				return "", fmt.Errorf("invalid ClusterCSIDriver %s: storageClassParameters %s conflicts with the encryption key in driverConfig", cr.Name, name)
+			}
+			params[name] = value
+		}
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+params[name])
+	}
+	return strings.Join(pairs, ","), nil
+}
+
+// getOperandImageOverride returns the CSI driver operator image set by
+// operandImageOverrideAnnotation on ClusterCSIDriver, if any.
+func (c *CSIDriverOperatorDeploymentController) getOperandImageOverride() (string, error) {
+	cr, err := c.clusterCSIDriverLister.Get(c.csiOperatorConfig.CSIDriverName)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return cr.Annotations[operandImageOverrideAnnotation], nil
+}
+
+// injectServiceAccountIssuer annotates pod template of the Deployment with
+// the current ServiceAccount issuer, so the operator is restarted with valid
+// tokens when the issuer changes. The change is reported in the Progressing
+// condition until the Deployment is rolled out.
+func (c *CSIDriverOperatorDeploymentController) injectServiceAccountIssuer(deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	issuer, err := csoutils.GetServiceAccountIssuer(c.authLister)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := c.deploymentLister.Deployments(deployment.Namespace).Get(deployment.Name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		oldIssuer, found := existing.Spec.Template.Annotations[csoutils.ServiceAccountIssuerAnnotation]
+		if found && oldIssuer != issuer {
+			klog.V(2).Infof("ServiceAccount issuer changed from %q to %q, restarting Deployment %s", oldIssuer, issuer, deployment.Name)
+			c.eventRecorder.Eventf("ServiceAccountIssuerChanged", "Restarting Deployment %s with tokens of ServiceAccount issuer %s", deployment.Name, issuer)
+			c.issuerRollout = true
+		}
+	}
+	return csoutils.InjectServiceAccountIssuer(deployment, issuer), nil
+}
+
+// injectDependencyHashes annotates the Deployment with hashes of Secrets and
+// ConfigMaps consumed by the CSI driver operator. Objects that do not exist
+// yet are skipped, the Deployment is rolled out once they're created.
+func (c *CSIDriverOperatorDeploymentController) injectDependencyHashes(deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	var secrets []*corev1.Secret
+	if c.csiOperatorConfig.CredentialsRequestAsset != "" {
+		namespace, name, err := credentialsrequest.SecretRef(c.csiOperatorConfig.GetAssetFunc(), c.csiOperatorConfig.CredentialsRequestAsset)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := c.secretLister.Secrets(namespace).Get(name)
+		switch {
+		case err == nil:
+			secrets = append(secrets, secret)
+		case !apierrors.IsNotFound(err):
+			return nil, err
+		}
+	}
+	for _, name := range c.csiOperatorConfig.RolloutSecrets {
+		secret, err := c.secretLister.Secrets(csoclients.CSIOperatorNamespace).Get(name)
+		switch {
+		case err == nil:
+			secrets = append(secrets, secret)
+		case !apierrors.IsNotFound(err):
+			return nil, err
+		}
+	}
+
+	configMapNames := append([]string{}, c.csiOperatorConfig.RolloutConfigMaps...)
+	if c.csiOperatorConfig.CustomCABundle {
+		configMapNames = append(configMapNames, csoutils.CustomCABundleConfigMapName)
+	}
+	if !c.splitClients {
+		configMapNames = append(configMapNames, trustedCABundleConfigMapName)
+	}
+	var configMaps []*corev1.ConfigMap
+	for _, name := range configMapNames {
+		cm, err := c.configMapLister.ConfigMaps(csoclients.CSIOperatorNamespace).Get(name)
+		switch {
+		case err == nil:
+			configMaps = append(configMaps, cm)
+		case !apierrors.IsNotFound(err):
+			return nil, err
+		}
+	}
+
+	return csoutils.InjectDependencyHashes(deployment, secrets, configMaps)
+}
+
 func (c *CSIDriverOperatorDeploymentController) Run(ctx context.Context, workers int) {
 	// This adds event handlers to informers.
-	ctrl := c.factory.WithSync(c.Sync).ToController(c.Name(), c.eventRecorder)
+	ctrl := c.factory.WithSync(health.TrackSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
 	ctrl.Run(ctx, workers)
 }
 
@@ -0,0 +1,82 @@
+package csidriveroperator
+
+import (
+	"testing"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/credentialsrequest"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/operator/events"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestInjectDependencyHashesAnnotations checks that annotations with hashes
+// of all Secrets and ConfigMaps of the built-in CSI driver operators are
+// accepted by the API server.
+func TestInjectDependencyHashesAnnotations(t *testing.T) {
+	clients := csoclients.NewFakeClients(&csoclients.FakeTestObjects{})
+	recorder := events.NewInMemoryRecorder("test")
+	configs := []csioperatorclient.CSIOperatorConfig{
+		csioperatorclient.GetAWSEBSCSIOperatorConfig(),
+		csioperatorclient.GetGCPPDCSIOperatorConfig(),
+		csioperatorclient.GetOpenStackCinderCSIOperatorConfig(clients, recorder),
+		csioperatorclient.GetOVirtCSIOperatorConfig(clients, recorder),
+		csioperatorclient.GetManilaOperatorConfig(clients, recorder),
+		csioperatorclient.GetVMwareVSphereCSIOperatorConfig(),
+		csioperatorclient.GetAzureDiskCSIOperatorConfig(),
+		csioperatorclient.GetAzureFileCSIOperatorConfig(),
+		csioperatorclient.GetSharedResourceCSIOperatorConfig(),
+		csioperatorclient.GetNutanixCSIOperatorConfig(),
+		csioperatorclient.GetAlibabaDiskCSIOperatorConfig(),
+		csioperatorclient.GetKubeVirtCSIOperatorConfig(),
+		csioperatorclient.GetIBMVPCBlockCSIOperatorConfig(),
+		csioperatorclient.GetHostPathCSIOperatorConfig(),
+	}
+	for _, cfg := range configs {
+		t.Run(cfg.ConditionPrefix, func(t *testing.T) {
+			secrets := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			configMaps := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			data := map[string]string{"key": "value"}
+			if cfg.CredentialsRequestAsset != "" {
+				namespace, name, err := credentialsrequest.SecretRef(cfg.GetAssetFunc(), cfg.CredentialsRequestAsset)
+				if err != nil {
+					t.Fatalf("failed to read CredentialsRequest: %s", err)
+				}
+				secrets.Add(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}, StringData: data})
+			}
+			for _, name := range cfg.RolloutSecrets {
+				secrets.Add(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: csoclients.CSIOperatorNamespace, Name: name}, StringData: data})
+			}
+			names := append([]string{csoutils.CustomCABundleConfigMapName, trustedCABundleConfigMapName}, cfg.RolloutConfigMaps...)
+			for _, name := range names {
+				configMaps.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: csoclients.CSIOperatorNamespace, Name: name}, Data: data})
+			}
+			cfg.CustomCABundle = true
+			c := &CSIDriverOperatorDeploymentController{
+				csiOperatorConfig: cfg,
+				secretLister:      corelisters.NewSecretLister(secrets),
+				configMapLister:   corelisters.NewConfigMapLister(configMaps),
+			}
+
+			deployment, err := c.injectDependencyHashes(&appsv1.Deployment{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			annotations := deployment.Spec.Template.Annotations
+			expected := len(secrets.List()) + len(configMaps.List())
+			if len(annotations) != expected {
+				t.Errorf("expected %d annotations, got %d: %v", expected, len(annotations), annotations)
+			}
+			if errs := apivalidation.ValidateAnnotations(annotations, field.NewPath("metadata", "annotations")); len(errs) > 0 {
+				t.Errorf("invalid annotations: %s", errs.ToAggregate())
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package csidriveroperator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/driverregistry"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+// syncDriverConfigs loads configs of CSI driver operators from
+// driverregistry.ConfigMapName and updates the list of ControllerManagers.
+// ControllerManagers of removed configs are stopped and their CSI driver
+// operator Deployments removed, ControllerManagers of changed configs are
+// stopped and created again with the new config in this sync. Drivers
+// compiled into CSO or registered by driverregistry.Register take
+// precedence, a loaded config with the same CSI driver name or condition
+// prefix is ignored. Invalid configs are reported in
+// CSIDriverStarterDriverConfigDegraded condition and do not affect the
+// other ones.
+func (c *CSIDriverStarterController) syncDriverConfigs(ctx context.Context) error {
+	data := map[string]string{}
+	cm, err := c.configMapLister.ConfigMaps(csoclients.OperatorNamespace).Get(driverregistry.ConfigMapName)
+	switch {
+	case err == nil:
+		data = cm.Data
+	case !errors.IsNotFound(err):
+		return err
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var invalid []string
+	loaded := map[string]csiDriverControllerManager{}
+	for _, key := range keys {
+		cfg, err := driverregistry.ParseConfig([]byte(data[key]))
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", key, err))
+			continue
+		}
+		loaded[key] = csiDriverControllerManager{
+			operatorConfig: cfg,
+			configKey:      key,
+			configSource:   data[key],
+		}
+	}
+
+	// Keep built-in and unchanged controllers.
+	var controllers []csiDriverControllerManager
+	for i := range c.controllers {
+		ctrl := &c.controllers[i]
+		if ctrl.configKey == "" {
+			controllers = append(controllers, *ctrl)
+			continue
+		}
+		next, found := loaded[ctrl.configKey]
+		if found && next.configSource == ctrl.configSource {
+			controllers = append(controllers, *ctrl)
+			delete(loaded, ctrl.configKey)
+			continue
+		}
+		if found && next.operatorConfig.CSIDriverName == ctrl.operatorConfig.CSIDriverName {
+			klog.V(2).Infof("Config of CSI driver %s changed, restarting its ControllerManager", ctrl.operatorConfig.CSIDriverName)
+			if ctrl.running {
+				c.stopManager(ctrl)
+				relatedObjects.remove(ctrl.operatorConfig.CSIDriverName)
+			}
+			c.eventRecorder.Eventf("CSIDriverConfigChanged", "Reloaded config of CSI driver %s from ConfigMap %s", ctrl.operatorConfig.CSIDriverName, driverregistry.ConfigMapName)
+			continue
+		}
+		klog.V(2).Infof("Config of CSI driver %s removed, stopping its ControllerManager", ctrl.operatorConfig.CSIDriverName)
+		if err := c.stopController(ctx, ctrl); err != nil {
+			return err
+		}
+		c.eventRecorder.Eventf("CSIDriverConfigRemoved", "Removed config of CSI driver %s from ConfigMap %s", ctrl.operatorConfig.CSIDriverName, driverregistry.ConfigMapName)
+	}
+
+	used := map[string]string{}
+	for _, ctrl := range controllers {
+		used[ctrl.operatorConfig.CSIDriverName] = ctrl.operatorConfig.CSIDriverName
+		used[ctrl.operatorConfig.ConditionPrefix] = ctrl.operatorConfig.CSIDriverName
+	}
+	for _, key := range keys {
+		ctrl, found := loaded[key]
+		if !found {
+			continue
+		}
+		cfg := ctrl.operatorConfig
+		if driver, conflict := used[cfg.CSIDriverName]; conflict {
+			invalid = append(invalid, fmt.Sprintf("%s: CSI driver %s is already managed by the operator", key, driver))
+			continue
+		}
+		if driver, conflict := used[cfg.ConditionPrefix]; conflict {
+			invalid = append(invalid, fmt.Sprintf("%s: condition prefix %s is already used by CSI driver %s", key, cfg.ConditionPrefix, driver))
+			continue
+		}
+		used[cfg.CSIDriverName] = cfg.CSIDriverName
+		used[cfg.ConditionPrefix] = cfg.CSIDriverName
+		klog.V(2).Infof("Loaded config of CSI driver %s from ConfigMap %s", cfg.CSIDriverName, driverregistry.ConfigMapName)
+		controllers = append(controllers, ctrl)
+	}
+	c.controllers = controllers
+
+	return c.updateDriverConfigDegraded(invalid)
+}
+
+// updateDriverConfigDegraded reports invalid configs in
+// driverregistry.ConfigMapName.
+func (c *CSIDriverStarterController) updateDriverConfigDegraded(invalid []string) error {
+	cnd := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarterDriverConfig" + operatorapi.OperatorStatusTypeDegraded,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	if len(invalid) > 0 {
+		cnd.Status = operatorapi.ConditionTrue
+		cnd.Reason = "InvalidDriverConfig"
+		cnd.Message = fmt.Sprintf("Invalid CSI driver configs in ConfigMap %s/%s are ignored: %s", csoclients.OperatorNamespace, driverregistry.ConfigMapName, strings.Join(invalid, "; "))
+	}
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(cnd))
+	return err
+}
@@ -3,25 +3,36 @@ package csidriveroperator
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	operatorapi "github.com/openshift/api/operator/v1"
 	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
-	"github.com/openshift/cluster-storage-operator/assets"
+	oplisters "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/driverregistry"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/credentialsrequest"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/decision"
 	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/controller/manager"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/staticresourcecontroller"
 	"github.com/openshift/library-go/pkg/operator/status"
-	storagev1 "k8s.io/api/storage/v1"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	corelister "k8s.io/client-go/listers/core/v1"
 	storagelister "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
 
@@ -29,27 +40,73 @@ const (
 	infraConfigName       = "cluster"
 	featureGateConfigName = "cluster"
 
-	annOpenShiftManaged = "csi.openshift.io/managed"
-)
+	// Retry intervals while waiting for Infrastructure and FeatureGate
+	// during installation.
+	minBootstrapBackoff = time.Second
+	maxBootstrapBackoff = time.Minute
+
+	// Retry interval of failed preflight checks.
+	preflightRetryInterval = time.Minute
 
-var (
-	relatedObjects []configv1.ObjectReference
+	// OwnerComponent is value of csoutils.ComponentLabel of objects created
+	// for CSI driver operators.
+	OwnerComponent = "csi-driver-operator"
 )
 
 // This CSIDriverStarterController starts CSI driver controllers based on the
 // underlying cloud and removes it from OLM. It does not install anything by
 // itself, only monitors Infrastructure instance and starts individual
-// ControllerManagers for the particular cloud. It produces following Conditions:
-// CSIDriverStarterDegraded - error checking the Infrastructure
+// ControllerManagers for the particular cloud. When a CSI driver should not
+// run anymore, e.g. its feature gate was disabled, it stops its
+// ControllerManager and removes the CSI driver operator Deployment and
+// CredentialsRequest. It produces following Conditions:
+// CSIDriverStarterDegraded - error checking the Infrastructure or creating
+// controllers of a CSI driver
+// CSIDriverStarterUpgradeable - false when the platform is overridden by
+// csoclients.PlatformOverrideEnv
+// CSIDriverStarterCapabilityDisabled - true when a CSI driver for the
+// platform does not run, because its cluster capability is disabled
+// CSIDriverStarterProgressing - true while Infrastructure or FeatureGate
+// does not exist yet during installation
+// CSIDriverStarterPlatformChangedDegraded - true when Infrastructure
+// platform changed after CSI drivers were installed
+// CSIDriverStarterPausedUpgradeable - false when reconciliation of a CSI
+// driver is paused
+// CSIDriverStarterAvailable - reports removal of all objects in
+// ManagementState Removed
+// CSIDriverStarterDriverConfigDegraded - true when ConfigMap
+// driverregistry.ConfigMapName has invalid CSI driver configs
+// <CSI driver name>CSIDriverOperatorPreflightDegraded - true when
+// a preflight check of a CSI driver failed and its operator is not started,
+// see csioperatorclient.PreflightCheck
+// CSIDriverStarterPlatformVariantDegraded - true when the cluster runs on
+// an unsupported variant of its platform, see
+// csioperatorclient.CheckPlatformVariant
 type CSIDriverStarterController struct {
-	operatorClient    *operatorclient.OperatorClient
-	infraLister       openshiftv1.InfrastructureLister
-	featureGateLister openshiftv1.FeatureGateLister
-	csiDriverLister   storagelister.CSIDriverLister
-	versionGetter     status.VersionGetter
-	targetVersion     string
-	eventRecorder     events.Recorder
-	controllers       []csiDriverControllerManager
+	clients                *csoclients.Clients
+	resyncInterval         time.Duration
+	operatorClient         *operatorclient.OperatorClient
+	kubeClient             kubernetes.Interface
+	dynamicClient          dynamic.Interface
+	infraLister            openshiftv1.InfrastructureLister
+	featureGateLister      openshiftv1.FeatureGateLister
+	dynamicFGLister        cache.GenericLister
+	clusterVersionLister   cache.GenericLister
+	dynamicInfraLister     cache.GenericLister
+	csiDriverLister        storagelister.CSIDriverLister
+	clusterCSIDriverLister oplisters.ClusterCSIDriverLister
+	configMapLister        corelister.ConfigMapLister
+	versionGetter          status.VersionGetter
+	targetVersion          string
+	eventRecorder          events.Recorder
+	workers                csoutils.ControllerWorkers
+	platformOverride       configv1.PlatformType
+	controllers            []csiDriverControllerManager
+	// bootstrapBackoff is the current retry interval while waiting for
+	// Infrastructure and FeatureGate during installation.
+	bootstrapBackoff time.Duration
+	// removed is true when objects were removed in ManagementState Removed.
+	removed bool
 }
 
 type RelatedObjectGetter interface {
@@ -58,11 +115,27 @@ type RelatedObjectGetter interface {
 
 type csiDriverControllerManager struct {
 	operatorConfig csioperatorclient.CSIOperatorConfig
+	// configKey is key of the config in driverregistry.ConfigMapName and
+	// configSource its content. Both are empty for configs compiled into
+	// CSO.
+	configKey    string
+	configSource string
 	// ControllerManager that installs the CSI driver operator and all its
-	// objects.
+	// objects. It's created only when the CSI driver should run.
 	mgr                manager.ControllerManager
 	running            bool
+	stop               context.CancelFunc
 	ctrlRelatedObjects RelatedObjectGetter
+	// Whether CredentialsRequest of a driver that should not run has been
+	// removed.
+	credentialsRemoved bool
+	// exited is closed when the running ControllerManager exits.
+	exited    chan struct{}
+	startTime time.Time
+	// restartBackoff is the delay of the next restart of a ControllerManager
+	// that exited, see checkManagerExited.
+	restartBackoff time.Duration
+	restartTime    time.Time
 }
 
 func NewCSIDriverStarterController(
@@ -71,40 +144,53 @@ func NewCSIDriverStarterController(
 	versionGetter status.VersionGetter,
 	targetVersion string,
 	eventRecorder events.Recorder,
-	driverConfigs []csioperatorclient.CSIOperatorConfig) factory.Controller {
+	driverConfigs []csioperatorclient.CSIOperatorConfig,
+	workers csoutils.ControllerWorkers) factory.Controller {
 	c := &CSIDriverStarterController{
-		operatorClient:    clients.OperatorClient,
-		infraLister:       clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
-		featureGateLister: clients.ConfigInformers.Config().V1().FeatureGates().Lister(),
-		csiDriverLister:   clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Lister(),
-		versionGetter:     versionGetter,
-		targetVersion:     targetVersion,
-		eventRecorder:     eventRecorder.WithComponentSuffix("CSIDriverStarter"),
-	}
-	relatedObjects = []configv1.ObjectReference{}
-
-	// Populating all CSI driver operator ControllerManagers here simplifies
-	// the startup a lot
-	// - All necessary informers are populated.
-	// - CSO then just Run()s
-	// All ControllerManagers are not running at this point! They will be
-	// started in sync() when their platform is detected.
+		clients:                clients,
+		resyncInterval:         resyncInterval,
+		operatorClient:         clients.OperatorClient,
+		kubeClient:             clients.KubeClient,
+		dynamicClient:          clients.DynamicClient,
+		infraLister:            clients.InfrastructureLister(),
+		featureGateLister:      clients.ConfigInformers.Config().V1().FeatureGates().Lister(),
+		dynamicFGLister:        clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Lister(),
+		clusterVersionLister:   clients.DynamicInformers.ForResource(csoutils.ClusterVersionResource).Lister(),
+		dynamicInfraLister:     clients.DynamicInformers.ForResource(csoutils.InfrastructureResource).Lister(),
+		csiDriverLister:        clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Lister(),
+		clusterCSIDriverLister: clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Lister(),
+		configMapLister:        clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		versionGetter:          versionGetter,
+		targetVersion:          targetVersion,
+		eventRecorder:          eventRecorder.WithComponentSuffix("CSIDriverStarter"),
+		workers:                workers,
+		platformOverride:       clients.PlatformOverride,
+	}
+	relatedObjects.setStorageClassLister(clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister())
+
+	// ControllerManagers are created in sync() when their platform is
+	// detected, only one or two CSI drivers run on a cluster and each
+	// ControllerManager adds its own informers and controllers.
 	c.controllers = []csiDriverControllerManager{}
-	for _, cfg := range driverConfigs {
-		mgr, ctrlRelatedObjects := c.createCSIControllerManager(cfg, clients, resyncInterval)
+	for _, cfg := range dedupConfigs(driverConfigs) {
 		c.controllers = append(c.controllers, csiDriverControllerManager{
-			operatorConfig:     cfg,
-			mgr:                mgr,
-			running:            false,
-			ctrlRelatedObjects: ctrlRelatedObjects,
+			operatorConfig: cfg,
+			running:        false,
 		})
 	}
 
-	return factory.New().WithSync(c.sync).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+	return factory.New().WithSync(health.TrackSync("CSIDriverStarter", c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
 		clients.OperatorClient.Informer(),
 		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
 		clients.ConfigInformers.Config().V1().FeatureGates().Informer(),
 		clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Informer(),
+		clients.OperatorInformers.Operator().V1().ClusterCSIDrivers().Informer(),
+		clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Informer(),
+		clients.DynamicInformers.ForResource(csoutils.ClusterVersionResource).Informer(),
+		clients.DynamicInformers.ForResource(csoutils.InfrastructureResource).Informer(),
+	).WithFilteredEventsInformers(
+		factory.NamesFilter(driverregistry.ConfigMapName),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
 	).ToController("CSIDriverStarter", eventRecorder)
 }
 
@@ -116,20 +202,76 @@ func (c *CSIDriverStarterController) sync(ctx context.Context, syncCtx factory.S
 	if err != nil {
 		return err
 	}
+	if opSpec.ManagementState == operatorapi.Removed {
+		return c.syncRemoved(ctx)
+	}
 	if opSpec.ManagementState != operatorapi.Managed {
 		return nil
 	}
+	if err := c.syncManaged(); err != nil {
+		return err
+	}
 
 	infrastructure, err := c.infraLister.Get(infraConfigName)
+	if errors.IsNotFound(err) {
+		return c.waitForClusterConfig(syncCtx, "Infrastructure", infraConfigName)
+	}
 	if err != nil {
 		return err
 	}
+	if err := c.updateUpgradeable(); err != nil {
+		return err
+	}
 	featureGate, err := c.featureGateLister.Get(featureGateConfigName)
+	if errors.IsNotFound(err) {
+		return c.waitForClusterConfig(syncCtx, "FeatureGate", featureGateConfigName)
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.clusterConfigFound(); err != nil {
+		return err
+	}
+	infrastructure, err = c.checkPlatformChange(infrastructure)
+	if err != nil {
+		return err
+	}
+	if err := c.updatePlatformVariantDegraded(infrastructure); err != nil {
+		return err
+	}
+	// Use features rendered for this release in the FeatureGate status.
+	featureGate, err = csoutils.GetFeatureGateWithStatus(c.dynamicFGLister, featureGate, c.targetVersion)
+	if err != nil {
+		return err
+	}
+	capabilities, err := csoutils.GetCapabilities(c.clusterVersionLister)
 	if err != nil {
 		return err
 	}
 
-	// Start controller managers for this platform
+	if err := c.syncDriverConfigs(ctx); err != nil {
+		return err
+	}
+	var externalPlatformName string
+	if getPlatform(infrastructure) == csioperatorclient.ExternalPlatformType {
+		externalPlatformName, err = csoutils.GetExternalPlatformName(c.dynamicInfraLister)
+		if err != nil {
+			return err
+		}
+	}
+
+	legacyVSphereDriver, err := c.syncVSphereStorageDriver(infrastructure, featureGate)
+	if err != nil {
+		return err
+	}
+
+	// CSI drivers skipped because of a disabled capability.
+	var capabilityDisabled []string
+	// Paused CSI drivers.
+	var paused []string
+
+	// Start controller managers for this platform. Several CSI drivers can
+	// run on the same platform.
 	for i := range c.controllers {
 		ctrl := &c.controllers[i]
 
@@ -142,50 +284,259 @@ func (c *CSIDriverStarterController) sync(ctx context.Context, syncCtx factory.S
 			return err
 		}
 
-		if !ctrl.running {
-			shouldRun, err := shouldRunController(ctrl.operatorConfig, infrastructure, featureGate, csiDriver)
-			if err != nil {
+		clusterCSIDriver, err := c.clusterCSIDriverLister.Get(ctrl.operatorConfig.CSIDriverName)
+		if errors.IsNotFound(err) {
+			err = nil
+			clusterCSIDriver = nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if ctrl.running {
+			c.checkManagerExited(ctrl)
+		}
+		if isPaused(clusterCSIDriver) {
+			paused = append(paused, ctrl.operatorConfig.CSIDriverName)
+			if ctrl.running {
+				c.pauseController(ctrl)
+			}
+			continue
+		}
+
+		runDecision, err := decision.ShouldRun(ctrl.operatorConfig, infrastructure, featureGate, capabilities, csiDriver, clusterCSIDriver)
+		runDecision = decision.FilterExternalPlatform(runDecision, ctrl.operatorConfig, externalPlatformName)
+		if ctrl.operatorConfig.CSIDriverName == csioperatorclient.VMwareVSphereDriverName {
+			runDecision = decision.FilterLegacyInTreeDriver(runDecision, legacyVSphereDriver)
+		}
+		if runDecision.Reason == decision.ReasonCapabilityDisabled {
+			capabilityDisabled = append(capabilityDisabled, fmt.Sprintf("%s: %s", ctrl.operatorConfig.CSIDriverName, runDecision.Message))
+		}
+		if ctrl.running {
+			// The error reports CSIDriver installed by someone else, while
+			// CSIDriver of a running CSI driver was installed by its
+			// operator.
+			if err == nil && !runDecision.Run {
+				klog.V(2).Infof("Stopping ControllerManager for %s: %s", ctrl.operatorConfig.ConditionPrefix, runDecision.Message)
+				if err := c.stopController(ctx, ctrl); err != nil {
+					return err
+				}
+				if err := c.uninstall(ctx, ctrl, clusterCSIDriver); err != nil {
+					return err
+				}
+			}
+			if err == nil && runDecision.Run {
+				if err := c.addUninstallFinalizer(ctx, ctrl, clusterCSIDriver); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !runDecision.Run {
+			klog.V(4).Infof("Not starting %s: %s", ctrl.operatorConfig.CSIDriverName, runDecision.Message)
+			health.SetDriverManagerRunning(ctrl.operatorConfig.ConditionPrefix, false)
+			if err := c.removeCredentialsRequest(ctx, ctrl); err != nil {
 				return err
 			}
-			if !shouldRun {
-				continue
+			// CSO may have restarted while uninstalling the CSI driver.
+			if err := c.uninstall(ctx, ctrl, clusterCSIDriver); err != nil {
+				return err
 			}
-			relatedObjects = append(relatedObjects, configv1.ObjectReference{
-				Group:    operatorapi.GroupName,
-				Resource: "clustercsidrivers",
-				Name:     ctrl.operatorConfig.CSIDriverName,
-			})
-			// add static assets
-			objs, err := ctrl.ctrlRelatedObjects.RelatedObjects()
-			if err != nil {
+			if err := c.removePreflightCondition(ctrl); err != nil {
 				return err
 			}
-			relatedObjects = append(relatedObjects, objs...)
-			klog.V(2).Infof("Starting ControllerManager for %s", ctrl.operatorConfig.ConditionPrefix)
-			go ctrl.mgr.Start(ctx)
-			ctrl.running = true
+			continue
+		}
+		if wait := time.Until(ctrl.restartTime); wait > 0 {
+			klog.V(4).Infof("Delaying restart of ControllerManager for %s by %s", ctrl.operatorConfig.ConditionPrefix, wait)
+			syncCtx.Queue().AddAfter(syncCtx.QueueKey(), wait)
+			continue
+		}
+		if err := c.removeFormerConditions(ctrl); err != nil {
+			return err
+		}
+		passed, err := c.runPreflightChecks(ctx, ctrl, infrastructure)
+		if err != nil {
+			return err
+		}
+		if !passed {
+			// Nothing informs about fixes of the cloud, retry periodically.
+			syncCtx.Queue().AddAfter(syncCtx.QueueKey(), preflightRetryInterval)
+			continue
+		}
+		if ctrl.mgr == nil {
+			mgr, relatedObjects, err := c.createCSIControllerManager(ctrl.operatorConfig, infrastructure, c.clients, c.resyncInterval)
+			if err != nil {
+				// This will set Degraded condition
+				return fmt.Errorf("failed to create controllers of CSI driver %s: %w", ctrl.operatorConfig.CSIDriverName, err)
+			}
+			ctrl.mgr, ctrl.ctrlRelatedObjects = mgr, relatedObjects
+			// Start informers added by the new ControllerManager, the
+			// already running ones are not affected.
+			csoclients.StartInformers(c.clients, ctx.Done())
+		}
+		if err := c.recordPlatform(ctrl.operatorConfig, infrastructure); err != nil {
+			return err
+		}
+		objs, err := getRelatedObjects(ctrl)
+		if err != nil {
+			return err
+		}
+		relatedObjects.set(ctrl.operatorConfig.CSIDriverName, objs)
+		klog.V(2).Infof("Starting ControllerManager for %s", ctrl.operatorConfig.ConditionPrefix)
+		if !ctrl.restartTime.IsZero() {
+			managerRestartsMetric.WithLabelValues(ctrl.operatorConfig.CSIDriverName).Inc()
+			c.eventRecorder.Eventf("CSIDriverManagerRestarted", "Restarted controllers of CSI driver %s", ctrl.operatorConfig.CSIDriverName)
+			ctrl.restartTime = time.Time{}
+		}
+		mgrCtx, stop := context.WithCancel(ctx)
+		ctrl.exited = make(chan struct{})
+		go runManager(mgrCtx, syncCtx, ctrl.operatorConfig.ConditionPrefix, ctrl.mgr, ctrl.exited)
+		ctrl.stop = stop
+		ctrl.startTime = time.Now()
+		ctrl.running = true
+		ctrl.credentialsRemoved = false
+		health.SetDriverManagerRunning(ctrl.operatorConfig.ConditionPrefix, true)
+		if err := c.addUninstallFinalizer(ctx, ctrl, clusterCSIDriver); err != nil {
+			return err
 		}
 	}
-	return nil
+	// The lists are complete only when all CSI drivers were checked. Partial
+	// lists would flap the conditions and a paused CSI driver missing in them
+	// would allow upgrades.
+	if err := c.updateCapabilityDisabled(capabilityDisabled); err != nil {
+		return err
+	}
+	return c.updatePausedUpgradeable(paused)
+}
+
+// stopController stops ControllerManager of a CSI driver that should not run
+// anymore and removes the CSI driver operator Deployment and
+// CredentialsRequest. Other objects of the CSI driver, such as its
+// ClusterCSIDriver, are kept.
+func (c *CSIDriverStarterController) stopController(ctx context.Context, ctrl *csiDriverControllerManager) error {
+	if ctrl.running {
+		c.stopManager(ctrl)
+		relatedObjects.remove(ctrl.operatorConfig.CSIDriverName)
+	}
+
+	deployment, err := csoutils.ReadUnstructuredAsset(ctrl.operatorConfig.GetAssetFunc(), ctrl.operatorConfig.DeploymentAsset)
+	if err != nil {
+		return err
+	}
+	namespace := deployment.GetNamespace()
+	if c.clients.SplitClients {
+		namespace = c.clients.ControlPlaneNamespace
+	}
+	err = c.clients.ControlPlaneKubeClient.AppsV1().Deployments(namespace).Delete(ctx, deployment.GetName(), metav1.DeleteOptions{})
+	switch {
+	case err == nil:
+		c.eventRecorder.Eventf("CSIDriverOperatorStopped", "Stopped CSI driver operator %s and removed Deployment %s/%s", ctrl.operatorConfig.CSIDriverName, namespace, deployment.GetName())
+	case !errors.IsNotFound(err):
+		return err
+	}
+	if ctrl.operatorConfig.KubeVirtInfraCluster {
+		if err := deleteInfraKubeconfig(ctx, c.clients.ControlPlaneKubeClient, namespace); err != nil {
+			return err
+		}
+	}
+	return c.removeCredentialsRequest(ctx, ctrl)
+}
+
+// stopManager stops running ControllerManager of a CSI driver.
+func (c *CSIDriverStarterController) stopManager(ctrl *csiDriverControllerManager) {
+	ctrl.stop()
+	ctrl.running = false
+	health.SetDriverManagerRunning(ctrl.operatorConfig.ConditionPrefix, false)
+	// Controllers of the stopped ControllerManager can't run again, a new
+	// ControllerManager is created when the CSI driver should run again.
+	ctrl.mgr = nil
+	ctrl.ctrlRelatedObjects = nil
+}
+
+// getRelatedObjects returns objects of the CSI driver for relatedObjects of
+// the ClusterOperator: its ClusterCSIDriver, operand namespaces, static
+// assets and the CSI driver operator Deployment.
+func getRelatedObjects(ctrl *csiDriverControllerManager) ([]configv1.ObjectReference, error) {
+	objs := []configv1.ObjectReference{{
+		Group:    operatorapi.GroupName,
+		Resource: "clustercsidrivers",
+		Name:     ctrl.operatorConfig.CSIDriverName,
+	}}
+	for _, ns := range ctrl.operatorConfig.GetOperandNamespaces() {
+		objs = append(objs, configv1.ObjectReference{
+			Resource: "namespaces",
+			Name:     ns,
+		})
+	}
+	// add static assets
+	assetObjs, err := ctrl.ctrlRelatedObjects.RelatedObjects()
+	if err != nil {
+		return nil, err
+	}
+	objs = append(objs, assetObjs...)
+
+	deployment, err := csoutils.ReadUnstructuredAsset(ctrl.operatorConfig.GetAssetFunc(), ctrl.operatorConfig.DeploymentAsset)
+	if err != nil {
+		return nil, err
+	}
+	objs = append(objs, configv1.ObjectReference{
+		Group:     "apps",
+		Resource:  "deployments",
+		Namespace: deployment.GetNamespace(),
+		Name:      deployment.GetName(),
+	})
+	return objs, nil
 }
 
 func (c *CSIDriverStarterController) createCSIControllerManager(
 	cfg csioperatorclient.CSIOperatorConfig,
+	infrastructure *configv1.Infrastructure,
 	clients *csoclients.Clients,
-	resyncInterval time.Duration) (manager.ControllerManager, RelatedObjectGetter) {
+	resyncInterval time.Duration) (manager.ControllerManager, RelatedObjectGetter, error) {
 
 	manager := manager.NewControllerManager()
+	addController := func(ctrl factory.Controller) {
+		manager = manager.WithController(ctrl, c.workers.Get(cfg.ConditionPrefix, ctrl.Name()))
+	}
 
+	// Static assets are selected once, the cluster profile does not change.
+	profile := csioperatorclient.GetClusterProfile(infrastructure)
+	klog.V(4).Infof("Using static assets of cluster profile %s for %s", profile, cfg.ConditionPrefix)
+	assetFunc := assettemplate.AssetFunc(cfg.GetAssetFunc(), assettemplate.ClusterValuesFunc(c.operatorClient, c.infraLister, clients.ConfigInformers.Config().V1().Networks().Lister(), getImages(cfg)))
 	src := staticresourcecontroller.NewStaticResourceController(
 		cfg.ConditionPrefix+"CSIDriverOperatorStaticController",
-		assets.ReadFile, cfg.StaticAssets, resourceapply.NewKubeClientHolder(clients.KubeClient), c.operatorClient, c.eventRecorder).
+		csoutils.AuditedAssetFunc(
+			csoutils.OwnedAssetFunc(csoutils.MirroredAssetFunc(assetFunc, clients.OperatorInformers.Operator().V1alpha1().ImageContentSourcePolicies().Lister()), OwnerComponent),
+			c.operatorClient, clients.DynamicClient, clients.RestMapper, c.eventRecorder),
+		cfg.GetStaticAssets(profile), resourceapply.NewKubeClientHolder(clients.KubeClient), c.operatorClient, c.eventRecorder).
 		AddKubeInformers(clients.KubeInformers).
+		AddInformer(clients.OperatorInformers.Operator().V1alpha1().ImageContentSourcePolicies().Informer()).
+		AddInformer(clients.ConfigInformers.Config().V1().Networks().Informer()).
 		AddRESTMapper(clients.RestMapper).
 		AddCategoryExpander(clients.CategoryExpander)
 
-	manager = manager.WithController(src, 1)
+	addController(src)
 	ctrlRelatedObjects := src
 
+	if cfg.CredentialsRequestAsset != "" {
+		credentialsRequestCtrl, err := credentialsrequest.NewController(
+			cfg.ConditionPrefix,
+			cfg.GetAssetFunc(),
+			cfg.CredentialsRequestAsset,
+			clients,
+			c.eventRecorder,
+			resyncInterval,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		addController(credentialsRequestCtrl)
+	}
+
 	crController := NewCSIDriverOperatorCRController(
 		cfg.ConditionPrefix,
 		clients,
@@ -193,105 +544,224 @@ func (c *CSIDriverStarterController) createCSIControllerManager(
 		c.eventRecorder,
 		resyncInterval,
 	)
-	manager = manager.WithController(crController, 1)
+	addController(crController)
 
-	manager = manager.WithController(NewCSIDriverOperatorDeploymentController(
+	addController(NewCSIDriverOperatorDeploymentController(
 		clients,
 		cfg,
 		c.versionGetter,
 		c.targetVersion,
 		c.eventRecorder,
 		resyncInterval,
-	), 1)
+	))
+
+	addController(NewPodSecurityController(
+		clients,
+		cfg,
+		c.eventRecorder,
+		resyncInterval,
+	))
+
+	if cfg.VolumeSnapshotClass != nil {
+		addController(NewVolumeSnapshotClassController(
+			clients,
+			cfg,
+			c.eventRecorder,
+			resyncInterval,
+		))
+	}
 
 	olmRemovalCtrl := NewOLMOperatorRemovalController(cfg, clients, c.eventRecorder, resyncInterval)
 	if olmRemovalCtrl != nil {
-		manager = manager.WithController(olmRemovalCtrl, 1)
+		addController(olmRemovalCtrl)
 	}
 
 	for i := range cfg.ExtraControllers {
-		manager = manager.WithController(cfg.ExtraControllers[i], 1)
+		addController(cfg.ExtraControllers[i])
+	}
+	if cfg.ExtraControllersFunc != nil {
+		for _, ctrl := range cfg.ExtraControllersFunc(clients, c.eventRecorder, resyncInterval) {
+			addController(ctrl)
+		}
 	}
 
-	return manager, ctrlRelatedObjects
+	return manager, ctrlRelatedObjects, nil
 }
 
-func RelatedObjectFunc() func() (isset bool, objs []configv1.ObjectReference) {
-	return func() (isset bool, objs []configv1.ObjectReference) {
-		if len(relatedObjects) == 0 {
-			return false, relatedObjects
-		}
-		return true, relatedObjects
+// removeCredentialsRequest removes CredentialsRequest of a CSI driver operator
+// that should not run on the cluster, e.g. a leftover from an older release
+// that shipped all CredentialsRequests as unconditional manifests.
+func (c *CSIDriverStarterController) removeCredentialsRequest(ctx context.Context, ctrl *csiDriverControllerManager) error {
+	if ctrl.credentialsRemoved || ctrl.operatorConfig.CredentialsRequestAsset == "" {
+		return nil
+	}
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
 	}
+	if err := credentialsrequest.DeleteCredentialsRequest(ctx, c.dynamicClient, opSpec, ctrl.operatorConfig.GetAssetFunc(), ctrl.operatorConfig.CredentialsRequestAsset, c.eventRecorder); err != nil {
+		return err
+	}
+	ctrl.credentialsRemoved = true
+	return nil
 }
 
-// shouldRunController returns true, if given CSI driver controller should run.
-func shouldRunController(cfg csioperatorclient.CSIOperatorConfig, infrastructure *configv1.Infrastructure, fg *configv1.FeatureGate, csiDriver *storagev1.CSIDriver) (bool, error) {
-	// Check the correct platform first, it will filter out most CSI driver operators
-	var platform configv1.PlatformType
-	if infrastructure.Status.PlatformStatus != nil {
-		platform = infrastructure.Status.PlatformStatus.Type
+// updateUpgradeable blocks upgrades of clusters with overridden platform,
+// they're meant for testing only.
+func (c *CSIDriverStarterController) updateUpgradeable() error {
+	upgradeable := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarter" + operatorapi.OperatorStatusTypeUpgradeable,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
 	}
-	if cfg.Platform != csioperatorclient.AllPlatforms && cfg.Platform != platform {
-		klog.V(5).Infof("Not starting %s: wrong platform %s", cfg.CSIDriverName, platform)
-		return false, nil
+	if c.platformOverride != "" {
+		upgradeable.Status = operatorapi.ConditionFalse
+		upgradeable.Reason = "PlatformOverridden"
+		upgradeable.Message = fmt.Sprintf("The cluster platform is overridden with %s by env. var. %s of the operator, this is not supported and the cluster can't be upgraded", c.platformOverride, csoclients.PlatformOverrideEnv)
 	}
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(upgradeable))
+	return err
+}
 
-	if cfg.RequireFeatureGate == "" {
-		// This is GA / always enabled operator, always run
-		klog.V(5).Infof("Starting %s: it's GA", cfg.CSIDriverName)
-		return true, nil
+// waitForClusterConfig reports a missing cluster config object as
+// Progressing instead of Degraded, it's created by the installer and it
+// may not exist yet during installation. It retries with an exponential
+// backoff.
+func (c *CSIDriverStarterController) waitForClusterConfig(syncCtx factory.SyncContext, kind, name string) error {
+	c.bootstrapBackoff *= 2
+	if c.bootstrapBackoff == 0 {
+		c.bootstrapBackoff = minBootstrapBackoff
 	}
-
-	if !featureGateEnabled(fg, cfg.RequireFeatureGate) {
-		klog.V(4).Infof("Not starting %s: feature %s is not enabled", cfg.CSIDriverName, cfg.RequireFeatureGate)
-		return false, nil
+	if c.bootstrapBackoff > maxBootstrapBackoff {
+		c.bootstrapBackoff = maxBootstrapBackoff
 	}
-
-	if isUnsupportedCSIDriverRunning(cfg, csiDriver) {
-		// Some other version of the CSI driver is running, degrade the whole cluster
-		return false, fmt.Errorf("detected CSI driver %s that is not provided by OpenShift - please remove it before enabling the OpenShift one", cfg.CSIDriverName)
+	klog.V(2).Infof("Waiting for %s %s, retrying in %s", kind, name, c.bootstrapBackoff)
+	progressing := operatorapi.OperatorCondition{
+		Type:    "CSIDriverStarter" + operatorapi.OperatorStatusTypeProgressing,
+		Status:  operatorapi.ConditionTrue,
+		Reason:  "WaitingFor" + kind,
+		Message: fmt.Sprintf("Waiting for %s %s to be created by the installer", kind, name),
+	}
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(progressing)); err != nil {
+		return err
 	}
+	syncCtx.Queue().AddAfter(syncCtx.QueueKey(), c.bootstrapBackoff)
+	return nil
+}
 
-	// Tech preview operator and tech preview is enabled
-	klog.V(5).Infof("Starting %s: feature %s is enabled", cfg.CSIDriverName, cfg.RequireFeatureGate)
-	return true, nil
+// clusterConfigFound resets Progressing condition set by
+// waitForClusterConfig.
+func (c *CSIDriverStarterController) clusterConfigFound() error {
+	c.bootstrapBackoff = 0
+	progressing := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarter" + operatorapi.OperatorStatusTypeProgressing,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(progressing))
+	return err
 }
 
-// Get list of enabled feature fates from FeatureGate CR.
-func getEnabledFeatures(fg *configv1.FeatureGate) []string {
-	if fg.Spec.FeatureSet == "" {
-		return nil
+// updateCapabilityDisabled reports CSI drivers that do not run because
+// their cluster capability is disabled.
+func (c *CSIDriverStarterController) updateCapabilityDisabled(drivers []string) error {
+	cnd := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarterCapabilityDisabled",
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
 	}
-	if fg.Spec.FeatureSet == configv1.CustomNoUpgrade {
-		return fg.Spec.CustomNoUpgrade.Enabled
+	if len(drivers) > 0 {
+		cnd.Status = operatorapi.ConditionTrue
+		cnd.Reason = "CapabilityDisabled"
+		cnd.Message = fmt.Sprintf("CSI drivers are not installed, because their cluster capability is disabled: %s", strings.Join(drivers, "; "))
 	}
-	gates := configv1.FeatureSets[fg.Spec.FeatureSet]
-	if gates == nil {
-		return nil
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(cnd))
+	return err
+}
+
+// updatePlatformVariantDegraded reports clusters on unsupported variants of
+// their platform. CSI drivers that support the variant still run.
+func (c *CSIDriverStarterController) updatePlatformVariantDegraded(infrastructure *configv1.Infrastructure) error {
+	cnd := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarterPlatformVariant" + operatorapi.OperatorStatusTypeDegraded,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
 	}
-	return gates.Enabled
+	if err := csioperatorclient.CheckPlatformVariant(infrastructure); err != nil {
+		cnd.Status = operatorapi.ConditionTrue
+		cnd.Reason = "UnsupportedPlatformVariant"
+		cnd.Message = fmt.Sprintf("No CSI driver can be installed: %s", err)
+	}
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(cnd))
+	return err
 }
 
-// featureGateEnabled returns true if a given feature is enabled in FeatureGate CR.
-func featureGateEnabled(fg *configv1.FeatureGate, feature string) bool {
-	enabledFeatures := getEnabledFeatures(fg)
-	for _, f := range enabledFeatures {
-		if f == feature {
-			return true
+// dedupConfigs returns configs with a single config per CSI driver. A
+// release where a CSI driver graduates from tech preview may ship both its
+// tech preview and GA configs. The GA one, i.e. the one without
+// RequireFeatureGates, wins, so two ControllerManagers never manage the same
+// CSI driver.
+func dedupConfigs(configs []csioperatorclient.CSIOperatorConfig) []csioperatorclient.CSIOperatorConfig {
+	indexes := map[string]int{}
+	// Condition prefixes of CSI drivers, several CSI drivers running on the
+	// same platform must not share conditions.
+	prefixes := map[string]string{}
+	var deduped []csioperatorclient.CSIOperatorConfig
+	for _, cfg := range configs {
+		if driver, found := prefixes[cfg.ConditionPrefix]; found && driver != cfg.CSIDriverName {
+			klog.Warningf("Ignoring config of CSI driver %s, its condition prefix %s is used by CSI driver %s", cfg.CSIDriverName, cfg.ConditionPrefix, driver)
+			continue
 		}
+		prefixes[cfg.ConditionPrefix] = cfg.CSIDriverName
+		i, found := indexes[cfg.CSIDriverName]
+		if !found {
+			indexes[cfg.CSIDriverName] = len(deduped)
+			deduped = append(deduped, cfg)
+			continue
+		}
+		if len(deduped[i].RequireFeatureGates) != 0 && len(cfg.RequireFeatureGates) == 0 {
+			klog.V(2).Infof("Replacing tech preview config of CSI driver %s with GA one", cfg.CSIDriverName)
+			deduped[i] = cfg
+			continue
+		}
+		klog.Warningf("Ignoring duplicate config of CSI driver %s", cfg.CSIDriverName)
 	}
-	return false
+	return deduped
 }
 
-func isUnsupportedCSIDriverRunning(cfg csioperatorclient.CSIOperatorConfig, csiDriver *storagev1.CSIDriver) bool {
-	if csiDriver == nil {
-		return false
+// removeFormerConditions removes conditions with FormerConditionPrefixes of
+// the CSI driver, they're not updated by anyone after the driver graduated.
+func (c *CSIDriverStarterController) removeFormerConditions(ctrl *csiDriverControllerManager) error {
+	if len(ctrl.operatorConfig.FormerConditionPrefixes) == 0 {
+		return nil
 	}
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, func(status *operatorapi.OperatorStatus) error {
+		var conditions []operatorapi.OperatorCondition
+		for _, cond := range status.Conditions {
+			if isFormerCondition(ctrl.operatorConfig, cond.Type) {
+				klog.V(2).Infof("Removing condition %s of %s", cond.Type, ctrl.operatorConfig.CSIDriverName)
+				continue
+			}
+			conditions = append(conditions, cond)
+		}
+		status.Conditions = conditions
+		return nil
+	})
+	return err
+}
 
-	if metav1.HasAnnotation(csiDriver.ObjectMeta, annOpenShiftManaged) {
-		return false
+// isFormerCondition returns true when the longest prefix of the condition
+// type is a former condition prefix, the current and former prefixes may
+// start with each other.
+func isFormerCondition(cfg csioperatorclient.CSIOperatorConfig, conditionType string) bool {
+	currentLen := -1
+	if strings.HasPrefix(conditionType, cfg.ConditionPrefix) {
+		currentLen = len(cfg.ConditionPrefix)
 	}
-
-	return true
+	for _, prefix := range cfg.FormerConditionPrefixes {
+		if strings.HasPrefix(conditionType, prefix) && len(prefix) > currentLen {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,128 @@
+package csidriveroperator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	imagePullConditionType = "ImagePullDegraded"
+
+	reasonMirrorMissingImage  = "MirrorMissingImage"
+	reasonNoMirrorConfigured  = "NoMirrorConfigured"
+	reasonRegistryUnreachable = "RegistryUnreachable"
+	reasonImagePullFailed     = "ImagePullFailed"
+)
+
+// Parts of image pull error messages that indicate the registry can't be
+// reached at all.
+var registryUnreachablePatterns = []string{
+	"no such host",
+	"i/o timeout",
+	"connection refused",
+	"network is unreachable",
+	"no route to host",
+	"TLS handshake timeout",
+}
+
+// Waiting reasons of containers whose image can't be pulled.
+var imagePullFailureReasons = map[string]bool{
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+	"InvalidImageName": true,
+}
+
+// checkImagePull checks that pods of the Deployment could pull their images.
+// It returns <name>ImagePullDegraded condition with a specific reason and
+// remediation of the failure, see classifyImagePullFailure.
+// The check uses pull status reported by kubelet. The Deployment rolling
+// update keeps the old pods running, so the operator is not disrupted while
+// the new image is not available.
+func (c *CSIDriverOperatorDeploymentController) checkImagePull(deployment *appsv1.Deployment) (operatorv1.OperatorCondition, error) {
+	cnd := operatorv1.OperatorCondition{
+		Type:   c.Name() + imagePullConditionType,
+		Status: operatorv1.ConditionFalse,
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return cnd, err
+	}
+	pods, err := c.podLister.Pods(deployment.Namespace).List(selector)
+	if err != nil {
+		return cnd, err
+	}
+
+	policies, err := c.icspLister.List(labels.Everything())
+	if err != nil {
+		return cnd, err
+	}
+
+	var failures []string
+	reasons := map[string]bool{}
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting == nil || !imagePullFailureReasons[status.State.Waiting.Reason] {
+				continue
+			}
+			image := containerImage(pod, status.Name)
+			mirrors := csoutils.GetImageMirrors(policies, image)
+			reason, remediation := classifyImagePullFailure(status.State.Waiting.Message, mirrors, len(policies) > 0)
+			reasons[reason] = true
+			failures = append(failures, fmt.Sprintf("pod %s failed to pull image %s: %s. %s", pod.Name, image, status.State.Waiting.Message, remediation))
+		}
+	}
+
+	if len(failures) == 0 {
+		return cnd, nil
+	}
+	sort.Strings(failures)
+	cnd.Status = operatorv1.ConditionTrue
+	// Report the most specific reason when pods fail for different reasons.
+	for _, reason := range []string{reasonMirrorMissingImage, reasonNoMirrorConfigured, reasonRegistryUnreachable, reasonImagePullFailed} {
+		if reasons[reason] {
+			cnd.Reason = reason
+			break
+		}
+	}
+	cnd.Message = strings.Join(failures, "\n")
+	return cnd, nil
+}
+
+// classifyImagePullFailure returns reason of an image pull failure and
+// remediation steps for the cluster admin.
+// Disconnected clusters get their images from mirrors configured by
+// ImageContentSourcePolicies. Typical failures there are a mirror that does
+// not contain the image (e.g. it was not updated before upgrade) and a missing
+// ImageContentSourcePolicy for the image repository.
+func classifyImagePullFailure(pullMessage string, mirrors []string, clusterUsesMirrors bool) (string, string) {
+	switch {
+	case len(mirrors) > 0:
+		return reasonMirrorMissingImage, fmt.Sprintf("The image is mirrored to %s, make sure the mirror contains the image, e.g. by running 'oc adm release mirror' for the current release", strings.Join(mirrors, ", "))
+	case clusterUsesMirrors:
+		return reasonNoMirrorConfigured, "The cluster uses image mirrors, but no ImageContentSourcePolicy mirrors the image repository. Add the repository to an ImageContentSourcePolicy"
+	}
+	for _, pattern := range registryUnreachablePatterns {
+		if strings.Contains(pullMessage, pattern) {
+			return reasonRegistryUnreachable, "The image registry is not reachable from the node. Check the cluster proxy and firewall configuration, or configure a mirror with an ImageContentSourcePolicy"
+		}
+	}
+	return reasonImagePullFailed, "Check the image exists and the cluster pull secret allows pulling it"
+}
+
+func containerImage(pod *corev1.Pod, containerName string) string {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == containerName {
+			return container.Image
+		}
+	}
+	return ""
+}
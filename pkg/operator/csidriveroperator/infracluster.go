@@ -0,0 +1,124 @@
+package csidriveroperator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// infraKubeconfigSecretName is name of the Secret with kubeconfig of the
+	// KubeVirt infra cluster, created next to the CSI driver operator
+	// Deployment.
+	infraKubeconfigSecretName = "kubevirt-csi-infra-kubeconfig"
+	infraKubeconfigKey        = "kubeconfig"
+	infraKubeconfigVolumeName = "infra-kubeconfig"
+	infraKubeconfigDir        = "/etc/infra-kubeconfig"
+
+	// hotplugVolumesFeatureGate is KubeVirt feature gate that allows
+	// attaching volumes to running VMs. The KubeVirt CSI driver can't attach
+	// volumes without it.
+	hotplugVolumesFeatureGate = "HotplugVolumes"
+)
+
+var kubeVirtResource = schema.GroupVersionResource{Group: "kubevirt.io", Version: "v1", Resource: "kubevirts"}
+
+// applyInfraCluster passes kubeconfig and namespace of the KubeVirt infra
+// cluster to the Deployment of a CSI driver operator with
+// CSIOperatorConfig.KubeVirtInfraCluster. The kubeconfig is stored in a
+// Secret next to the Deployment and the Deployment is annotated with its
+// hash.
+func (c *CSIDriverOperatorDeploymentController) applyInfraCluster(ctx context.Context, deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	if c.infraCluster == nil {
+		return nil, fmt.Errorf("kubeconfig of the KubeVirt infra cluster is not configured, set env. vars %s and %s of the operator", csoclients.InfraKubeconfigEnv, csoclients.InfraNamespaceEnv)
+	}
+	if err := c.checkHotplugVolumes(ctx); err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      infraKubeconfigSecretName,
+			Namespace: deployment.Namespace,
+		},
+		Data: map[string][]byte{
+			infraKubeconfigKey: c.infraCluster.Kubeconfig,
+		},
+	}
+	csoutils.SetOwnedByLabel(secret, OwnerComponent)
+	if _, _, err := resourceapply.ApplySecret(ctx, c.kubeClient.CoreV1(), c.eventRecorder, secret); err != nil {
+		return nil, err
+	}
+
+	deploymentCopy := deployment.DeepCopy()
+	podSpec := &deploymentCopy.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: infraKubeconfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: infraKubeconfigSecretName,
+			},
+		},
+	})
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      infraKubeconfigVolumeName,
+			MountPath: infraKubeconfigDir,
+			ReadOnly:  true,
+		})
+	}
+	deploymentCopy = csoutils.InjectEnv(deploymentCopy, csioperatorclient.InfraClusterKubeconfigEnv, infraKubeconfigDir+"/"+infraKubeconfigKey)
+	deploymentCopy = csoutils.InjectEnv(deploymentCopy, csioperatorclient.InfraClusterNamespaceEnv, c.infraCluster.Namespace)
+	return csoutils.InjectDependencyHashes(deploymentCopy, []*corev1.Secret{secret}, nil)
+}
+
+// checkHotplugVolumes returns an error when KubeVirt in the infra cluster
+// does not enable hotplugVolumesFeatureGate. Infra cluster kubeconfigs are
+// often limited to the namespace of the VMs and can't read the KubeVirt CR,
+// the check is skipped then.
+func (c *CSIDriverOperatorDeploymentController) checkHotplugVolumes(ctx context.Context) error {
+	list, err := c.infraCluster.DynamicClient.Resource(kubeVirtResource).List(ctx, metav1.ListOptions{})
+	if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+		klog.V(4).Infof("Skipping check of KubeVirt feature gate %s in the infra cluster: %s", hotplugVolumesFeatureGate, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list KubeVirt in the infra cluster: %w", err)
+	}
+	for _, kv := range list.Items {
+		featureGates, _, err := unstructured.NestedStringSlice(kv.Object, "spec", "configuration", "developerConfiguration", "featureGates")
+		if err != nil {
+			return fmt.Errorf("failed to parse KubeVirt %s/%s in the infra cluster: %w", kv.GetNamespace(), kv.GetName(), err)
+		}
+		for _, featureGate := range featureGates {
+			if featureGate == hotplugVolumesFeatureGate {
+				return nil
+			}
+		}
+		return fmt.Errorf("KubeVirt %s/%s in the infra cluster does not enable feature gate %s, volumes can't be attached to VMs", kv.GetNamespace(), kv.GetName(), hotplugVolumesFeatureGate)
+	}
+	return nil
+}
+
+// deleteInfraKubeconfig removes the Secret with kubeconfig of the KubeVirt
+// infra cluster when the CSI driver operator is stopped, so the credentials
+// do not outlive the operator.
+func deleteInfraKubeconfig(ctx context.Context, client kubernetes.Interface, namespace string) error {
+	err := client.CoreV1().Secrets(namespace).Delete(ctx, infraKubeconfigSecretName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
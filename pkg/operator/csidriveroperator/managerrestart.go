@@ -0,0 +1,81 @@
+package csidriveroperator
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/controller/manager"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Intervals between restarts of a ControllerManager that exited, they
+	// double with each restart.
+	minManagerRestartBackoff = 10 * time.Second
+	maxManagerRestartBackoff = 5 * time.Minute
+)
+
+var managerRestartsMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cluster_storage_operator_csi_driver_manager_restarts_total",
+		Help: "Number of restarts of the controller manager of a CSI driver operator after it exited unexpectedly, labeled by CSI driver name.",
+	},
+	[]string{"driver"},
+)
+
+func init() {
+	prometheus.MustRegister(managerRestartsMetric)
+}
+
+// runManager runs ControllerManager of a CSI driver until ctx is cancelled.
+// When the ControllerManager exits earlier, e.g. when it panics, it closes
+// exited and queues sync() to restart it.
+func runManager(ctx context.Context, syncCtx factory.SyncContext, name string, mgr manager.ControllerManager, exited chan struct{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			klog.Errorf("ControllerManager for %s panicked: %v", name, r)
+		}
+		close(exited)
+		if ctx.Err() == nil && syncCtx != nil {
+			syncCtx.Queue().Add(syncCtx.QueueKey())
+		}
+	}()
+	mgr.Start(ctx)
+}
+
+// checkManagerExited returns true when ControllerManager of a running CSI
+// driver exited on its own. The CSI driver is then marked as not running and
+// its restart is delayed by an exponential backoff. It resets the backoff
+// of ControllerManagers that run long enough.
+func (c *CSIDriverStarterController) checkManagerExited(ctrl *csiDriverControllerManager) bool {
+	select {
+	case <-ctrl.exited:
+	default:
+		if time.Since(ctrl.startTime) > maxManagerRestartBackoff {
+			ctrl.restartBackoff = 0
+		}
+		return false
+	}
+
+	ctrl.stop()
+	ctrl.running = false
+	// Controllers of the exited ControllerManager can't run again.
+	ctrl.mgr = nil
+	ctrl.ctrlRelatedObjects = nil
+	health.SetDriverManagerRunning(ctrl.operatorConfig.ConditionPrefix, false)
+
+	ctrl.restartBackoff *= 2
+	if ctrl.restartBackoff == 0 {
+		ctrl.restartBackoff = minManagerRestartBackoff
+	}
+	if ctrl.restartBackoff > maxManagerRestartBackoff {
+		ctrl.restartBackoff = maxManagerRestartBackoff
+	}
+	ctrl.restartTime = time.Now().Add(ctrl.restartBackoff)
+	klog.Warningf("ControllerManager for %s exited, restarting in %s", ctrl.operatorConfig.ConditionPrefix, ctrl.restartBackoff)
+	c.eventRecorder.Warningf("CSIDriverManagerExited", "Controllers of CSI driver %s exited unexpectedly, restarting them in %s", ctrl.operatorConfig.CSIDriverName, ctrl.restartBackoff)
+	return true
+}
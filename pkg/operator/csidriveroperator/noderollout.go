@@ -0,0 +1,52 @@
+package csidriveroperator
+
+import (
+	"fmt"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// defaultNodeMaxUnavailable is maxUnavailable of CSI driver node DaemonSets
+// when it's not set in the Storage CR. The DaemonSet controller rounds it up,
+// so small clusters get 1 and large clusters update 10% of the nodes at once.
+var defaultNodeMaxUnavailable = intstr.FromString("10%")
+
+// nodeRolloutPolicy is rollout policy of CSI driver node DaemonSets. The
+// operator API does not have a typed field for it, it's read from Storage
+// CR spec.unsupportedConfigOverrides:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    nodeDaemonSetRollout:
+//	      maxUnavailable: 20%
+type nodeRolloutPolicy struct {
+	// MaxUnavailable is absolute number or percentage of nodes whose CSI
+	// driver pods can be unavailable during an update.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// getNodeMaxUnavailable returns maxUnavailable of CSI driver node DaemonSets,
+// an absolute number or a percentage that the DaemonSet controller scales to
+// the number of nodes. Zero is replaced by 1, so the DaemonSets can be
+// updated.
+func getNodeMaxUnavailable(opSpec *operatorapi.OperatorSpec) (string, error) {
+	policy := nodeRolloutPolicy{}
+	if _, err := csoutils.GetUnsupportedConfigOverride(opSpec, "nodeDaemonSetRollout", &policy); err != nil {
+		return "", err
+	}
+	maxUnavailable := defaultNodeMaxUnavailable
+	if policy.MaxUnavailable != nil {
+		maxUnavailable = *policy.MaxUnavailable
+	}
+	// Scale to 100 nodes just to validate the value.
+	value, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, 100, true)
+	if err != nil {
+		return "", fmt.Errorf("invalid unsupportedConfigOverrides.nodeDaemonSetRollout.maxUnavailable: %w", err)
+	}
+	if value < 1 {
+		return "1", nil
+	}
+	return maxUnavailable.String(), nil
+}
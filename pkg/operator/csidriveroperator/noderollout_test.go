@@ -0,0 +1,67 @@
+package csidriveroperator
+
+import (
+	"testing"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGetNodeMaxUnavailable(t *testing.T) {
+	tests := []struct {
+		name        string
+		overrides   string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "default",
+			expected: "10%",
+		},
+		{
+			name:      "percentage",
+			overrides: `{"nodeDaemonSetRollout":{"maxUnavailable":"25%"}}`,
+			expected:  "25%",
+		},
+		{
+			name:      "absolute",
+			overrides: `{"nodeDaemonSetRollout":{"maxUnavailable":3}}`,
+			expected:  "3",
+		},
+		{
+			name:      "zero",
+			overrides: `{"nodeDaemonSetRollout":{"maxUnavailable":0}}`,
+			expected:  "1",
+		},
+		{
+			name:      "zero percent",
+			overrides: `{"nodeDaemonSetRollout":{"maxUnavailable":"0%"}}`,
+			expected:  "1",
+		},
+		{
+			name:        "invalid",
+			overrides:   `{"nodeDaemonSetRollout":{"maxUnavailable":"many"}}`,
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opSpec := &operatorapi.OperatorSpec{
+				UnsupportedConfigOverrides: runtime.RawExtension{Raw: []byte(test.overrides)},
+			}
+			value, err := getNodeMaxUnavailable(opSpec)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if value != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, value)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	operatorapi "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
 	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
 	"github.com/openshift/library-go/pkg/controller/factory"
@@ -372,7 +373,7 @@ func (c *OLMOperatorRemovalController) ensureCRRemoved(ctx context.Context, res
 
 func (c *OLMOperatorRemovalController) Run(ctx context.Context, workers int) {
 	// This adds event handlers to informers.
-	ctrl := c.factory.WithSync(c.Sync).ToController(c.name+csiDriverControllerName, c.eventRecorder)
+	ctrl := c.factory.WithSync(health.TrackSync(c.name+csiDriverControllerName, c.Sync)).ToController(c.name+csiDriverControllerName, c.eventRecorder)
 	ctrl.Run(ctx, workers)
 }
 
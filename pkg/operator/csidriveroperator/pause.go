@@ -0,0 +1,48 @@
+package csidriveroperator
+
+import (
+	"fmt"
+	"strings"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/klog/v2"
+)
+
+// Annotation of ClusterCSIDriver that pauses reconciliation of the CSI
+// driver by CSO, e.g. so SREs can fix the CSI driver operator Deployment
+// manually during an incident. Its only supported value is "true". The CSI
+// driver operator itself still reconciles its operands, unless its
+// ClusterCSIDriver is Unmanaged.
+const pausedAnnotation = "storage.openshift.io/paused"
+
+// isPaused returns true when reconciliation of the CSI driver is paused.
+func isPaused(cr *operatorapi.ClusterCSIDriver) bool {
+	return cr != nil && cr.Annotations[pausedAnnotation] == "true"
+}
+
+// pauseController stops ControllerManager of a paused CSI driver. Unlike
+// stopController, it keeps all objects of the CSI driver, the
+// ControllerManager is started again when the annotation is removed.
+func (c *CSIDriverStarterController) pauseController(ctrl *csiDriverControllerManager) {
+	klog.V(2).Infof("Pausing ControllerManager for %s", ctrl.operatorConfig.ConditionPrefix)
+	c.stopManager(ctrl)
+	c.eventRecorder.Warningf("CSIDriverPaused", "Paused reconciliation of CSI driver %s, its ClusterCSIDriver has annotation %s", ctrl.operatorConfig.CSIDriverName, pausedAnnotation)
+}
+
+// updatePausedUpgradeable blocks upgrades while any CSI driver is paused,
+// the upgrade would not update it.
+func (c *CSIDriverStarterController) updatePausedUpgradeable(paused []string) error {
+	upgradeable := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarterPaused" + operatorapi.OperatorStatusTypeUpgradeable,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+	if len(paused) > 0 {
+		upgradeable.Status = operatorapi.ConditionFalse
+		upgradeable.Reason = "Paused"
+		upgradeable.Message = fmt.Sprintf("Reconciliation of CSI drivers %s is paused, remove annotation %s of their ClusterCSIDrivers to allow the upgrade", strings.Join(paused, ", "), pausedAnnotation)
+	}
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(upgradeable))
+	return err
+}
@@ -0,0 +1,70 @@
+package csidriveroperator
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/klog/v2"
+)
+
+// Annotation of the Storage CR with the platform of CSI drivers installed
+// by CSO. A cluster can't change its platform, CSO does not install CSI
+// drivers of another platform when Infrastructure reports one. Cluster
+// admin can remove the annotation to install CSI drivers of the new
+// platform.
+const installedPlatformAnnotation = "storage.openshift.io/csi-driver-platform"
+
+// checkPlatformChange compares platform of Infrastructure with platform of
+// the installed CSI drivers and reports CSIDriverStarterPlatformChangedDegraded
+// when they differ. It returns Infrastructure to use for decisions about
+// the CSI drivers, with the platform of the installed CSI drivers, so they
+// keep running and CSI drivers of the new platform are not started.
+func (c *CSIDriverStarterController) checkPlatformChange(infrastructure *configv1.Infrastructure) (*configv1.Infrastructure, error) {
+	meta, err := c.operatorClient.GetObjectMeta()
+	if err != nil {
+		return nil, err
+	}
+	installed := configv1.PlatformType(meta.Annotations[installedPlatformAnnotation])
+	platform := getPlatform(infrastructure)
+
+	degraded := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarterPlatformChanged" + operatorapi.OperatorStatusTypeDegraded,
+		Status: operatorapi.ConditionFalse,
+	}
+	result := infrastructure
+	if installed != "" && installed != platform {
+		klog.Warningf("Infrastructure platform changed from %s to %s, not installing CSI drivers for %s", installed, platform, platform)
+		degraded.Status = operatorapi.ConditionTrue
+		degraded.Reason = "PlatformChanged"
+		degraded.Message = fmt.Sprintf("Infrastructure platform changed from %s to %s after CSI drivers for %s were installed. This is not supported, CSI drivers for %s are not installed. Remove annotation %s of the Storage CR to install them.", installed, platform, installed, platform, installedPlatformAnnotation)
+		result = infrastructure.DeepCopy()
+		if result.Status.PlatformStatus == nil {
+			result.Status.PlatformStatus = &configv1.PlatformStatus{}
+		}
+		result.Status.PlatformStatus.Type = installed
+	}
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(degraded)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// recordPlatform records platform of a started CSI driver in the Storage CR.
+func (c *CSIDriverStarterController) recordPlatform(cfg csioperatorclient.CSIOperatorConfig, infrastructure *configv1.Infrastructure) error {
+	if cfg.Platform == csioperatorclient.AllPlatforms {
+		return nil
+	}
+	return c.operatorClient.SetObjectAnnotations(map[string]string{
+		installedPlatformAnnotation: string(getPlatform(infrastructure)),
+	})
+}
+
+func getPlatform(infrastructure *configv1.Infrastructure) configv1.PlatformType {
+	if infrastructure.Status.PlatformStatus == nil {
+		return ""
+	}
+	return infrastructure.Status.PlatformStatus.Type
+}
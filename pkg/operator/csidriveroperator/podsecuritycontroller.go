@@ -0,0 +1,157 @@
+package csidriveroperator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	podSecurityControllerName = "PodSecurityController"
+
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+	podSecurityAuditLabel   = "pod-security.kubernetes.io/audit"
+	podSecurityWarnLabel    = "pod-security.kubernetes.io/warn"
+	// Disable OpenShift's label syncer, CSO owns the labels of its operand
+	// namespaces.
+	podSecurityLabelSyncLabel = "security.openshift.io/scc.podSecurityLabelSync"
+)
+
+var podSecurityLevelOrder = map[csioperatorclient.PodSecurityLevel]int{
+	csioperatorclient.PodSecurityRestricted: 0,
+	csioperatorclient.PodSecurityBaseline:   1,
+	csioperatorclient.PodSecurityPrivileged: 2,
+}
+
+// This PodSecurityController makes sure that namespaces where a CSI driver
+// operator and its operands run have PodSecurity admission labels that allow
+// the driver pods to run. Several drivers can share the same namespace, each
+// of them only raises the level when needed and never lowers it. In
+// audit-only mode (see csoutils.IsAuditOnly) missing labels are only
+// reported as drift.
+// It produces following Conditions:
+// <CSI driver name>PodSecurityControllerDegraded - error updating the namespaces.
+type PodSecurityController struct {
+	name              string
+	operatorClient    v1helpers.OperatorClient
+	csiOperatorConfig csioperatorclient.CSIOperatorConfig
+	kubeClient        kubernetes.Interface
+	namespaceLister   corelisters.NamespaceLister
+	eventRecorder     events.Recorder
+	factory           *factory.Factory
+}
+
+var _ factory.Controller = &PodSecurityController{}
+
+func NewPodSecurityController(
+	clients *csoclients.Clients,
+	csiOperatorConfig csioperatorclient.CSIOperatorConfig,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration,
+) factory.Controller {
+	namespaceInformer := clients.KubeInformers.InformersFor("").Core().V1().Namespaces()
+	f := factory.New()
+	f = f.ResyncEvery(resyncInterval)
+	f = f.WithSyncDegradedOnError(clients.OperatorClient)
+	// Necessary to do initial Sync after the controller starts.
+	f = f.WithPostStartHooks(initalSync)
+	// Add informers to the factory now, but the actual event handlers
+	// are added later in PodSecurityController.Run(),
+	// when we're 100% sure the controller is going to start (because it
+	// depends on the platform).
+	f = f.WithInformers(
+		clients.OperatorClient.Informer(),
+		namespaceInformer.Informer())
+
+	c := &PodSecurityController{
+		name:              csiOperatorConfig.ConditionPrefix,
+		operatorClient:    clients.OperatorClient,
+		csiOperatorConfig: csiOperatorConfig,
+		kubeClient:        clients.KubeClient,
+		namespaceLister:   namespaceInformer.Lister(),
+		eventRecorder:     eventRecorder.WithComponentSuffix(csiOperatorConfig.ConditionPrefix),
+		factory:           f,
+	}
+	return c
+}
+
+func (c *PodSecurityController) Sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("PodSecurityController sync started")
+	defer klog.V(4).Infof("PodSecurityController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		return err
+	}
+	level := c.csiOperatorConfig.GetPodSecurityLevel()
+	for _, nsName := range c.csiOperatorConfig.GetOperandNamespaces() {
+		ns, err := c.namespaceLister.Get(nsName)
+		if err != nil {
+			// The namespace is created either by CVO or by the static
+			// resource controller, wait for it.
+			return fmt.Errorf("failed to get namespace %s: %w", nsName, err)
+		}
+
+		newNs := ns.DeepCopy()
+		modified := false
+		for _, label := range []string{podSecurityEnforceLabel, podSecurityAuditLabel, podSecurityWarnLabel} {
+			current := csioperatorclient.PodSecurityLevel(newNs.Labels[label])
+			if currentOrder, found := podSecurityLevelOrder[current]; found && currentOrder >= podSecurityLevelOrder[level] {
+				continue
+			}
+			metav1.SetMetaDataLabel(&newNs.ObjectMeta, label, string(level))
+			modified = true
+		}
+		if newNs.Labels[podSecurityLabelSyncLabel] != "false" {
+			metav1.SetMetaDataLabel(&newNs.ObjectMeta, podSecurityLabelSyncLabel, "false")
+			modified = true
+		}
+		if !modified {
+			continue
+		}
+		if auditOnly {
+			// Not cleared when the labels are fixed, the namespace may be
+			// shared with CSI drivers that need a lower level.
+			csoutils.ReportDrift(c.eventRecorder, csoutils.DriftedObject{Kind: "Namespace", Name: nsName}, true)
+			continue
+		}
+
+		klog.V(2).Infof("Setting PodSecurity level %s on namespace %s", level, nsName)
+		if _, err := c.kubeClient.CoreV1().Namespaces().Update(ctx, newNs, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update PodSecurity labels of namespace %s: %w", nsName, err)
+		}
+		c.eventRecorder.Eventf("NamespacePodSecurityUpdated", "Set PodSecurity level of namespace %s to %s", nsName, level)
+	}
+	return nil
+}
+
+func (c *PodSecurityController) Run(ctx context.Context, workers int) {
+	// This adds event handlers to informers.
+	ctrl := c.factory.WithSync(health.TrackSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
+	ctrl.Run(ctx, workers)
+}
+
+func (c *PodSecurityController) Name() string {
+	return c.name + podSecurityControllerName
+}
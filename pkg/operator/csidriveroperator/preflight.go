@@ -0,0 +1,168 @@
+package csidriveroperator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/credentialsrequest"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// preflightConditionType is suffix of the condition with failed
+	// preflight checks of a CSI driver.
+	preflightConditionType = "CSIDriverOperatorPreflightDegraded"
+
+	cloudCredentialConfigName = "cluster"
+	authenticationConfigName  = "cluster"
+)
+
+// runPreflightChecks runs preflight checks of a CSI driver that should start
+// and reports the first failed one in
+// <CSI driver name>CSIDriverOperatorPreflightDegraded condition. It returns
+// false when a check failed and the CSI driver operator must not start.
+func (c *CSIDriverStarterController) runPreflightChecks(ctx context.Context, ctrl *csiDriverControllerManager, infrastructure *configv1.Infrastructure) (bool, error) {
+	cfg := ctrl.operatorConfig
+	checks := append([]csioperatorclient.PreflightCheck{cloudCredentialsCheck(cfg)}, cfg.PreflightChecks...)
+	cnd := operatorapi.OperatorCondition{
+		Type:   cfg.ConditionPrefix + preflightConditionType,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	for _, check := range checks {
+		if err := check.Run(ctx, c.clients, infrastructure); err != nil {
+			klog.V(2).Infof("Preflight check %s of CSI driver %s failed: %s", check.Name, cfg.CSIDriverName, err)
+			cnd.Status = operatorapi.ConditionTrue
+			cnd.Reason = check.Name + "Failed"
+			cnd.Message = fmt.Sprintf("CSI driver %s is not installed: %s", cfg.CSIDriverName, err)
+			break
+		}
+	}
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(cnd)); err != nil {
+		return false, err
+	}
+	return cnd.Status == operatorapi.ConditionFalse, nil
+}
+
+// removePreflightCondition removes the preflight condition of a CSI driver
+// that should not run.
+func (c *CSIDriverStarterController) removePreflightCondition(ctrl *csiDriverControllerManager) error {
+	conditionType := ctrl.operatorConfig.ConditionPrefix + preflightConditionType
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, func(status *operatorapi.OperatorStatus) error {
+		v1helpers.RemoveOperatorCondition(&status.Conditions, conditionType)
+		return nil
+	})
+	return err
+}
+
+// cloudCredentialsCheck verifies that the cloud credentials Secret of the CSI
+// driver exists when cloud-credential-operator runs in Manual mode, it's not
+// created by cloud-credential-operator then. On clusters with short-lived
+// credentials (AWS STS, GCP and Azure Workload Identity) CSO creates the
+// Secret from the cloud identity in ClusterCSIDriver, when the cluster admin
+// has set it. Otherwise the check tells the admin exactly what's missing.
+func cloudCredentialsCheck(cfg csioperatorclient.CSIOperatorConfig) csioperatorclient.PreflightCheck {
+	return csioperatorclient.PreflightCheck{
+		Name: "CloudCredentials",
+		Run: func(ctx context.Context, clients *csoclients.Clients, infrastructure *configv1.Infrastructure) error {
+			if cfg.CredentialsRequestAsset == "" {
+				return nil
+			}
+			cloudCredential, err := clients.OperatorClientSet.OperatorV1().CloudCredentials().Get(ctx, cloudCredentialConfigName, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if cloudCredential.Spec.CredentialsMode != operatorapi.CloudCredentialsModeManual {
+				return nil
+			}
+			namespace, name, err := credentialsrequest.SecretRef(cfg.GetAssetFunc(), cfg.CredentialsRequestAsset)
+			if err != nil {
+				return err
+			}
+			_, err = clients.KubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if !errors.IsNotFound(err) {
+				return err
+			}
+
+			var identity *csioperatorclient.CloudIdentityConfig
+			if cfg.BoundSAToken != nil {
+				identity = cfg.BoundSAToken.CloudIdentity
+			}
+			if identity == nil {
+				return fmt.Errorf("cloud credentials are in Manual mode and Secret %s/%s does not exist, create it from CredentialsRequest of the CSI driver, e.g. with ccoctl", namespace, name)
+			}
+			shortLived := false
+			authentication, err := clients.ConfigClientSet.ConfigV1().Authentications().Get(ctx, authenticationConfigName, metav1.GetOptions{})
+			switch {
+			case err == nil:
+				shortLived = csoutils.ShortLivedTokenMode(cloudCredential, authentication)
+			case !errors.IsNotFound(err):
+				return err
+			}
+			if !shortLived {
+				return fmt.Errorf("cloud credentials are in Manual mode and Secret %s/%s does not exist, create it from CredentialsRequest of the CSI driver, e.g. with ccoctl", namespace, name)
+			}
+			return createCloudIdentitySecret(ctx, clients, cfg.CSIDriverName, identity, namespace, name)
+		},
+	}
+}
+
+// createCloudIdentitySecret creates the cloud credentials Secret of a CSI
+// driver from its cloud identity in ClusterCSIDriver. It returns an error
+// with all missing identity keys when the identity is not complete.
+func createCloudIdentitySecret(ctx context.Context, clients *csoclients.Clients, csiDriverName string, identity *csioperatorclient.CloudIdentityConfig, namespace, name string) error {
+	cr, err := clients.OperatorClientSet.OperatorV1().ClusterCSIDrivers().Get(ctx, csiDriverName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	values := map[string]string{}
+	if err == nil {
+		if values, err = csoutils.GetCloudIdentity(&cr.Spec.OperatorSpec); err != nil {
+			return err
+		}
+	}
+	var missing []string
+	for _, key := range identity.Keys {
+		if values[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cluster uses %s and Secret %s/%s does not exist, create it or set %s in ClusterCSIDriver %s spec.unsupportedConfigOverrides.cloudIdentity", identity.Mode, namespace, name, strings.Join(missing, ", "), csiDriverName)
+	}
+
+	data, err := identity.SecretDataFunc(values)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Data: data,
+	}
+	csoutils.SetOwnedByLabel(secret, OwnerComponent)
+	_, err = clients.KubeClient.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("Created Secret %s/%s with %s credentials of CSI driver %s", namespace, name, identity.Mode, csiDriverName)
+	return nil
+}
@@ -0,0 +1,108 @@
+package csidriveroperator
+
+import (
+	"sort"
+	"sync"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	storagelister "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+)
+
+// relatedObjectsRegistry is a list of objects of running CSI drivers,
+// reported in relatedObjects of the storage ClusterOperator, so they're
+// collected by must-gather. It's updated by CSIDriverStarterController and
+// read by the ClusterOperator status controller in another goroutine.
+type relatedObjectsRegistry struct {
+	lock sync.Mutex
+	// objects of running CSI drivers, keyed by CSI driver name.
+	objects map[string][]configv1.ObjectReference
+	// storageClassLister lists StorageClasses of the CSI drivers. They're
+	// created by CSI driver operators and they're not known in advance.
+	storageClassLister storagelister.StorageClassLister
+}
+
+var relatedObjects = &relatedObjectsRegistry{
+	objects: map[string][]configv1.ObjectReference{},
+}
+
+// set replaces objects of the CSI driver.
+func (r *relatedObjectsRegistry) set(csiDriverName string, objs []configv1.ObjectReference) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.objects[csiDriverName] = objs
+}
+
+// remove removes objects of the CSI driver.
+func (r *relatedObjectsRegistry) remove(csiDriverName string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.objects, csiDriverName)
+}
+
+func (r *relatedObjectsRegistry) setStorageClassLister(lister storagelister.StorageClassLister) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.storageClassLister = lister
+}
+
+// list returns objects of all CSI drivers and their StorageClasses, without
+// duplicates, e.g. namespaces shared by several CSI drivers.
+func (r *relatedObjectsRegistry) list() []configv1.ObjectReference {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	csiDriverNames := make([]string, 0, len(r.objects))
+	for name := range r.objects {
+		csiDriverNames = append(csiDriverNames, name)
+	}
+	// Stable order, so the ClusterOperator does not change on each sync.
+	sort.Strings(csiDriverNames)
+
+	var storageClassNames map[string][]string
+	if r.storageClassLister != nil && len(csiDriverNames) > 0 {
+		storageClasses, err := r.storageClassLister.List(labels.Everything())
+		if err != nil {
+			klog.Warningf("Failed to list StorageClasses: %s", err)
+		}
+		storageClassNames = map[string][]string{}
+		for _, sc := range storageClasses {
+			storageClassNames[sc.Provisioner] = append(storageClassNames[sc.Provisioner], sc.Name)
+		}
+	}
+
+	seen := map[configv1.ObjectReference]bool{}
+	objs := []configv1.ObjectReference{}
+	add := func(obj configv1.ObjectReference) {
+		if !seen[obj] {
+			seen[obj] = true
+			objs = append(objs, obj)
+		}
+	}
+	for _, csiDriverName := range csiDriverNames {
+		for _, obj := range r.objects[csiDriverName] {
+			add(obj)
+		}
+		scNames := storageClassNames[csiDriverName]
+		sort.Strings(scNames)
+		for _, name := range scNames {
+			add(configv1.ObjectReference{
+				Group:    "storage.k8s.io",
+				Resource: "storageclasses",
+				Name:     name,
+			})
+		}
+	}
+	return objs
+}
+
+func RelatedObjectFunc() func() (isset bool, objs []configv1.ObjectReference) {
+	return func() (isset bool, objs []configv1.ObjectReference) {
+		objs = relatedObjects.list()
+		if len(objs) == 0 {
+			return false, objs
+		}
+		return true, objs
+	}
+}
@@ -0,0 +1,86 @@
+package csidriveroperator
+
+import (
+	"context"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/cleanup"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/klog/v2"
+)
+
+// syncRemoved handles ManagementState Removed of the Storage CR. It stops
+// all ControllerManagers and removes all objects created by CSO, except
+// ClusterCSIDrivers, which hold configuration of the CSI drivers by the
+// cluster admin. Other CSO controllers do not sync in the Removed state, so
+// nothing re-creates the objects. The objects are removed only once, CSO
+// installs them again when the state is Managed. In audit-only mode the
+// objects are only reported as drifted, see deleteObjects.
+func (c *CSIDriverStarterController) syncRemoved(ctx context.Context) error {
+	if c.removed {
+		return nil
+	}
+	klog.V(2).Infof("Removing CSI drivers and all objects created by the operator")
+	for i := range c.controllers {
+		ctrl := &c.controllers[i]
+		if ctrl.running {
+			if err := c.stopController(ctx, ctrl); err != nil {
+				return err
+			}
+		}
+		relatedObjects.remove(ctrl.operatorConfig.CSIDriverName)
+		if err := c.removeConditions(ctrl); err != nil {
+			return err
+		}
+	}
+
+	objects, err := cleanup.List(ctx, c.dynamicClient)
+	if err != nil {
+		return err
+	}
+	var toDelete []cleanup.Object
+	for _, obj := range objects {
+		if obj.Resource.Resource == "clustercsidrivers" {
+			continue
+		}
+		toDelete = append(toDelete, obj)
+	}
+	if err := c.deleteObjects(ctx, toDelete); err != nil {
+		return err
+	}
+
+	available := operatorapi.OperatorCondition{
+		Type:    "CSIDriverStarter" + operatorapi.OperatorStatusTypeAvailable,
+		Status:  operatorapi.ConditionTrue,
+		Reason:  "Removed",
+		Message: "The operator is removed, CSI drivers and storage classes installed by the operator were removed",
+	}
+	progressing := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarter" + operatorapi.OperatorStatusTypeProgressing,
+		Status: operatorapi.ConditionFalse,
+		Reason: "Removed",
+	}
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(available), v1helpers.UpdateConditionFn(progressing)); err != nil {
+		return err
+	}
+	c.eventRecorder.Eventf("OperatorRemoved", "Removed %d objects created by the operator", len(toDelete))
+	c.removed = true
+	return nil
+}
+
+// syncManaged resets conditions set by syncRemoved.
+func (c *CSIDriverStarterController) syncManaged() error {
+	if !c.removed {
+		return nil
+	}
+	available := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarter" + operatorapi.OperatorStatusTypeAvailable,
+		Status: operatorapi.ConditionTrue,
+		Reason: "AsExpected",
+	}
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(available)); err != nil {
+		return err
+	}
+	c.removed = false
+	return nil
+}
@@ -0,0 +1,224 @@
+package csidriveroperator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+const (
+	snapshotClassControllerName = "VolumeSnapshotClassController"
+
+	defaultSnapshotClassAnnotation = "snapshot.storage.kubernetes.io/is-default-class"
+)
+
+var volumeSnapshotClassResource = schema.GroupVersionResource{
+	Group:    "snapshot.storage.k8s.io",
+	Version:  "v1",
+	Resource: "volumesnapshotclasses",
+}
+
+// This VolumeSnapshotClassController creates the default VolumeSnapshotClass
+// of a CSI driver with CSIOperatorConfig.VolumeSnapshotClass, so snapshots
+// work without the admin creating a class first. It's enabled in the Storage
+// CR:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    defaultVolumeSnapshotClasses: true
+//
+// When disabled, the VolumeSnapshotClass created by CSO is removed. The admin
+// can make the class non-default, the annotation is not reconciled once the
+// class exists. In audit-only mode (see csoutils.IsAuditOnly) the class is
+// not changed, its drift is only reported.
+// It produces following Conditions:
+// <CSI driver name>VolumeSnapshotClassControllerDegraded - error applying the VolumeSnapshotClass.
+type VolumeSnapshotClassController struct {
+	name              string
+	operatorClient    v1helpers.OperatorClient
+	csiOperatorConfig csioperatorclient.CSIOperatorConfig
+	dynamicClient     dynamic.Interface
+	eventRecorder     events.Recorder
+	factory           *factory.Factory
+}
+
+var _ factory.Controller = &VolumeSnapshotClassController{}
+
+func NewVolumeSnapshotClassController(
+	clients *csoclients.Clients,
+	csiOperatorConfig csioperatorclient.CSIOperatorConfig,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration,
+) factory.Controller {
+	f := factory.New()
+	f = f.ResyncEvery(resyncInterval)
+	f = f.WithSyncDegradedOnError(clients.OperatorClient)
+	// Necessary to do initial Sync after the controller starts.
+	f = f.WithPostStartHooks(initalSync)
+	// VolumeSnapshotClasses are not watched, their CRD may not be installed
+	// (the CSISnapshot capability is disabled). Changes are reverted on
+	// resync.
+	f = f.WithInformers(clients.OperatorClient.Informer())
+
+	c := &VolumeSnapshotClassController{
+		name:              csiOperatorConfig.ConditionPrefix,
+		operatorClient:    clients.OperatorClient,
+		csiOperatorConfig: csiOperatorConfig,
+		dynamicClient:     clients.DynamicClient,
+		eventRecorder:     eventRecorder.WithComponentSuffix(csiOperatorConfig.ConditionPrefix),
+		factory:           f,
+	}
+	return c
+}
+
+func (c *VolumeSnapshotClassController) Sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("VolumeSnapshotClassController sync started")
+	defer klog.V(4).Infof("VolumeSnapshotClassController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	enabled := false
+	if _, err := csoutils.GetUnsupportedConfigOverride(opSpec, "defaultVolumeSnapshotClasses", &enabled); err != nil {
+		return err
+	}
+
+	cfg := c.csiOperatorConfig.VolumeSnapshotClass
+	client := c.dynamicClient.Resource(volumeSnapshotClassResource)
+	existing, err := client.Get(ctx, cfg.Name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+	owned := found && existing.GetLabels()[csoutils.ComponentLabel] == OwnerComponent
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		if !owned {
+			return nil
+		}
+		if auditOnly {
+			csoutils.ReportDrift(c.eventRecorder, csoutils.DriftedObject{Kind: existing.GetKind(), Name: cfg.Name}, true)
+			return nil
+		}
+		err := client.Delete(ctx, cfg.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete VolumeSnapshotClass %s: %w", cfg.Name, err)
+		}
+		c.eventRecorder.Eventf("VolumeSnapshotClassDeleted", "Deleted VolumeSnapshotClass %s", cfg.Name)
+		return nil
+	}
+
+	if found && !owned {
+		klog.V(4).Infof("VolumeSnapshotClass %s was not created by CSO, not reconciling it", cfg.Name)
+		return nil
+	}
+
+	required := c.requiredSnapshotClass()
+	if auditOnly {
+		if !found {
+			return csoutils.ReportObjectDrift(c.eventRecorder, required.GetKind(), required, nil)
+		}
+		required.SetAnnotations(existing.GetAnnotations())
+		return csoutils.ReportObjectDrift(c.eventRecorder, required.GetKind(), required, existing)
+	}
+	if !found {
+		_, err := client.Create(ctx, required, metav1.CreateOptions{})
+		if apierrors.IsNotFound(err) {
+			klog.V(2).Infof("VolumeSnapshotClass CRD is not installed, not creating VolumeSnapshotClass %s", cfg.Name)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create VolumeSnapshotClass %s: %w", cfg.Name, err)
+		}
+		c.eventRecorder.Eventf("VolumeSnapshotClassCreated", "Created default VolumeSnapshotClass %s", cfg.Name)
+		return nil
+	}
+
+	// Keep annotations of the existing class, the admin may have made it
+	// non-default.
+	required.SetAnnotations(existing.GetAnnotations())
+	required.SetResourceVersion(existing.GetResourceVersion())
+	if equalSnapshotClass(existing, required) {
+		return nil
+	}
+	if _, err := client.Update(ctx, required, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update VolumeSnapshotClass %s: %w", cfg.Name, err)
+	}
+	c.eventRecorder.Eventf("VolumeSnapshotClassUpdated", "Updated VolumeSnapshotClass %s", cfg.Name)
+	return nil
+}
+
+// requiredSnapshotClass returns the default VolumeSnapshotClass of the CSI
+// driver.
+func (c *VolumeSnapshotClassController) requiredSnapshotClass() *unstructured.Unstructured {
+	cfg := c.csiOperatorConfig.VolumeSnapshotClass
+	deletionPolicy := cfg.DeletionPolicy
+	if deletionPolicy == "" {
+		deletionPolicy = "Delete"
+	}
+	vsc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion":     volumeSnapshotClassResource.GroupVersion().String(),
+		"kind":           "VolumeSnapshotClass",
+		"driver":         c.csiOperatorConfig.CSIDriverName,
+		"deletionPolicy": deletionPolicy,
+	}}
+	vsc.SetName(cfg.Name)
+	vsc.SetAnnotations(map[string]string{defaultSnapshotClassAnnotation: "true"})
+	if len(cfg.Parameters) > 0 {
+		params := map[string]interface{}{}
+		for k, v := range cfg.Parameters {
+			params[k] = v
+		}
+		vsc.Object["parameters"] = params
+	}
+	csoutils.SetOwnedByLabel(vsc, OwnerComponent)
+	return vsc
+}
+
+func equalSnapshotClass(existing, required *unstructured.Unstructured) bool {
+	for _, field := range []string{"driver", "deletionPolicy", "parameters"} {
+		if fmt.Sprint(existing.Object[field]) != fmt.Sprint(required.Object[field]) {
+			return false
+		}
+	}
+	for k, v := range required.GetLabels() {
+		if existing.GetLabels()[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *VolumeSnapshotClassController) Run(ctx context.Context, workers int) {
+	// This adds event handlers to informers.
+	ctrl := c.factory.WithSync(health.TrackSync(c.Name(), c.Sync)).ToController(c.Name(), c.eventRecorder)
+	ctrl.Run(ctx, workers)
+}
+
+func (c *VolumeSnapshotClassController) Name() string {
+	return c.name + snapshotClassControllerName
+}
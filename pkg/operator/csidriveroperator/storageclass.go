@@ -0,0 +1,186 @@
+package csidriveroperator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+)
+
+// storageClassState tells the CSI driver operator what to do with
+// StorageClasses it provides. The operator API does not have a typed field
+// for it yet, it's read from ClusterCSIDriver spec.unsupportedConfigOverrides:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    storageClassState: Unmanaged
+type storageClassState string
+
+const (
+	// storageClassStateManaged - the operator creates and reconciles its
+	// StorageClasses. This is the default.
+	storageClassStateManaged storageClassState = "Managed"
+	// storageClassStateUnmanaged - the operator creates its StorageClasses
+	// when they do not exist, but does not overwrite changes of the admin.
+	storageClassStateUnmanaged storageClassState = "Unmanaged"
+	// storageClassStateRemoved - the operator deletes its StorageClasses.
+	storageClassStateRemoved storageClassState = "Removed"
+
+	storageClassStateType = "StorageClassManaged"
+)
+
+// getStorageClassState returns storageClassState from the ClusterCSIDriver.
+func (c *CSIDriverOperatorDeploymentController) getStorageClassState() (storageClassState, error) {
+	cr, err := c.clusterCSIDriverLister.Get(c.csiOperatorConfig.CSIDriverName)
+	if apierrors.IsNotFound(err) {
+		return storageClassStateManaged, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	state := storageClassStateManaged
+	if _, err := csoutils.GetUnsupportedConfigOverride(&cr.Spec.OperatorSpec, "storageClassState", &state); err != nil {
+		return "", fmt.Errorf("invalid ClusterCSIDriver %s: %w", cr.Name, err)
+	}
+	switch state {
+	case storageClassStateManaged, storageClassStateUnmanaged, storageClassStateRemoved:
+		return state, nil
+	}
+	return "", fmt.Errorf("invalid ClusterCSIDriver %s: unsupportedConfigOverrides.storageClassState %q is not one of %s, %s, %s",
+		cr.Name, state, storageClassStateManaged, storageClassStateUnmanaged, storageClassStateRemoved)
+}
+
+// storageClassStateCondition reports storageClassState passed to the CSI
+// driver operator. It's True when the StorageClasses are managed.
+func (c *CSIDriverOperatorDeploymentController) storageClassStateCondition(state storageClassState, progressing bool) operatorv1.OperatorCondition {
+	cnd := operatorv1.OperatorCondition{
+		Type:   c.Name() + storageClassStateType,
+		Status: operatorv1.ConditionFalse,
+		Reason: string(state),
+	}
+	switch {
+	case progressing:
+		cnd.Reason = "Deploying"
+		cnd.Message = fmt.Sprintf("Waiting for the CSI driver operator to apply StorageClass state %s", state)
+	case state == storageClassStateManaged:
+		cnd.Status = operatorv1.ConditionTrue
+		cnd.Message = "StorageClasses of the CSI driver are managed by its operator"
+	case state == storageClassStateUnmanaged:
+		cnd.Message = fmt.Sprintf("StorageClasses of the CSI driver are not reconciled, storageClassState in ClusterCSIDriver %s is %s", c.csiOperatorConfig.CSIDriverName, state)
+	case state == storageClassStateRemoved:
+		cnd.Message = fmt.Sprintf("StorageClasses of the CSI driver are removed, storageClassState in ClusterCSIDriver %s is %s", c.csiOperatorConfig.CSIDriverName, state)
+	}
+	return cnd
+}
+
+// getStorageClassParametersOverlay returns parameters that the admin wants
+// to add to StorageClasses of the CSI driver, instead of cloning them. The
+// operator API does not have a typed field for it yet, it's read from
+// ClusterCSIDriver spec.unsupportedConfigOverrides:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    storageClassParameters:
+//	      iops: "4000"
+//	      throughput: "250"
+//
+// The CSI driver operator merges them into its StorageClasses, overriding
+// its own parameters with the same name.
+func getStorageClassParametersOverlay(opSpec *operatorv1.OperatorSpec) (map[string]string, error) {
+	params := map[string]string{}
+	if _, err := csoutils.GetUnsupportedConfigOverride(opSpec, "storageClassParameters", &params); err != nil {
+		return nil, err
+	}
+	for name, value := range params {
+		if name == "" || strings.ContainsAny(name, "=,") {
+			return nil, fmt.Errorf("unsupportedConfigOverrides.storageClassParameters: invalid parameter name %q", name)
+		}
+		if strings.Contains(value, ",") {
+			return nil, fmt.Errorf("unsupportedConfigOverrides.storageClassParameters.%s: value must not contain a comma", name)
+		}
+	}
+	return params, nil
+}
+
+// getNodeZones returns sorted zones of the nodes, from their
+// topology.kubernetes.io/zone label. It's empty in clusters without zones.
+func getNodeZones(nodes []*corev1.Node) []string {
+	zoneSet := sets.NewString()
+	for _, node := range nodes {
+		if zone := node.Labels[corev1.LabelTopologyZone]; zone != "" {
+			zoneSet.Insert(zone)
+		}
+	}
+	return zoneSet.List()
+}
+
+// injectStorageClassZones returns a copy of the Deployment with
+// StorageClassZonesEnv. The zones are zones of the Infrastructure failure
+// domains and of the nodes, a new zone gets to StorageClasses when it's
+// configured or when its first node joins the cluster. The env. var is not
+// set in clusters without zones.
+func (c *CSIDriverOperatorDeploymentController) injectStorageClassZones(deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	failureDomainZones, err := csoutils.GetFailureDomainZones(c.dynamicInfraLister)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	zones := sets.NewString(failureDomainZones...).Insert(getNodeZones(nodes)...)
+	if zones.Len() == 0 {
+		return deployment, nil
+	}
+	return csoutils.InjectEnv(deployment, csioperatorclient.StorageClassZonesEnv, strings.Join(zones.List(), ",")), nil
+}
+
+// nodeZoneChangeHook returns factory.PostStartHook that syncs the controller
+// when a node with a zone is added or removed or when zone of a node
+// changes. Other node updates, like heartbeats, are ignored.
+func nodeZoneChangeHook(nodeInformer cache.SharedIndexInformer) factory.PostStartHook {
+	return func(ctx context.Context, syncCtx factory.SyncContext) error {
+		queue := func() {
+			syncCtx.Queue().Add(factory.DefaultQueueKey)
+		}
+		nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				if getNodeZone(obj) != "" {
+					queue()
+				}
+			},
+			UpdateFunc: func(old, new interface{}) {
+				if getNodeZone(old) != getNodeZone(new) {
+					queue()
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if getNodeZone(obj) != "" {
+					queue()
+				}
+			},
+		})
+		return nil
+	}
+}
+
+func getNodeZone(obj interface{}) string {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return ""
+	}
+	return node.Labels[corev1.LabelTopologyZone]
+}
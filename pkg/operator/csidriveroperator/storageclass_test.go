@@ -0,0 +1,141 @@
+package csidriveroperator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func zonalNode(name, zone string) *corev1.Node {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if zone != "" {
+		node.Labels = map[string]string{corev1.LabelTopologyZone: zone}
+	}
+	return node
+}
+
+func vSphereInfrastructure(zones ...string) *unstructured.Unstructured {
+	var failureDomains []interface{}
+	for _, zone := range zones {
+		failureDomains = append(failureDomains, map[string]interface{}{
+			"name": "fd-" + zone,
+			"zone": zone,
+		})
+	}
+	infra := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"platformSpec": map[string]interface{}{
+				"type": "VSphere",
+				"vsphere": map[string]interface{}{
+					"failureDomains": failureDomains,
+				},
+			},
+		},
+	}}
+	infra.SetName("cluster")
+	return infra
+}
+
+func TestGetNodeZones(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []*corev1.Node
+		expected []string
+	}{
+		{
+			name:     "no nodes",
+			expected: []string{},
+		},
+		{
+			name:     "nodes without zones",
+			nodes:    []*corev1.Node{zonalNode("a", ""), zonalNode("b", "")},
+			expected: []string{},
+		},
+		{
+			name: "sorted unique zones",
+			nodes: []*corev1.Node{
+				zonalNode("a", "us-east-1b"),
+				zonalNode("b", "us-east-1a"),
+				zonalNode("c", "us-east-1b"),
+				zonalNode("d", ""),
+			},
+			expected: []string{"us-east-1a", "us-east-1b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if zones := getNodeZones(test.nodes); !reflect.DeepEqual(zones, test.expected) {
+				t.Errorf("expected zones %v, got %v", test.expected, zones)
+			}
+		})
+	}
+}
+
+func TestInjectStorageClassZones(t *testing.T) {
+	tests := []struct {
+		name     string
+		infra    *unstructured.Unstructured
+		nodes    []*corev1.Node
+		expected string
+	}{
+		{
+			name:     "cluster without zones",
+			nodes:    []*corev1.Node{zonalNode("a", "")},
+			expected: "",
+		},
+		{
+			name:     "zones of nodes",
+			nodes:    []*corev1.Node{zonalNode("a", "2"), zonalNode("b", "1")},
+			expected: "1,2",
+		},
+		{
+			name:     "zones of vSphere failure domains without nodes",
+			infra:    vSphereInfrastructure("zone-b", "zone-a"),
+			expected: "zone-a,zone-b",
+		},
+		{
+			name:     "zones of vSphere failure domains and nodes",
+			infra:    vSphereInfrastructure("zone-a", "zone-b"),
+			nodes:    []*corev1.Node{zonalNode("a", "zone-a"), zonalNode("b", "zone-c")},
+			expected: "zone-a,zone-b,zone-c",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			infras := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if test.infra != nil {
+				infras.Add(test.infra)
+			}
+			nodes := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, node := range test.nodes {
+				nodes.Add(node)
+			}
+			c := &CSIDriverOperatorDeploymentController{
+				dynamicInfraLister: cache.NewGenericLister(infras, csoutils.InfrastructureResource.GroupResource()),
+				nodeLister:         corelisters.NewNodeLister(nodes),
+			}
+			deployment := &appsv1.Deployment{}
+			deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "operator"}}
+
+			deployment, err := c.injectStorageClassZones(deployment)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			var env []corev1.EnvVar
+			if test.expected != "" {
+				env = []corev1.EnvVar{{Name: csioperatorclient.StorageClassZonesEnv, Value: test.expected}}
+			}
+			if result := deployment.Spec.Template.Spec.Containers[0].Env; !reflect.DeepEqual(result, env) {
+				t.Errorf("expected env %v, got %v", env, result)
+			}
+		})
+	}
+}
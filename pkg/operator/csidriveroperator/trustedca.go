@@ -0,0 +1,37 @@
+package csidriveroperator
+
+import (
+	"context"
+
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// trustedCABundleConfigMapName is name of the ConfigMap in
+// csioperatorclient.TrustedCABundleAsset.
+const trustedCABundleConfigMapName = "csi-driver-operator-trusted-ca-bundle"
+
+// applyTrustedCABundle creates ConfigMap for the cluster trusted CA bundle
+// in the namespace of CSI driver operators and mounts it to the Deployment
+// of the operator. The bundle itself is injected by the cluster network
+// operator and it's kept by the apply. The ConfigMap is shared by all CSI
+// driver operators, Deployments in other namespaces are not changed.
+func (c *CSIDriverOperatorDeploymentController) applyTrustedCABundle(ctx context.Context, deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	data, err := assets.ReadFile(csioperatorclient.TrustedCABundleAsset)
+	if err != nil {
+		return nil, err
+	}
+	required := resourceread.ReadConfigMapV1OrDie(data)
+	if required.Namespace != deployment.Namespace {
+		return deployment, nil
+	}
+	csoutils.SetOwnedByLabel(required, OwnerComponent)
+	if _, _, err := resourceapply.ApplyConfigMap(ctx, c.kubeClient.CoreV1(), c.eventRecorder, required); err != nil {
+		return nil, err
+	}
+	return csoutils.InjectTrustedCABundle(deployment, required.Name), nil
+}
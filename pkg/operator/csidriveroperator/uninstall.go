@@ -0,0 +1,189 @@
+package csidriveroperator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/cleanup"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/decision"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// uninstallFinalizer is finalizer of ClusterCSIDriver of optional CSI
+// drivers (see CSIOperatorConfig.OptionalDriver). CSO removes the CSI driver
+// operator and its objects when the cluster admin deletes the
+// ClusterCSIDriver and then removes the finalizer.
+const uninstallFinalizer = "storage.openshift.io/uninstall-csi-driver"
+
+// addUninstallFinalizer adds uninstallFinalizer to ClusterCSIDriver of a
+// running optional CSI driver.
+func (c *CSIDriverStarterController) addUninstallFinalizer(ctx context.Context, ctrl *csiDriverControllerManager, cr *operatorapi.ClusterCSIDriver) error {
+	if !ctrl.operatorConfig.OptionalDriver || cr == nil || cr.DeletionTimestamp != nil || hasFinalizer(cr.Finalizers, uninstallFinalizer) {
+		return nil
+	}
+	crCopy := cr.DeepCopy()
+	crCopy.Finalizers = append(crCopy.Finalizers, uninstallFinalizer)
+	_, err := c.clients.OperatorClientSet.OperatorV1().ClusterCSIDrivers().Update(ctx, crCopy, metav1.UpdateOptions{})
+	return err
+}
+
+// uninstall removes objects of an optional CSI driver whose ClusterCSIDriver
+// is being deleted: the CSI driver operator Deployment, its
+// CredentialsRequest and static assets, the CSIDriver object and conditions
+// of the CSI driver. ControllerManager of the CSI driver must be already
+// stopped. Operands of the CSI driver operator are not known to CSO, the
+// operator is expected to set their owner references to its Deployment.
+func (c *CSIDriverStarterController) uninstall(ctx context.Context, ctrl *csiDriverControllerManager, cr *operatorapi.ClusterCSIDriver) error {
+	if !ctrl.operatorConfig.OptionalDriver || cr == nil || cr.DeletionTimestamp == nil || !hasFinalizer(cr.Finalizers, uninstallFinalizer) {
+		return nil
+	}
+	klog.V(2).Infof("Uninstalling CSI driver %s", ctrl.operatorConfig.CSIDriverName)
+	if err := c.stopController(ctx, ctrl); err != nil {
+		return err
+	}
+
+	// Only the CSI driver's own static assets, the shared ones (e.g. RBAC
+	// for CSIStorageCapacity) may be used by other CSI drivers.
+	var objects []cleanup.Object
+	for _, asset := range ctrl.operatorConfig.GetOwnStaticAssets() {
+		obj, err := csoutils.ReadUnstructuredAsset(ctrl.operatorConfig.GetAssetFunc(), asset)
+		if err != nil {
+			return err
+		}
+		gvk := obj.GroupVersionKind()
+		mapping, err := c.clients.RestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, cleanup.Object{
+			Resource:  mapping.Resource,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			Component: OwnerComponent,
+		})
+	}
+	if err := c.deleteObjects(ctx, objects); err != nil {
+		return err
+	}
+
+	csiDriver, err := c.csiDriverLister.Get(ctrl.operatorConfig.CSIDriverName)
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	case !decision.IsUnsupportedCSIDriverRunning(csiDriver):
+		err = c.kubeClient.StorageV1().CSIDrivers().Delete(ctx, csiDriver.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if err := c.removeConditions(ctrl); err != nil {
+		return err
+	}
+
+	crCopy := cr.DeepCopy()
+	crCopy.Finalizers = removeFinalizer(crCopy.Finalizers, uninstallFinalizer)
+	_, err = c.clients.OperatorClientSet.OperatorV1().ClusterCSIDrivers().Update(ctx, crCopy, metav1.UpdateOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	c.eventRecorder.Eventf("CSIDriverUninstalled", "Uninstalled CSI driver %s, its ClusterCSIDriver was deleted", ctrl.operatorConfig.CSIDriverName)
+	return nil
+}
+
+// deleteObjects deletes objects created by CSO. In audit-only mode (see
+// csoutils.IsAuditOnly) the existing ones are only reported as drifted and
+// an error is returned, so the caller retries.
+func (c *CSIDriverStarterController) deleteObjects(ctx context.Context, objects []cleanup.Object) error {
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		return err
+	}
+	if !auditOnly {
+		return cleanup.Delete(ctx, c.dynamicClient, objects)
+	}
+
+	existing := 0
+	for _, obj := range objects {
+		_, err := c.dynamicClient.Resource(obj.Resource).Namespace(obj.Namespace).Get(ctx, obj.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		gvk, err := c.clients.RestMapper.KindFor(obj.Resource)
+		if err != nil {
+			return err
+		}
+		csoutils.ReportDrift(c.eventRecorder, csoutils.DriftedObject{Kind: gvk.Kind, Namespace: obj.Namespace, Name: obj.Name}, true)
+		existing++
+	}
+	if existing > 0 {
+		return fmt.Errorf("%d objects are not deleted in audit-only mode", existing)
+	}
+	return nil
+}
+
+// removeConditions removes conditions of the CSI driver, i.e. conditions
+// with its ConditionPrefix that do not belong to another CSI driver with a
+// longer prefix.
+func (c *CSIDriverStarterController) removeConditions(ctrl *csiDriverControllerManager) error {
+	_, _, err := v1helpers.UpdateStatus(c.operatorClient, func(status *operatorapi.OperatorStatus) error {
+		var conditions []operatorapi.OperatorCondition
+		for _, cond := range status.Conditions {
+			if c.conditionOwner(cond.Type) == ctrl.operatorConfig.CSIDriverName {
+				continue
+			}
+			conditions = append(conditions, cond)
+		}
+		status.Conditions = conditions
+		return nil
+	})
+	return err
+}
+
+// conditionOwner returns name of the CSI driver with the longest
+// ConditionPrefix of the condition type or an empty string.
+func (c *CSIDriverStarterController) conditionOwner(conditionType string) string {
+	owner := ""
+	ownerLen := 0
+	for i := range c.controllers {
+		cfg := &c.controllers[i].operatorConfig
+		if strings.HasPrefix(conditionType, cfg.ConditionPrefix) && len(cfg.ConditionPrefix) > ownerLen {
+			owner = cfg.CSIDriverName
+			ownerLen = len(cfg.ConditionPrefix)
+		}
+	}
+	return owner
+}
+
+func hasFinalizer(finalizers []string, finalizer string) bool {
+	for _, f := range finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	var result []string
+	for _, f := range finalizers {
+		if f != finalizer {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package csidriveroperator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Annotation of the CSI driver operator Deployment with the release
+	// version it was rendered for.
+	operandVersionAnnotation = "storage.openshift.io/operand-version"
+	// Annotation of the CSI driver operator Deployment with the previous
+	// release version, set while its post-upgrade hooks did not succeed yet.
+	upgradedFromAnnotation = "storage.openshift.io/upgraded-from"
+)
+
+// preUpgrade runs CSIOperatorConfig.PreUpgradeHooks when the existing
+// Deployment of the CSI driver operator was rendered for another version
+// than the target one. It returns the required Deployment annotated with the
+// versions, so post-upgrade hooks run after the new Deployment is rolled out,
+// even when CSO restarts in the meantime.
+func (c *CSIDriverOperatorDeploymentController) preUpgrade(ctx context.Context, required *appsv1.Deployment) (*appsv1.Deployment, error) {
+	if c.targetVersion == "" {
+		return required, nil
+	}
+	requiredCopy := required.DeepCopy()
+	if requiredCopy.Annotations == nil {
+		requiredCopy.Annotations = map[string]string{}
+	}
+	requiredCopy.Annotations[operandVersionAnnotation] = c.targetVersion
+
+	existing, err := c.deploymentLister.Deployments(required.Namespace).Get(required.Name)
+	if apierrors.IsNotFound(err) {
+		// A new installation, not an upgrade.
+		return requiredCopy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fromVersion := existing.Annotations[upgradedFromAnnotation]
+	if existingVersion := existing.Annotations[operandVersionAnnotation]; existingVersion != "" && existingVersion != c.targetVersion {
+		fromVersion = existingVersion
+		if err := c.runUpgradeHooks(ctx, "PreUpgrade", c.csiOperatorConfig.PreUpgradeHooks, fromVersion); err != nil {
+			return nil, err
+		}
+	}
+	if fromVersion != "" {
+		requiredCopy.Annotations[upgradedFromAnnotation] = fromVersion
+	}
+	return requiredCopy, nil
+}
+
+// postUpgrade runs CSIOperatorConfig.PostUpgradeHooks once the Deployment
+// of the CSI driver operator updated to a new version is rolled out.
+func (c *CSIDriverOperatorDeploymentController) postUpgrade(ctx context.Context, deployment *appsv1.Deployment, progressing bool) error {
+	fromVersion := deployment.Annotations[upgradedFromAnnotation]
+	if fromVersion == "" || progressing {
+		return nil
+	}
+	if err := c.runUpgradeHooks(ctx, "PostUpgrade", c.csiOperatorConfig.PostUpgradeHooks, fromVersion); err != nil {
+		return err
+	}
+
+	// JSON merge patch, null removes the annotation.
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{upgradedFromAnnotation: nil},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.kubeClient.AppsV1().Deployments(deployment.Namespace).Patch(ctx, deployment.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	c.eventRecorder.Eventf("UpgradeCompleted", "CSI driver operator updated from %s to %s", fromVersion, c.targetVersion)
+	return nil
+}
+
+// runUpgradeHooks runs the hooks in order. The first error is reported in
+// the Progressing condition with given reason prefix.
+func (c *CSIDriverOperatorDeploymentController) runUpgradeHooks(ctx context.Context, phase string, hooks []csioperatorclient.UpgradeHook, fromVersion string) error {
+	for _, hook := range hooks {
+		klog.V(2).Infof("Running %s hook %s of %s", phase, hook.Name, c.csiOperatorConfig.CSIDriverName)
+		if hookErr := hook.Run(ctx, fromVersion, c.targetVersion); hookErr != nil {
+			progressing := operatorv1.OperatorCondition{
+				Type:    c.name + operatorv1.OperatorStatusTypeProgressing,
+				Status:  operatorv1.ConditionTrue,
+				Reason:  phase + "HookFailed",
+				Message: fmt.Sprintf("Upgrade from %s to %s: %s hook %s failed: %s", fromVersion, c.targetVersion, phase, hook.Name, hookErr),
+			}
+			if _, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(progressing)); err != nil {
+				return err
+			}
+			// Will set Degraded condition.
+			return fmt.Errorf("%s hook %s failed: %w", phase, hook.Name, hookErr)
+		}
+	}
+	return nil
+}
@@ -12,6 +12,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcehelper"
@@ -28,17 +30,29 @@ const (
 )
 
 var (
-	sidecarReplacer = strings.NewReplacer(
-		"${PROVISIONER_IMAGE}", os.Getenv(envProvisionerImage),
-		"${ATTACHER_IMAGE}", os.Getenv(envAttacherImage),
-		"${RESIZER_IMAGE}", os.Getenv(envResizerImage),
-		"${SNAPSHOTTER_IMAGE}", os.Getenv(envSnapshotterImage),
-		"${NODE_DRIVER_REGISTRAR_IMAGE}", os.Getenv(envNodeDriverRegistrarImage),
-		"${LIVENESS_PROBE_IMAGE}", os.Getenv(envLivenessProbeImage),
-		"${KUBE_RBAC_PROXY_IMAGE}", os.Getenv(envKubeRBACProxyImage),
-	)
+	sidecarImages = map[string]string{
+		assettemplate.ImageProvisioner:         os.Getenv(envProvisionerImage),
+		assettemplate.ImageAttacher:            os.Getenv(envAttacherImage),
+		assettemplate.ImageResizer:             os.Getenv(envResizerImage),
+		assettemplate.ImageSnapshotter:         os.Getenv(envSnapshotterImage),
+		assettemplate.ImageNodeDriverRegistrar: os.Getenv(envNodeDriverRegistrarImage),
+		assettemplate.ImageLivenessProbe:       os.Getenv(envLivenessProbeImage),
+		assettemplate.ImageKubeRBACProxy:       os.Getenv(envKubeRBACProxyImage),
+	}
 )
 
+// getImages returns images of the CSI driver operator and CSI sidecars.
+func getImages(cfg csioperatorclient.CSIOperatorConfig) map[string]string {
+	images := map[string]string{}
+	for name, image := range sidecarImages {
+		images[name] = image
+	}
+	for name, image := range cfg.Images {
+		images[name] = image
+	}
+	return images
+}
+
 // factory.PostStartHook to poke newly started controller to resync.
 // This is useful if a controller is started later than at CSO startup
 // - CSO's CR may have been already processes and there may be no
@@ -0,0 +1,109 @@
+package csidriveroperator
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/decision"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// vSphereStorageDriver is the storage driver of vSphere clusters selected by
+// the cluster admin. The operator API does not have a typed field for it
+// yet, it's read from the Storage CR:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    vsphereStorageDriver: CSIWithMigrationDriver
+type vSphereStorageDriver string
+
+const (
+	// vSphereDefaultDriver - the admin did not select any driver, CSO runs
+	// the CSI driver.
+	vSphereDefaultDriver vSphereStorageDriver = ""
+	// vSphereCSIWithMigrationDriver - CSO runs the CSI driver, in-tree
+	// volumes are migrated to it.
+	vSphereCSIWithMigrationDriver vSphereStorageDriver = "CSIWithMigrationDriver"
+	// vSphereLegacyInTreeDriver - CSO does not run the CSI driver, volumes
+	// are provisioned by the in-tree volume plugin. It can be selected only
+	// until the CSI driver is installed, migration of volumes to CSI can't be
+	// reverted.
+	vSphereLegacyInTreeDriver vSphereStorageDriver = "LegacyDeprecatedInTreeDriver"
+
+	vSphereMigrationFeatureGate = "CSIMigrationVSphere"
+)
+
+// syncVSphereStorageDriver returns true when the cluster admin selected the
+// legacy in-tree volume plugin on a vSphere cluster and CSO must not run the
+// vSphere CSI driver operator. Invalid vsphereStorageDriver and attempts to
+// switch back to the in-tree plugin after the CSI driver was installed are
+// reported in CSIDriverStarterVSphereStorageDriverDegraded condition, the CSI
+// driver keeps running then.
+func (c *CSIDriverStarterController) syncVSphereStorageDriver(infrastructure *configv1.Infrastructure, fg *configv1.FeatureGate) (bool, error) {
+	if getPlatform(infrastructure) != configv1.VSpherePlatformType {
+		return false, nil
+	}
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return false, err
+	}
+	csiDriver, err := c.csiDriverLister.Get(csioperatorclient.VMwareVSphereDriverName)
+	if errors.IsNotFound(err) {
+		err = nil
+		csiDriver = nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	legacy, cnd := getVSphereStorageDriverCondition(opSpec, fg, csiDriver)
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(cnd)); err != nil {
+		return false, err
+	}
+	return legacy, nil
+}
+
+// getVSphereStorageDriverCondition returns true when the legacy in-tree
+// volume plugin should be used, together with
+// CSIDriverStarterVSphereStorageDriverDegraded condition. The CSI driver is
+// installed when CSIDriver object installed by OpenShift exists, or will be
+// installed when CSI migration is already enabled.
+func getVSphereStorageDriverCondition(opSpec *operatorapi.OperatorSpec, fg *configv1.FeatureGate, csiDriver *storagev1.CSIDriver) (bool, operatorapi.OperatorCondition) {
+	cnd := operatorapi.OperatorCondition{
+		Type:   "CSIDriverStarterVSphereStorageDriver" + operatorapi.OperatorStatusTypeDegraded,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	driver := vSphereDefaultDriver
+	if _, err := csoutils.GetUnsupportedConfigOverride(opSpec, "vsphereStorageDriver", &driver); err != nil {
+		cnd.Status = operatorapi.ConditionTrue
+		cnd.Reason = "InvalidVSphereStorageDriver"
+		cnd.Message = err.Error()
+		return false, cnd
+	}
+
+	switch driver {
+	case vSphereDefaultDriver, vSphereCSIWithMigrationDriver:
+		return false, cnd
+	case vSphereLegacyInTreeDriver:
+		csiInstalled := csiDriver != nil && !decision.IsUnsupportedCSIDriverRunning(csiDriver)
+		if csiInstalled || csoutils.FeatureGateEnabled(fg, vSphereMigrationFeatureGate) {
+			cnd.Status = operatorapi.ConditionTrue
+			cnd.Reason = "MigrationCannotBeReverted"
+			cnd.Message = fmt.Sprintf("vsphereStorageDriver %s can't be selected, the vSphere CSI driver is already installed and volumes are migrated to it. Set vsphereStorageDriver to %s", driver, vSphereCSIWithMigrationDriver)
+			return false, cnd
+		}
+		cnd.Reason = string(vSphereLegacyInTreeDriver)
+		cnd.Message = "The vSphere CSI driver is not installed, volumes are provisioned by the deprecated in-tree volume plugin"
+		return true, cnd
+	}
+	cnd.Status = operatorapi.ConditionTrue
+	cnd.Reason = "InvalidVSphereStorageDriver"
+	cnd.Message = fmt.Sprintf("vsphereStorageDriver %q is not one of %s, %s", driver, vSphereCSIWithMigrationDriver, vSphereLegacyInTreeDriver)
+	return false, cnd
+}
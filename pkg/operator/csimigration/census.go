@@ -0,0 +1,76 @@
+package csimigration
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// provisionedByAnnotation is set by the PV controller on dynamically
+	// provisioned PVs.
+	provisionedByAnnotation = "pv.kubernetes.io/provisioned-by"
+	unknownProvisioner      = "unknown"
+)
+
+var persistentVolumesDesc = prometheus.NewDesc(
+	"cso_persistent_volumes",
+	"Number of PersistentVolumes by their provisioner: name of the in-tree volume plugin or the CSI driver.",
+	[]string{"provisioner"},
+	nil,
+)
+
+// pvCensusCollector counts PersistentVolumes by provisioner when the metrics
+// are scraped, so migration tooling and telemetry can see how many volumes
+// still use in-tree volume plugins. PVs of in-tree plugins are counted under
+// the in-tree provisioner even after they were migrated to CSI, their volume
+// source does not change.
+type pvCensusCollector struct {
+	pvLister corelisters.PersistentVolumeLister
+}
+
+var _ prometheus.Collector = &pvCensusCollector{}
+
+func (c *pvCensusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- persistentVolumesDesc
+}
+
+func (c *pvCensusCollector) Collect(ch chan<- prometheus.Metric) {
+	pvs, err := c.pvLister.List(labels.Everything())
+	if err != nil {
+		klog.Warningf("Failed to list PersistentVolumes for metrics: %s", err)
+		return
+	}
+	for provisioner, count := range countPVsByProvisioner(pvs) {
+		ch <- prometheus.MustNewConstMetric(persistentVolumesDesc, prometheus.GaugeValue, float64(count), provisioner)
+	}
+}
+
+// countPVsByProvisioner returns number of PVs of each provisioner.
+func countPVsByProvisioner(pvs []*corev1.PersistentVolume) map[string]int {
+	counts := map[string]int{}
+	for _, pv := range pvs {
+		counts[getProvisioner(pv)]++
+	}
+	return counts
+}
+
+// getProvisioner returns name of the CSI driver or in-tree volume plugin of
+// the PV. Statically provisioned PVs of other volume plugins (e.g. NFS) are
+// unknown.
+func getProvisioner(pv *corev1.PersistentVolume) string {
+	if pv.Spec.CSI != nil {
+		return pv.Spec.CSI.Driver
+	}
+	for _, plugin := range InTreePlugins {
+		if plugin.UsedBy(pv) {
+			return plugin.Provisioner
+		}
+	}
+	if provisioner := pv.Annotations[provisionedByAnnotation]; provisioner != "" {
+		return provisioner
+	}
+	return unknownProvisioner
+}
@@ -0,0 +1,305 @@
+package csimigration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName        = "CSIMigrationController"
+	featureGateConfigName = "cluster"
+
+	migratedConditionType = "Migrated"
+)
+
+// migrationState is state of CSI migration of an in-tree plugin.
+type migrationState string
+
+const (
+	// migrationNotNeeded - the plugin is not migrated and no PV uses it.
+	migrationNotNeeded migrationState = "NotNeeded"
+	// migrationNotEnabled - the plugin is not migrated and PVs use it, the
+	// next release still has the plugin.
+	migrationNotEnabled migrationState = "NotMigrated"
+	// migrationBlocked - the plugin is not migrated and PVs use it, the
+	// next release does not have the plugin.
+	migrationBlocked migrationState = "NotMigratedBeforeRemoval"
+	// migrationPending - the CSIMigration feature gate is enabled, but the
+	// CSI driver is not installed yet.
+	migrationPending migrationState = "WaitingForCSIDriver"
+	// migrationComplete - the feature gate is enabled and the CSI driver is
+	// installed, in-tree PVs are handled by the CSI driver.
+	migrationComplete migrationState = "Migrated"
+)
+
+// pluginStatus is observed migration status of an in-tree plugin.
+type pluginStatus struct {
+	plugin Plugin
+	state  migrationState
+	// inTreePVs is the number of PVs with volume source of the plugin.
+	inTreePVs int
+}
+
+// This Controller coordinates migration of in-tree volume plugins to CSI.
+// For each plugin in InTreePlugins it counts PVs provisioned by the plugin
+// and checks its CSIMigration feature gate and CSI driver. PVs of a plugin
+// that is not migrated block the upgrade when the next release removes the
+// plugin, see Plugin.RemovedIn. Once the migration of a plugin is complete, the controller
+// moves the default annotation from the in-tree StorageClass to
+// a StorageClass of the CSI driver, see flipDefaultStorageClass. Numbers of
+// PVs of all provisioners are exposed as metrics, see pvCensusCollector.
+// It produces following Conditions:
+// CSIMigrationControllerUpgradeable - false when PVs of a plugin that is not
+// migrated exist and the next release removes the plugin.
+// CSIMigrationController<plugin>Migrated - migration state of each in-tree
+// plugin used in the cluster, with the number of its PVs.
+// CSIMigrationControllerDegraded - error updating StorageClasses.
+type Controller struct {
+	operatorClient     v1helpers.OperatorClient
+	kubeClient         kubernetes.Interface
+	featureGateLister  openshiftv1.FeatureGateLister
+	dynamicFGLister    cache.GenericLister
+	pvLister           corelisters.PersistentVolumeLister
+	csiDriverLister    storagelisters.CSIDriverLister
+	storageClassLister storagelisters.StorageClassLister
+	eventRecorder      events.Recorder
+	// version is the current release.
+	version string
+	// nextRelease is the release after the current one, <major>.<minor+1>.
+	nextRelease semver.Version
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	version string) factory.Controller {
+	c := &Controller{
+		operatorClient:     clients.OperatorClient,
+		kubeClient:         clients.KubeClient,
+		featureGateLister:  clients.ConfigInformers.Config().V1().FeatureGates().Lister(),
+		dynamicFGLister:    clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Lister(),
+		pvLister:           clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumes().Lister(),
+		csiDriverLister:    clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Lister(),
+		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		eventRecorder:      eventRecorder.WithComponentSuffix("csi-migration"),
+		version:            version,
+		nextRelease:        nextRelease(version),
+	}
+	if err := prometheus.Register(&pvCensusCollector{pvLister: c.pvLister}); err != nil {
+		klog.Warningf("Failed to register PersistentVolume metrics: %s", err)
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().FeatureGates().Informer(),
+		clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Informer(),
+		clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumes().Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("CSIMigrationController sync started")
+	defer klog.V(4).Infof("CSIMigrationController sync finished")
+
+	opSpec, opStatus, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	featureGate, err := c.featureGateLister.Get(featureGateConfigName)
+	if err != nil {
+		return err
+	}
+	// Use features rendered for this release in the FeatureGate status.
+	featureGate, err = csoutils.GetFeatureGateWithStatus(c.dynamicFGLister, featureGate, c.version)
+	if err != nil {
+		return err
+	}
+	pvs, err := c.pvLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	statuses, err := c.getPluginStatuses(featureGate, pvs)
+	if err != nil {
+		return err
+	}
+
+	var updateFuncs []v1helpers.UpdateStatusFunc
+	var blocked []string
+	for _, status := range statuses {
+		cndType := controllerName + status.plugin.Name + migratedConditionType
+		if status.state == migrationNotNeeded {
+			// Keep the status short, report only plugins used in the cluster.
+			if v1helpers.FindOperatorCondition(opStatus.Conditions, cndType) != nil {
+				updateFuncs = append(updateFuncs, removeConditionFn(cndType))
+			}
+			continue
+		}
+		updateFuncs = append(updateFuncs, v1helpers.UpdateConditionFn(migratedCondition(cndType, status)))
+		if status.state == migrationBlocked {
+			blocked = append(blocked, fmt.Sprintf("%d PersistentVolumes of %s", status.inTreePVs, status.plugin.Provisioner))
+		}
+	}
+
+	upgradeable := operatorapi.OperatorCondition{
+		Type:   controllerName + operatorapi.OperatorStatusTypeUpgradeable,
+		Status: operatorapi.ConditionTrue,
+	}
+	if len(blocked) > 0 {
+		upgradeable.Status = operatorapi.ConditionFalse
+		upgradeable.Reason = "InTreeVolumesNotMigrated"
+		upgradeable.Message = fmt.Sprintf("CSI migration is not enabled for %s, release %d.%d does not support their in-tree volume plugins", strings.Join(blocked, ", "), c.nextRelease.Major, c.nextRelease.Minor)
+		if !v1helpers.IsOperatorConditionFalse(opStatus.Conditions, upgradeable.Type) {
+			c.eventRecorder.Warningf("InTreeVolumesNotMigrated", "Upgrades are blocked: %s", upgradeable.Message)
+		}
+	}
+	updateFuncs = append(updateFuncs, v1helpers.UpdateConditionFn(upgradeable))
+
+	syncErr := c.syncDefaultStorageClass(ctx, opSpec, statuses)
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient, updateFuncs...); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// syncDefaultStorageClass moves the default StorageClass to CSI drivers of
+// migrated plugins, unless the admin selected the default StorageClass in
+// the Storage CR (defaultStorageClassName).
+func (c *Controller) syncDefaultStorageClass(ctx context.Context, opSpec *operatorapi.OperatorSpec, statuses []pluginStatus) error {
+	selected := ""
+	if _, err := csoutils.GetUnsupportedConfigOverride(opSpec, "defaultStorageClassName", &selected); err != nil {
+		return err
+	}
+	if selected != "" {
+		return nil
+	}
+	storageClasses, err := c.storageClassLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.state != migrationComplete {
+			continue
+		}
+		if err := c.flipDefaultStorageClass(ctx, status.plugin, storageClasses); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPluginStatuses returns migration status of all InTreePlugins.
+func (c *Controller) getPluginStatuses(featureGate *configv1.FeatureGate, pvs []*corev1.PersistentVolume) ([]pluginStatus, error) {
+	var statuses []pluginStatus
+	for _, plugin := range InTreePlugins {
+		status := pluginStatus{plugin: plugin}
+		for _, pv := range pvs {
+			if plugin.UsedBy(pv) {
+				status.inTreePVs++
+			}
+		}
+
+		switch {
+		case !csoutils.FeatureGateEnabled(featureGate, plugin.FeatureGate) && status.inTreePVs == 0:
+			status.state = migrationNotNeeded
+		case !csoutils.FeatureGateEnabled(featureGate, plugin.FeatureGate) && c.removedInNextRelease(plugin):
+			status.state = migrationBlocked
+		case !csoutils.FeatureGateEnabled(featureGate, plugin.FeatureGate):
+			status.state = migrationNotEnabled
+		default:
+			_, err := c.csiDriverLister.Get(plugin.CSIDriverName)
+			switch {
+			case err == nil:
+				status.state = migrationComplete
+			case apierrors.IsNotFound(err):
+				status.state = migrationPending
+			default:
+				return nil, err
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].plugin.Name < statuses[j].plugin.Name
+	})
+	return statuses, nil
+}
+
+// nextRelease returns the release after the given version of CSO. Versions
+// that cannot be parsed, e.g. of development builds, give zero version, no
+// plugin is removed in it.
+func nextRelease(version string) semver.Version {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		klog.Warningf("Failed to parse operator version %q: %s", version, err)
+		return semver.Version{}
+	}
+	return semver.Version{Major: v.Major, Minor: v.Minor + 1}
+}
+
+// removedInNextRelease returns true when the next release does not have the
+// in-tree plugin.
+func (c *Controller) removedInNextRelease(plugin Plugin) bool {
+	if plugin.RemovedIn == "" {
+		return false
+	}
+	removedIn, err := semver.ParseTolerant(plugin.RemovedIn)
+	if err != nil {
+		klog.Warningf("Failed to parse release %q of in-tree plugin %s: %s", plugin.RemovedIn, plugin.Name, err)
+		return false
+	}
+	return c.nextRelease.GTE(removedIn)
+}
+
+func migratedCondition(cndType string, status pluginStatus) operatorapi.OperatorCondition {
+	cnd := operatorapi.OperatorCondition{
+		Type:   cndType,
+		Status: operatorapi.ConditionFalse,
+		Reason: string(status.state),
+	}
+	switch status.state {
+	case migrationNotEnabled:
+		cnd.Message = fmt.Sprintf("%d PersistentVolumes use in-tree volume plugin %s, CSI migration feature gate %s is not enabled", status.inTreePVs, status.plugin.Provisioner, status.plugin.FeatureGate)
+	case migrationBlocked:
+		cnd.Message = fmt.Sprintf("%d PersistentVolumes use in-tree volume plugin %s, which is removed in release %s, CSI migration feature gate %s is not enabled", status.inTreePVs, status.plugin.Provisioner, status.plugin.RemovedIn, status.plugin.FeatureGate)
+	case migrationPending:
+		cnd.Message = fmt.Sprintf("CSI migration of %s is enabled, waiting for CSI driver %s to be installed", status.plugin.Provisioner, status.plugin.CSIDriverName)
+	case migrationComplete:
+		cnd.Status = operatorapi.ConditionTrue
+		cnd.Message = fmt.Sprintf("%d PersistentVolumes of in-tree volume plugin %s are handled by CSI driver %s", status.inTreePVs, status.plugin.Provisioner, status.plugin.CSIDriverName)
+	}
+	return cnd
+}
+
+func removeConditionFn(condType string) v1helpers.UpdateStatusFunc {
+	return func(oldStatus *operatorapi.OperatorStatus) error {
+		v1helpers.RemoveOperatorCondition(&oldStatus.Conditions, condType)
+		return nil
+	}
+}
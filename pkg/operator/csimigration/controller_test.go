@@ -0,0 +1,177 @@
+package csimigration
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/testharness"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestFlipDefaultStorageClass(t *testing.T) {
+	ebs := InTreePlugins[0]
+	storageClasses := []*storagev1.StorageClass{
+		storageClass("gp2", ebs.Provisioner, true),
+		storageClass("gp3-csi", ebs.CSIDriverName, false),
+		storageClass("gp2-csi", ebs.CSIDriverName, false),
+	}
+	initialObjects := &csoclients.FakeTestObjects{}
+	for _, sc := range storageClasses {
+		initialObjects.CoreObjects = append(initialObjects.CoreObjects, sc)
+	}
+	clients := csoclients.NewFakeClients(initialObjects)
+	c := &Controller{
+		kubeClient:    clients.KubeClient,
+		eventRecorder: events.NewInMemoryRecorder("operator"),
+	}
+
+	if err := c.flipDefaultStorageClass(context.TODO(), ebs, storageClasses); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, name := range []string{"gp2", "gp2-csi", "gp3-csi"} {
+		sc, err := clients.KubeClient.StorageV1().StorageClasses().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if isDefault(sc) != (name == "gp2-csi") {
+			t.Errorf("unexpected default annotation of StorageClass %s: %v", name, sc.Annotations)
+		}
+	}
+}
+
+func TestGetPluginStatuses(t *testing.T) {
+	featureGate := &configv1.FeatureGate{
+		Spec: configv1.FeatureGateSpec{
+			FeatureGateSelection: configv1.FeatureGateSelection{
+				FeatureSet: configv1.CustomNoUpgrade,
+				CustomNoUpgrade: &configv1.CustomFeatureGates{
+					Enabled: []string{"CSIMigrationAWS", "CSIMigrationGCE"},
+				},
+			},
+		},
+	}
+	pvs := []*corev1.PersistentVolume{
+		{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{AWSElasticBlockStore: &corev1.AWSElasticBlockStoreVolumeSource{}}}},
+		{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{Cinder: &corev1.CinderPersistentVolumeSource{}}}},
+		{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{AzureDisk: &corev1.AzureDiskVolumeSource{}}}},
+		{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{}}}},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(&storagev1.CSIDriver{ObjectMeta: metav1.ObjectMeta{Name: InTreePlugins[0].CSIDriverName}})
+	c := &Controller{
+		csiDriverLister: storagelisters.NewCSIDriverLister(indexer),
+		// Cinder is removed in the next release, Azure Disk is not.
+		nextRelease: nextRelease("4.12.5"),
+	}
+
+	statuses, err := c.getPluginStatuses(featureGate, pvs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]pluginStatus{
+		"AWSEBS":    {state: migrationComplete, inTreePVs: 1},
+		"AzureDisk": {state: migrationNotEnabled, inTreePVs: 1},
+		"AzureFile": {state: migrationNotNeeded},
+		"Cinder":    {state: migrationBlocked, inTreePVs: 1},
+		"GCEPD":     {state: migrationPending},
+		"VSphere":   {state: migrationNotNeeded},
+	}
+	for _, status := range statuses {
+		exp := expected[status.plugin.Name]
+		if status.state != exp.state || status.inTreePVs != exp.inTreePVs {
+			t.Errorf("unexpected status of plugin %s: state %s with %d PVs, expected %s with %d PVs", status.plugin.Name, status.state, status.inTreePVs, exp.state, exp.inTreePVs)
+		}
+	}
+}
+
+func TestUpgradeable(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		enabled  []string
+		expected operatorapi.ConditionStatus
+	}{
+		{
+			name:     "next release has the in-tree plugin",
+			version:  "4.12.0",
+			expected: operatorapi.ConditionTrue,
+		},
+		{
+			name:     "next release removes the in-tree plugin",
+			version:  "4.13.0",
+			expected: operatorapi.ConditionFalse,
+		},
+		{
+			name:     "migration enabled in FeatureGate status",
+			version:  "4.13.0",
+			enabled:  []string{"CSIMigrationAWS"},
+			expected: operatorapi.ConditionTrue,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The vendored Default FeatureSet does not know CSIMigration
+			// feature gates, they're enabled only in the status.
+			featureGate := &configv1.FeatureGate{
+				ObjectMeta: metav1.ObjectMeta{Name: featureGateConfigName},
+				Spec: configv1.FeatureGateSpec{
+					FeatureGateSelection: configv1.FeatureGateSelection{FeatureSet: configv1.Default},
+				},
+			}
+			pv := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+				Spec:       corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{AWSElasticBlockStore: &corev1.AWSElasticBlockStoreVolumeSource{}}},
+			}
+			h := testharness.New(t, &csoclients.FakeTestObjects{
+				CoreObjects:     []runtime.Object{pv},
+				OperatorObjects: []runtime.Object{testharness.NewStorage()},
+				ConfigObjects:   []runtime.Object{featureGate},
+				DynamicObjects:  []runtime.Object{testharness.NewFeatureGateStatus(test.version, test.enabled...)},
+			})
+			ctrl := NewController(h.Clients, h.Recorder, test.version)
+			h.Start()
+
+			h.Sync(ctrl)
+
+			h.AssertCondition(controllerName+operatorapi.OperatorStatusTypeUpgradeable, test.expected)
+		})
+	}
+}
+
+func TestCountPVsByProvisioner(t *testing.T) {
+	pvs := []*corev1.PersistentVolume{
+		{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{AWSElasticBlockStore: &corev1.AWSElasticBlockStoreVolumeSource{}}}},
+		{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com"}}}},
+		{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com"}}}},
+		{Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{NFS: &corev1.NFSVolumeSource{}}}},
+	}
+	expected := map[string]int{
+		"kubernetes.io/aws-ebs": 1,
+		"ebs.csi.aws.com":       2,
+		unknownProvisioner:      1,
+	}
+	if counts := countPVsByProvisioner(pvs); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected %v, got %v", expected, counts)
+	}
+}
+
+func storageClass(name, provisioner string, isDefaultClass bool) *storagev1.StorageClass {
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: name},
+		Provisioner: provisioner,
+	}
+	if isDefaultClass {
+		sc.Annotations = map[string]string{defaultStorageClassAnnotation: "true"}
+	}
+	return sc
+}
@@ -0,0 +1,76 @@
+package csimigration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// flipDefaultStorageClass makes a StorageClass of the CSI driver the default
+// one instead of the default StorageClass of the migrated in-tree plugin. The
+// CSI StorageClass is annotated first, so the cluster has a default
+// StorageClass all the time. When the CSI driver has more StorageClasses, the
+// first one by name is used. Nothing is changed when the default StorageClass
+// is not an in-tree one.
+func (c *Controller) flipDefaultStorageClass(ctx context.Context, plugin Plugin, storageClasses []*storagev1.StorageClass) error {
+	storageClasses = append([]*storagev1.StorageClass{}, storageClasses...)
+	sort.Slice(storageClasses, func(i, j int) bool {
+		return storageClasses[i].Name < storageClasses[j].Name
+	})
+
+	var inTreeDefaults []*storagev1.StorageClass
+	var csiSC *storagev1.StorageClass
+	for _, sc := range storageClasses {
+		switch {
+		case sc.Provisioner == plugin.CSIDriverName && isDefault(sc):
+			// The CSI driver already provides the default StorageClass.
+			return nil
+		case sc.Provisioner == plugin.CSIDriverName && csiSC == nil:
+			csiSC = sc
+		case sc.Provisioner == plugin.Provisioner && isDefault(sc):
+			inTreeDefaults = append(inTreeDefaults, sc)
+		}
+	}
+	if len(inTreeDefaults) == 0 {
+		return nil
+	}
+	if csiSC == nil {
+		klog.V(4).Infof("CSI migration of %s is complete, waiting for a StorageClass of %s", plugin.Provisioner, plugin.CSIDriverName)
+		return nil
+	}
+
+	if err := c.setDefault(ctx, csiSC, true); err != nil {
+		return err
+	}
+	for _, sc := range inTreeDefaults {
+		if err := c.setDefault(ctx, sc, false); err != nil {
+			return err
+		}
+		c.eventRecorder.Eventf("DefaultStorageClassMigrated", "Default StorageClass changed from %s to %s after CSI migration of %s", sc.Name, csiSC.Name, plugin.Provisioner)
+	}
+	return nil
+}
+
+func (c *Controller) setDefault(ctx context.Context, sc *storagev1.StorageClass, isDefaultClass bool) error {
+	newSC := sc.DeepCopy()
+	if isDefaultClass {
+		metav1.SetMetaDataAnnotation(&newSC.ObjectMeta, defaultStorageClassAnnotation, "true")
+	} else {
+		delete(newSC.Annotations, defaultStorageClassAnnotation)
+	}
+	klog.V(2).Infof("Setting StorageClass %s default: %t", sc.Name, isDefaultClass)
+	if _, err := c.kubeClient.StorageV1().StorageClasses().Update(ctx, newSC, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update StorageClass %s: %w", sc.Name, err)
+	}
+	return nil
+}
+
+func isDefault(sc *storagev1.StorageClass) bool {
+	return sc.Annotations[defaultStorageClassAnnotation] == "true"
+}
@@ -0,0 +1,78 @@
+package csimigration
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Plugin describes an in-tree volume plugin and the CSI driver that replaces
+// it once CSI migration is enabled.
+type Plugin struct {
+	// Name is a short name of the plugin, used in conditions.
+	Name          string
+	Provisioner   string
+	FeatureGate   string
+	CSIDriverName string
+	// RemovedIn is the first OpenShift release (<major>.<minor>) without the
+	// in-tree plugin, empty when its removal is not planned.
+	RemovedIn string
+	// usedBy returns true when the PV uses the in-tree plugin.
+	usedBy func(pv *corev1.PersistentVolume) bool
+}
+
+// UsedBy returns true when the PersistentVolume has volume source of the
+// in-tree plugin. PVs keep the in-tree source after migration, the source
+// only tells which plugin the PV was provisioned by.
+func (p Plugin) UsedBy(pv *corev1.PersistentVolume) bool {
+	return p.usedBy(pv)
+}
+
+// InTreePlugins are the in-tree volume plugins being migrated to CSI.
+// RemovedIn follows removal of the plugins in Kubernetes.
+var InTreePlugins = []Plugin{
+	{
+		Name:          "AWSEBS",
+		Provisioner:   "kubernetes.io/aws-ebs",
+		FeatureGate:   "CSIMigrationAWS",
+		CSIDriverName: "ebs.csi.aws.com",
+		RemovedIn:     "4.14",
+		usedBy:        func(pv *corev1.PersistentVolume) bool { return pv.Spec.AWSElasticBlockStore != nil },
+	},
+	{
+		Name:          "GCEPD",
+		Provisioner:   "kubernetes.io/gce-pd",
+		FeatureGate:   "CSIMigrationGCE",
+		CSIDriverName: "pd.csi.storage.gke.io",
+		RemovedIn:     "4.15",
+		usedBy:        func(pv *corev1.PersistentVolume) bool { return pv.Spec.GCEPersistentDisk != nil },
+	},
+	{
+		Name:          "AzureDisk",
+		Provisioner:   "kubernetes.io/azure-disk",
+		FeatureGate:   "CSIMigrationAzureDisk",
+		CSIDriverName: "disk.csi.azure.com",
+		RemovedIn:     "4.14",
+		usedBy:        func(pv *corev1.PersistentVolume) bool { return pv.Spec.AzureDisk != nil },
+	},
+	{
+		Name:          "AzureFile",
+		Provisioner:   "kubernetes.io/azure-file",
+		FeatureGate:   "CSIMigrationAzureFile",
+		CSIDriverName: "file.csi.azure.com",
+		usedBy:        func(pv *corev1.PersistentVolume) bool { return pv.Spec.AzureFile != nil },
+	},
+	{
+		Name:          "Cinder",
+		Provisioner:   "kubernetes.io/cinder",
+		FeatureGate:   "CSIMigrationOpenStack",
+		CSIDriverName: "cinder.csi.openstack.org",
+		RemovedIn:     "4.13",
+		usedBy:        func(pv *corev1.PersistentVolume) bool { return pv.Spec.Cinder != nil },
+	},
+	{
+		Name:          "VSphere",
+		Provisioner:   "kubernetes.io/vsphere-volume",
+		FeatureGate:   "CSIMigrationVSphere",
+		CSIDriverName: "csi.vsphere.vmware.com",
+		usedBy:        func(pv *corev1.PersistentVolume) bool { return pv.Spec.VsphereVolume != nil },
+	},
+}
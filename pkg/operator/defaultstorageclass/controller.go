@@ -3,19 +3,25 @@ package defaultstorageclass
 import (
 	"context"
 	"errors"
+	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
 	operatorapi "github.com/openshift/api/operator/v1"
 	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
 	"github.com/openshift/cluster-storage-operator/assets"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	errutil "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/kubernetes"
 	v1 "k8s.io/client-go/listers/storage/v1"
@@ -26,6 +32,13 @@ const (
 	conditionsPrefix      = "DefaultStorageClassController"
 	infraConfigName       = "cluster"
 	disabledConditionType = "Disabled"
+	ownerComponent        = "default-storageclass"
+
+	// unmanagedAnnotation on the default StorageClass stops the controller
+	// from reverting changes of the StorageClass made by the admin. To stop
+	// re-creating a deleted StorageClass, pause it in the Storage CR, see
+	// csoutils.IsResourcePaused.
+	unmanagedAnnotation = "storage.openshift.io/unmanaged"
 )
 
 var unsupportedPlatformError = errors.New("unsupported platform")
@@ -33,6 +46,12 @@ var supportedByCSIError = errors.New("only supported by a provided CSI Driver")
 
 // This Controller deploys a default StorageClass for in-tree volume plugins,
 // based on the underlying cloud (read from Infrastructure instance).
+// On AWS, Azure and GCP it encrypts volumes of the StorageClass with a customer
+// managed key, when configured (see csoutils.DriverConfig).
+// It re-creates the StorageClass when it's deleted or when its immutable
+// fields are changed, unless it's annotated with unmanagedAnnotation. In
+// audit-only mode (see csoutils.IsAuditOnly) it only reports drift of the
+// StorageClass.
 // It produces following Conditions:
 // DefaultStorageClassControllerAvailable: the default storage class has been
 //    created.
@@ -45,6 +64,9 @@ type Controller struct {
 	infraLister        openshiftv1.InfrastructureLister
 	storageClassLister v1.StorageClassLister
 	eventRecorder      events.Recorder
+	// storageClassSeen is true when the StorageClass existed in a previous
+	// sync, i.e. it's restored and not created for the first time.
+	storageClassSeen bool
 }
 
 func NewController(
@@ -53,11 +75,11 @@ func NewController(
 	c := &Controller{
 		operatorClient:     clients.OperatorClient,
 		kubeClient:         clients.KubeClient,
-		infraLister:        clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
+		infraLister:        clients.InfrastructureLister(),
 		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
 		eventRecorder:      eventRecorder,
 	}
-	return factory.New().WithSync(c.sync).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+	return factory.New().WithSync(health.TrackSync("DefaultStorageClassController", c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
 		clients.OperatorClient.Informer(),
 		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
 		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
@@ -85,7 +107,7 @@ func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) erro
 		Status: operatorapi.ConditionFalse,
 	}
 
-	syncErr := c.syncStorageClass(ctx)
+	syncErr := c.syncStorageClass(ctx, opSpec)
 	if syncErr != nil {
 		if syncErr == unsupportedPlatformError {
 			// Set Disabled condition - there is nothing to do
@@ -142,7 +164,7 @@ func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) erro
 	return syncErr
 }
 
-func (c *Controller) syncStorageClass(ctx context.Context) error {
+func (c *Controller) syncStorageClass(ctx context.Context, opSpec *operatorapi.OperatorSpec) error {
 	infrastructure, err := c.infraLister.Get(infraConfigName)
 	if err != nil {
 		return err
@@ -158,25 +180,96 @@ func (c *Controller) syncStorageClass(ctx context.Context) error {
 		return err
 	}
 
+	cfg, err := csoutils.GetDriverConfig(opSpec)
+	if err != nil {
+		return err
+	}
+	if err := applyEncryption(expectedSC, infrastructure.Status.PlatformStatus.Type, cfg); err != nil {
+		return err
+	}
+	csoutils.SetOwnedByLabel(expectedSC, ownerComponent)
+
+	paused, err := csoutils.IsResourcePaused(opSpec, csoutils.PausedKindStorageClass, "", expectedSC.Name)
+	if err != nil {
+		return err
+	}
+	if paused {
+		klog.V(2).Infof("Reconciliation of StorageClass %s is paused", expectedSC.Name)
+		return nil
+	}
+
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		return err
+	}
+
 	existingSC, err := c.storageClassLister.Get(expectedSC.Name)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
+			if auditOnly {
+				return csoutils.ReportObjectDrift(c.eventRecorder, "StorageClass", expectedSC, nil)
+			}
 			klog.V(2).Infof("StorageClass %s does not exist, creating", expectedSC.Name)
 			_, _, err = resourceapply.ApplyStorageClass(ctx, c.kubeClient.StorageV1(), c.eventRecorder, expectedSC)
+			if err == nil && c.storageClassSeen {
+				c.eventRecorder.Warningf("StorageClassRestored", "StorageClass %s was deleted, re-created it", expectedSC.Name)
+			}
 			return err
 		}
 		return err
 	}
+	c.storageClassSeen = true
+
+	if existingSC.Annotations[unmanagedAnnotation] == "true" {
+		klog.V(4).Infof("StorageClass %s is annotated with %s, not reconciling it", existingSC.Name, unmanagedAnnotation)
+		return nil
+	}
 
 	// Don't overwrite default storage class annotations of the existing storage class!
 	// User may have made it non-default.
 	expectedSC.Annotations = existingSC.Annotations
+
+	if auditOnly {
+		return csoutils.ReportObjectDrift(c.eventRecorder, "StorageClass", expectedSC, existingSC)
+	}
+
+	if changed := changedImmutableFields(existingSC, expectedSC); len(changed) > 0 {
+		// The fields are immutable, re-create the StorageClass. Existing
+		// PVs are not affected, only new volumes get the new parameters
+		// (e.g. encryption key).
+		klog.V(2).Infof("StorageClass %s %s changed, re-creating", expectedSC.Name, strings.Join(changed, ", "))
+		err = c.kubeClient.StorageV1().StorageClasses().Delete(ctx, existingSC.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		c.eventRecorder.Eventf("StorageClassRecreated", "StorageClass %s re-created to restore its %s", expectedSC.Name, strings.Join(changed, ", "))
+	}
+
 	klog.V(2).Infof("Existing StorageClass %s found, reconciling", expectedSC.Name)
 	_, _, err = resourceapply.ApplyStorageClass(ctx, c.kubeClient.StorageV1(), c.eventRecorder, expectedSC)
 
 	return err
 }
 
+// changedImmutableFields returns names of immutable fields of the existing
+// StorageClass that differ from the expected one.
+func changedImmutableFields(existing, expected *storagev1.StorageClass) []string {
+	var changed []string
+	if existing.Provisioner != expected.Provisioner {
+		changed = append(changed, "provisioner")
+	}
+	if !equality.Semantic.DeepEqual(existing.Parameters, expected.Parameters) {
+		changed = append(changed, "parameters")
+	}
+	if expected.ReclaimPolicy != nil && !equality.Semantic.DeepEqual(existing.ReclaimPolicy, expected.ReclaimPolicy) {
+		changed = append(changed, "reclaimPolicy")
+	}
+	if expected.VolumeBindingMode != nil && !equality.Semantic.DeepEqual(existing.VolumeBindingMode, expected.VolumeBindingMode) {
+		changed = append(changed, "volumeBindingMode")
+	}
+	return changed
+}
+
 // Returns either the StorageClass, if the PlatformType is supported, or an error
 // indicating whether the StorageClass is provided by a CSI driver or an unsupported platform
 func newStorageClassForCluster(infrastructure *configv1.Infrastructure) (*storagev1.StorageClass, error) {
@@ -196,7 +289,13 @@ func newStorageClassForCluster(infrastructure *configv1.Infrastructure) (*storag
 		storageClassFile = "storageclasses/openstack.yaml"
 	case configv1.VSpherePlatformType:
 		storageClassFile = "storageclasses/vsphere.yaml"
-	case configv1.OvirtPlatformType:
+	case configv1.IBMCloudPlatformType:
+		if infrastructure.Status.PlatformStatus.IBMCloud != nil &&
+			infrastructure.Status.PlatformStatus.IBMCloud.ProviderType == configv1.IBMCloudProviderTypeVPC {
+			return nil, supportedByCSIError
+		}
+		return nil, unsupportedPlatformError
+	case configv1.OvirtPlatformType, configv1.AlibabaCloudPlatformType, configv1.KubevirtPlatformType, csioperatorclient.NutanixPlatformType:
 		return nil, supportedByCSIError
 	default:
 		return nil, unsupportedPlatformError
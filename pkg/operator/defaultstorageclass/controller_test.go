@@ -11,6 +11,7 @@ import (
 	"github.com/openshift/cluster-storage-operator/assets"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
 	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
@@ -77,7 +78,12 @@ func getPlatformStorageClass(filename string, modifiers ...storageClassModifier)
 }
 
 func withNoDefault(class *storagev1.StorageClass) *storagev1.StorageClass {
-	class.Annotations = nil
+	class.Annotations = map[string]string{}
+	return class
+}
+
+func withOwnedByLabel(class *storagev1.StorageClass) *storagev1.StorageClass {
+	csoutils.SetOwnedByLabel(class, ownerComponent)
 	return class
 }
 
@@ -172,7 +178,7 @@ func TestSync(t *testing.T) {
 					withTrueConditions(conditionsPrefix+opv1.OperatorStatusTypeAvailable),
 					withFalseConditions(conditionsPrefix+opv1.OperatorStatusTypeProgressing),
 				),
-				storageClasses: []*storagev1.StorageClass{getPlatformStorageClass("storageclasses/aws.yaml")},
+				storageClasses: []*storagev1.StorageClass{getPlatformStorageClass("storageclasses/aws.yaml", withOwnedByLabel)},
 			},
 			expectErr: false,
 		},
@@ -207,7 +213,7 @@ func TestSync(t *testing.T) {
 					withTrueConditions(conditionsPrefix+opv1.OperatorStatusTypeAvailable),
 					withFalseConditions(conditionsPrefix+opv1.OperatorStatusTypeProgressing),
 				),
-				storageClasses: []*storagev1.StorageClass{getPlatformStorageClass("storageclasses/aws.yaml")},
+				storageClasses: []*storagev1.StorageClass{getPlatformStorageClass("storageclasses/aws.yaml", withOwnedByLabel)},
 			},
 			expectErr: false,
 		},
@@ -224,7 +230,7 @@ func TestSync(t *testing.T) {
 					withTrueConditions(conditionsPrefix+opv1.OperatorStatusTypeAvailable),
 					withFalseConditions(conditionsPrefix+opv1.OperatorStatusTypeProgressing),
 				),
-				storageClasses: []*storagev1.StorageClass{getPlatformStorageClass("storageclasses/aws.yaml", withNoDefault)},
+				storageClasses: []*storagev1.StorageClass{getPlatformStorageClass("storageclasses/aws.yaml", withNoDefault, withOwnedByLabel)},
 			},
 			expectErr: false,
 		},
@@ -0,0 +1,27 @@
+package defaultstorageclass
+
+import (
+	configv1 "github.com/openshift/api/config/v1"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	storagev1 "k8s.io/api/storage/v1"
+)
+
+// applyEncryption adds parameters that enable encryption with a customer
+// managed key to the StorageClass. The key is configured in driverConfig
+// of the Storage CR, see csoutils.DriverConfig.
+func applyEncryption(sc *storagev1.StorageClass, platform configv1.PlatformType, cfg *csoutils.DriverConfig) error {
+	params, err := csoutils.GetEncryptionParameters(platform, cfg)
+	if err != nil {
+		return err
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	if sc.Parameters == nil {
+		sc.Parameters = map[string]string{}
+	}
+	for k, v := range params {
+		sc.Parameters[k] = v
+	}
+	return nil
+}
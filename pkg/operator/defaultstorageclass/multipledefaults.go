@@ -0,0 +1,103 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/labels"
+	v1 "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultsCheckControllerName = "DefaultStorageClassCheckController"
+	multipleDefaultsType        = "MultipleDefaults"
+)
+
+var defaultStorageClassesMetric = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "cluster_storage_operator_default_storageclasses",
+		Help: "Number of StorageClasses annotated as the default one.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(defaultStorageClassesMetric)
+}
+
+// This DefaultStorageClassCheckController reports when more than one
+// StorageClass is annotated as the default one. PVCs without
+// storageClassName are then rejected by the DefaultStorageClass admission
+// plugin. It exposes the number of default StorageClasses in
+// cluster_storage_operator_default_storageclasses metric, which is used by
+// MultipleDefaultStorageClasses alert.
+// It produces following Conditions:
+// DefaultStorageClassCheckControllerMultipleDefaults - informational, true
+// when there are multiple default StorageClasses.
+type DefaultStorageClassCheckController struct {
+	operatorClient     v1helpers.OperatorClient
+	storageClassLister v1.StorageClassLister
+	eventRecorder      events.Recorder
+}
+
+func NewDefaultStorageClassCheckController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &DefaultStorageClassCheckController{
+		operatorClient:     clients.OperatorClient,
+		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		eventRecorder:      eventRecorder,
+	}
+	return factory.New().WithSync(health.TrackSync(defaultsCheckControllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
+	).ToController(defaultsCheckControllerName, eventRecorder)
+}
+
+func (c *DefaultStorageClassCheckController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("DefaultStorageClassCheckController sync started")
+	defer klog.V(4).Infof("DefaultStorageClassCheckController sync finished")
+
+	_, opStatus, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+
+	storageClasses, err := c.storageClassLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	var defaults []string
+	for _, sc := range storageClasses {
+		if isDefaultStorageClass(sc) {
+			defaults = append(defaults, sc.Name)
+		}
+	}
+	sort.Strings(defaults)
+	defaultStorageClassesMetric.Set(float64(len(defaults)))
+
+	cnd := operatorapi.OperatorCondition{
+		Type:   defaultsCheckControllerName + multipleDefaultsType,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	if len(defaults) > 1 {
+		cnd.Status = operatorapi.ConditionTrue
+		cnd.Reason = "MultipleDefaultStorageClasses"
+		cnd.Message = fmt.Sprintf("StorageClasses %s are all annotated as the default one, PVCs without storageClassName can't be created. Remove %s annotation from all but one of them", strings.Join(defaults, ", "), defaultStorageClassAnnotation)
+		if !v1helpers.IsOperatorConditionTrue(opStatus.Conditions, cnd.Type) {
+			c.eventRecorder.Warningf("MultipleDefaultStorageClasses", "Multiple default StorageClasses: %s", strings.Join(defaults, ", "))
+		}
+	}
+	_, _, err = v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(cnd))
+	return err
+}
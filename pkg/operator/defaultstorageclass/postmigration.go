@@ -0,0 +1,203 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csimigration"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	v1 "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	postMigrationControllerName = "PostMigrationCleanupController"
+	featureGateConfigName       = "cluster"
+
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+	// Label put on all in-tree StorageClasses of a plugin that has been
+	// migrated to CSI.
+	deprecatedInTreeLabel = "storage.openshift.io/deprecated-in-tree"
+	// Annotation with name of the CSI driver that replaced the in-tree plugin.
+	migratedToAnnotation = "storage.openshift.io/migrated-to"
+)
+
+// This PostMigrationCleanupController cleans up in-tree StorageClasses after
+// CSI migration of their volume plugin is complete, i.e. the CSIMigration
+// feature gate of the plugin is enabled and the replacing CSI driver is
+// installed. It:
+// - Labels all StorageClasses of the in-tree plugin as deprecated.
+// - Annotates them with the name of the CSI driver that replaces them.
+// - Removes the default annotation from the in-tree StorageClass, when a
+//   StorageClass of the CSI driver is already the default one.
+// - Emits an event summarizing what has been changed.
+// It produces following Conditions:
+// PostMigrationCleanupControllerDegraded - error updating StorageClasses.
+type PostMigrationCleanupController struct {
+	operatorClient     v1helpers.OperatorClient
+	kubeClient         kubernetes.Interface
+	featureGateLister  openshiftv1.FeatureGateLister
+	dynamicFGLister    cache.GenericLister
+	storageClassLister v1.StorageClassLister
+	csiDriverLister    v1.CSIDriverLister
+	eventRecorder      events.Recorder
+	// version is the current release.
+	version string
+}
+
+func NewPostMigrationCleanupController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	version string) factory.Controller {
+	c := &PostMigrationCleanupController{
+		operatorClient:     clients.OperatorClient,
+		kubeClient:         clients.KubeClient,
+		featureGateLister:  clients.ConfigInformers.Config().V1().FeatureGates().Lister(),
+		dynamicFGLister:    clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Lister(),
+		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		csiDriverLister:    clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Lister(),
+		eventRecorder:      eventRecorder.WithComponentSuffix("post-migration-cleanup"),
+		version:            version,
+	}
+	return factory.New().WithSync(health.TrackSync(postMigrationControllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().FeatureGates().Informer(),
+		clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().CSIDrivers().Informer(),
+	).ToController(postMigrationControllerName, eventRecorder)
+}
+
+func (c *PostMigrationCleanupController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("PostMigrationCleanupController sync started")
+	defer klog.V(4).Infof("PostMigrationCleanupController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	featureGate, err := c.featureGateLister.Get(featureGateConfigName)
+	if err != nil {
+		return err
+	}
+	// Use features rendered for this release in the FeatureGate status.
+	featureGate, err = csoutils.GetFeatureGateWithStatus(c.dynamicFGLister, featureGate, c.version)
+	if err != nil {
+		return err
+	}
+
+	storageClasses, err := c.storageClassLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var report []string
+	for _, plugin := range csimigration.InTreePlugins {
+		complete, err := c.migrationComplete(plugin, featureGate)
+		if err != nil {
+			return err
+		}
+		if !complete {
+			continue
+		}
+		changed, err := c.cleanupPlugin(ctx, plugin, storageClasses)
+		if err != nil {
+			return err
+		}
+		report = append(report, changed...)
+	}
+
+	if len(report) > 0 {
+		sort.Strings(report)
+		c.eventRecorder.Eventf("InTreeStorageClassesCleanedUp", "Cleaned up in-tree StorageClasses after CSI migration:\n%s", strings.Join(report, "\n"))
+	}
+	return nil
+}
+
+// migrationComplete returns true when the CSI migration feature gate of the
+// plugin is enabled and the replacing CSI driver is installed.
+func (c *PostMigrationCleanupController) migrationComplete(plugin csimigration.Plugin, fg *configv1.FeatureGate) (bool, error) {
+	if !csoutils.FeatureGateEnabled(fg, plugin.FeatureGate) {
+		return false, nil
+	}
+	_, err := c.csiDriverLister.Get(plugin.CSIDriverName)
+	if apierrors.IsNotFound(err) {
+		klog.V(4).Infof("CSI migration of %s is enabled, but CSI driver %s is not installed yet", plugin.Provisioner, plugin.CSIDriverName)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// cleanupPlugin labels, annotates and demotes StorageClasses of a migrated
+// in-tree plugin. It returns human readable list of changes.
+func (c *PostMigrationCleanupController) cleanupPlugin(ctx context.Context, plugin csimigration.Plugin, storageClasses []*storagev1.StorageClass) ([]string, error) {
+	csiDefault := false
+	for _, sc := range storageClasses {
+		if sc.Provisioner == plugin.CSIDriverName && isDefaultStorageClass(sc) {
+			csiDefault = true
+			break
+		}
+	}
+
+	var changes []string
+	for _, sc := range storageClasses {
+		if sc.Provisioner != plugin.Provisioner {
+			continue
+		}
+		newSC := sc.DeepCopy()
+		var scChanges []string
+		if newSC.Labels[deprecatedInTreeLabel] != "true" {
+			metav1.SetMetaDataLabel(&newSC.ObjectMeta, deprecatedInTreeLabel, "true")
+			scChanges = append(scChanges, "marked as deprecated")
+		}
+		if newSC.Annotations[migratedToAnnotation] != plugin.CSIDriverName {
+			metav1.SetMetaDataAnnotation(&newSC.ObjectMeta, migratedToAnnotation, plugin.CSIDriverName)
+			scChanges = append(scChanges, "annotated as migrated to "+plugin.CSIDriverName)
+		}
+		if csiDefault && isDefaultStorageClass(newSC) {
+			delete(newSC.Annotations, defaultStorageClassAnnotation)
+			scChanges = append(scChanges, "removed default annotation")
+		}
+		if equalMeta(sc, newSC) {
+			continue
+		}
+
+		klog.V(2).Infof("Cleaning up in-tree StorageClass %s migrated to %s", sc.Name, plugin.CSIDriverName)
+		if _, err := c.kubeClient.StorageV1().StorageClasses().Update(ctx, newSC, metav1.UpdateOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to update StorageClass %s: %w", sc.Name, err)
+		}
+		changes = append(changes, fmt.Sprintf("%s: %s", sc.Name, strings.Join(scChanges, ", ")))
+	}
+	return changes, nil
+}
+
+func isDefaultStorageClass(sc *storagev1.StorageClass) bool {
+	return sc.Annotations[defaultStorageClassAnnotation] == "true"
+}
+
+func equalMeta(a, b *storagev1.StorageClass) bool {
+	return labels.Equals(a.Labels, b.Labels) && labels.Equals(a.Annotations, b.Annotations)
+}
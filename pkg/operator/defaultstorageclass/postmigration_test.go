@@ -0,0 +1,103 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csimigration"
+	"github.com/openshift/library-go/pkg/operator/events"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPostMigrationCleanup(t *testing.T) {
+	ebs := csimigration.InTreePlugins[0]
+	tests := []struct {
+		name            string
+		storageClasses  []*storagev1.StorageClass
+		expectDefault   bool
+		expectedChanges []string
+	}{
+		{
+			name: "in-tree default is kept when CSI class is not default",
+			storageClasses: []*storagev1.StorageClass{
+				getPlatformStorageClass("storageclasses/aws.yaml"),
+				storageClass("gp2-csi", ebs.CSIDriverName, false),
+			},
+			expectDefault:   true,
+			expectedChanges: []string{"gp2: marked as deprecated, annotated as migrated to ebs.csi.aws.com"},
+		},
+		{
+			name: "already deprecated in-tree class is annotated",
+			storageClasses: []*storagev1.StorageClass{
+				deprecatedStorageClass(getPlatformStorageClass("storageclasses/aws.yaml")),
+				storageClass("gp2-csi", ebs.CSIDriverName, false),
+			},
+			expectDefault:   true,
+			expectedChanges: []string{"gp2: annotated as migrated to ebs.csi.aws.com"},
+		},
+		{
+			name: "in-tree default is demoted when CSI class is default",
+			storageClasses: []*storagev1.StorageClass{
+				getPlatformStorageClass("storageclasses/aws.yaml"),
+				storageClass("gp2-csi", ebs.CSIDriverName, true),
+			},
+			expectDefault:   false,
+			expectedChanges: []string{"gp2: marked as deprecated, annotated as migrated to ebs.csi.aws.com, removed default annotation"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			initialObjects := &csoclients.FakeTestObjects{}
+			for _, sc := range test.storageClasses {
+				initialObjects.CoreObjects = append(initialObjects.CoreObjects, sc)
+			}
+			clients := csoclients.NewFakeClients(initialObjects)
+			c := &PostMigrationCleanupController{
+				kubeClient:    clients.KubeClient,
+				eventRecorder: events.NewInMemoryRecorder("operator"),
+			}
+
+			changes, err := c.cleanupPlugin(context.TODO(), ebs, test.storageClasses)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(changes, test.expectedChanges) {
+				t.Errorf("expected changes %q, got %q", test.expectedChanges, changes)
+			}
+
+			sc, err := clients.KubeClient.StorageV1().StorageClasses().Get(context.TODO(), "gp2", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if sc.Labels[deprecatedInTreeLabel] != "true" {
+				t.Errorf("expected StorageClass to be labeled as deprecated, got labels %v", sc.Labels)
+			}
+			if sc.Annotations[migratedToAnnotation] != ebs.CSIDriverName {
+				t.Errorf("expected StorageClass to be annotated with %s, got %v", ebs.CSIDriverName, sc.Annotations)
+			}
+			if isDefaultStorageClass(sc) != test.expectDefault {
+				t.Errorf("expected default %t, got %t", test.expectDefault, isDefaultStorageClass(sc))
+			}
+		})
+	}
+}
+
+func deprecatedStorageClass(sc *storagev1.StorageClass) *storagev1.StorageClass {
+	metav1.SetMetaDataLabel(&sc.ObjectMeta, deprecatedInTreeLabel, "true")
+	return sc
+}
+
+func storageClass(name, provisioner string, isDefault bool) *storagev1.StorageClass {
+	sc := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: name},
+		Provisioner: provisioner,
+	}
+	if isDefault {
+		sc.Annotations = map[string]string{defaultStorageClassAnnotation: "true"}
+	}
+	return sc
+}
@@ -0,0 +1,203 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	v1 "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	retroactiveControllerName = "RetroactiveDefaultStorageClassController"
+
+	// retroactiveDefaultStorageClassFeatureGate allows setting
+	// storageClassName of existing PVCs that don't have any.
+	retroactiveDefaultStorageClassFeatureGate = "RetroactiveDefaultStorageClass"
+	// skipRetroactiveDefaultAnnotation on a PVC keeps it without
+	// StorageClass.
+	skipRetroactiveDefaultAnnotation = "storage.openshift.io/skip-retroactive-default-storageclass"
+)
+
+var retroactivePVCsMetric = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "cluster_storage_operator_retroactive_default_storageclass_pvcs_total",
+		Help: "Number of PVCs assigned to the default StorageClass after they were created.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(retroactivePVCsMetric)
+}
+
+// This RetroactiveDefaultStorageClassController assigns the default
+// StorageClass to unbound PVCs created without storageClassName, typically
+// while the cluster had no default StorageClass. PVCs with storageClassName
+// "" explicitly ask for no StorageClass and are kept as they are, as well as
+// PVCs annotated with skipRetroactiveDefaultAnnotation. It runs only when
+// RetroactiveDefaultStorageClass feature gate is enabled, the API server
+// does not allow setting storageClassName of existing PVCs otherwise.
+// PVCs that cannot be updated are reported in events when they fail for the
+// first time, they don't block the other PVCs.
+// It produces following Conditions:
+// RetroactiveDefaultStorageClassControllerDegraded - error listing PVCs or
+// StorageClasses.
+type RetroactiveDefaultStorageClassController struct {
+	operatorClient     v1helpers.OperatorClient
+	kubeClient         kubernetes.Interface
+	featureGateLister  openshiftv1.FeatureGateLister
+	dynamicFGLister    cache.GenericLister
+	storageClassLister v1.StorageClassLister
+	pvcLister          corelisters.PersistentVolumeClaimLister
+	eventRecorder      events.Recorder
+	// version is the current release.
+	version string
+	// failedPVCs are UIDs of PVCs that could not be updated in the last
+	// sync, they're already reported in events.
+	failedPVCs map[types.UID]bool
+}
+
+func NewRetroactiveDefaultStorageClassController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	version string) factory.Controller {
+	c := &RetroactiveDefaultStorageClassController{
+		operatorClient:     clients.OperatorClient,
+		kubeClient:         clients.KubeClient,
+		featureGateLister:  clients.ConfigInformers.Config().V1().FeatureGates().Lister(),
+		dynamicFGLister:    clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Lister(),
+		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		pvcLister:          clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumeClaims().Lister(),
+		eventRecorder:      eventRecorder.WithComponentSuffix("retroactive-default-storageclass"),
+		version:            version,
+	}
+	return factory.New().WithSync(health.TrackSync(retroactiveControllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().FeatureGates().Informer(),
+		clients.DynamicInformers.ForResource(csoutils.FeatureGateResource).Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
+		clients.KubeInformers.InformersFor("").Core().V1().PersistentVolumeClaims().Informer(),
+	).ToController(retroactiveControllerName, eventRecorder)
+}
+
+func (c *RetroactiveDefaultStorageClassController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("RetroactiveDefaultStorageClassController sync started")
+	defer klog.V(4).Infof("RetroactiveDefaultStorageClassController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	featureGate, err := c.featureGateLister.Get(featureGateConfigName)
+	if err != nil {
+		return err
+	}
+	// Use features rendered for this release in the FeatureGate status.
+	featureGate, err = csoutils.GetFeatureGateWithStatus(c.dynamicFGLister, featureGate, c.version)
+	if err != nil {
+		return err
+	}
+	if !csoutils.FeatureGateEnabled(featureGate, retroactiveDefaultStorageClassFeatureGate) {
+		return nil
+	}
+
+	storageClasses, err := c.storageClassLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	defaultSC := getDefaultStorageClass(storageClasses)
+	if defaultSC == "" {
+		return nil
+	}
+
+	pvcs, err := c.pvcLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	failedPVCs := map[types.UID]bool{}
+	for _, pvc := range pvcs {
+		if !needsDefaultStorageClass(pvc) {
+			continue
+		}
+		if err := c.assignStorageClass(ctx, pvc, defaultSC); err != nil {
+			// The PVC may be rejected e.g. by a quota or an admission
+			// webhook, it's not an issue of the cluster storage.
+			failedPVCs[pvc.UID] = true
+			if c.failedPVCs[pvc.UID] {
+				klog.V(4).Info(err)
+				continue
+			}
+			klog.Warning(err)
+			c.eventRecorder.Warningf("DefaultStorageClassAssignmentFailed", "%s", err)
+		}
+	}
+	c.failedPVCs = failedPVCs
+	return nil
+}
+
+// getDefaultStorageClass returns name of the default StorageClass. It returns
+// an empty string when there is none or when there are multiple default
+// StorageClasses, it's not clear which one to use then.
+func getDefaultStorageClass(storageClasses []*storagev1.StorageClass) string {
+	name := ""
+	for _, sc := range storageClasses {
+		if !isDefaultStorageClass(sc) {
+			continue
+		}
+		if name != "" {
+			return ""
+		}
+		name = sc.Name
+	}
+	return name
+}
+
+func needsDefaultStorageClass(pvc *corev1.PersistentVolumeClaim) bool {
+	return pvc.Spec.StorageClassName == nil &&
+		pvc.Spec.VolumeName == "" &&
+		pvc.Status.Phase == corev1.ClaimPending &&
+		pvc.Annotations[skipRetroactiveDefaultAnnotation] != "true"
+}
+
+func (c *RetroactiveDefaultStorageClassController) assignStorageClass(ctx context.Context, pvc *corev1.PersistentVolumeClaim, storageClassName string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"storageClassName": storageClassName},
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("Assigning default StorageClass %s to PVC %s/%s", storageClassName, pvc.Namespace, pvc.Name)
+	_, err = c.kubeClient.CoreV1().PersistentVolumeClaims(pvc.Namespace).Patch(ctx, pvc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to assign StorageClass %s to PVC %s/%s: %w", storageClassName, pvc.Namespace, pvc.Name, err)
+	}
+	retroactivePVCsMetric.Inc()
+	c.eventRecorder.Eventf("DefaultStorageClassAssigned", "Assigned default StorageClass %s to PVC %s/%s", storageClassName, pvc.Namespace, pvc.Name)
+	return nil
+}
@@ -0,0 +1,178 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	cfgv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/testharness"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
+)
+
+func pvc(name string, modifiers ...func(*corev1.PersistentVolumeClaim)) *corev1.PersistentVolumeClaim {
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}
+	for _, modifier := range modifiers {
+		modifier(claim)
+	}
+	return claim
+}
+
+func TestNeedsDefaultStorageClass(t *testing.T) {
+	tests := []struct {
+		name     string
+		pvc      *corev1.PersistentVolumeClaim
+		expected bool
+	}{
+		{
+			name:     "pending PVC without StorageClass",
+			pvc:      pvc("test"),
+			expected: true,
+		},
+		{
+			name: "PVC with empty StorageClass",
+			pvc: pvc("test", func(c *corev1.PersistentVolumeClaim) {
+				c.Spec.StorageClassName = pointer.StringPtr("")
+			}),
+			expected: false,
+		},
+		{
+			name: "PVC with StorageClass",
+			pvc: pvc("test", func(c *corev1.PersistentVolumeClaim) {
+				c.Spec.StorageClassName = pointer.StringPtr("gp2")
+			}),
+			expected: false,
+		},
+		{
+			name: "bound PVC",
+			pvc: pvc("test", func(c *corev1.PersistentVolumeClaim) {
+				c.Spec.VolumeName = "pv"
+				c.Status.Phase = corev1.ClaimBound
+			}),
+			expected: false,
+		},
+		{
+			name: "skipped PVC",
+			pvc: pvc("test", func(c *corev1.PersistentVolumeClaim) {
+				c.Annotations = map[string]string{skipRetroactiveDefaultAnnotation: "true"}
+			}),
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := needsDefaultStorageClass(test.pvc); result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetDefaultStorageClass(t *testing.T) {
+	tests := []struct {
+		name           string
+		storageClasses []*storagev1.StorageClass
+		expected       string
+	}{
+		{
+			name:     "no StorageClass",
+			expected: "",
+		},
+		{
+			name: "single default",
+			storageClasses: []*storagev1.StorageClass{
+				storageClass("gp2", "kubernetes.io/aws-ebs", false),
+				storageClass("gp3-csi", "ebs.csi.aws.com", true),
+			},
+			expected: "gp3-csi",
+		},
+		{
+			name: "multiple defaults",
+			storageClasses: []*storagev1.StorageClass{
+				storageClass("gp2", "kubernetes.io/aws-ebs", true),
+				storageClass("gp3-csi", "ebs.csi.aws.com", true),
+			},
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := getDefaultStorageClass(test.storageClasses); result != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestRetroactiveSync(t *testing.T) {
+	const version = "4.13.0"
+	// The vendored Default FeatureSet does not know the feature gate, it's
+	// enabled only in the status.
+	featureGate := &cfgv1.FeatureGate{
+		ObjectMeta: metav1.ObjectMeta{Name: featureGateConfigName},
+		Spec: cfgv1.FeatureGateSpec{
+			FeatureGateSelection: cfgv1.FeatureGateSelection{FeatureSet: cfgv1.Default},
+		},
+	}
+	h := testharness.New(t, &csoclients.FakeTestObjects{
+		CoreObjects: []runtime.Object{
+			storageClass("gp3-csi", "ebs.csi.aws.com", true),
+			pvc("rejected"),
+			pvc("assigned"),
+			pvc("empty", func(c *corev1.PersistentVolumeClaim) {
+				c.Spec.StorageClassName = pointer.StringPtr("")
+			}),
+		},
+		OperatorObjects: []runtime.Object{testharness.NewStorage()},
+		ConfigObjects:   []runtime.Object{featureGate},
+		DynamicObjects:  []runtime.Object{testharness.NewFeatureGateStatus(version, retroactiveDefaultStorageClassFeatureGate)},
+	})
+	// A PVC that cannot be updated must not block the others.
+	h.Clients.KubeClient.(*fake.Clientset).PrependReactor("patch", "persistentvolumeclaims", func(action core.Action) (bool, runtime.Object, error) {
+		if action.(core.PatchAction).GetName() == "rejected" {
+			return true, nil, fmt.Errorf("rejected by admission webhook")
+		}
+		return false, nil, nil
+	})
+	ctrl := NewRetroactiveDefaultStorageClassController(h.Clients, h.Recorder, version)
+	h.Start()
+
+	h.Sync(ctrl)
+	// The failed PVC is reported only once.
+	h.Sync(ctrl)
+
+	for name, expected := range map[string]string{
+		"rejected": "",
+		"assigned": "gp3-csi",
+		"empty":    "",
+	} {
+		claim, err := h.Clients.KubeClient.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if sc := pointer.StringDeref(claim.Spec.StorageClassName, ""); sc != expected {
+			t.Errorf("PVC %s: expected StorageClass %q, got %q", name, expected, sc)
+		}
+	}
+	failed := 0
+	for _, event := range h.Recorder.(events.InMemoryRecorder).Events() {
+		if event.Reason == "DefaultStorageClassAssignmentFailed" {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected 1 DefaultStorageClassAssignmentFailed event, got %d", failed)
+	}
+}
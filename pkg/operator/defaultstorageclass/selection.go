@@ -0,0 +1,127 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"fmt"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	v1 "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+)
+
+const selectionControllerName = "DefaultStorageClassSelectionController"
+
+// This DefaultStorageClassSelectionController makes the StorageClass chosen
+// by the cluster admin the default one. The operator API does not have
+// a typed field for it yet, it's read from the Storage CR:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    defaultStorageClassName: gp3-csi
+//
+// The controller annotates the StorageClass as the default one and removes
+// the annotation from all other StorageClasses, including the ones created
+// by CSI driver operators, which would otherwise make themselves default
+// again. Without defaultStorageClassName it does nothing, the admin manages
+// the annotations.
+// It produces following Conditions:
+// DefaultStorageClassSelectionControllerDegraded - the StorageClass does not
+// exist or error updating StorageClasses.
+type DefaultStorageClassSelectionController struct {
+	operatorClient     v1helpers.OperatorClient
+	kubeClient         kubernetes.Interface
+	storageClassLister v1.StorageClassLister
+	eventRecorder      events.Recorder
+}
+
+func NewDefaultStorageClassSelectionController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &DefaultStorageClassSelectionController{
+		operatorClient:     clients.OperatorClient,
+		kubeClient:         clients.KubeClient,
+		storageClassLister: clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Lister(),
+		eventRecorder:      eventRecorder.WithComponentSuffix("default-storageclass-selection"),
+	}
+	return factory.New().WithSync(health.TrackSync(selectionControllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor("").Storage().V1().StorageClasses().Informer(),
+	).ToController(selectionControllerName, eventRecorder)
+}
+
+func (c *DefaultStorageClassSelectionController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("DefaultStorageClassSelectionController sync started")
+	defer klog.V(4).Infof("DefaultStorageClassSelectionController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	name := ""
+	if _, err := csoutils.GetUnsupportedConfigOverride(opSpec, "defaultStorageClassName", &name); err != nil {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+
+	storageClasses, err := c.storageClassLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	return c.selectDefault(ctx, name, storageClasses)
+}
+
+// selectDefault makes StorageClass with given name the only default one.
+func (c *DefaultStorageClassSelectionController) selectDefault(ctx context.Context, name string, storageClasses []*storagev1.StorageClass) error {
+	found := false
+	for _, sc := range storageClasses {
+		if sc.Name == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("StorageClass %s set as defaultStorageClassName in the Storage CR does not exist", name)
+	}
+
+	for _, sc := range storageClasses {
+		isDefault := sc.Name == name
+		if isDefaultStorageClass(sc) == isDefault {
+			continue
+		}
+		newSC := sc.DeepCopy()
+		if isDefault {
+			metav1.SetMetaDataAnnotation(&newSC.ObjectMeta, defaultStorageClassAnnotation, "true")
+		} else {
+			delete(newSC.Annotations, defaultStorageClassAnnotation)
+		}
+		klog.V(2).Infof("Setting %s=%t on StorageClass %s", defaultStorageClassAnnotation, isDefault, sc.Name)
+		_, err := c.kubeClient.StorageV1().StorageClasses().Update(ctx, newSC, metav1.UpdateOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update StorageClass %s: %w", sc.Name, err)
+		}
+		if isDefault {
+			c.eventRecorder.Eventf("DefaultStorageClassSelected", "StorageClass %s is the default one", sc.Name)
+		}
+	}
+	return nil
+}
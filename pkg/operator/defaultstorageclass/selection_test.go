@@ -0,0 +1,44 @@
+package defaultstorageclass
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/library-go/pkg/operator/events"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelectDefault(t *testing.T) {
+	storageClasses := []*storagev1.StorageClass{
+		storageClass("gp2", "kubernetes.io/aws-ebs", true),
+		storageClass("gp3-csi", "ebs.csi.aws.com", true),
+		storageClass("io2", "ebs.csi.aws.com", false),
+	}
+	initialObjects := &csoclients.FakeTestObjects{}
+	for _, sc := range storageClasses {
+		initialObjects.CoreObjects = append(initialObjects.CoreObjects, sc)
+	}
+	clients := csoclients.NewFakeClients(initialObjects)
+	c := &DefaultStorageClassSelectionController{
+		kubeClient:    clients.KubeClient,
+		eventRecorder: events.NewInMemoryRecorder("operator"),
+	}
+
+	if err := c.selectDefault(context.TODO(), "missing", storageClasses); err == nil {
+		t.Errorf("expected error for missing StorageClass")
+	}
+	if err := c.selectDefault(context.TODO(), "io2", storageClasses); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, name := range []string{"gp2", "gp3-csi", "io2"} {
+		sc, err := clients.KubeClient.StorageV1().StorageClasses().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if isDefaultStorageClass(sc) != (name == "io2") {
+			t.Errorf("unexpected default annotation of StorageClass %s: %v", name, sc.Annotations)
+		}
+	}
+}
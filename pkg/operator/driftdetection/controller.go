@@ -0,0 +1,100 @@
+package driftdetection
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "DriftDetectionController"
+
+	// driftedConditionType lists objects that differ from their expected
+	// state in audit-only mode.
+	driftedConditionType = controllerName + "Drifted"
+)
+
+// This Controller reports objects that differ from their expected state in
+// audit-only mode (see csoutils.IsAuditOnly). The drift is detected by the
+// static resource and deployment controllers, which do not revert it in
+// audit-only mode. The drifted objects are also reported as events and as
+// cluster_storage_operator_drifted_object metric.
+// It produces following Conditions:
+// DriftDetectionControllerDrifted - true when any object differs from its
+// expected state.
+// DriftDetectionControllerDegraded - invalid audit-only configuration.
+type Controller struct {
+	operatorClient v1helpers.OperatorClient
+	eventRecorder  events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration) factory.Controller {
+	c := &Controller{
+		operatorClient: clients.OperatorClient,
+		eventRecorder:  eventRecorder,
+	}
+	// The drift is reported by other controllers, there is no informer for
+	// it.
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).ResyncEvery(resyncInterval).WithInformers(
+		clients.OperatorClient.Informer(),
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("DriftDetectionController sync started")
+	defer klog.V(4).Infof("DriftDetectionController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		// Will set DriftDetectionControllerDegraded = true
+		return err
+	}
+
+	drifted := operatorapi.OperatorCondition{
+		Type:   driftedConditionType,
+		Status: operatorapi.ConditionFalse,
+	}
+	if !auditOnly {
+		// The controllers revert all drift.
+		csoutils.ResetDrift()
+		drifted.Reason = "AuditOnlyDisabled"
+	} else if objs := csoutils.GetDriftedObjects(); len(objs) > 0 {
+		var names []string
+		for _, obj := range objs {
+			names = append(names, obj.String())
+		}
+		drifted.Status = operatorapi.ConditionTrue
+		drifted.Reason = "ObjectsDrifted"
+		drifted.Message = fmt.Sprintf("Objects differ from their expected state and are not reverted in audit-only mode: %s", strings.Join(names, ", "))
+	} else {
+		drifted.Reason = "AsExpected"
+	}
+
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient,
+		v1helpers.UpdateConditionFn(drifted),
+	); err != nil {
+		return err
+	}
+	return nil
+}
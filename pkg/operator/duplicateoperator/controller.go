@@ -0,0 +1,228 @@
+package duplicateoperator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "DuplicateOperatorController"
+	degradedType   = controllerName + "Degraded"
+
+	// leaderLockName is name of the leader election ConfigMap of CSO, see
+	// controllercmd.ControllerCommandConfig.
+	leaderLockName = "cluster-storage-operator-lock"
+
+	// conflictWindow is how long ago another field manager must have
+	// updated spec of a Deployment owned by CSO to be reported. Older
+	// updates were already reverted by CSO.
+	conflictWindow = 10 * time.Minute
+)
+
+// This Controller detects another controller that reconciles objects
+// owned by CSO, e.g. a second CSO instance started outside of the
+// cluster, which would make the objects flap between two versions. It
+// reports the leader election lock of CSO held by another host than this
+// one and Deployments owned by CSO whose spec was recently updated by
+// another field manager than CSO. Changes made by kubectl are not
+// reported, they're reverted by CSO.
+// It produces following Conditions:
+// DuplicateOperatorControllerDegraded - another controller reconciles
+// objects owned by CSO.
+type Controller struct {
+	operatorClient    v1helpers.OperatorClient
+	configMapLister   corelisters.ConfigMapLister
+	deploymentListers []appslisters.DeploymentLister
+	eventRecorder     events.Recorder
+	// hostname is the host prefix of the leader election identity of this
+	// CSO instance.
+	hostname string
+	// fieldManager is the field manager of CSO, i.e. the default user
+	// agent prefix.
+	fieldManager string
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration) factory.Controller {
+	hostname, err := os.Hostname()
+	if err != nil {
+		klog.Warningf("Failed to get hostname, leader election lock is not checked: %s", err)
+	}
+	c := &Controller{
+		operatorClient:  clients.OperatorClient,
+		configMapLister: clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		deploymentListers: []appslisters.DeploymentLister{
+			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Apps().V1().Deployments().Lister(),
+			clients.KubeInformers.InformersFor(csoclients.CSIOperatorNamespace).Apps().V1().Deployments().Lister(),
+		},
+		eventRecorder: eventRecorder,
+		hostname:      hostname,
+		fieldManager:  filepath.Base(os.Args[0]),
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).ResyncEvery(resyncInterval).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Apps().V1().Deployments().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.CSIOperatorNamespace).Apps().V1().Deployments().Informer(),
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("DuplicateOperatorController sync started")
+	defer klog.V(4).Infof("DuplicateOperatorController sync finished")
+
+	opSpec, opStatus, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	degraded := operatorapi.OperatorCondition{
+		Type:   degradedType,
+		Status: operatorapi.ConditionFalse,
+		Reason: "AsExpected",
+	}
+
+	holder, err := c.getConflictingLeader()
+	if err != nil {
+		return err
+	}
+	managers, err := c.getConflictingFieldManagers(time.Now())
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case holder != "":
+		degraded.Status = operatorapi.ConditionTrue
+		degraded.Reason = "ConflictingLeader"
+		degraded.Message = fmt.Sprintf("Leader election lock %s/%s is held by %q while this operator runs on %q, another instance of the operator may be running", csoclients.OperatorNamespace, leaderLockName, holder, c.hostname)
+	case len(managers) > 0:
+		degraded.Status = operatorapi.ConditionTrue
+		degraded.Reason = "ConflictingFieldManager"
+		degraded.Message = fmt.Sprintf("Deployments owned by the operator were recently updated by another controller: %s", strings.Join(managers, ", "))
+	}
+	if degraded.Status == operatorapi.ConditionTrue && !v1helpers.IsOperatorConditionTrue(opStatus.Conditions, degradedType) {
+		c.eventRecorder.Warningf(degraded.Reason, degraded.Message)
+	}
+
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient,
+		v1helpers.UpdateConditionFn(degraded),
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// getConflictingLeader returns identity of the current holder of the leader
+// election lock, when it's another host than this one.
+func (c *Controller) getConflictingLeader() (string, error) {
+	if c.hostname == "" {
+		return "", nil
+	}
+	cm, err := c.configMapLister.ConfigMaps(csoclients.OperatorNamespace).Get(leaderLockName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Leader election is disabled.
+			return "", nil
+		}
+		return "", err
+	}
+	recordJSON := cm.Annotations[resourcelock.LeaderElectionRecordAnnotationKey]
+	if recordJSON == "" {
+		return "", nil
+	}
+	record := resourcelock.LeaderElectionRecord{}
+	if err := json.Unmarshal([]byte(recordJSON), &record); err != nil {
+		return "", fmt.Errorf("failed to parse leader election record of ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+	return conflictingLeader(record, c.hostname, time.Now()), nil
+}
+
+// conflictingLeader returns identity of the holder of the record when it's
+// not expired and it's held by another host than hostname. The identity is
+// "<hostname>_<uuid>", see leaderelection.ToConfigMapLeaderElection.
+func conflictingLeader(record resourcelock.LeaderElectionRecord, hostname string, now time.Time) string {
+	if record.HolderIdentity == "" {
+		return ""
+	}
+	expiration := record.RenewTime.Add(time.Duration(record.LeaseDurationSeconds) * time.Second)
+	if now.After(expiration) {
+		return ""
+	}
+	if strings.HasPrefix(record.HolderIdentity, hostname+"_") {
+		return ""
+	}
+	return record.HolderIdentity
+}
+
+// getConflictingFieldManagers returns "<field manager> (<Deployment>)" of
+// recent spec updates of Deployments owned by CSO by other field managers.
+func (c *Controller) getConflictingFieldManagers(now time.Time) ([]string, error) {
+	selector, err := labels.Parse(csoutils.OwnedBySelector)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []string
+	for _, lister := range c.deploymentListers {
+		deployments, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, deployment := range deployments {
+			for _, manager := range conflictingFieldManagers(deployment, c.fieldManager, now) {
+				conflicts = append(conflicts, fmt.Sprintf("%s (Deployment %s/%s)", manager, deployment.Namespace, deployment.Name))
+			}
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// conflictingFieldManagers returns field managers other than ownManager and
+// kubectl that updated spec of the Deployment within conflictWindow.
+func conflictingFieldManagers(deployment *appsv1.Deployment, ownManager string, now time.Time) []string {
+	var managers []string
+	for _, entry := range deployment.ManagedFields {
+		if entry.Operation != metav1.ManagedFieldsOperationUpdate && entry.Operation != metav1.ManagedFieldsOperationApply {
+			continue
+		}
+		if entry.Manager == ownManager || strings.HasPrefix(entry.Manager, "kubectl") {
+			continue
+		}
+		if entry.Time == nil || now.Sub(entry.Time.Time) > conflictWindow {
+			continue
+		}
+		if entry.FieldsV1 == nil || !strings.Contains(string(entry.FieldsV1.Raw), `"f:spec"`) {
+			continue
+		}
+		managers = append(managers, entry.Manager)
+	}
+	return managers
+}
@@ -0,0 +1,77 @@
+package duplicateoperator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+func TestConflictingLeader(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		holder   string
+		renewed  time.Time
+		expected string
+	}{
+		{
+			name:    "this host",
+			holder:  "cso-abcd_1234",
+			renewed: now,
+		},
+		{
+			name:     "another host",
+			holder:   "laptop_5678",
+			renewed:  now,
+			expected: "laptop_5678",
+		},
+		{
+			name:    "expired",
+			holder:  "laptop_5678",
+			renewed: now.Add(-time.Hour),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record := resourcelock.LeaderElectionRecord{
+				HolderIdentity:       test.holder,
+				LeaseDurationSeconds: 137,
+				RenewTime:            metav1.NewTime(test.renewed),
+			}
+			if holder := conflictingLeader(record, "cso-abcd", now); holder != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, holder)
+			}
+		})
+	}
+}
+
+func TestConflictingFieldManagers(t *testing.T) {
+	now := time.Now()
+	recent := metav1.NewTime(now.Add(-time.Minute))
+	old := metav1.NewTime(now.Add(-time.Hour))
+	spec := &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)}
+	status := &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:replicas":{}}}`)}
+	entry := func(manager string, t *metav1.Time, fields *metav1.FieldsV1) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: t, FieldsV1: fields}
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				entry("cluster-storage-operator", &recent, spec),
+				entry("kubectl-edit", &recent, spec),
+				entry("kube-controller-manager", &recent, status),
+				entry("old-operator", &old, spec),
+				entry("other-operator", &recent, spec),
+			},
+		},
+	}
+	managers := conflictingFieldManagers(deployment, "cluster-storage-operator", now)
+	expected := []string{"other-operator"}
+	if !reflect.DeepEqual(managers, expected) {
+		t.Errorf("expected %v, got %v", expected, managers)
+	}
+}
@@ -0,0 +1,87 @@
+package forceresync
+
+import (
+	"context"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "ForceResyncController"
+
+	// Annotation of the Storage CR that requests full re-sync of all objects
+	// managed by CSO. Any new value requests a new re-sync, e.g.:
+	// oc annotate storage cluster storage.openshift.io/force-resync="$(date)" --overwrite
+	forceResyncAnnotation = "storage.openshift.io/force-resync"
+	// Annotation of the Storage CR with the value of forceResyncAnnotation
+	// that was already handled.
+	forceResyncCompletedAnnotation = "storage.openshift.io/force-resync-completed"
+)
+
+// This Controller forces full re-sync of all objects managed by CSO when
+// forceResyncAnnotation of the Storage CR changes. It's a recovery tool for
+// objects that were manually mangled.
+// It forgets generations of all Deployments and DaemonSets applied by CSO, so
+// they're applied again even when their generation did not change. The
+// change of the Storage CR then triggers sync of all static resource, CR
+// and Deployment controllers, which re-apply their objects.
+// It produces following Conditions:
+// ForceResyncControllerDegraded - error forcing the re-sync.
+type Controller struct {
+	operatorClient *operatorclient.OperatorClient
+	eventRecorder  events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient: clients.OperatorClient,
+		eventRecorder:  eventRecorder,
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("ForceResyncController sync started")
+	defer klog.V(4).Infof("ForceResyncController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	meta, err := c.operatorClient.GetObjectMeta()
+	if err != nil {
+		return err
+	}
+	requested := meta.Annotations[forceResyncAnnotation]
+	if requested == "" || requested == meta.Annotations[forceResyncCompletedAnnotation] {
+		return nil
+	}
+
+	klog.V(2).Infof("Forcing re-sync of all objects, requested by %s=%s", forceResyncAnnotation, requested)
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient, func(status *operatorapi.OperatorStatus) error {
+		status.Generations = nil
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := c.operatorClient.SetObjectAnnotations(map[string]string{forceResyncCompletedAnnotation: requested}); err != nil {
+		return err
+	}
+	c.eventRecorder.Eventf("ForceResync", "Forced re-sync of all objects managed by the operator")
+	return nil
+}
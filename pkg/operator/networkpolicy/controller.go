@@ -0,0 +1,240 @@
+package networkpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName    = "NetworkPolicyController"
+	ownerComponent    = "network-policy"
+	networkConfigName = "cluster"
+
+	cloudEgressAsset = "networkpolicies/05_allow_egress_cloud.yaml"
+
+	// Namespace of the Manila CSI driver, it exists only when the driver
+	// runs.
+	manilaNamespace = "openshift-manila-csi-driver"
+)
+
+var networkPolicyAssets = []string{
+	"networkpolicies/01_default_deny.yaml",
+	"networkpolicies/02_allow_egress_api_server.yaml",
+	"networkpolicies/03_allow_egress_dns.yaml",
+	"networkpolicies/04_allow_ingress_metrics.yaml",
+	cloudEgressAsset,
+	"networkpolicies/06_allow_ingress_host_network.yaml",
+	"networkpolicies/07_allow_same_namespace.yaml",
+}
+
+// Namespaces of CSO, CSI driver operators and their operands. The assets
+// are applied to each of them that exists.
+var targetNamespaces = []string{
+	csoclients.OperatorNamespace,
+	csoclients.CSIOperatorNamespace,
+	manilaNamespace,
+}
+
+// This Controller creates and reconciles NetworkPolicies in namespaces of
+// CSO, CSI driver operators and their operands. All traffic is denied
+// except:
+// - Egress to the API server and DNS.
+// - Egress to cloud endpoints, i.e. outside of the cluster and service networks.
+// - Ingress from the monitoring stack (metrics scraping).
+// - Ingress from host network to webhook ports.
+// - Traffic between pods in the same namespace.
+// In audit-only mode (see csoutils.IsAuditOnly) drift of the NetworkPolicies
+// is only reported.
+// It produces following Conditions:
+// NetworkPolicyControllerDegraded - error applying NetworkPolicies.
+type Controller struct {
+	operatorClient v1helpers.OperatorClient
+	kubeClient     kubernetes.Interface
+	networkLister  openshiftv1.NetworkLister
+	nsLister       corelisters.NamespaceLister
+	eventRecorder  events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	resyncInterval time.Duration) factory.Controller {
+	c := &Controller{
+		operatorClient: clients.OperatorClient,
+		kubeClient:     clients.KubeClient,
+		networkLister:  clients.ConfigInformers.Config().V1().Networks().Lister(),
+		nsLister:       clients.KubeInformers.InformersFor("").Core().V1().Namespaces().Lister(),
+		eventRecorder:  eventRecorder.WithComponentSuffix("network-policy"),
+	}
+	// CSO does not run informers in the Manila namespace, its NetworkPolicies
+	// are reconciled on resync.
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).ResyncEvery(resyncInterval).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().Networks().Informer(),
+		clients.KubeInformers.InformersFor("").Core().V1().Namespaces().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Networking().V1().NetworkPolicies().Informer(),
+		clients.KubeInformers.InformersFor(csoclients.CSIOperatorNamespace).Networking().V1().NetworkPolicies().Informer(),
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("NetworkPolicyController sync started")
+	defer klog.V(4).Infof("NetworkPolicyController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	network, err := c.networkLister.Get(networkConfigName)
+	if err != nil {
+		return err
+	}
+	var clusterCIDRs []string
+	for _, entry := range network.Status.ClusterNetwork {
+		clusterCIDRs = append(clusterCIDRs, entry.CIDR)
+	}
+	clusterCIDRs = append(clusterCIDRs, network.Status.ServiceNetwork...)
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range targetNamespaces {
+		_, err := c.nsLister.Get(ns)
+		if apierrors.IsNotFound(err) {
+			klog.V(4).Infof("Namespace %s does not exist, skipping its NetworkPolicies", ns)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, asset := range networkPolicyAssets {
+			required, err := readNetworkPolicy(asset)
+			if err != nil {
+				return err
+			}
+			required.Namespace = ns
+			if asset == cloudEgressAsset {
+				if err := excludeCIDRs(required, clusterCIDRs); err != nil {
+					return err
+				}
+			}
+			csoutils.SetOwnedByLabel(required, ownerComponent)
+			if err := c.applyNetworkPolicy(ctx, required, auditOnly); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// excludeCIDRs adds given CIDRs to the except list of ipBlocks of the same
+// IP family.
+func excludeCIDRs(policy *networkingv1.NetworkPolicy, cidrs []string) error {
+	for i := range policy.Spec.Egress {
+		for j := range policy.Spec.Egress[i].To {
+			ipBlock := policy.Spec.Egress[i].To[j].IPBlock
+			if ipBlock == nil {
+				continue
+			}
+			blockIP, _, err := net.ParseCIDR(ipBlock.CIDR)
+			if err != nil {
+				return err
+			}
+			for _, cidr := range cidrs {
+				ip, _, err := net.ParseCIDR(cidr)
+				if err != nil {
+					return fmt.Errorf("failed to parse cluster network %q: %w", cidr, err)
+				}
+				if (ip.To4() == nil) == (blockIP.To4() == nil) {
+					ipBlock.Except = append(ipBlock.Except, cidr)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Controller) applyNetworkPolicy(ctx context.Context, required *networkingv1.NetworkPolicy, auditOnly bool) error {
+	client := c.kubeClient.NetworkingV1().NetworkPolicies(required.Namespace)
+	existing, err := client.Get(ctx, required.Name, metav1.GetOptions{})
+	if auditOnly {
+		if apierrors.IsNotFound(err) {
+			return csoutils.ReportObjectDrift(c.eventRecorder, "NetworkPolicy", required, nil)
+		}
+		if err != nil {
+			return err
+		}
+		return csoutils.ReportObjectDrift(c.eventRecorder, "NetworkPolicy", required, existing)
+	}
+	if apierrors.IsNotFound(err) {
+		_, err := client.Create(ctx, required, metav1.CreateOptions{})
+		if err != nil {
+			c.eventRecorder.Warningf("NetworkPolicyCreateFailed", "Failed to create NetworkPolicy %s/%s: %v", required.Namespace, required.Name, err)
+			return err
+		}
+		c.eventRecorder.Eventf("NetworkPolicyCreated", "Created NetworkPolicy %s/%s", required.Namespace, required.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	modified := resourcemerge.BoolPtr(false)
+	existingCopy := existing.DeepCopy()
+	resourcemerge.EnsureObjectMeta(modified, &existingCopy.ObjectMeta, required.ObjectMeta)
+	if !*modified && equality.Semantic.DeepEqual(existingCopy.Spec, required.Spec) {
+		return nil
+	}
+
+	existingCopy.Spec = required.Spec
+	klog.V(2).Infof("Updating NetworkPolicy %s/%s", required.Namespace, required.Name)
+	if _, err := client.Update(ctx, existingCopy, metav1.UpdateOptions{}); err != nil {
+		c.eventRecorder.Warningf("NetworkPolicyUpdateFailed", "Failed to update NetworkPolicy %s/%s: %v", required.Namespace, required.Name, err)
+		return err
+	}
+	c.eventRecorder.Eventf("NetworkPolicyUpdated", "Updated NetworkPolicy %s/%s", required.Namespace, required.Name)
+	return nil
+}
+
+func readNetworkPolicy(asset string) (*networkingv1.NetworkPolicy, error) {
+	assetBytes, err := assets.ReadFile(asset)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := runtime.Decode(scheme.Codecs.UniversalDecoder(networkingv1.SchemeGroupVersion), assetBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", asset, err)
+	}
+	policy, ok := obj.(*networkingv1.NetworkPolicy)
+	if !ok {
+		return nil, fmt.Errorf("asset %s is not a NetworkPolicy: %T", asset, obj)
+	}
+	return policy, nil
+}
@@ -0,0 +1,123 @@
+package networkpolicy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/testharness"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const hostNetworkLabel = "policy-group.network.openshift.io/host-network"
+
+func TestExcludeCIDRs(t *testing.T) {
+	policy, err := readNetworkPolicy(cloudEgressAsset)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = excludeCIDRs(policy, []string{"10.128.0.0/14", "fd01::/48", "172.30.0.0/16"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	to := policy.Spec.Egress[0].To
+	if diff := cmp.Diff([]string{"10.128.0.0/14", "172.30.0.0/16"}, to[0].IPBlock.Except); diff != "" {
+		t.Errorf("unexpected IPv4 except list: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"fd01::/48"}, to[1].IPBlock.Except); diff != "" {
+		t.Errorf("unexpected IPv6 except list: %s", diff)
+	}
+}
+
+func TestReadNetworkPolicies(t *testing.T) {
+	for _, asset := range networkPolicyAssets {
+		if _, err := readNetworkPolicy(asset); err != nil {
+			t.Errorf("failed to read %s: %s", asset, err)
+		}
+	}
+}
+
+func newNamespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func newNetwork() *configv1.Network {
+	return &configv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: networkConfigName},
+		Status: configv1.NetworkStatus{
+			ClusterNetwork: []configv1.ClusterNetworkEntry{{CIDR: "10.128.0.0/14"}},
+			ServiceNetwork: []string{"172.30.0.0/16"},
+		},
+	}
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name               string
+		namespaces         []string
+		expectedNamespaces []string
+		skippedNamespaces  []string
+	}{
+		{
+			name:               "without Manila",
+			namespaces:         []string{csoclients.OperatorNamespace, csoclients.CSIOperatorNamespace},
+			expectedNamespaces: []string{csoclients.OperatorNamespace, csoclients.CSIOperatorNamespace},
+			skippedNamespaces:  []string{manilaNamespace},
+		},
+		{
+			name:               "with Manila",
+			namespaces:         []string{csoclients.OperatorNamespace, csoclients.CSIOperatorNamespace, manilaNamespace},
+			expectedNamespaces: []string{csoclients.OperatorNamespace, csoclients.CSIOperatorNamespace, manilaNamespace},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var kubeObjects []runtime.Object
+			for _, ns := range test.namespaces {
+				kubeObjects = append(kubeObjects, newNamespace(ns))
+			}
+			h := testharness.New(t, &csoclients.FakeTestObjects{
+				CoreObjects:     kubeObjects,
+				OperatorObjects: []runtime.Object{testharness.NewStorage()},
+				ConfigObjects:   []runtime.Object{newNetwork()},
+			})
+			ctrl := NewController(h.Clients, h.Recorder, time.Minute)
+			h.Start()
+			h.Sync(ctrl)
+
+			for _, ns := range test.expectedNamespaces {
+				policies, err := h.Clients.KubeClient.NetworkingV1().NetworkPolicies(ns).List(context.TODO(), metav1.ListOptions{})
+				if err != nil {
+					t.Fatalf("failed to list NetworkPolicies: %s", err)
+				}
+				if len(policies.Items) != len(networkPolicyAssets) {
+					t.Errorf("expected %d NetworkPolicies in %s, got %d", len(networkPolicyAssets), ns, len(policies.Items))
+				}
+				hostNetwork, err := h.Clients.KubeClient.NetworkingV1().NetworkPolicies(ns).Get(context.TODO(), "allow-ingress-from-host-network", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("failed to get host network NetworkPolicy in %s: %s", ns, err)
+				}
+				selector := hostNetwork.Spec.Ingress[0].From[0].NamespaceSelector
+				if _, found := selector.MatchLabels[hostNetworkLabel]; !found {
+					t.Errorf("expected host network policy in %s to select namespaces with %s, got %+v", ns, hostNetworkLabel, selector)
+				}
+			}
+			for _, ns := range test.skippedNamespaces {
+				policies, err := h.Clients.KubeClient.NetworkingV1().NetworkPolicies(ns).List(context.TODO(), metav1.ListOptions{})
+				if err != nil {
+					t.Fatalf("failed to list NetworkPolicies: %s", err)
+				}
+				if len(policies.Items) != 0 {
+					t.Errorf("expected no NetworkPolicies in missing namespace %s, got %d", ns, len(policies.Items))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package pausedresources
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"k8s.io/klog/v2"
+)
+
+const controllerName = "PausedResourcesController"
+
+// This Controller reports objects whose reconciliation is paused in the
+// Storage CR (see csoutils.GetPausedResources) and blocks upgrades while any
+// of them is paused. The objects themselves are skipped by the controllers
+// that apply them.
+// It produces following Conditions:
+// PausedResourcesControllerUpgradeable - false when any object is paused.
+// PausedResourcesControllerDegraded - invalid list of paused objects.
+type Controller struct {
+	operatorClient v1helpers.OperatorClient
+	eventRecorder  events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient: clients.OperatorClient,
+		eventRecorder:  eventRecorder,
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("PausedResourcesController sync started")
+	defer klog.V(4).Infof("PausedResourcesController sync finished")
+
+	opSpec, opStatus, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	resources, err := csoutils.GetPausedResources(opSpec)
+	if err != nil {
+		// Will set PausedResourcesControllerDegraded = true
+		return err
+	}
+
+	upgradeable := operatorapi.OperatorCondition{
+		Type:   controllerName + operatorapi.OperatorStatusTypeUpgradeable,
+		Status: operatorapi.ConditionTrue,
+	}
+	if len(resources) > 0 {
+		var names []string
+		for _, r := range resources {
+			names = append(names, r.String())
+		}
+		upgradeable.Status = operatorapi.ConditionFalse
+		upgradeable.Reason = "ReconciliationPaused"
+		upgradeable.Message = fmt.Sprintf("Reconciliation of %s is paused in unsupportedConfigOverrides.pausedResources, remove them to allow upgrades", strings.Join(names, ", "))
+		if !v1helpers.IsOperatorConditionFalse(opStatus.Conditions, upgradeable.Type) {
+			c.eventRecorder.Warningf("ReconciliationPaused", "Reconciliation of %s is paused, upgrades are blocked", strings.Join(names, ", "))
+		}
+	}
+
+	if _, _, err := v1helpers.UpdateStatus(c.operatorClient,
+		v1helpers.UpdateConditionFn(upgradeable),
+	); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package problemdetector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	awsEBSDriverName = "ebs.csi.aws.com"
+
+	// Problems are reported when the usage of a limit reaches the threshold,
+	// before provisioning or attaching of new volumes starts failing.
+	awsEBSVolumeCountThreshold = 0.8
+	awsEBSAttachThreshold      = 0.9
+
+	awsCheckInterval = 15 * time.Minute
+
+	// CredentialsRequest of the AWS EBS CSI driver operator.
+	awsEBSCredentialsRequestNamespace = "openshift-cloud-credential-operator"
+	awsEBSCredentialsRequestName      = "aws-ebs-csi-driver-operator"
+)
+
+var credentialsRequestResource = schema.GroupVersionResource{
+	Group:    "cloudcredential.openshift.io",
+	Version:  "v1",
+	Resource: "credentialsrequests",
+}
+
+var awsChecks = []Check{
+	{
+		Name:     "AWSEBSVolumeCount",
+		Interval: awsCheckInterval,
+		Run:      checkAWSEBSVolumeCount,
+	},
+	{
+		Name:     "AWSEBSAttachLimit",
+		Interval: awsCheckInterval,
+		Run:      checkAWSEBSAttachLimit,
+	},
+	{
+		Name:     "AWSIAMPolicy",
+		Interval: awsCheckInterval,
+		Run:      checkAWSIAMPolicy,
+	},
+}
+
+// checkAWSEBSVolumeCount reports when the number of EBS volumes of the cluster
+// gets close to Config.AWSEBSVolumeLimit. Volumes of other clusters in the
+// same account are not counted.
+func checkAWSEBSVolumeCount(ctx context.Context, checkCtx *CheckContext) ([]string, error) {
+	limit := checkCtx.Config.AWSEBSVolumeLimit
+	if limit == 0 {
+		return nil, nil
+	}
+	pvs, err := checkCtx.KubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	count := countAWSEBSVolumes(pvs.Items)
+	if float64(count) < awsEBSVolumeCountThreshold*float64(limit) {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("The cluster uses %d EBS volumes, the limit of the AWS account is %d", count, limit)}, nil
+}
+
+func countAWSEBSVolumes(pvs []corev1.PersistentVolume) int {
+	count := 0
+	for _, pv := range pvs {
+		if pv.Spec.AWSElasticBlockStore != nil || (pv.Spec.CSI != nil && pv.Spec.CSI.Driver == awsEBSDriverName) {
+			count++
+		}
+	}
+	return count
+}
+
+// checkAWSEBSAttachLimit reports nodes that have almost all EBS volumes they
+// can attach already attached. The limit depends on the instance type, it's
+// reported by the CSI driver in CSINode.
+func checkAWSEBSAttachLimit(ctx context.Context, checkCtx *CheckContext) ([]string, error) {
+	csiNodes, err := checkCtx.KubeClient.StorageV1().CSINodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := checkCtx.KubeClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := checkCtx.KubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return getAWSEBSAttachProblems(csiNodes.Items, attachments.Items, nodes.Items), nil
+}
+
+func getAWSEBSAttachProblems(csiNodes []storagev1.CSINode, attachments []storagev1.VolumeAttachment, nodes []corev1.Node) []string {
+	attached := map[string]int{}
+	for _, va := range attachments {
+		if va.Spec.Attacher == awsEBSDriverName {
+			attached[va.Spec.NodeName]++
+		}
+	}
+	instanceTypes := map[string]string{}
+	for _, node := range nodes {
+		instanceTypes[node.Name] = node.Labels[corev1.LabelInstanceTypeStable]
+	}
+
+	var problems []string
+	for _, csiNode := range csiNodes {
+		for _, driver := range csiNode.Spec.Drivers {
+			if driver.Name != awsEBSDriverName || driver.Allocatable == nil || driver.Allocatable.Count == nil {
+				continue
+			}
+			limit := int(*driver.Allocatable.Count)
+			if count := attached[csiNode.Name]; limit > 0 && float64(count) >= awsEBSAttachThreshold*float64(limit) {
+				problems = append(problems, fmt.Sprintf("Node %s (%s) has %d EBS volumes attached, it can attach %d", csiNode.Name, instanceTypes[csiNode.Name], count, limit))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// checkAWSIAMPolicy reports when the cloud credential operator can't keep IAM
+// credentials of the AWS EBS CSI driver in sync with its CredentialsRequest,
+// e.g. the IAM user was changed outside of OpenShift or the credentials of
+// the cluster lack permissions to fix it.
+func checkAWSIAMPolicy(ctx context.Context, checkCtx *CheckContext) ([]string, error) {
+	cr, err := checkCtx.DynamicClient.Resource(credentialsRequestResource).Namespace(awsEBSCredentialsRequestNamespace).Get(ctx, awsEBSCredentialsRequestName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return getCredentialsRequestProblems(cr)
+}
+
+func getCredentialsRequestProblems(cr *unstructured.Unstructured) ([]string, error) {
+	conditions, _, err := unstructured.NestedSlice(cr.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, c := range conditions {
+		cnd, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cndType, _, _ := unstructured.NestedString(cnd, "type")
+		status, _, _ := unstructured.NestedString(cnd, "status")
+		message, _, _ := unstructured.NestedString(cnd, "message")
+		switch cndType {
+		case "CredentialsProvisionFailure", "InsufficientCloudCreds":
+			if status == string(metav1.ConditionTrue) {
+				problems = append(problems, fmt.Sprintf("CredentialsRequest %s is %s: %s", cr.GetName(), cndType, message))
+			}
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,48 @@
+package problemdetector
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetAWSEBSAttachProblems(t *testing.T) {
+	csiNodes := []storagev1.CSINode{
+		csiNode("node-1", 10),
+		csiNode("node-2", 10),
+	}
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{corev1.LabelInstanceTypeStable: "m5.large"}}},
+	}
+	var attachments []storagev1.VolumeAttachment
+	for i := 0; i < 9; i++ {
+		attachments = append(attachments, volumeAttachment(awsEBSDriverName, "node-1"))
+		attachments = append(attachments, volumeAttachment("other.csi.example.com", "node-2"))
+	}
+
+	expected := []string{"Node node-1 (m5.large) has 9 EBS volumes attached, it can attach 10"}
+	if problems := getAWSEBSAttachProblems(csiNodes, attachments, nodes); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected problems %v, got %v", expected, problems)
+	}
+}
+
+func csiNode(name string, count int32) storagev1.CSINode {
+	return storagev1.CSINode{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: storagev1.CSINodeSpec{
+			Drivers: []storagev1.CSINodeDriver{{
+				Name:        awsEBSDriverName,
+				Allocatable: &storagev1.VolumeNodeResources{Count: &count},
+			}},
+		},
+	}
+}
+
+func volumeAttachment(attacher, nodeName string) storagev1.VolumeAttachment {
+	return storagev1.VolumeAttachment{
+		Spec: storagev1.VolumeAttachmentSpec{Attacher: attacher, NodeName: nodeName},
+	}
+}
@@ -0,0 +1,157 @@
+// Package problemdetector runs periodic checks of the cloud or virtualization
+// platform of the cluster that detect problems before they break storage,
+// e.g. unsupported versions or missing permissions. Checks are registered
+// per platform in platformChecks, the controller runs them on schedule and
+// reports their results in metrics and operator conditions.
+package problemdetector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const defaultCheckInterval = time.Hour
+
+// Check is a single problem check of a platform.
+type Check struct {
+	// Name of the check, used in metrics and conditions.
+	Name string
+	// Interval between runs of the check. Defaults to one hour.
+	Interval time.Duration
+	// BlocksUpgrade marks checks whose problems make the cluster not
+	// upgradeable.
+	BlocksUpgrade bool
+	// UpgradeReason is reason of the Upgradeable condition when a check that
+	// blocks upgrades finds problems. Defaults to PlatformProblemsDetected.
+	UpgradeReason string
+	// Run runs the check.
+	Run CheckFunc
+}
+
+// CheckFunc returns human readable problems found by a check. An error means
+// that the check could not run, it's not a problem of the platform.
+type CheckFunc func(ctx context.Context, checkCtx *CheckContext) ([]string, error)
+
+// CheckContext is input of checks. It's shared by all checks that run in
+// a single sync of the controller.
+type CheckContext struct {
+	Infrastructure *configv1.Infrastructure
+	KubeClient     kubernetes.Interface
+	DynamicClient  dynamic.Interface
+	Config         *Config
+	// getMetrics returns metrics exposed on the given URL.
+	getMetrics func(ctx context.Context, url string) (map[string]*dto.MetricFamily, error)
+	// metrics caches metrics by their URL, so checks that read the same
+	// metrics fetch them only once.
+	metrics map[string]map[string]*dto.MetricFamily
+}
+
+// Metrics returns metrics exposed on the given URL, e.g. by a problem
+// detector that runs as a separate operand.
+func (c *CheckContext) Metrics(ctx context.Context, url string) (map[string]*dto.MetricFamily, error) {
+	if families, found := c.metrics[url]; found {
+		return families, nil
+	}
+	families, err := c.getMetrics(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if c.metrics == nil {
+		c.metrics = map[string]map[string]*dto.MetricFamily{}
+	}
+	c.metrics[url] = families
+	return families, nil
+}
+
+// Config is configuration of the checks. The operator API does not have
+// a typed field for it yet, it's read from the Storage CR:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    problemDetector:
+//	      awsEBSVolumeLimit: 5000
+//	      vsphereCheckInterval: 8h
+//	      disabledVSphereChecks:
+//	      - CheckStorageClasses
+type Config struct {
+	// AWSEBSVolumeLimit is the number of EBS volumes the AWS account allows
+	// in the region of the cluster. AWSEBSVolumeCount check does not run
+	// without it, CSO can't read the AWS quotas.
+	AWSEBSVolumeLimit int `json:"awsEBSVolumeLimit,omitempty"`
+	// VSphereCheckInterval is interval between runs of vsphere-problem-detector
+	// checks, e.g. "8h". Its own default is used when empty. Scans of all
+	// datastores can load very large vCenters.
+	VSphereCheckInterval string `json:"vsphereCheckInterval,omitempty"`
+	// DisabledVSphereChecks are names of vsphere-problem-detector checks that
+	// do not run, e.g. "CheckStorageClasses".
+	DisabledVSphereChecks []string `json:"disabledVSphereChecks,omitempty"`
+}
+
+// minVSphereCheckInterval prevents intervals that would load vCenter more
+// than the default.
+const minVSphereCheckInterval = 10 * time.Minute
+
+// GetConfig returns Config from the Storage CR.
+func GetConfig(opSpec *operatorapi.OperatorSpec) (*Config, error) {
+	cfg := &Config{}
+	if _, err := csoutils.GetUnsupportedConfigOverride(opSpec, "problemDetector", cfg); err != nil {
+		return nil, err
+	}
+	if cfg.AWSEBSVolumeLimit < 0 {
+		return nil, fmt.Errorf("invalid unsupportedConfigOverrides.problemDetector.awsEBSVolumeLimit %d: it must not be negative", cfg.AWSEBSVolumeLimit)
+	}
+	if cfg.VSphereCheckInterval != "" {
+		interval, err := time.ParseDuration(cfg.VSphereCheckInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsupportedConfigOverrides.problemDetector.vsphereCheckInterval %q: %w", cfg.VSphereCheckInterval, err)
+		}
+		if interval < minVSphereCheckInterval {
+			return nil, fmt.Errorf("invalid unsupportedConfigOverrides.problemDetector.vsphereCheckInterval %q: it must be at least %s", cfg.VSphereCheckInterval, minVSphereCheckInterval)
+		}
+	}
+	return cfg, nil
+}
+
+// PlatformConditions are conditions of a platform that do not belong to
+// a single check, e.g. health of each vCenter of the cluster.
+type PlatformConditions struct {
+	// Prefix of types of all the conditions, after the controller name.
+	// Conditions with the prefix that Run did not return are removed.
+	Prefix string
+	// Run returns the conditions. It runs in each sync of the controller.
+	Run func(ctx context.Context, checkCtx *CheckContext) ([]operatorapi.OperatorCondition, error)
+}
+
+// platformChecks are checks of each platform. Platforms that are not listed
+// are not checked.
+var platformChecks = map[configv1.PlatformType][]Check{
+	configv1.AWSPlatformType:     awsChecks,
+	configv1.VSpherePlatformType: vSphereChecks,
+}
+
+// platformConditions are extra conditions of each platform.
+var platformConditions = map[configv1.PlatformType]PlatformConditions{
+	configv1.VSpherePlatformType: vCenterConditions,
+}
+
+func (c Check) upgradeReason() string {
+	if c.UpgradeReason == "" {
+		return defaultUpgradeReason
+	}
+	return c.UpgradeReason
+}
+
+func (c Check) interval() time.Duration {
+	if c.Interval == 0 {
+		return defaultCheckInterval
+	}
+	return c.Interval
+}
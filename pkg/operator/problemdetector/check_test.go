@@ -0,0 +1,54 @@
+package problemdetector
+
+import (
+	"testing"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGetConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+		expectErr bool
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name:      "vSphere checks",
+			overrides: `{"problemDetector": {"vsphereCheckInterval": "8h", "disabledVSphereChecks": ["CheckStorageClasses"]}}`,
+		},
+		{
+			name:      "invalid interval",
+			overrides: `{"problemDetector": {"vsphereCheckInterval": "daily"}}`,
+			expectErr: true,
+		},
+		{
+			name:      "too short interval",
+			overrides: `{"problemDetector": {"vsphereCheckInterval": "1m"}}`,
+			expectErr: true,
+		},
+		{
+			name:      "negative EBS volume limit",
+			overrides: `{"problemDetector": {"awsEBSVolumeLimit": -1}}`,
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opSpec := &operatorapi.OperatorSpec{}
+			if test.overrides != "" {
+				opSpec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(test.overrides)}
+			}
+			_, err := GetConfig(opSpec)
+			if test.expectErr && err == nil {
+				t.Errorf("expected error, got none")
+			}
+			if !test.expectErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package problemdetector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/transport"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName  = "ProblemDetectorController"
+	infraConfigName = "cluster"
+
+	problemsConditionType = "ProblemsDetected"
+
+	// defaultUpgradeReason is reason of the Upgradeable condition when
+	// checks with different reasons block upgrades.
+	defaultUpgradeReason = "PlatformProblemsDetected"
+
+	// resyncInterval is how often the controller looks for checks that
+	// are due, checks run less often.
+	resyncInterval = time.Minute
+
+	// Token and CA of the operator ServiceAccount, used to read metrics of
+	// operands secured by the service CA.
+	serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceCAFile           = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+)
+
+var (
+	problemsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cluster_storage_operator_problem_detector_problems",
+			Help: "Number of problems found by the last run of a platform problem check.",
+		},
+		[]string{"platform", "check"},
+	)
+	checkErrorsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cluster_storage_operator_problem_detector_check_errors",
+			Help: "A metric with value '1' when the last run of a platform problem check failed.",
+		},
+		[]string{"platform", "check"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(problemsMetric, checkErrorsMetric)
+}
+
+// checkResult is result of the last run of a check.
+type checkResult struct {
+	lastRun  time.Time
+	problems []string
+	err      error
+}
+
+// This Controller runs problem checks of the cluster platform, see
+// platformChecks. Each check runs in its own interval, its results are kept
+// until the next run.
+// It produces following Conditions:
+// ProblemDetectorController<check>ProblemsDetected - true when the check
+// found problems, unknown when it could not run.
+// ProblemDetectorControllerUpgradeable - false when a check that blocks
+// upgrades found problems, with reason of the check, see
+// Check.UpgradeReason.
+// ProblemDetectorControllerDegraded - invalid configuration of the checks.
+// Platforms can add their own conditions, see platformConditions.
+type Controller struct {
+	operatorClient v1helpers.OperatorClient
+	kubeClient     kubernetes.Interface
+	dynamicClient  dynamic.Interface
+	infraLister    openshiftv1.InfrastructureLister
+	eventRecorder  events.Recorder
+	checks         map[configv1.PlatformType][]Check
+	conditions     map[configv1.PlatformType]PlatformConditions
+	results        map[string]*checkResult
+	getMetrics     func(ctx context.Context, url string) (map[string]*dto.MetricFamily, error)
+
+	metricsClientOnce sync.Once
+	metricsClient     *http.Client
+	metricsClientErr  error
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient: clients.OperatorClient,
+		kubeClient:     clients.KubeClient,
+		dynamicClient:  clients.DynamicClient,
+		infraLister:    clients.InfrastructureLister(),
+		eventRecorder:  eventRecorder.WithComponentSuffix("problem-detector"),
+		checks:         platformChecks,
+		conditions:     platformConditions,
+		results:        map[string]*checkResult{},
+	}
+	c.getMetrics = c.getServiceMetrics
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
+	).ResyncEvery(resyncInterval).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("ProblemDetectorController sync started")
+	defer klog.V(4).Infof("ProblemDetectorController sync finished")
+
+	opSpec, opStatus, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+	infrastructure, err := c.infraLister.Get(infraConfigName)
+	if err != nil {
+		return err
+	}
+	var platform configv1.PlatformType
+	if infrastructure.Status.PlatformStatus != nil {
+		platform = infrastructure.Status.PlatformStatus.Type
+	}
+	checks := c.checks[platform]
+	if len(checks) == 0 {
+		return nil
+	}
+	cfg, err := GetConfig(opSpec)
+	if err != nil {
+		// This will set Degraded condition
+		return err
+	}
+
+	checkCtx := &CheckContext{
+		Infrastructure: infrastructure,
+		KubeClient:     c.kubeClient,
+		DynamicClient:  c.dynamicClient,
+		Config:         cfg,
+		getMetrics:     c.getMetrics,
+	}
+	c.runChecks(ctx, checkCtx, checks, time.Now())
+
+	var updateFuncs []v1helpers.UpdateStatusFunc
+	var blocking, blockingReasons []string
+	for _, check := range checks {
+		result := c.results[check.Name]
+		problemsMetric.WithLabelValues(string(platform), check.Name).Set(float64(len(result.problems)))
+		checkError := 0.0
+		if result.err != nil {
+			checkError = 1
+		}
+		checkErrorsMetric.WithLabelValues(string(platform), check.Name).Set(checkError)
+
+		cnd := problemsCondition(check, result)
+		if cnd.Status == operatorapi.ConditionTrue && !v1helpers.IsOperatorConditionTrue(opStatus.Conditions, cnd.Type) {
+			c.eventRecorder.Warningf("PlatformProblemsDetected", "Check %s found problems: %s", check.Name, cnd.Message)
+		}
+		updateFuncs = append(updateFuncs, v1helpers.UpdateConditionFn(cnd))
+		if check.BlocksUpgrade && len(result.problems) > 0 {
+			blocking = append(blocking, cnd.Message)
+			blockingReasons = append(blockingReasons, check.upgradeReason())
+		}
+	}
+
+	upgradeable := operatorapi.OperatorCondition{
+		Type:   controllerName + operatorapi.OperatorStatusTypeUpgradeable,
+		Status: operatorapi.ConditionTrue,
+	}
+	if len(blocking) > 0 {
+		upgradeable.Status = operatorapi.ConditionFalse
+		upgradeable.Reason = defaultUpgradeReason
+		if reasons := sets.NewString(blockingReasons...); reasons.Len() == 1 {
+			upgradeable.Reason = reasons.List()[0]
+		}
+		upgradeable.Message = strings.Join(blocking, "; ")
+	}
+	updateFuncs = append(updateFuncs, v1helpers.UpdateConditionFn(upgradeable))
+
+	if platformConds, found := c.conditions[platform]; found {
+		conditions, err := platformConds.Run(ctx, checkCtx)
+		if err != nil {
+			// Keep the last known conditions, checks report the error.
+			klog.V(2).Infof("Failed to get %s conditions: %s", platform, err)
+		} else {
+			updateFuncs = append(updateFuncs, replaceConditions(controllerName+platformConds.Prefix, conditions))
+		}
+	}
+
+	_, _, err = v1helpers.UpdateStatus(c.operatorClient, updateFuncs...)
+	return err
+}
+
+// runChecks runs checks that are due. Checks that did not run yet are always
+// due.
+func (c *Controller) runChecks(ctx context.Context, checkCtx *CheckContext, checks []Check, now time.Time) {
+	for _, check := range checks {
+		result, found := c.results[check.Name]
+		if found && now.Sub(result.lastRun) < check.interval() {
+			continue
+		}
+		klog.V(4).Infof("Running platform problem check %s", check.Name)
+		problems, err := check.Run(ctx, checkCtx)
+		if err != nil {
+			klog.V(2).Infof("Platform problem check %s failed: %s", check.Name, err)
+		}
+		c.results[check.Name] = &checkResult{lastRun: now, problems: problems, err: err}
+	}
+}
+
+// replaceConditions returns UpdateStatusFunc that sets the conditions and
+// removes other conditions with the prefix.
+func replaceConditions(prefix string, conditions []operatorapi.OperatorCondition) v1helpers.UpdateStatusFunc {
+	return func(status *operatorapi.OperatorStatus) error {
+		current := sets.NewString()
+		for _, cnd := range conditions {
+			v1helpers.SetOperatorCondition(&status.Conditions, cnd)
+			current.Insert(cnd.Type)
+		}
+		var stale []string
+		for _, cnd := range status.Conditions {
+			if strings.HasPrefix(cnd.Type, prefix) && !current.Has(cnd.Type) {
+				stale = append(stale, cnd.Type)
+			}
+		}
+		for _, cndType := range stale {
+			v1helpers.RemoveOperatorCondition(&status.Conditions, cndType)
+		}
+		return nil
+	}
+}
+
+func problemsCondition(check Check, result *checkResult) operatorapi.OperatorCondition {
+	cnd := operatorapi.OperatorCondition{
+		Type:   controllerName + check.Name + problemsConditionType,
+		Status: operatorapi.ConditionFalse,
+		Reason: "NoProblemsDetected",
+	}
+	switch {
+	case result.err != nil:
+		cnd.Status = operatorapi.ConditionUnknown
+		cnd.Reason = "CheckFailed"
+		cnd.Message = fmt.Sprintf("Check %s failed: %s", check.Name, result.err)
+	case len(result.problems) > 0:
+		cnd.Status = operatorapi.ConditionTrue
+		cnd.Reason = "ProblemsDetected"
+		cnd.Message = strings.Join(result.problems, "; ")
+	}
+	return cnd
+}
+
+// getServiceMetrics returns metrics of an operand Service, authenticated by
+// the operator ServiceAccount token.
+func (c *Controller) getServiceMetrics(ctx context.Context, url string) (map[string]*dto.MetricFamily, error) {
+	c.metricsClientOnce.Do(func() {
+		var rt http.RoundTripper
+		rt, c.metricsClientErr = transport.New(&transport.Config{
+			BearerTokenFile: serviceAccountTokenFile,
+			TLS:             transport.TLSConfig{CAFile: serviceCAFile},
+		})
+		c.metricsClient = &http.Client{Transport: rt, Timeout: 30 * time.Second}
+	})
+	if c.metricsClientErr != nil {
+		return nil, c.metricsClientErr
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.metricsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get metrics from %s: %s", url, resp.Status)
+	}
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(resp.Body)
+}
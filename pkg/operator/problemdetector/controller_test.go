@@ -0,0 +1,30 @@
+package problemdetector
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunChecks(t *testing.T) {
+	runs := 0
+	checks := []Check{{
+		Name:     "Test",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, checkCtx *CheckContext) ([]string, error) {
+			runs++
+			return nil, nil
+		},
+	}}
+	c := &Controller{results: map[string]*checkResult{}}
+	now := time.Now()
+	c.runChecks(context.TODO(), &CheckContext{}, checks, now)
+	c.runChecks(context.TODO(), &CheckContext{}, checks, now.Add(time.Minute))
+	if runs != 1 {
+		t.Errorf("expected the check to run once within its interval, it ran %d times", runs)
+	}
+	c.runChecks(context.TODO(), &CheckContext{}, checks, now.Add(2*time.Hour))
+	if runs != 2 {
+		t.Errorf("expected the check to run again after its interval, it ran %d times", runs)
+	}
+}
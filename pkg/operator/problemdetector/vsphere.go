@@ -0,0 +1,306 @@
+package problemdetector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/blang/semver"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// vSphere checks run in vsphere-problem-detector, which has access to
+// vCenter. The checks here read its results from its metrics.
+var vSphereProblemDetectorMetricsURL = fmt.Sprintf("https://vsphere-problem-detector-metrics.%s.svc:8444/metrics", csoclients.OperatorNamespace)
+
+const (
+	// vSphereCheckInterval is shorter than the default, the checks only
+	// read metrics of vsphere-problem-detector.
+	vSphereCheckInterval = 10 * time.Minute
+
+	// Minimal versions supported by the vSphere CSI driver.
+	minVSphereHWVersion = 15
+)
+
+var minVSphereVersion = semver.MustParse("6.7.3")
+
+// Minimal versions of vCenter and ESXi supported by the vSphere CSI driver in
+// the next OpenShift release. The in-tree vSphere volume plugin is removed
+// there, upgrade of clusters with older versions would break their storage.
+// vCenter builds of minUpgradeVSphereVersion older than minUpgradeVCenterBuild
+// (7.0 Update 2 GA) miss fixes the CSI driver depends on.
+var minUpgradeVSphereVersion = semver.MustParse("7.0.2")
+
+const minUpgradeVCenterBuild = 17694817
+
+var vSphereChecks = []Check{
+	{
+		Name:     "VSphereDatastore",
+		Interval: vSphereCheckInterval,
+		Run:      vSphereClusterCheck("CheckDefaultDatastore", "CheckStorageClasses"),
+	},
+	{
+		Name:     "VSpherePermissions",
+		Interval: vSphereCheckInterval,
+		Run:      vSphereClusterCheck("CheckAccountPermissions"),
+	},
+	{
+		Name:          "VSphereHWVersion",
+		Interval:      vSphereCheckInterval,
+		BlocksUpgrade: true,
+		UpgradeReason: "VSphereOlderHWVersionDetected",
+		Run:           checkVSphereHWVersion,
+	},
+	{
+		Name:     "VSphereVersion",
+		Interval: vSphereCheckInterval,
+		Run:      checkVSphereVersion,
+	},
+	{
+		Name:          "VSphereUpgradeVersion",
+		Interval:      vSphereCheckInterval,
+		BlocksUpgrade: true,
+		UpgradeReason: "VSphereOlderVersionDetected",
+		Run:           checkVSphereUpgradeVersion,
+	},
+}
+
+// vCenterConditions report connection of vsphere-problem-detector to each
+// vCenter of clusters that span several vCenters, in
+// ProblemDetectorControllerVCenter<server>Connected conditions.
+var vCenterConditions = PlatformConditions{
+	Prefix: "VCenter",
+	Run:    getVCenterConditions,
+}
+
+// getVSphereMetrics returns metrics of vsphere-problem-detector. It returns
+// an error when vsphere-problem-detector can't connect to any vCenter, its
+// other metrics are not up to date then. Metrics of vCenters it can't
+// connect to are not up to date either, vCenterConditions report them.
+func getVSphereMetrics(ctx context.Context, checkCtx *CheckContext) (map[string]*dto.MetricFamily, error) {
+	families, err := checkCtx.Metrics(ctx, vSphereProblemDetectorMetricsURL)
+	if err != nil {
+		return nil, err
+	}
+	syncErrors := metrics(families, "vsphere_sync_errors")
+	failed := 0
+	for _, m := range syncErrors {
+		if metricValue(m) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 && failed == len(syncErrors) {
+		return nil, fmt.Errorf("vsphere-problem-detector can't connect to vCenter")
+	}
+	return families, nil
+}
+
+// getVCenterConditions returns a condition for each vCenter reported by
+// vsphere-problem-detector. vsphere-problem-detector labels its metrics with
+// vCenter server only when the cluster spans several vCenters.
+func getVCenterConditions(ctx context.Context, checkCtx *CheckContext) ([]operatorapi.OperatorCondition, error) {
+	families, err := checkCtx.Metrics(ctx, vSphereProblemDetectorMetricsURL)
+	if err != nil {
+		return nil, err
+	}
+	var conditions []operatorapi.OperatorCondition
+	for _, m := range metrics(families, "vsphere_sync_errors") {
+		server := label(m, "vcenter")
+		if server == "" {
+			continue
+		}
+		cnd := operatorapi.OperatorCondition{
+			Type:   controllerName + "VCenter" + conditionName(server) + "Connected",
+			Status: operatorapi.ConditionTrue,
+			Reason: "AsExpected",
+		}
+		if metricValue(m) > 0 {
+			cnd.Status = operatorapi.ConditionFalse
+			cnd.Reason = "SyncFailed"
+			cnd.Message = fmt.Sprintf("vsphere-problem-detector can't connect to vCenter %s", server)
+		}
+		conditions = append(conditions, cnd)
+	}
+	return conditions, nil
+}
+
+// conditionName returns server name usable in condition type, e.g.
+// "vcenter-1.example.com" -> "Vcenter1ExampleCom".
+func conditionName(server string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range server {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// vCenterPrefix returns prefix of problems found in a metric of
+// a vCenter, on clusters that span several vCenters.
+func vCenterPrefix(m *dto.Metric) string {
+	if server := label(m, "vcenter"); server != "" {
+		return fmt.Sprintf("vCenter %s: ", server)
+	}
+	return ""
+}
+
+// vSphereClusterCheck returns a CheckFunc that reports failed cluster checks
+// of vsphere-problem-detector.
+func vSphereClusterCheck(checkNames ...string) CheckFunc {
+	return func(ctx context.Context, checkCtx *CheckContext) ([]string, error) {
+		families, err := getVSphereMetrics(ctx, checkCtx)
+		if err != nil {
+			return nil, err
+		}
+		var problems []string
+		for _, m := range metrics(families, "vsphere_cluster_check_errors") {
+			name := label(m, "check")
+			for _, checkName := range checkNames {
+				if name == checkName && metricValue(m) > 0 {
+					problems = append(problems, fmt.Sprintf("%svSphere check %s failed", vCenterPrefix(m), name))
+				}
+			}
+		}
+		sort.Strings(problems)
+		return problems, nil
+	}
+}
+
+// checkVSphereHWVersion reports VMs with hardware version not supported by
+// the vSphere CSI driver.
+func checkVSphereHWVersion(ctx context.Context, checkCtx *CheckContext) ([]string, error) {
+	families, err := getVSphereMetrics(ctx, checkCtx)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, m := range metrics(families, "vsphere_node_hw_version_total") {
+		hwVersion := label(m, "hw_version")
+		var version int
+		if _, err := fmt.Sscanf(hwVersion, "vmx-%d", &version); err != nil {
+			continue
+		}
+		if count := metricValue(m); version < minVSphereHWVersion && count > 0 {
+			problems = append(problems, fmt.Sprintf("%s%d VMs have hardware version %s, vmx-%d or newer is required", vCenterPrefix(m), int(count), hwVersion, minVSphereHWVersion))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// checkVSphereVersion reports vCenter and ESXi hosts with version not
+// supported by the vSphere CSI driver.
+func checkVSphereVersion(ctx context.Context, checkCtx *CheckContext) ([]string, error) {
+	families, err := getVSphereMetrics(ctx, checkCtx)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, m := range metrics(families, "vsphere_vcenter_info") {
+		if apiVersion := label(m, "api_version"); olderThan(apiVersion, minVSphereVersion) {
+			problems = append(problems, fmt.Sprintf("%svCenter has version %s, %s or newer is required", vCenterPrefix(m), apiVersion, minVSphereVersion))
+		}
+	}
+	for _, m := range metrics(families, "vsphere_esxi_version_total") {
+		apiVersion := label(m, "api_version")
+		if count := metricValue(m); olderThan(apiVersion, minVSphereVersion) && count > 0 {
+			problems = append(problems, fmt.Sprintf("%s%d ESXi hosts have version %s, %s or newer is required", vCenterPrefix(m), int(count), apiVersion, minVSphereVersion))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// checkVSphereUpgradeVersion reports vCenter and ESXi hosts with version not
+// supported by the vSphere CSI driver in the next OpenShift release.
+func checkVSphereUpgradeVersion(ctx context.Context, checkCtx *CheckContext) ([]string, error) {
+	families, err := getVSphereMetrics(ctx, checkCtx)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, m := range metrics(families, "vsphere_vcenter_info") {
+		apiVersion := label(m, "api_version")
+		if olderThan(apiVersion, minUpgradeVSphereVersion) {
+			problems = append(problems, fmt.Sprintf("%svCenter has version %s, %s or newer is required by the next OpenShift release", vCenterPrefix(m), apiVersion, minUpgradeVSphereVersion))
+			continue
+		}
+		if build := label(m, "build"); olderBuild(apiVersion, build) {
+			problems = append(problems, fmt.Sprintf("%svCenter %s has build %s, build %d or newer is required by the next OpenShift release", vCenterPrefix(m), apiVersion, build, minUpgradeVCenterBuild))
+		}
+	}
+	for _, m := range metrics(families, "vsphere_esxi_version_total") {
+		apiVersion := label(m, "api_version")
+		if count := metricValue(m); olderThan(apiVersion, minUpgradeVSphereVersion) && count > 0 {
+			problems = append(problems, fmt.Sprintf("%s%d ESXi hosts have version %s, %s or newer is required by the next OpenShift release", vCenterPrefix(m), int(count), apiVersion, minUpgradeVSphereVersion))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// olderBuild returns true when vCenter of version minUpgradeVSphereVersion
+// has valid build older than minUpgradeVCenterBuild. Newer versions have
+// newer builds.
+func olderBuild(version, build string) bool {
+	v, err := semver.ParseTolerant(version)
+	if err != nil || !v.EQ(minUpgradeVSphereVersion) {
+		return false
+	}
+	b, err := strconv.Atoi(build)
+	if err != nil {
+		return false
+	}
+	return b < minUpgradeVCenterBuild
+}
+
+// olderThan returns true when the version is valid and older than min.
+func olderThan(version string, min semver.Version) bool {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+	return v.LT(min)
+}
+
+func metrics(families map[string]*dto.MetricFamily, name string) []*dto.Metric {
+	if family, found := families[name]; found {
+		return family.Metric
+	}
+	return nil
+}
+
+func label(m *dto.Metric, name string) string {
+	for _, l := range m.Label {
+		if strings.EqualFold(l.GetName(), name) {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Untyped != nil:
+		return m.Untyped.GetValue()
+	}
+	return 0
+}
@@ -0,0 +1,102 @@
+package problemdetector
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const testVSphereMetrics = `
+vsphere_sync_errors 0
+vsphere_cluster_check_errors{check="CheckAccountPermissions"} 1
+vsphere_cluster_check_errors{check="CheckDefaultDatastore"} 0
+vsphere_node_hw_version_total{hw_version="vmx-13"} 2
+vsphere_node_hw_version_total{hw_version="vmx-15"} 3
+vsphere_vcenter_info{api_version="7.0.3"} 1
+vsphere_esxi_version_total{api_version="6.7.0"} 1
+vsphere_esxi_version_total{api_version="7.0.2"} 4
+`
+
+func testMetricsGetter(text string) func(ctx context.Context, url string) (map[string]*dto.MetricFamily, error) {
+	return func(ctx context.Context, url string) (map[string]*dto.MetricFamily, error) {
+		var parser expfmt.TextParser
+		return parser.TextToMetricFamilies(strings.NewReader(text))
+	}
+}
+
+func TestVSphereChecks(t *testing.T) {
+	expected := map[string][]string{
+		"VSphereDatastore":   nil,
+		"VSpherePermissions": {"vSphere check CheckAccountPermissions failed"},
+		"VSphereHWVersion":   {"2 VMs have hardware version vmx-13, vmx-15 or newer is required"},
+		"VSphereVersion":     {"1 ESXi hosts have version 6.7.0, 6.7.3 or newer is required"},
+		"VSphereUpgradeVersion": {
+			"1 ESXi hosts have version 6.7.0, 7.0.2 or newer is required by the next OpenShift release",
+		},
+	}
+	checkCtx := &CheckContext{getMetrics: testMetricsGetter(testVSphereMetrics)}
+	for _, check := range vSphereChecks {
+		problems, err := check.Run(context.TODO(), checkCtx)
+		if err != nil {
+			t.Errorf("check %s: unexpected error: %s", check.Name, err)
+		}
+		if !reflect.DeepEqual(problems, expected[check.Name]) {
+			t.Errorf("check %s: expected problems %v, got %v", check.Name, expected[check.Name], problems)
+		}
+	}
+
+	checkCtx = &CheckContext{getMetrics: testMetricsGetter(`vsphere_vcenter_info{api_version="7.0.2",build="17004997"} 1` + "\n")}
+	problems, err := checkVSphereUpgradeVersion(context.TODO(), checkCtx)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	expectedProblems := []string{"vCenter 7.0.2 has build 17004997, build 17694817 or newer is required by the next OpenShift release"}
+	if !reflect.DeepEqual(problems, expectedProblems) {
+		t.Errorf("expected problems %v, got %v", expectedProblems, problems)
+	}
+
+	checkCtx = &CheckContext{getMetrics: testMetricsGetter("vsphere_sync_errors 1\n")}
+	if _, err := checkVSphereVersion(context.TODO(), checkCtx); err == nil {
+		t.Errorf("expected error when vsphere-problem-detector can't connect to vCenter")
+	}
+}
+
+func TestVCenterConditions(t *testing.T) {
+	text := `
+vsphere_sync_errors{vcenter="vcenter-1.example.com"} 0
+vsphere_sync_errors{vcenter="vcenter-2.example.com"} 1
+vsphere_vcenter_info{api_version="6.7.0",vcenter="vcenter-1.example.com"} 1
+`
+	checkCtx := &CheckContext{getMetrics: testMetricsGetter(text)}
+	conditions, err := getVCenterConditions(context.TODO(), checkCtx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]operatorapi.ConditionStatus{
+		"ProblemDetectorControllerVCenterVcenter1ExampleComConnected": operatorapi.ConditionTrue,
+		"ProblemDetectorControllerVCenterVcenter2ExampleComConnected": operatorapi.ConditionFalse,
+	}
+	if len(conditions) != len(expected) {
+		t.Errorf("expected %d conditions, got %+v", len(expected), conditions)
+	}
+	for _, cnd := range conditions {
+		if expected[cnd.Type] != cnd.Status {
+			t.Errorf("expected condition %s to be %s, got %s", cnd.Type, expected[cnd.Type], cnd.Status)
+		}
+	}
+
+	// Checks of the connected vCenter still run.
+	problems, err := checkVSphereVersion(context.TODO(), checkCtx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedProblems := []string{"vCenter vcenter-1.example.com: vCenter has version 6.7.0, 6.7.3 or newer is required"}
+	if !reflect.DeepEqual(problems, expectedProblems) {
+		t.Errorf("expected problems %v, got %v", expectedProblems, problems)
+	}
+}
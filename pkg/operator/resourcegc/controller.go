@@ -0,0 +1,263 @@
+package resourcegc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const controllerName = "ResourceGCController"
+
+// AssetSet is a set of assets applied by CSO.
+type AssetSet struct {
+	AssetFunc resourceapply.AssetFunc
+	Assets    []string
+}
+
+// AssetSetsFunc returns asset sets that change at runtime, e.g. of CSI
+// drivers loaded from a ConfigMap.
+type AssetSetsFunc func() ([]AssetSet, error)
+
+// DynamicAssetSets are asset sets read on each sync by Get from caches of
+// Informers. Nothing is deleted until the caches are synced, objects of
+// asset sets missing in an empty cache would be deleted.
+type DynamicAssetSets struct {
+	Get       AssetSetsFunc
+	Informers []factory.Informer
+}
+
+// gcResource is a kind of objects that are garbage collected.
+type gcResource struct {
+	kind   string
+	list   func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error)
+	delete func(ctx context.Context, client kubernetes.Interface, namespace, name string) error
+}
+
+var gcResources = []gcResource{
+	{
+		kind: "Deployment",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.AppsV1().Deployments("").List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+	{
+		kind: "DaemonSet",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.AppsV1().DaemonSets("").List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.AppsV1().DaemonSets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+	{
+		kind: "Service",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().Services("").List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+	{
+		kind: "ServiceAccount",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().ServiceAccounts("").List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.CoreV1().ServiceAccounts(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+	{
+		kind: "ConfigMap",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.CoreV1().ConfigMaps("").List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.CoreV1().ConfigMaps(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+	{
+		kind: "Role",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.RbacV1().Roles("").List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.RbacV1().Roles(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+	{
+		kind: "RoleBinding",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.RbacV1().RoleBindings("").List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.RbacV1().RoleBindings(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+	{
+		kind: "ClusterRole",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.RbacV1().ClusterRoles().List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.RbacV1().ClusterRoles().Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+	{
+		kind: "ClusterRoleBinding",
+		list: func(ctx context.Context, client kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+			return client.RbacV1().ClusterRoleBindings().List(ctx, opts)
+		},
+		delete: func(ctx context.Context, client kubernetes.Interface, namespace, name string) error {
+			return client.RbacV1().ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{})
+		},
+	},
+}
+
+// This Controller deletes objects of a CSO component (see
+// csoutils.SetOwnedByLabel) that are not in any of the component asset sets
+// shipped with the current CSO version, e.g. leftovers of CSI driver
+// operators restructured in a previous release. Objects without the labels
+// are never touched. In audit-only mode (see csoutils.IsAuditOnly) the
+// objects are only reported as drifted.
+// It produces following Conditions:
+// ResourceGCControllerDegraded - error reading the assets or deleting objects.
+type Controller struct {
+	operatorClient v1helpers.OperatorClient
+	kubeClient     kubernetes.Interface
+	component      string
+	assetSets      []AssetSet
+	// dynamicAssetSets are asset sets read on each sync, optional.
+	dynamicAssetSets *DynamicAssetSets
+	eventRecorder    events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder,
+	component string,
+	assetSets []AssetSet,
+	dynamicAssetSets *DynamicAssetSets,
+	resyncInterval time.Duration) factory.Controller {
+	c := &Controller{
+		operatorClient:   clients.OperatorClient,
+		kubeClient:       clients.KubeClient,
+		component:        component,
+		assetSets:        assetSets,
+		dynamicAssetSets: dynamicAssetSets,
+		eventRecorder:    eventRecorder.WithComponentSuffix("resource-gc"),
+	}
+	informers := []factory.Informer{clients.OperatorClient.Informer()}
+	if dynamicAssetSets != nil {
+		informers = append(informers, dynamicAssetSets.Informers...)
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).ResyncEvery(resyncInterval).WithInformers(
+		informers...,
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("ResourceGCController sync started")
+	defer klog.V(4).Infof("ResourceGCController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	// Delete nothing when any asset can't be read, it could delete objects
+	// that are still in use.
+	expected, err := c.expectedObjects()
+	if err != nil {
+		return err
+	}
+	auditOnly, err := csoutils.IsAuditOnly(opSpec)
+	if err != nil {
+		return err
+	}
+
+	selector := metav1.ListOptions{LabelSelector: csoutils.OwnedBySelector + "," + csoutils.ComponentLabel + "=" + c.component}
+	for _, res := range gcResources {
+		list, err := res.list(ctx, c.kubeClient, selector)
+		if err != nil {
+			return fmt.Errorf("failed to list %ss: %w", res.kind, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			obj, err := meta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			if expected[objectKey(res.kind, obj.GetNamespace(), obj.GetName())] {
+				continue
+			}
+			if auditOnly {
+				csoutils.ReportDrift(c.eventRecorder, csoutils.DriftedObject{Kind: res.kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}, true)
+				continue
+			}
+			klog.V(2).Infof("Deleting orphaned %s %s/%s", res.kind, obj.GetNamespace(), obj.GetName())
+			if err := res.delete(ctx, c.kubeClient, obj.GetNamespace(), obj.GetName()); err != nil && !apierrors.IsNotFound(err) {
+				c.eventRecorder.Warningf("OrphanedResourceDeleteFailed", "Failed to delete orphaned %s %s/%s: %v", res.kind, obj.GetNamespace(), obj.GetName(), err)
+				return err
+			}
+			c.eventRecorder.Eventf("OrphanedResourceDeleted", "Deleted orphaned %s %s/%s", res.kind, obj.GetNamespace(), obj.GetName())
+		}
+	}
+	return nil
+}
+
+// expectedObjects returns keys of all objects in the asset sets.
+func (c *Controller) expectedObjects() (map[string]bool, error) {
+	expected := map[string]bool{}
+	assetSets := c.assetSets
+	if c.dynamicAssetSets != nil {
+		for _, informer := range c.dynamicAssetSets.Informers {
+			if !informer.HasSynced() {
+				return nil, fmt.Errorf("caches of dynamic asset sets are not synced yet")
+			}
+		}
+		dynamicSets, err := c.dynamicAssetSets.Get()
+		if err != nil {
+			return nil, err
+		}
+		assetSets = append(append([]AssetSet{}, assetSets...), dynamicSets...)
+	}
+	for _, set := range assetSets {
+		for _, asset := range set.Assets {
+			obj, err := csoutils.ReadUnstructuredAsset(set.AssetFunc, asset)
+			if err != nil {
+				return nil, err
+			}
+			expected[objectKey(obj.GetKind(), obj.GetNamespace(), obj.GetName())] = true
+		}
+	}
+	return expected, nil
+}
+
+func objectKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
@@ -0,0 +1,117 @@
+package resourcegc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/testharness"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+const testComponent = "test"
+
+func clusterRole(name string, owned bool) *rbacv1.ClusterRole {
+	cr := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if owned {
+		csoutils.SetOwnedByLabel(cr, testComponent)
+	}
+	return cr
+}
+
+// unsyncedInformer is an informer whose cache never syncs.
+type unsyncedInformer struct{}
+
+func (unsyncedInformer) AddEventHandler(handler cache.ResourceEventHandler) {}
+
+func (unsyncedInformer) HasSynced() bool { return false }
+
+func TestSync(t *testing.T) {
+	const expectedName = "aws-ebs-csi-driver-operator-clusterrole"
+	tests := []struct {
+		name      string
+		auditOnly bool
+		// unsynced makes the GC wait for an unsynced cache of dynamic
+		// asset sets.
+		unsynced bool
+	}{
+		{
+			name: "orphan deleted",
+		},
+		{
+			name:      "audit-only",
+			auditOnly: true,
+		},
+		{
+			name:     "unsynced dynamic asset sets",
+			unsynced: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer csoutils.ResetDrift()
+			storage := testharness.NewStorage()
+			if test.auditOnly {
+				storage.Spec.UnsupportedConfigOverrides.Raw = []byte(`{"auditOnly":true}`)
+			}
+			h := testharness.New(t, &csoclients.FakeTestObjects{
+				CoreObjects: []runtime.Object{
+					clusterRole(expectedName, true),
+					clusterRole("orphan", true),
+					clusterRole("not-owned", false),
+				},
+				OperatorObjects: []runtime.Object{storage},
+			})
+			var dynamicAssetSets *DynamicAssetSets
+			if test.unsynced {
+				dynamicAssetSets = &DynamicAssetSets{
+					Get:       func() ([]AssetSet, error) { return nil, nil },
+					Informers: []factory.Informer{unsyncedInformer{}},
+				}
+			}
+			ctrl := NewController(h.Clients, h.Recorder, testComponent, []AssetSet{
+				{
+					AssetFunc: assets.ReadFile,
+					Assets:    []string{"csidriveroperators/aws-ebs/05_clusterrole.yaml"},
+				},
+			}, dynamicAssetSets, time.Minute)
+			h.Start()
+
+			if test.unsynced {
+				if err := h.SyncWithError(ctrl); err == nil {
+					t.Errorf("expected error with unsynced cache")
+				}
+			} else {
+				h.Sync(ctrl)
+			}
+
+			client := h.Clients.KubeClient.RbacV1().ClusterRoles()
+			for name, expectDeleted := range map[string]bool{
+				expectedName: false,
+				"orphan":     !test.auditOnly && !test.unsynced,
+				"not-owned":  false,
+			} {
+				_, err := client.Get(context.TODO(), name, metav1.GetOptions{})
+				if deleted := apierrors.IsNotFound(err); deleted != expectDeleted {
+					t.Errorf("ClusterRole %s: expected deleted=%t, got %t (err: %v)", name, expectDeleted, deleted, err)
+				}
+			}
+			expectedDrift := []csoutils.DriftedObject{}
+			if test.auditOnly {
+				expectedDrift = []csoutils.DriftedObject{{Kind: "ClusterRole", Name: "orphan"}}
+			}
+			if drift := csoutils.GetDriftedObjects(); !reflect.DeepEqual(drift, expectedDrift) {
+				t.Errorf("expected drifted objects %v, got %v", expectedDrift, drift)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	operatorapi "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
@@ -44,7 +45,7 @@ func NewController(
 		crdLister:      clients.ExtensionInformer.Apiextensions().V1().CustomResourceDefinitions().Lister(),
 		eventRecorder:  eventRecorder,
 	}
-	return factory.New().WithSync(c.sync).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+	return factory.New().WithSync(health.TrackSync("SnapshotCRDController", c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
 		clients.OperatorClient.Informer(),
 		clients.ExtensionInformer.Apiextensions().V1().CustomResourceDefinitions().Informer(),
 	).ToController("SnapshotCRDController", eventRecorder)
@@ -0,0 +1,144 @@
+package snapshotrbac
+
+import (
+	"context"
+	"fmt"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	v1 "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "SnapshotRBACController"
+	ownerComponent = "snapshot-rbac"
+
+	snapshotCRDName        = "volumesnapshots.snapshot.storage.k8s.io"
+	snapshotClassCRDName   = "volumesnapshotclasses.snapshot.storage.k8s.io"
+	snapshotContentCRDName = "volumesnapshotcontents.snapshot.storage.k8s.io"
+)
+
+var clusterRoleAssets = []string{
+	"snapshot_rbac/01_view_clusterrole.yaml",
+	"snapshot_rbac/02_edit_clusterrole.yaml",
+	"snapshot_rbac/03_admin_clusterrole.yaml",
+}
+
+// This Controller creates and reconciles ClusterRoles that are aggregated to
+// the default view, edit and admin roles and allow users to manage
+// VolumeSnapshots in their namespaces. The ClusterRoles are applied only
+// after all VolumeSnapshot CRDs are established and only when CSISnapshot
+// cluster capability is enabled.
+// It produces following Conditions:
+// SnapshotRBACControllerDegraded - error applying the ClusterRoles.
+type Controller struct {
+	operatorClient v1helpers.OperatorClient
+	kubeClient     kubernetes.Interface
+	// clusterVersionLister is lister of a dynamic informer, the typed API
+	// does not know capabilities.
+	clusterVersionLister cache.GenericLister
+	crdLister            v1.CustomResourceDefinitionLister
+	eventRecorder        events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient:       clients.OperatorClient,
+		kubeClient:           clients.KubeClient,
+		clusterVersionLister: clients.DynamicInformers.ForResource(csoutils.ClusterVersionResource).Lister(),
+		crdLister:            clients.ExtensionInformer.Apiextensions().V1().CustomResourceDefinitions().Lister(),
+		eventRecorder:        eventRecorder.WithComponentSuffix("snapshot-rbac"),
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		clients.OperatorClient.Informer(),
+		clients.ExtensionInformer.Apiextensions().V1().CustomResourceDefinitions().Informer(),
+		clients.KubeInformers.InformersFor("").Rbac().V1().ClusterRoles().Informer(),
+		clients.DynamicInformers.ForResource(csoutils.ClusterVersionResource).Informer(),
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("SnapshotRBACController sync started")
+	defer klog.V(4).Infof("SnapshotRBACController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	capabilities, err := csoutils.GetCapabilities(c.clusterVersionLister)
+	if err != nil {
+		return err
+	}
+	if !capabilities.Enabled(csoutils.CapabilityCSISnapshot) {
+		klog.V(4).Infof("Capability %s is disabled, skipping snapshot RBAC", csoutils.CapabilityCSISnapshot)
+		return nil
+	}
+
+	established, err := c.snapshotCRDsEstablished()
+	if err != nil {
+		return err
+	}
+	if !established {
+		klog.V(4).Infof("VolumeSnapshot CRDs are not established yet, skipping snapshot RBAC")
+		return nil
+	}
+
+	for _, asset := range clusterRoleAssets {
+		content, err := assets.ReadFile(asset)
+		if err != nil {
+			return err
+		}
+		required := resourceread.ReadClusterRoleV1OrDie(content)
+		csoutils.SetOwnedByLabel(required, ownerComponent)
+		if _, _, err := resourceapply.ApplyClusterRole(ctx, c.kubeClient.RbacV1(), c.eventRecorder, required); err != nil {
+			return fmt.Errorf("failed to apply ClusterRole %s: %w", required.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Controller) snapshotCRDsEstablished() (bool, error) {
+	for _, name := range []string{snapshotCRDName, snapshotClassCRDName, snapshotContentCRDName} {
+		crd, err := c.crdLister.Get(name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				klog.V(4).Infof("CRD %s not found", name)
+				return false, nil
+			}
+			return false, err
+		}
+		if !isEstablished(crd) {
+			klog.V(4).Infof("CRD %s is not established", name)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func isEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, cnd := range crd.Status.Conditions {
+		if cnd.Type == apiextensionsv1.Established {
+			return cnd.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -14,17 +14,34 @@ import (
 	"github.com/openshift/library-go/pkg/operator/management"
 	"github.com/openshift/library-go/pkg/operator/managementstatecontroller"
 	"github.com/openshift/library-go/pkg/operator/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/klog/v2"
 
+	"github.com/openshift/cluster-storage-operator/assets"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/driverregistry"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/backuplabels"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/cabundle"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/configobservercontroller"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/csidriveroperator/csioperatorclient"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/csimigration"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/defaultstorageclass"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/driftdetection"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/duplicateoperator"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/forceresync"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/networkpolicy"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/pausedresources"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/problemdetector"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/resourcegc"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/snapshotcrd"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/snapshotrbac"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/storagedefaults"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/vsphereproblemdetector"
 	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 )
 
 const (
@@ -36,7 +53,7 @@ const (
 	clusterOperatorName = "storage"
 )
 
-func RunOperator(ctx context.Context, controllerConfig *controllercmd.ControllerContext) error {
+func RunOperator(ctx context.Context, controllerConfig *controllercmd.ControllerContext, workers csoutils.ControllerWorkers) error {
 	clients, err := csoclients.NewClients(controllerConfig, resync)
 	if err != nil {
 		return err
@@ -50,11 +67,88 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 		controllerConfig.EventRecorder,
 	)
 
+	postMigrationController := defaultstorageclass.NewPostMigrationCleanupController(
+		clients,
+		controllerConfig.EventRecorder,
+		status.VersionForOperandFromEnv(),
+	)
+
+	csiMigrationController := csimigration.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+		status.VersionForOperandFromEnv(),
+	)
+
+	defaultStorageClassSelectionController := defaultstorageclass.NewDefaultStorageClassSelectionController(
+		clients,
+		controllerConfig.EventRecorder,
+	)
+
+	defaultStorageClassCheckController := defaultstorageclass.NewDefaultStorageClassCheckController(
+		clients,
+		controllerConfig.EventRecorder,
+	)
+
+	retroactiveDefaultStorageClassController := defaultstorageclass.NewRetroactiveDefaultStorageClassController(
+		clients,
+		controllerConfig.EventRecorder,
+		status.VersionForOperandFromEnv(),
+	)
+
+	caBundleController := cabundle.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+	)
+
+	networkPolicyController := networkpolicy.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+		resync,
+	)
+
 	snapshotCRDController := snapshotcrd.NewController(
 		clients,
 		controllerConfig.EventRecorder,
 	)
 
+	snapshotRBACController := snapshotrbac.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+	)
+
+	pausedResourcesController := pausedresources.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+	)
+
+	backupLabelsController := backuplabels.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+		resync,
+	)
+
+	driftDetectionController := driftdetection.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+		resync,
+	)
+
+	duplicateOperatorController := duplicateoperator.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+		resync,
+	)
+
+	storageDefaultsController := storagedefaults.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+	)
+
+	forceResyncController := forceresync.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+	)
+
 	relatedObjects := []configv1.ObjectReference{
 		{Resource: "namespaces", Name: operatorNamespace},
 		{Resource: "namespaces", Name: csoclients.CSIOperatorNamespace},
@@ -71,26 +165,41 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 	)
 
 	csiDriverConfigs := populateConfigs(clients, controllerConfig.EventRecorder)
+	resourceGCController := resourcegc.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+		csidriveroperator.OwnerComponent,
+		append(csiDriverAssetSets(csiDriverConfigs), sharedCSIDriverAssetSet),
+		loadedCSIDriverAssetSets(clients),
+		resync)
 	csiDriverController := csidriveroperator.NewCSIDriverStarterController(
 		clients,
 		resync,
 		versionGetter,
 		status.VersionForOperandFromEnv(),
 		controllerConfig.EventRecorder,
-		csiDriverConfigs)
+		csiDriverConfigs,
+		workers)
 	clusterOperatorStatus.WithRelatedObjectsFunc(csidriveroperator.RelatedObjectFunc())
 
+	problemDetectorController := problemdetector.NewController(
+		clients,
+		controllerConfig.EventRecorder,
+	)
+
 	vsphereProblemDetector := vsphereproblemdetector.NewVSphereProblemDetectorStarter(
 		clients,
 		resync,
 		versionGetter,
 		status.VersionForOperandFromEnv(),
-		controllerConfig.EventRecorder)
+		controllerConfig.EventRecorder,
+		workers)
 
 	managementStateController := managementstatecontroller.NewOperatorManagementStateController(clusterOperatorName, clients.OperatorClient, controllerConfig.EventRecorder)
 
-	// This controller syncs CR.Status.Conditions with the value in the field CR.Spec.ManagementStatus. It only supports Managed state
-	management.SetOperatorNotRemovable()
+	// This controller syncs CR.Status.Conditions with the value in the field CR.Spec.ManagementStatus.
+	// In Removed state, CSIDriverStarter removes all objects created by CSO.
+	management.SetOperatorRemovable()
 
 	// This controller syncs the operator log level with the value set in the CR.Spec.OperatorLogLevel
 	logLevelController := loglevel.NewClusterOperatorLoggingController(clients.OperatorClient, controllerConfig.EventRecorder)
@@ -98,6 +207,15 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 	// This controller observes a config (proxy for now) and writes it to CR.Spec.ObservedConfig for later use by the operator
 	configObserverController := configobservercontroller.NewConfigObserverController(clients, controllerConfig.EventRecorder)
 
+	// Controllers resync more often when they fail, see health.TrackSync.
+	health.SetMaxResyncInterval(resync)
+
+	// Serve state of all controllers. The server authenticates and
+	// authorizes requests, just like for /metrics.
+	if controllerConfig.Server != nil {
+		controllerConfig.Server.Handler.NonGoRestfulMux.Handle(health.Path, health.NewHandler(clients.OperatorClient))
+	}
+
 	klog.Info("Starting the Informers.")
 
 	csoclients.StartInformers(clients, ctx.Done())
@@ -109,13 +227,29 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 		managementStateController,
 		configObserverController,
 		storageClassController,
+		postMigrationController,
+		csiMigrationController,
+		defaultStorageClassSelectionController,
+		defaultStorageClassCheckController,
+		retroactiveDefaultStorageClassController,
+		caBundleController,
+		networkPolicyController,
 		snapshotCRDController,
+		snapshotRBACController,
+		resourceGCController,
+		pausedResourcesController,
+		backupLabelsController,
+		driftDetectionController,
+		duplicateOperatorController,
+		storageDefaultsController,
+		forceResyncController,
 		csiDriverController,
 		vsphereProblemDetector,
+		problemDetectorController,
 	} {
 		go func(ctrl factory.Controller) {
 			defer utilruntime.HandleCrash()
-			ctrl.Run(ctx, 1)
+			ctrl.Run(ctx, workers.Get("", ctrl.Name()))
 		}(c)
 	}
 
@@ -125,7 +259,7 @@ func RunOperator(ctx context.Context, controllerConfig *controllercmd.Controller
 }
 
 func populateConfigs(clients *csoclients.Clients, recorder events.Recorder) []csioperatorclient.CSIOperatorConfig {
-	return []csioperatorclient.CSIOperatorConfig{
+	configs := []csioperatorclient.CSIOperatorConfig{
 		csioperatorclient.GetAWSEBSCSIOperatorConfig(),
 		csioperatorclient.GetGCPPDCSIOperatorConfig(),
 		csioperatorclient.GetOpenStackCinderCSIOperatorConfig(clients, recorder),
@@ -135,5 +269,77 @@ func populateConfigs(clients *csoclients.Clients, recorder events.Recorder) []cs
 		csioperatorclient.GetAzureDiskCSIOperatorConfig(),
 		csioperatorclient.GetAzureFileCSIOperatorConfig(),
 		csioperatorclient.GetSharedResourceCSIOperatorConfig(),
+		csioperatorclient.GetNutanixCSIOperatorConfig(),
+		csioperatorclient.GetAlibabaDiskCSIOperatorConfig(),
+		csioperatorclient.GetKubeVirtCSIOperatorConfig(),
+		csioperatorclient.GetIBMVPCBlockCSIOperatorConfig(),
+		csioperatorclient.GetHostPathCSIOperatorConfig(),
+	}
+
+	// Add CSI driver operators registered by layered products.
+	builtin := map[string]bool{}
+	for _, cfg := range configs {
+		builtin[cfg.CSIDriverName] = true
+		builtin[cfg.ConditionPrefix] = true
+	}
+	for _, cfg := range driverregistry.Registered() {
+		if builtin[cfg.CSIDriverName] || builtin[cfg.ConditionPrefix] {
+			klog.Warningf("Ignoring registered CSI driver %s, it conflicts with a CSI driver shipped with the operator", cfg.CSIDriverName)
+			continue
+		}
+		klog.V(2).Infof("Adding registered CSI driver %s", cfg.CSIDriverName)
+		configs = append(configs, cfg)
+	}
+	return configs
+}
+
+// loadedCSIDriverAssetSets returns assets of CSI driver operators loaded
+// from ConfigMap driverregistry.ConfigMapName. It returns an error when any
+// of the configs is invalid, so objects of a CSI driver whose config was
+// broken by an edit are not deleted.
+func loadedCSIDriverAssetSets(clients *csoclients.Clients) *resourcegc.DynamicAssetSets {
+	informer := clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps()
+	lister := informer.Lister()
+	get := func() ([]resourcegc.AssetSet, error) {
+		cm, err := lister.ConfigMaps(csoclients.OperatorNamespace).Get(driverregistry.ConfigMapName)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var configs []csioperatorclient.CSIOperatorConfig
+		for key, data := range cm.Data {
+			cfg, err := driverregistry.ParseConfig([]byte(data))
+			if err != nil {
+				return nil, fmt.Errorf("invalid config %s in ConfigMap %s: %w", key, driverregistry.ConfigMapName, err)
+			}
+			configs = append(configs, cfg)
+		}
+		return csiDriverAssetSets(configs), nil
+	}
+	return &resourcegc.DynamicAssetSets{
+		Get:       get,
+		Informers: []factory.Informer{informer.Informer()},
+	}
+}
+
+// sharedCSIDriverAssetSet are assets shared by all CSI driver operators.
+var sharedCSIDriverAssetSet = resourcegc.AssetSet{
+	AssetFunc: assets.ReadFile,
+	Assets:    []string{csioperatorclient.TrustedCABundleAsset},
+}
+
+// csiDriverAssetSets returns assets of the CSI driver operators that are
+// labeled as owned by CSO.
+func csiDriverAssetSets(configs []csioperatorclient.CSIOperatorConfig) []resourcegc.AssetSet {
+	var sets []resourcegc.AssetSet
+	for i := range configs {
+		cfg := &configs[i]
+		sets = append(sets, resourcegc.AssetSet{
+			AssetFunc: cfg.GetAssetFunc(),
+			Assets:    append(cfg.GetAllStaticAssets(), cfg.DeploymentAsset),
+		})
 	}
+	return sets
 }
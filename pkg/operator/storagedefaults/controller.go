@@ -0,0 +1,154 @@
+package storagedefaults
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	configlisters "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	controllerName = "StorageDefaultsController"
+	ownerComponent = "storage-defaults"
+
+	infraConfigName          = "cluster"
+	clusterVersionConfigName = "version"
+)
+
+// Namespaces of operands that get the storage defaults.
+var targetNamespaces = []string{
+	csoclients.CSIOperatorNamespace,
+	csoclients.OperatorNamespace,
+}
+
+// This Controller syncs ConfigMap with cluster-wide values (platform,
+// topology, cluster ID, proxy, FIPS mode and log level) to namespaces of
+// all operands, so all CSI driver operators read the same values from a
+// single source. Deployment controllers of the operands mount the
+// ConfigMap, see csoutils.InjectStorageDefaults.
+// It produces following Conditions:
+// StorageDefaultsControllerDegraded - error syncing the ConfigMap.
+type Controller struct {
+	operatorClient       v1helpers.OperatorClient
+	kubeClient           kubernetes.Interface
+	infraLister          configlisters.InfrastructureLister
+	networkLister        configlisters.NetworkLister
+	clusterVersionLister configlisters.ClusterVersionLister
+	eventRecorder        events.Recorder
+}
+
+func NewController(
+	clients *csoclients.Clients,
+	eventRecorder events.Recorder) factory.Controller {
+	c := &Controller{
+		operatorClient:       clients.OperatorClient,
+		kubeClient:           clients.KubeClient,
+		infraLister:          clients.InfrastructureLister(),
+		networkLister:        clients.ConfigInformers.Config().V1().Networks().Lister(),
+		clusterVersionLister: clients.ConfigInformers.Config().V1().ClusterVersions().Lister(),
+		eventRecorder:        eventRecorder.WithComponentSuffix(ownerComponent),
+	}
+	informers := []factory.Informer{
+		clients.OperatorClient.Informer(),
+		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
+		clients.ConfigInformers.Config().V1().Networks().Informer(),
+		clients.ConfigInformers.Config().V1().ClusterVersions().Informer(),
+	}
+	for _, ns := range targetNamespaces {
+		informers = append(informers, clients.KubeInformers.InformersFor(ns).Core().V1().ConfigMaps().Informer())
+	}
+	return factory.New().WithSync(health.TrackSync(controllerName, c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+		informers...,
+	).ToController(controllerName, eventRecorder)
+}
+
+func (c *Controller) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	klog.V(4).Infof("StorageDefaultsController sync started")
+	defer klog.V(4).Infof("StorageDefaultsController sync finished")
+
+	opSpec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return err
+	}
+	if opSpec.ManagementState != operatorapi.Managed {
+		return nil
+	}
+
+	data, err := c.getStorageDefaults(opSpec)
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range targetNamespaces {
+		required := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      csoutils.StorageDefaultsConfigMapName,
+				Namespace: ns,
+			},
+			Data: data,
+		}
+		csoutils.SetOwnedByLabel(required, ownerComponent)
+		if _, _, err := resourceapply.ApplyConfigMap(ctx, c.kubeClient.CoreV1(), c.eventRecorder, required); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Controller) getStorageDefaults(opSpec *operatorapi.OperatorSpec) (map[string]string, error) {
+	infra, err := c.infraLister.Get(infraConfigName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get infrastructure resource: %w", err)
+	}
+	network, err := assettemplate.GetNetwork(c.networkLister)
+	if err != nil {
+		return nil, err
+	}
+	// Assets and the storage defaults use the same values.
+	values, err := assettemplate.NewValues(infra, network, opSpec, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterID := ""
+	clusterVersion, err := c.clusterVersionLister.Get(clusterVersionConfigName)
+	switch {
+	case err == nil:
+		clusterID = string(clusterVersion.Spec.ClusterID)
+	case !apierrors.IsNotFound(err):
+		return nil, err
+	}
+
+	fipsEnabled, err := csoutils.IsFIPSEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect FIPS mode: %w", err)
+	}
+
+	return map[string]string{
+		csoutils.StorageDefaultsPlatformKey:               string(values.Platform),
+		csoutils.StorageDefaultsControlPlaneTopologyKey:   string(values.ControlPlaneTopology),
+		csoutils.StorageDefaultsInfrastructureTopologyKey: string(values.InfrastructureTopology),
+		csoutils.StorageDefaultsClusterIDKey:              clusterID,
+		csoutils.StorageDefaultsInfrastructureNameKey:     infra.Status.InfrastructureName,
+		csoutils.StorageDefaultsHTTPProxyKey:              values.Proxy.HTTPProxy,
+		csoutils.StorageDefaultsHTTPSProxyKey:             values.Proxy.HTTPSProxy,
+		csoutils.StorageDefaultsNoProxyKey:                values.Proxy.NoProxy,
+		csoutils.StorageDefaultsFIPSKey:                   strconv.FormatBool(fipsEnabled),
+		csoutils.StorageDefaultsLogLevelKey:               strconv.Itoa(values.LogLevel),
+	}, nil
+}
@@ -8,6 +8,8 @@ import (
 	operatorapi "github.com/openshift/api/operator/v1"
 	"github.com/openshift/cluster-storage-operator/assets"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
@@ -66,7 +68,7 @@ func newMonitoringController(
 	}
 
 	return factory.New().
-		WithSync(c.sync).
+		WithSync(health.TrackSync(monitoringControllerName, c.sync)).
 		WithInformers(
 			c.operatorClient.Informer(),
 			clients.MonitoringInformer.Monitoring().V1().ServiceMonitors().Informer(),
@@ -93,6 +95,7 @@ func (c *monitoringController) sync(ctx context.Context, syncContext factory.Syn
 		return err
 	}
 	serviceMonitor := resourceread.ReadUnstructuredOrDie(smBytes)
+	csoutils.SetOwnedByLabel(serviceMonitor, ownerComponent)
 	_, _, err = resourceapply.ApplyServiceMonitor(ctx, c.dynamicClient, c.eventRecorder, serviceMonitor)
 	if err != nil {
 		return err
@@ -129,6 +132,7 @@ func (c *monitoringController) syncPrometheusRule(ctx context.Context, prometheu
 	if !ok {
 		return nil, false, fmt.Errorf("invalid prometheusrule: %+v", requiredObj)
 	}
+	csoutils.SetOwnedByLabel(prometheusRule, ownerComponent)
 
 	existingRule, err := c.monitoringClient.MonitoringV1().PrometheusRules(prometheusRule.Namespace).Get(ctx, prometheusRule.Name, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
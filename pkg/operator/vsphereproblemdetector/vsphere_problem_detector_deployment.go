@@ -4,37 +4,62 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	operatorapi "github.com/openshift/api/operator/v1"
 	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
 	"github.com/openshift/cluster-storage-operator/pkg/operator/configobservation/util"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/problemdetector"
 	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/status"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	vSphereProblemDetectorOperatorImage = "VSPHERE_PROBLEM_DETECTOR_OPERATOR_IMAGE"
 	deploymentControllerName            = "VSphereProblemDetectorDeploymentController"
+
+	// configName is name of ConfigMap with vSphere cloud config and
+	// configuration of checks rendered for vsphere-problem-detector, see
+	// syncConfig.
+	configName     = "vsphere-problem-detector-config"
+	cloudConfigKey = "cloud.conf"
+	checksKey      = "config.yaml"
 )
 
+// checksConfig is configuration of vsphere-problem-detector checks.
+type checksConfig struct {
+	CheckInterval  string   `json:"checkInterval,omitempty"`
+	DisabledChecks []string `json:"disabledChecks,omitempty"`
+}
+
 // TODO: move to DeploymentController form library-go instead
 type VSphereProblemDetectorDeploymentController struct {
-	operatorClient v1helpers.OperatorClient
-	kubeClient     kubernetes.Interface
-	infraLister    openshiftv1.InfrastructureLister
-	versionGetter  status.VersionGetter
-	targetVersion  string
-	eventRecorder  events.Recorder
+	operatorClient  v1helpers.OperatorClient
+	kubeClient      kubernetes.Interface
+	infraLister     openshiftv1.InfrastructureLister
+	networkLister   openshiftv1.NetworkLister
+	configMapLister corelisters.ConfigMapLister
+	// cloudConfigLister lists ConfigMaps in csoclients.CloudConfigNamespace.
+	cloudConfigLister corelisters.ConfigMapLister
+	versionGetter     status.VersionGetter
+	targetVersion     string
+	eventRecorder     events.Recorder
 }
 
 func NewVSphereProblemDetectorDeploymentController(
@@ -44,19 +69,25 @@ func NewVSphereProblemDetectorDeploymentController(
 	eventRecorder events.Recorder,
 	resyncInterval time.Duration) factory.Controller {
 	c := &VSphereProblemDetectorDeploymentController{
-		operatorClient: clients.OperatorClient,
-		kubeClient:     clients.KubeClient,
-		infraLister:    clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
-		versionGetter:  versionGetter,
-		eventRecorder:  eventRecorder,
-		targetVersion:  targetVersion,
+		operatorClient:    clients.OperatorClient,
+		kubeClient:        clients.KubeClient,
+		infraLister:       clients.InfrastructureLister(),
+		networkLister:     clients.ConfigInformers.Config().V1().Networks().Lister(),
+		configMapLister:   clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Lister(),
+		cloudConfigLister: clients.KubeInformers.InformersFor(csoclients.CloudConfigNamespace).Core().V1().ConfigMaps().Lister(),
+		versionGetter:     versionGetter,
+		eventRecorder:     eventRecorder,
+		targetVersion:     targetVersion,
 	}
 	return factory.New().
-		WithSync(c.sync).
+		WithSync(health.TrackSync(deploymentControllerName, c.sync)).
 		WithInformers(
 			c.operatorClient.Informer(),
 			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Apps().V1().Deployments().Informer(),
-			clients.ConfigInformers.Config().V1().Infrastructures().Informer()).
+			clients.KubeInformers.InformersFor(csoclients.OperatorNamespace).Core().V1().ConfigMaps().Informer(),
+			clients.KubeInformers.InformersFor(csoclients.CloudConfigNamespace).Core().V1().ConfigMaps().Informer(),
+			clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
+			clients.ConfigInformers.Config().V1().Networks().Informer()).
 		ResyncEvery(resyncInterval).
 		WithSyncDegradedOnError(clients.OperatorClient).
 		ToController(deploymentControllerName, eventRecorder.WithComponentSuffix("vsphere-problem-detector-deployment"))
@@ -77,17 +108,23 @@ func (c *VSphereProblemDetectorDeploymentController) sync(ctx context.Context, s
 		return nil
 	}
 
-	pairs := []string{
-		"${OPERATOR_IMAGE}", os.Getenv(vSphereProblemDetectorOperatorImage),
+	infrastructure, err := c.infraLister.Get(infraConfigName)
+	if err != nil {
+		return err
 	}
 
-	infrastructure, err := c.infraLister.Get(infraConfigName)
+	network, err := assettemplate.GetNetwork(c.networkLister)
 	if err != nil {
 		return err
 	}
 
-	replacer := strings.NewReplacer(pairs...)
-	required, err := csoutils.GetRequiredDeployment("vsphere_problem_detector/07_deployment.yaml", opSpec, replacer)
+	values, err := assettemplate.NewValues(infrastructure, network, opSpec, map[string]string{
+		assettemplate.ImageOperator: os.Getenv(vSphereProblemDetectorOperatorImage),
+	})
+	if err != nil {
+		return err
+	}
+	required, err := csoutils.GetRequiredDeployment(assets.ReadFile, "vsphere_problem_detector/07_deployment.yaml", values)
 	if err != nil {
 		return fmt.Errorf("failed to generate required Deployment: %s", err)
 	}
@@ -97,12 +134,37 @@ func (c *VSphereProblemDetectorDeploymentController) sync(ctx context.Context, s
 		return fmt.Errorf("failed to inject proxy data into deployment: %w", err)
 	}
 
+	csoutils.SetOwnedByLabel(requiredCopy, ownerComponent)
+
 	if shouldScheduleOnWorkers(infrastructure) {
 		requiredCopy.Spec.Template.Spec.NodeSelector = map[string]string{}
 	}
 
+	requiredCopy = csoutils.InjectStorageDefaults(requiredCopy)
+
+	// Mount user provided CA bundle for vCenter and roll out the
+	// Deployment when it changes.
+	requiredCopy = csoutils.InjectCustomCABundle(requiredCopy)
+	config, err := c.syncConfig(ctx, infrastructure, opSpec)
+	if err != nil {
+		return err
+	}
+	configMaps := []*corev1.ConfigMap{config}
+	caBundle, err := c.configMapLister.ConfigMaps(csoclients.OperatorNamespace).Get(csoutils.CustomCABundleConfigMapName)
+	switch {
+	case err == nil:
+		configMaps = append(configMaps, caBundle)
+	case !apierrors.IsNotFound(err):
+		return err
+	}
+	requiredCopy, err = csoutils.InjectDependencyHashes(requiredCopy, nil, configMaps)
+	if err != nil {
+		return err
+	}
+
 	_, err = csoutils.CreateDeployment(ctx, csoutils.DeploymentOptions{
 		Required:       requiredCopy,
+		OpSpec:         opSpec,
 		ControllerName: deploymentControllerName,
 		OpStatus:       opStatus,
 		EventRecorder:  c.eventRecorder,
@@ -115,6 +177,47 @@ func (c *VSphereProblemDetectorDeploymentController) sync(ctx context.Context, s
 	return err
 }
 
+// syncConfig renders config of vsphere-problem-detector and returns the
+// rendered ConfigMap. The Deployment is rolled out when it changes. The
+// ConfigMap contains:
+// - vSphere cloud config with all vCenters and datacenters of the cluster,
+// so it checks each of them. Older cloud configs list only the vCenter and
+// datacenter in [Workspace].
+// - check interval and disabled checks from the Storage CR, see
+// problemdetector.Config.
+func (c *VSphereProblemDetectorDeploymentController) syncConfig(ctx context.Context, infrastructure *configv1.Infrastructure, opSpec *operatorapi.OperatorSpec) (*corev1.ConfigMap, error) {
+	cloudConfig, err := csoutils.GetVSphereCloudConfig(c.cloudConfigLister, infrastructure)
+	if err != nil {
+		// This will set Degraded condition
+		return nil, fmt.Errorf("failed to read vSphere cloud config: %w", err)
+	}
+	cfg, err := problemdetector.GetConfig(opSpec)
+	if err != nil {
+		// This will set Degraded condition
+		return nil, err
+	}
+	checks, err := yaml.Marshal(checksConfig{
+		CheckInterval:  cfg.VSphereCheckInterval,
+		DisabledChecks: cfg.DisabledVSphereChecks,
+	})
+	if err != nil {
+		return nil, err
+	}
+	required := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configName,
+			Namespace: csoclients.OperatorNamespace,
+		},
+		Data: map[string]string{
+			cloudConfigKey: cloudConfig.String(),
+			checksKey:      string(checks),
+		},
+	}
+	csoutils.SetOwnedByLabel(required, ownerComponent)
+	cm, _, err := resourceapply.ApplyConfigMap(ctx, c.kubeClient.CoreV1(), c.eventRecorder, required)
+	return cm, err
+}
+
 func shouldScheduleOnWorkers(infra *configv1.Infrastructure) bool {
 	return infra.Status.ControlPlaneTopology == configv1.ExternalTopologyMode
 }
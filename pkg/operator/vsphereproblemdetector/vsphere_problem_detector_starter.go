@@ -8,8 +8,12 @@ import (
 	operatorapi "github.com/openshift/api/operator/v1"
 	openshiftv1 "github.com/openshift/client-go/config/listers/config/v1"
 	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/health"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/credentialsrequest"
 	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
+	csoutils "github.com/openshift/cluster-storage-operator/pkg/utils"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/controller/manager"
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -17,13 +21,23 @@ import (
 	"github.com/openshift/library-go/pkg/operator/staticresourcecontroller"
 	"github.com/openshift/library-go/pkg/operator/status"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 )
 
 const (
-	infraConfigName = "cluster"
+	infraConfigName         = "cluster"
+	credentialsRequestAsset = "vsphere_problem_detector/00_credentials_request.yaml"
+
+	// ownerComponent is value of csoutils.ComponentLabel of objects created
+	// for vsphere-problem-detector.
+	ownerComponent = "vsphere-problem-detector"
 )
 
+// VSphereProblemDetectorStarter runs vsphere-problem-detector on vSphere
+// clusters. It checks vCenter and exposes the results as metrics, which are
+// read by vSphere checks of the platform problem detector, see package
+// problemdetector.
 type VSphereProblemDetectorStarter struct {
 	controller     manager.ControllerManager
 	operatorClient *operatorclient.OperatorClient
@@ -31,7 +45,13 @@ type VSphereProblemDetectorStarter struct {
 	versionGetter  status.VersionGetter
 	targetVersion  string
 	eventRecorder  events.Recorder
+	dynamicClient  dynamic.Interface
 	running        bool
+	workers        csoutils.ControllerWorkers
+	// Whether CredentialsRequest has been removed on non-vSphere platform.
+	credentialsRemoved bool
+	// Error creating the controller, it's reported on sync.
+	controllerErr error
 }
 
 func NewVSphereProblemDetectorStarter(
@@ -39,16 +59,19 @@ func NewVSphereProblemDetectorStarter(
 	resyncInterval time.Duration,
 	versionGetter status.VersionGetter,
 	targetVersion string,
-	eventRecorder events.Recorder) factory.Controller {
+	eventRecorder events.Recorder,
+	workers csoutils.ControllerWorkers) factory.Controller {
 	c := &VSphereProblemDetectorStarter{
 		operatorClient: clients.OperatorClient,
-		infraLister:    clients.ConfigInformers.Config().V1().Infrastructures().Lister(),
+		infraLister:    clients.InfrastructureLister(),
 		versionGetter:  versionGetter,
 		targetVersion:  targetVersion,
 		eventRecorder:  eventRecorder.WithComponentSuffix("VSphereProblemDetectorStarter"),
+		dynamicClient:  clients.DynamicClient,
+		workers:        workers,
 	}
-	c.controller = c.createVSphereProblemDetectorManager(clients, resyncInterval)
-	return factory.New().WithSync(c.sync).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
+	c.controller, c.controllerErr = c.createVSphereProblemDetectorManager(clients, resyncInterval)
+	return factory.New().WithSync(health.TrackSync("VSphereProblemDetectorStarter", c.sync)).WithSyncDegradedOnError(clients.OperatorClient).WithInformers(
 		clients.OperatorClient.Informer(),
 		clients.ConfigInformers.Config().V1().Infrastructures().Informer(),
 	).ToController("VSphereProblemDetectorStarter", eventRecorder)
@@ -82,9 +105,19 @@ func (c *VSphereProblemDetectorStarter) sync(ctx context.Context, syncCtx factor
 
 	// if not vsphere turn without any error
 	if platform != configv1.VSpherePlatformType {
+		if !c.credentialsRemoved {
+			if err := credentialsrequest.DeleteCredentialsRequest(ctx, c.dynamicClient, opSpec, assets.ReadFile, credentialsRequestAsset, c.eventRecorder); err != nil {
+				return err
+			}
+			c.credentialsRemoved = true
+		}
 		return nil
 	}
 
+	if c.controllerErr != nil {
+		// This will set Degraded condition
+		return c.controllerErr
+	}
 	if !c.running {
 		go c.controller.Start(ctx)
 		c.running = true
@@ -94,8 +127,11 @@ func (c *VSphereProblemDetectorStarter) sync(ctx context.Context, syncCtx factor
 
 func (c *VSphereProblemDetectorStarter) createVSphereProblemDetectorManager(
 	clients *csoclients.Clients,
-	resyncInterval time.Duration) manager.ControllerManager {
+	resyncInterval time.Duration) (manager.ControllerManager, error) {
 	mgr := manager.NewControllerManager()
+	addController := func(ctrl factory.Controller) {
+		mgr = mgr.WithController(ctrl, c.workers.Get("", ctrl.Name()))
+	}
 
 	staticAssets := []string{
 		"vsphere_problem_detector/01_sa.yaml",
@@ -107,25 +143,41 @@ func (c *VSphereProblemDetectorStarter) createVSphereProblemDetectorManager(
 		"vsphere_problem_detector/10_service.yaml",
 	}
 
-	mgr = mgr.WithController(staticresourcecontroller.NewStaticResourceController(
+	addController(staticresourcecontroller.NewStaticResourceController(
 		"VSphereProblemDetectorStarterStaticController",
-		assets.ReadFile,
+		csoutils.AuditedAssetFunc(
+			csoutils.OwnedAssetFunc(csoutils.MirroredAssetFunc(assettemplate.AssetFunc(assets.ReadFile, assettemplate.ClusterValuesFunc(c.operatorClient, c.infraLister, clients.ConfigInformers.Config().V1().Networks().Lister(), nil)), clients.OperatorInformers.Operator().V1alpha1().ImageContentSourcePolicies().Lister()), ownerComponent),
+			c.operatorClient, clients.DynamicClient, clients.RestMapper, c.eventRecorder),
 		staticAssets,
 		resourceapply.NewKubeClientHolder(clients.KubeClient),
 		c.operatorClient,
-		c.eventRecorder).AddKubeInformers(clients.KubeInformers), 1)
+		c.eventRecorder).
+		AddKubeInformers(clients.KubeInformers).
+		AddInformer(clients.OperatorInformers.Operator().V1alpha1().ImageContentSourcePolicies().Informer()).
+		AddInformer(clients.ConfigInformers.Config().V1().Networks().Informer()))
 
-	mgr = mgr.WithController(NewVSphereProblemDetectorDeploymentController(
+	credentialsRequestCtrl, err := credentialsrequest.NewController(
+		"VSphereProblemDetector",
+		assets.ReadFile,
+		credentialsRequestAsset,
+		clients,
+		c.eventRecorder,
+		resyncInterval)
+	if err != nil {
+		return nil, err
+	}
+	addController(credentialsRequestCtrl)
+	addController(NewVSphereProblemDetectorDeploymentController(
 		clients,
 		c.versionGetter,
 		c.targetVersion,
 		c.eventRecorder,
-		resyncInterval), 1)
+		resyncInterval))
 
-	mgr = mgr.WithController(newMonitoringController(
+	addController(newMonitoringController(
 		clients,
 		c.eventRecorder,
-		resyncInterval), 1)
+		resyncInterval))
 
-	return mgr
+	return mgr, nil
 }
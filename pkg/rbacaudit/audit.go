@@ -0,0 +1,287 @@
+// Package rbacaudit compares RBAC rules shipped as CSO assets with API calls
+// that the ServiceAccounts bound to them actually made, as recorded in the
+// API server audit log. It reports rules that are broader than necessary,
+// so the ClusterRoles of CSI driver operators can be shrunk.
+package rbacaudit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/openshift/cluster-storage-operator/assets"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+)
+
+// AssetDirs are asset directories with RBAC objects of CSO operands.
+var AssetDirs = []string{
+	"csidriveroperators/alibaba-disk",
+	"csidriveroperators/aws-ebs",
+	"csidriveroperators/azure-disk",
+	"csidriveroperators/azure-file",
+	"csidriveroperators/gcp-pd",
+	"csidriveroperators/hostpath",
+	"csidriveroperators/ibm-vpc-block",
+	"csidriveroperators/kubevirt",
+	"csidriveroperators/manila",
+	"csidriveroperators/nutanix",
+	"csidriveroperators/openstack-cinder",
+	"csidriveroperators/ovirt",
+	"csidriveroperators/shared-resource",
+	"csidriveroperators/vsphere",
+	"vsphere_problem_detector",
+}
+
+// Verbs that are checked by the API server or admission plugins without
+// a corresponding API call in the audit log.
+var unauditableVerbs = sets.NewString("use", "bind", "escalate", "impersonate")
+
+// Capability is a single API call permission.
+type Capability struct {
+	APIGroup string
+	Resource string
+	Verb     string
+}
+
+func (c Capability) String() string {
+	group := c.APIGroup
+	if group == "" {
+		group = "core"
+	}
+	return fmt.Sprintf("%s %s/%s", c.Verb, group, c.Resource)
+}
+
+// Finding is a RBAC rule that grants more than the ServiceAccount uses.
+type Finding struct {
+	// Asset is name of the asset with the Role / ClusterRole.
+	Asset string
+	// Role is kind and name of the Role / ClusterRole.
+	Role string
+	// Capability is the over-broad permission.
+	Capability Capability
+	// Reason is human readable explanation of the finding.
+	Reason string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", f.Asset, f.Role, f.Capability, f.Reason)
+}
+
+// role is a Role or ClusterRole read from assets, together with
+// ServiceAccounts bound to it.
+type role struct {
+	asset     string
+	kind      string
+	name      string
+	namespace string
+	rules     []rbacv1.PolicyRule
+	subjects  sets.String
+}
+
+// Audit reads RBAC objects from given asset directories and audit events
+// from the audit log and returns list of over-broad rules, sorted by asset.
+func Audit(dirs []string, auditLog io.Reader) ([]Finding, error) {
+	var roles []*role
+	for _, dir := range dirs {
+		dirRoles, err := readRoles(dir)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, dirRoles...)
+	}
+
+	used, err := readAuditLog(auditLog)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, r := range roles {
+		findings = append(findings, auditRole(r, used)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Asset < findings[j].Asset
+	})
+	return findings, nil
+}
+
+// usedCapabilities are capabilities used by ServiceAccounts, indexed by
+// ServiceAccount user name and namespace of the API call.
+type usedCapabilities map[string]map[Capability]sets.String
+
+func (u usedCapabilities) used(user, namespace string, c Capability) bool {
+	namespaces, found := u[user][c]
+	if !found {
+		return false
+	}
+	return namespace == "" || namespaces.Has(namespace)
+}
+
+func auditRole(r *role, used usedCapabilities) []Finding {
+	var findings []Finding
+	roleName := r.kind + "/" + r.name
+	for _, rule := range r.rules {
+		if len(rule.NonResourceURLs) > 0 {
+			continue
+		}
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					c := Capability{APIGroup: group, Resource: resource, Verb: verb}
+					if group == rbacv1.APIGroupAll || resource == rbacv1.ResourceAll || verb == rbacv1.VerbAll {
+						findings = append(findings, Finding{
+							Asset:      r.asset,
+							Role:       roleName,
+							Capability: c,
+							Reason:     "wildcard rule",
+						})
+						continue
+					}
+					if unauditableVerbs.Has(verb) {
+						continue
+					}
+					usedBySubject := false
+					for _, user := range r.subjects.List() {
+						if used.used(user, r.namespace, c) {
+							usedBySubject = true
+							break
+						}
+					}
+					if !usedBySubject {
+						findings = append(findings, Finding{
+							Asset:      r.asset,
+							Role:       roleName,
+							Capability: c,
+							Reason:     "not used",
+						})
+					}
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// readRoles reads all Roles and ClusterRoles from given asset directory
+// and finds ServiceAccounts bound to them by RoleBindings and
+// ClusterRoleBindings from the same directory.
+func readRoles(dir string) ([]*role, error) {
+	entries, err := assets.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := map[string]*role{}
+	var bindings []rbacv1.RoleBinding
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		asset := path.Join(dir, entry.Name())
+		assetBytes, err := assets.ReadFile(asset)
+		if err != nil {
+			return nil, err
+		}
+		jsonBytes, err := yaml.ToJSON(assetBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse asset %s: %w", asset, err)
+		}
+		typeMeta := metav1.TypeMeta{}
+		if err := json.Unmarshal(jsonBytes, &typeMeta); err != nil {
+			return nil, fmt.Errorf("failed to parse asset %s: %w", asset, err)
+		}
+
+		switch typeMeta.Kind {
+		case "Role", "ClusterRole":
+			obj := &rbacv1.Role{}
+			if err := json.Unmarshal(jsonBytes, obj); err != nil {
+				return nil, fmt.Errorf("failed to parse asset %s: %w", asset, err)
+			}
+			roles[typeMeta.Kind+"/"+obj.Name] = &role{
+				asset:     asset,
+				kind:      typeMeta.Kind,
+				name:      obj.Name,
+				namespace: obj.Namespace,
+				rules:     obj.Rules,
+				subjects:  sets.NewString(),
+			}
+		case "RoleBinding", "ClusterRoleBinding":
+			// ClusterRoleBinding has the same structure as RoleBinding.
+			obj := rbacv1.RoleBinding{}
+			if err := json.Unmarshal(jsonBytes, &obj); err != nil {
+				return nil, fmt.Errorf("failed to parse asset %s: %w", asset, err)
+			}
+			bindings = append(bindings, obj)
+		}
+	}
+
+	for _, binding := range bindings {
+		r, found := roles[binding.RoleRef.Kind+"/"+binding.RoleRef.Name]
+		if !found {
+			// The role is not shipped in the same directory, e.g. it's
+			// a system role.
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			if subject.Kind != rbacv1.ServiceAccountKind {
+				continue
+			}
+			r.subjects.Insert(fmt.Sprintf("system:serviceaccount:%s:%s", subject.Namespace, subject.Name))
+		}
+	}
+
+	var ret []*role
+	for _, r := range roles {
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].asset < ret[j].asset
+	})
+	return ret, nil
+}
+
+// readAuditLog reads audit events, one JSON event per line, and returns
+// capabilities used by ServiceAccounts.
+func readAuditLog(auditLog io.Reader) (usedCapabilities, error) {
+	used := usedCapabilities{}
+	scanner := bufio.NewScanner(auditLog)
+	// Audit events can be long, esp. with request / response bodies.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		event := auditv1.Event{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse audit event: %w", err)
+		}
+		if event.ObjectRef == nil || !strings.HasPrefix(event.User.Username, "system:serviceaccount:") {
+			continue
+		}
+		resource := event.ObjectRef.Resource
+		if event.ObjectRef.Subresource != "" {
+			resource += "/" + event.ObjectRef.Subresource
+		}
+		c := Capability{APIGroup: event.ObjectRef.APIGroup, Resource: resource, Verb: event.Verb}
+		if used[event.User.Username] == nil {
+			used[event.User.Username] = map[Capability]sets.String{}
+		}
+		if used[event.User.Username][c] == nil {
+			used[event.User.Username][c] = sets.NewString()
+		}
+		used[event.User.Username][c].Insert(event.ObjectRef.Namespace)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return used, nil
+}
@@ -0,0 +1,35 @@
+package rbacaudit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	getClusterCSIDrivers := Capability{APIGroup: "operator.openshift.io", Resource: "clustercsidrivers", Verb: "get"}
+	patchClusterCSIDrivers := Capability{APIGroup: "operator.openshift.io", Resource: "clustercsidrivers", Verb: "patch"}
+	auditLog := `{"verb":"get","user":{"username":"system:serviceaccount:openshift-cluster-csi-drivers:aws-ebs-csi-driver-operator"},"objectRef":{"apiGroup":"operator.openshift.io","resource":"clustercsidrivers","name":"ebs.csi.aws.com"}}
+{"verb":"patch","user":{"username":"system:serviceaccount:openshift-cluster-csi-drivers:some-other-operator"},"objectRef":{"apiGroup":"operator.openshift.io","resource":"clustercsidrivers","name":"ebs.csi.aws.com"}}
+`
+
+	findings, err := Audit([]string{"csidriveroperators/aws-ebs"}, strings.NewReader(auditLog))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	reported := map[Capability]string{}
+	for _, f := range findings {
+		if f.Role == "ClusterRole/aws-ebs-csi-driver-operator-clusterrole" {
+			reported[f.Capability] = f.Reason
+		}
+	}
+	if reason, found := reported[getClusterCSIDrivers]; found {
+		t.Errorf("expected %s not to be reported, got %q", getClusterCSIDrivers, reason)
+	}
+	if reason := reported[patchClusterCSIDrivers]; reason != "not used" {
+		t.Errorf("expected %s to be reported as not used, got %q", patchClusterCSIDrivers, reason)
+	}
+	if len(reported) == 0 {
+		t.Errorf("expected findings for the ClusterRole, got none")
+	}
+}
@@ -0,0 +1,178 @@
+// Package testharness helps to test controllers of CSO and their integration
+// with CSI driver operators registered via pkg/driverregistry. It runs the
+// controllers against fake clients, so the tests don't need a cluster.
+//
+// Typical usage:
+//
+//	h := testharness.New(t, &csoclients.FakeTestObjects{
+//		OperatorObjects: []runtime.Object{testharness.NewStorage()},
+//		ConfigObjects:   []runtime.Object{testharness.NewInfrastructure(configv1.AWSPlatformType)},
+//	})
+//	ctrl := defaultstorageclass.NewController(h.Clients, h.Recorder)
+//	h.Start()
+//	h.Sync(ctrl)
+//	h.AssertCondition("DefaultStorageClassControllerAvailable", operatorv1.ConditionTrue)
+package testharness
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operatorclient"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	appsv1 "k8s.io/api/apps/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	infraConfigName       = "cluster"
+	featureGateConfigName = "cluster"
+)
+
+// Harness runs CSO controllers against fake clients.
+type Harness struct {
+	// Clients are fake clients to create controllers with.
+	Clients *csoclients.Clients
+	// Recorder is an in-memory event recorder to create controllers with.
+	Recorder events.Recorder
+
+	t   testing.TB
+	ctx context.Context
+}
+
+// New returns a new Harness with fake clients pre-populated with given
+// objects. Informers are stopped when the test finishes.
+func New(t testing.TB, objects *csoclients.FakeTestObjects) *Harness {
+	if objects == nil {
+		objects = &csoclients.FakeTestObjects{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Harness{
+		Clients:  csoclients.NewFakeClients(objects),
+		Recorder: events.NewInMemoryRecorder("cluster-storage-operator"),
+		t:        t,
+		ctx:      ctx,
+	}
+}
+
+// Start starts informers and waits for their caches to sync. It must be
+// called after all controllers are created, so their informers are started
+// too.
+func (h *Harness) Start() {
+	csoclients.StartInformers(h.Clients, h.ctx.Done())
+	csoclients.WaitForSync(h.Clients, h.ctx.Done())
+}
+
+// Sync runs a single sync of the controller and fails the test on error.
+func (h *Harness) Sync(ctrl factory.Controller) {
+	h.t.Helper()
+	if err := h.SyncWithError(ctrl); err != nil {
+		h.t.Fatalf("%s sync failed: %s", ctrl.Name(), err)
+	}
+}
+
+// SyncWithError runs a single sync of the controller and returns its error.
+func (h *Harness) SyncWithError(ctrl factory.Controller) error {
+	return ctrl.Sync(h.ctx, factory.NewSyncContext(ctrl.Name(), h.Recorder))
+}
+
+// AssertCondition fails the test when the Storage CR does not have the
+// condition with given status.
+func (h *Harness) AssertCondition(conditionType string, status operatorv1.ConditionStatus) {
+	h.t.Helper()
+	// Read the Storage CR from the API server, the informer may not have
+	// caught up with the last sync yet.
+	storage, err := h.Clients.OperatorClientSet.OperatorV1().Storages().Get(h.ctx, operatorclient.GlobalConfigName, metav1.GetOptions{})
+	if err != nil {
+		h.t.Fatalf("failed to get Storage %s: %s", operatorclient.GlobalConfigName, err)
+	}
+	cnd := v1helpers.FindOperatorCondition(storage.Status.Conditions, conditionType)
+	if cnd == nil {
+		h.t.Fatalf("condition %s not found", conditionType)
+	}
+	if cnd.Status != status {
+		h.t.Errorf("expected condition %s to be %s, got %s: %s: %s", conditionType, status, cnd.Status, cnd.Reason, cnd.Message)
+	}
+}
+
+// GetStorageClass returns the StorageClass from the API server, failing the
+// test when it does not exist.
+func (h *Harness) GetStorageClass(name string) *storagev1.StorageClass {
+	h.t.Helper()
+	sc, err := h.Clients.KubeClient.StorageV1().StorageClasses().Get(h.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		h.t.Fatalf("failed to get StorageClass %s: %s", name, err)
+	}
+	return sc
+}
+
+// GetDeployment returns the Deployment from the API server, failing the test
+// when it does not exist.
+func (h *Harness) GetDeployment(namespace, name string) *appsv1.Deployment {
+	h.t.Helper()
+	deployment, err := h.Clients.KubeClient.AppsV1().Deployments(namespace).Get(h.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		h.t.Fatalf("failed to get Deployment %s/%s: %s", namespace, name, err)
+	}
+	return deployment
+}
+
+// NewStorage returns the Managed Storage CR.
+func NewStorage() *operatorv1.Storage {
+	return &operatorv1.Storage{
+		ObjectMeta: metav1.ObjectMeta{Name: operatorclient.GlobalConfigName},
+		Spec: operatorv1.StorageSpec{
+			OperatorSpec: operatorv1.OperatorSpec{
+				ManagementState: operatorv1.Managed,
+			},
+		},
+	}
+}
+
+// NewInfrastructure returns the Infrastructure of a cluster on the given
+// platform.
+func NewInfrastructure(platform configv1.PlatformType) *configv1.Infrastructure {
+	return &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: infraConfigName},
+		Status: configv1.InfrastructureStatus{
+			Platform: platform,
+			PlatformStatus: &configv1.PlatformStatus{
+				Type: platform,
+			},
+			ControlPlaneTopology:   configv1.HighlyAvailableTopologyMode,
+			InfrastructureTopology: configv1.HighlyAvailableTopologyMode,
+		},
+	}
+}
+
+// NewFeatureGateStatus returns the FeatureGate CR for DynamicObjects, with
+// features enabled for the release version in its status.
+func NewFeatureGateStatus(version string, enabled ...string) *unstructured.Unstructured {
+	var features []interface{}
+	for _, name := range enabled {
+		features = append(features, map[string]interface{}{"name": name})
+	}
+	fg := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "config.openshift.io/v1",
+		"kind":       "FeatureGate",
+		"status": map[string]interface{}{
+			"featureGates": []interface{}{
+				map[string]interface{}{
+					"version":  version,
+					"enabled":  features,
+					"disabled": []interface{}{},
+				},
+			},
+		},
+	}}
+	fg.SetName(featureGateConfigName)
+	return fg
+}
@@ -0,0 +1,28 @@
+package testharness
+
+import (
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-storage-operator/pkg/csoclients"
+	"github.com/openshift/cluster-storage-operator/pkg/operator/defaultstorageclass"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestHarness(t *testing.T) {
+	h := New(t, &csoclients.FakeTestObjects{
+		OperatorObjects: []runtime.Object{NewStorage()},
+		ConfigObjects:   []runtime.Object{NewInfrastructure(configv1.AWSPlatformType)},
+	})
+	ctrl := defaultstorageclass.NewController(h.Clients, h.Recorder)
+	h.Start()
+
+	h.Sync(ctrl)
+
+	h.AssertCondition("DefaultStorageClassControllerAvailable", operatorv1.ConditionTrue)
+	sc := h.GetStorageClass("gp2")
+	if sc.Provisioner != "kubernetes.io/aws-ebs" {
+		t.Errorf("unexpected provisioner of StorageClass gp2: %s", sc.Provisioner)
+	}
+}
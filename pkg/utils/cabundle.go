@@ -0,0 +1,128 @@
+package utils
+
+import (
+	operatorapi "github.com/openshift/api/operator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// CustomCABundleConfigMapName is name of ConfigMap with user provided CA
+	// bundle, synced by CSO to namespaces of operands that need it.
+	CustomCABundleConfigMapName = "custom-ca-bundle"
+	// CustomCABundleKey is key of the CA bundle in the ConfigMap.
+	CustomCABundleKey = "ca-bundle.crt"
+
+	trustedCABundleVolumeName = "csi-driver-operator-trusted-ca-bundle"
+	trustedCABundleDir        = "/etc/pki/ca-trust/extracted/pem"
+	trustedCABundleFile       = "tls-ca-bundle.pem"
+
+	customCABundleVolumeName = "custom-ca-bundle"
+	customCABundleDir        = "/etc/pki/ca-trust/custom"
+	// Go crypto/x509 loads all certificates in SSL_CERT_DIR in addition to
+	// the system CA bundle file.
+	sslCertDirEnv = "SSL_CERT_DIR"
+)
+
+// GetCustomCABundleName returns name of ConfigMap in openshift-config namespace
+// with user provided CA bundle for vCenter / OpenStack endpoints. The operator
+// API does not have a typed field for it yet, it's read from Storage CR
+// spec.unsupportedConfigOverrides:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    customCABundle:
+//	      name: my-ca-bundle
+//
+// Empty string is returned when no CA bundle is configured.
+func GetCustomCABundleName(opSpec *operatorapi.OperatorSpec) (string, error) {
+	caBundle := struct {
+		Name string `json:"name"`
+	}{}
+	if _, err := GetUnsupportedConfigOverride(opSpec, "customCABundle", &caBundle); err != nil {
+		return "", err
+	}
+	return caBundle.Name, nil
+}
+
+// InjectCustomCABundle returns a copy of the Deployment with the custom CA
+// bundle ConfigMap mounted to all its containers and added to their trusted
+// CAs. The ConfigMap is optional, the Deployment runs with the system CAs
+// when it does not exist.
+func InjectCustomCABundle(deployment *appsv1.Deployment) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	podSpec := &deploymentCopy.Spec.Template.Spec
+	optional := true
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: customCABundleVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: CustomCABundleConfigMapName},
+				Items: []corev1.KeyToPath{
+					{Key: CustomCABundleKey, Path: CustomCABundleKey},
+				},
+				Optional: &optional,
+			},
+		},
+	})
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      customCABundleVolumeName,
+			MountPath: customCABundleDir,
+			ReadOnly:  true,
+		})
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  sslCertDirEnv,
+			Value: customCABundleDir,
+		})
+	}
+	return deploymentCopy
+}
+
+// InjectTrustedCABundle returns a copy of the Deployment with ConfigMap with
+// the cluster trusted CA bundle mounted to all its containers as the system
+// CA bundle. Containers that already mount their own trusted CA bundle there
+// are left untouched.
+func InjectTrustedCABundle(deployment *appsv1.Deployment, configMapName string) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	podSpec := &deploymentCopy.Spec.Template.Spec
+	optional := true
+	injected := false
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		if hasMountPath(container, trustedCABundleDir) {
+			continue
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      trustedCABundleVolumeName,
+			MountPath: trustedCABundleDir,
+			ReadOnly:  true,
+		})
+		injected = true
+	}
+	if injected {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: trustedCABundleVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMapName},
+					Items: []corev1.KeyToPath{
+						{Key: CustomCABundleKey, Path: trustedCABundleFile},
+					},
+					Optional: &optional,
+				},
+			},
+		})
+	}
+	return deploymentCopy
+}
+
+func hasMountPath(container *corev1.Container, path string) bool {
+	for _, mount := range container.VolumeMounts {
+		if mount.MountPath == path {
+			return true
+		}
+	}
+	return false
+}
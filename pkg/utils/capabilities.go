@@ -0,0 +1,54 @@
+package utils
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// CapabilityCSISnapshot is the cluster capability of CSI volume
+	// snapshots.
+	CapabilityCSISnapshot = "CSISnapshot"
+	// CapabilityStorage is the cluster capability of storage.
+	CapabilityStorage = "Storage"
+
+	clusterVersionName = "version"
+)
+
+// Capabilities are cluster capabilities enabled in ClusterVersion. Nil
+// Capabilities have all capabilities enabled, e.g. on clusters that do not
+// support capabilities.
+type Capabilities struct {
+	enabled map[string]bool
+}
+
+// Enabled returns true when the capability is enabled.
+func (c *Capabilities) Enabled(capability string) bool {
+	if c == nil {
+		return true
+	}
+	return c.enabled[capability]
+}
+
+// GetCapabilities returns capabilities enabled in ClusterVersion status.
+// The typed API vendored in CSO does not know the capabilities, ClusterVersion
+// is read from a lister of ClusterVersionResource.
+func GetCapabilities(lister cache.GenericLister) (*Capabilities, error) {
+	obj, err := getUnstructured(lister, clusterVersionName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	enabled, found, err := unstructured.NestedStringSlice(obj.Object, "status", "capabilities", "enabledCapabilities")
+	if err != nil || !found {
+		return nil, err
+	}
+	capabilities := &Capabilities{enabled: map[string]bool{}}
+	for _, capability := range enabled {
+		capabilities.enabled[capability] = true
+	}
+	return capabilities, nil
+}
@@ -3,20 +3,22 @@ package utils
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
 
 	operatorapi "github.com/openshift/api/operator/v1"
-	"github.com/openshift/cluster-storage-operator/assets"
+	"github.com/openshift/cluster-storage-operator/pkg/assettemplate"
 	"github.com/openshift/library-go/pkg/operator/events"
-	"github.com/openshift/library-go/pkg/operator/loglevel"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
 	"github.com/openshift/library-go/pkg/operator/status"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/klog/v2"
 )
 
 type DeploymentOptions struct {
@@ -29,11 +31,19 @@ type DeploymentOptions struct {
 	TargetVersion  string
 	VersionGetter  status.VersionGetter
 	VersionName    string
+
+	// OpSpec is spec of the operator CR. The Deployment is not applied when
+	// its reconciliation is paused there, see GetPausedResources.
+	OpSpec *operatorapi.OperatorSpec
 }
 
 func CreateDeployment(ctx context.Context, depOpts DeploymentOptions) (*appsv1.Deployment, error) {
-	lastGeneration := resourcemerge.ExpectedDeploymentGeneration(depOpts.Required, depOpts.OpStatus.Generations)
-	deployment, _, err := resourceapply.ApplyDeployment(ctx, depOpts.KubeClient.AppsV1(), depOpts.EventRecorder, depOpts.Required, lastGeneration)
+	required, err := InjectConfigHash(depOpts.Required)
+	if err != nil {
+		return nil, err
+	}
+	lastGeneration := resourcemerge.ExpectedDeploymentGeneration(required, depOpts.OpStatus.Generations)
+	deployment, err := ApplyDeploymentUnlessPaused(ctx, depOpts.KubeClient.AppsV1(), depOpts.EventRecorder, depOpts.OpSpec, required, lastGeneration)
 	if err != nil {
 		// This will set Degraded condition
 		return nil, err
@@ -91,26 +101,80 @@ func CreateDeployment(ctx context.Context, depOpts DeploymentOptions) (*appsv1.D
 	return deployment, nil
 }
 
-// GetRequiredDeployment returns a deployment from given assset after replacing necessary strings and setting
-// correct log level.
-func GetRequiredDeployment(deploymentAsset string, spec *operatorapi.OperatorSpec, replacers ...*strings.Replacer) (*appsv1.Deployment, error) {
-	deploymentBytes, err := assets.ReadFile(deploymentAsset)
+// ApplyDeploymentUnlessPaused applies the Deployment, unless its
+// reconciliation is paused in opSpec. The existing Deployment is returned
+// then. In audit-only mode (see IsAuditOnly), drift of the existing
+// Deployment is only reported and the existing Deployment is returned.
+func ApplyDeploymentUnlessPaused(ctx context.Context, client appsclientv1.DeploymentsGetter, recorder events.Recorder, opSpec *operatorapi.OperatorSpec, required *appsv1.Deployment, expectedGeneration int64) (*appsv1.Deployment, error) {
+	if opSpec != nil {
+		paused, err := IsResourcePaused(opSpec, PausedKindDeployment, required.Namespace, required.Name)
+		if err != nil {
+			return nil, err
+		}
+		if paused {
+			klog.V(2).Infof("Reconciliation of Deployment %s/%s is paused", required.Namespace, required.Name)
+			return client.Deployments(required.Namespace).Get(ctx, required.Name, metav1.GetOptions{})
+		}
+		auditOnly, err := IsAuditOnly(opSpec)
+		if err != nil {
+			return nil, err
+		}
+		if auditOnly {
+			return auditDeployment(ctx, client, recorder, required, expectedGeneration)
+		}
+	}
+	deployment, _, err := resourceapply.ApplyDeployment(ctx, client, recorder, required, expectedGeneration)
+	return deployment, err
+}
+
+// auditDeployment reports drift of the existing Deployment, using the same
+// checks as resourceapply.ApplyDeployment uses to decide if the Deployment
+// needs an update.
+func auditDeployment(ctx context.Context, client appsclientv1.DeploymentsGetter, recorder events.Recorder, required *appsv1.Deployment, expectedGeneration int64) (*appsv1.Deployment, error) {
+	obj := DriftedObject{Kind: "Deployment", Namespace: required.Namespace, Name: required.Name}
+	existing, err := client.Deployments(required.Namespace).Get(ctx, required.Name, metav1.GetOptions{})
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			ReportDrift(recorder, obj, true)
+			return nil, fmt.Errorf("%s is missing, it is not created in audit-only mode", obj)
+		}
 		return nil, err
 	}
-	deploymentString := string(deploymentBytes)
 
-	for _, replacer := range replacers {
-		// Replace images
-		if replacer != nil {
-			deploymentString = replacer.Replace(deploymentString)
-		}
+	requiredCopy := required.DeepCopy()
+	if err := resourceapply.SetSpecHashAnnotation(&requiredCopy.ObjectMeta, requiredCopy.Spec); err != nil {
+		return nil, err
 	}
+	modified := resourcemerge.BoolPtr(false)
+	existingMeta := existing.ObjectMeta.DeepCopy()
+	resourcemerge.EnsureObjectMeta(modified, existingMeta, requiredCopy.ObjectMeta)
+	// Negative expectedGeneration means the generation was not recorded yet.
+	drifted := *modified || (expectedGeneration >= 0 && existing.Generation != expectedGeneration)
+	ReportDrift(recorder, obj, drifted)
+	return existing, nil
+}
 
-	// Replace log level
-	logLevel := loglevel.LogLevelToVerbosity(spec.LogLevel)
-	deploymentString = strings.ReplaceAll(deploymentString, "${LOG_LEVEL}", strconv.Itoa(logLevel))
+// GetRequiredDeployment returns a deployment from given assset rendered with given values.
+func GetRequiredDeployment(assetFunc resourceapply.AssetFunc, deploymentAsset string, values *assettemplate.Values) (*appsv1.Deployment, error) {
+	deploymentBytes, err := assettemplate.AssetFunc(assetFunc, func() (*assettemplate.Values, error) {
+		return values, nil
+	})(deploymentAsset)
+	if err != nil {
+		return nil, err
+	}
+	return resourceread.ReadDeploymentV1OrDie(deploymentBytes), nil
+}
 
-	deployment := resourceread.ReadDeploymentV1OrDie([]byte(deploymentString))
-	return deployment, nil
+// InjectEnv returns a copy of the Deployment with the env. var set in all
+// its containers.
+func InjectEnv(deployment *appsv1.Deployment, name, value string) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	podSpec := &deploymentCopy.Spec.Template.Spec
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, corev1.EnvVar{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return deploymentCopy
 }
@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// DriftedObject is an object managed by CSO that differs from its expected
+// state.
+type DriftedObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (o DriftedObject) String() string {
+	if o.Namespace == "" {
+		return o.Kind + " " + o.Name
+	}
+	return o.Kind + " " + o.Namespace + "/" + o.Name
+}
+
+var (
+	driftedObjectsLock sync.Mutex
+	driftedObjects     = map[DriftedObject]bool{}
+
+	driftedObjectsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cluster_storage_operator_drifted_object",
+			Help: "A metric with a constant '1' value labeled by kind, namespace and name of an object that differs from its expected state in audit-only mode.",
+		},
+		[]string{"kind", "namespace", "name"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(driftedObjectsMetric)
+}
+
+// IsAuditOnly returns true when CSO only reports drift of the objects it
+// manages instead of reverting it, so it's possible to see who changes
+// them during incident response. The operator API does not have a typed
+// field for it, it's read from Storage CR spec.unsupportedConfigOverrides:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    auditOnly: true
+func IsAuditOnly(opSpec *operatorapi.OperatorSpec) (bool, error) {
+	auditOnly := false
+	if _, err := GetUnsupportedConfigOverride(opSpec, "auditOnly", &auditOnly); err != nil {
+		return false, err
+	}
+	return auditOnly, nil
+}
+
+// ReportDrift records whether the object differs from its expected state.
+// An event is emitted when the object starts to differ.
+func ReportDrift(recorder events.Recorder, obj DriftedObject, drifted bool) {
+	driftedObjectsLock.Lock()
+	defer driftedObjectsLock.Unlock()
+
+	if !drifted {
+		if driftedObjects[obj] {
+			delete(driftedObjects, obj)
+			driftedObjectsMetric.DeleteLabelValues(obj.Kind, obj.Namespace, obj.Name)
+		}
+		return
+	}
+	if driftedObjects[obj] {
+		return
+	}
+	driftedObjects[obj] = true
+	driftedObjectsMetric.WithLabelValues(obj.Kind, obj.Namespace, obj.Name).Set(1)
+	klog.V(2).Infof("%s differs from its expected state", obj)
+	recorder.Warningf("ObjectDrifted", "%s differs from its expected state, it is not reverted in audit-only mode", obj)
+}
+
+// ReportObjectDrift reports drift of an object that a controller would
+// apply, for controllers that apply objects themselves in audit-only mode.
+// existing is nil when the object does not exist.
+func ReportObjectDrift(recorder events.Recorder, kind string, required, existing runtime.Object) error {
+	accessor, err := meta.Accessor(required)
+	if err != nil {
+		return err
+	}
+	obj := DriftedObject{Kind: kind, Namespace: accessor.GetNamespace(), Name: accessor.GetName()}
+	if existing == nil {
+		ReportDrift(recorder, obj, true)
+		return nil
+	}
+	requiredContent, err := toUnstructured(required)
+	if err != nil {
+		return err
+	}
+	existingContent, err := toUnstructured(existing)
+	if err != nil {
+		return err
+	}
+	drifted, err := isDrifted(requiredContent, existingContent)
+	if err != nil {
+		return err
+	}
+	ReportDrift(recorder, obj, drifted)
+	return nil
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return &unstructured.Unstructured{Object: u.UnstructuredContent()}, nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// GetDriftedObjects returns all objects reported as drifted, sorted.
+func GetDriftedObjects() []DriftedObject {
+	driftedObjectsLock.Lock()
+	defer driftedObjectsLock.Unlock()
+
+	objs := make([]DriftedObject, 0, len(driftedObjects))
+	for obj := range driftedObjects {
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].String() < objs[j].String()
+	})
+	return objs
+}
+
+// ResetDrift forgets all drifted objects, e.g. when audit-only mode is
+// disabled and the objects are reverted.
+func ResetDrift() {
+	driftedObjectsLock.Lock()
+	defer driftedObjectsLock.Unlock()
+
+	driftedObjects = map[DriftedObject]bool{}
+	driftedObjectsMetric.Reset()
+}
+
+// AuditedAssetFunc returns AssetFunc for static resource controllers that
+// supports audit-only mode, see IsAuditOnly. In audit-only mode, each
+// object returned by assetFunc is compared with the object in the API
+// server, drift is reported and the existing object is returned instead,
+// so the static resource controller does not revert the drift. Missing
+// objects are not created in audit-only mode, an error is returned
+// instead.
+func AuditedAssetFunc(assetFunc resourceapply.AssetFunc, operatorClient v1helpers.OperatorClient, dynamicClient dynamic.Interface, restMapper meta.RESTMapper, recorder events.Recorder) resourceapply.AssetFunc {
+	return func(name string) ([]byte, error) {
+		opSpec, _, _, err := operatorClient.GetOperatorState()
+		if err != nil {
+			return nil, err
+		}
+		auditOnly, err := IsAuditOnly(opSpec)
+		if err != nil {
+			return nil, err
+		}
+		if !auditOnly {
+			return assetFunc(name)
+		}
+
+		required, err := ReadUnstructuredAsset(assetFunc, name)
+		if err != nil {
+			return nil, err
+		}
+		gvk := required.GroupVersionKind()
+		mapping, err := restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to map asset %s: %w", name, err)
+		}
+		obj := DriftedObject{Kind: gvk.Kind, Namespace: required.GetNamespace(), Name: required.GetName()}
+		existing, err := dynamicClient.Resource(mapping.Resource).Namespace(required.GetNamespace()).Get(context.TODO(), required.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				ReportDrift(recorder, obj, true)
+				return nil, fmt.Errorf("%s is missing, it is not created in audit-only mode", obj)
+			}
+			return nil, err
+		}
+		drifted, err := isDrifted(required, existing)
+		if err != nil {
+			return nil, err
+		}
+		ReportDrift(recorder, obj, drifted)
+		return existing.MarshalJSON()
+	}
+}
+
+// isDrifted returns true when the existing object does not contain all
+// labels, annotations and fields of the required one. Fields defaulted by
+// the API server are not drift.
+func isDrifted(required, existing *unstructured.Unstructured) (bool, error) {
+	for k, v := range required.GetLabels() {
+		if existing.GetLabels()[k] != v {
+			return true, nil
+		}
+	}
+	for k, v := range required.GetAnnotations() {
+		if existing.GetAnnotations()[k] != v {
+			return true, nil
+		}
+	}
+	for field, value := range required.Object {
+		switch field {
+		case "apiVersion", "kind", "metadata", "status":
+			continue
+		}
+		existingValue, found := existing.Object[field]
+		if !found {
+			return true, nil
+		}
+		contained, err := isContained(value, existingValue)
+		if err != nil {
+			return false, err
+		}
+		if !contained {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isContained returns true when all map keys and list items of required
+// are present with the same values in existing.
+func isContained(required, existing interface{}) (bool, error) {
+	// Normalize numbers, YAML assets and API objects decode them differently.
+	var err error
+	if required, err = normalizeJSON(required); err != nil {
+		return false, err
+	}
+	if existing, err = normalizeJSON(existing); err != nil {
+		return false, err
+	}
+	return contains(required, existing), nil
+}
+
+func normalizeJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func contains(required, existing interface{}) bool {
+	switch r := required.(type) {
+	case map[string]interface{}:
+		e, ok := existing.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range r {
+			if !contains(v, e[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		e, ok := existing.([]interface{})
+		if !ok || len(r) != len(e) {
+			return false
+		}
+		for i := range r {
+			if !contains(r[i], e[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(required, existing)
+	}
+}
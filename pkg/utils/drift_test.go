@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newUnstructured(labels map[string]string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+	}}
+	for k, v := range fields {
+		obj.Object[k] = v
+	}
+	obj.SetName("test")
+	obj.SetLabels(labels)
+	return obj
+}
+
+func TestIsDrifted(t *testing.T) {
+	tests := []struct {
+		name     string
+		required *unstructured.Unstructured
+		existing *unstructured.Unstructured
+		expected bool
+	}{
+		{
+			name:     "equal",
+			required: newUnstructured(map[string]string{"a": "b"}, map[string]interface{}{"data": map[string]interface{}{"foo": "bar"}}),
+			existing: newUnstructured(map[string]string{"a": "b"}, map[string]interface{}{"data": map[string]interface{}{"foo": "bar"}}),
+			expected: false,
+		},
+		{
+			name:     "extra labels and fields of existing are not drift",
+			required: newUnstructured(map[string]string{"a": "b"}, map[string]interface{}{"data": map[string]interface{}{"foo": "bar"}}),
+			existing: newUnstructured(map[string]string{"a": "b", "c": "d"}, map[string]interface{}{
+				"data":   map[string]interface{}{"foo": "bar", "defaulted": "value"},
+				"status": map[string]interface{}{"phase": "Active"},
+			}),
+			expected: false,
+		},
+		{
+			name:     "missing label",
+			required: newUnstructured(map[string]string{"a": "b"}, nil),
+			existing: newUnstructured(nil, nil),
+			expected: true,
+		},
+		{
+			name:     "changed label",
+			required: newUnstructured(map[string]string{"a": "b"}, nil),
+			existing: newUnstructured(map[string]string{"a": "c"}, nil),
+			expected: true,
+		},
+		{
+			name:     "missing field",
+			required: newUnstructured(nil, map[string]interface{}{"data": map[string]interface{}{"foo": "bar"}}),
+			existing: newUnstructured(nil, nil),
+			expected: true,
+		},
+		{
+			name:     "changed nested field",
+			required: newUnstructured(nil, map[string]interface{}{"data": map[string]interface{}{"foo": "bar"}}),
+			existing: newUnstructured(nil, map[string]interface{}{"data": map[string]interface{}{"foo": "baz"}}),
+			expected: true,
+		},
+		{
+			name:     "numbers of different types",
+			required: newUnstructured(nil, map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}}),
+			existing: newUnstructured(nil, map[string]interface{}{"spec": map[string]interface{}{"replicas": float64(2)}}),
+			expected: false,
+		},
+		{
+			name:     "status is ignored",
+			required: newUnstructured(nil, map[string]interface{}{"status": map[string]interface{}{"phase": "Active"}}),
+			existing: newUnstructured(nil, nil),
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			drifted, err := isDrifted(test.required, test.existing)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if drifted != test.expected {
+				t.Errorf("expected drifted=%t, got %t", test.expected, drifted)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		required interface{}
+		existing interface{}
+		expected bool
+	}{
+		{
+			name:     "equal scalars",
+			required: "a",
+			existing: "a",
+			expected: true,
+		},
+		{
+			name:     "different scalars",
+			required: "a",
+			existing: "b",
+			expected: false,
+		},
+		{
+			name:     "map subset",
+			required: map[string]interface{}{"a": "b"},
+			existing: map[string]interface{}{"a": "b", "c": "d"},
+			expected: true,
+		},
+		{
+			name:     "map missing key",
+			required: map[string]interface{}{"a": "b", "c": "d"},
+			existing: map[string]interface{}{"a": "b"},
+			expected: false,
+		},
+		{
+			name:     "map vs scalar",
+			required: map[string]interface{}{"a": "b"},
+			existing: "a",
+			expected: false,
+		},
+		{
+			name:     "list items with defaulted fields",
+			required: []interface{}{map[string]interface{}{"name": "a"}},
+			existing: []interface{}{map[string]interface{}{"name": "a", "protocol": "TCP"}},
+			expected: true,
+		},
+		{
+			name:     "list with extra item",
+			required: []interface{}{"a"},
+			existing: []interface{}{"a", "b"},
+			expected: false,
+		},
+		{
+			name:     "list in different order",
+			required: []interface{}{"a", "b"},
+			existing: []interface{}{"b", "a"},
+			expected: false,
+		},
+		{
+			name:     "nil required",
+			required: nil,
+			existing: nil,
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := contains(test.required, test.existing); result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestReportObjectDrift(t *testing.T) {
+	defer ResetDrift()
+	recorder := events.NewInMemoryRecorder("test")
+	newStorageClass := func(provisioner string) *storagev1.StorageClass {
+		return &storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "standard"},
+			Provisioner: provisioner,
+		}
+	}
+	expected := []DriftedObject{{Kind: "StorageClass", Name: "standard"}}
+
+	if err := ReportObjectDrift(recorder, "StorageClass", newStorageClass("a"), nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if drifted := GetDriftedObjects(); !reflect.DeepEqual(drifted, expected) {
+		t.Errorf("expected missing object %v to be drifted, got %v", expected, drifted)
+	}
+
+	if err := ReportObjectDrift(recorder, "StorageClass", newStorageClass("a"), newStorageClass("a")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if drifted := GetDriftedObjects(); len(drifted) != 0 {
+		t.Errorf("expected no drifted objects, got %v", drifted)
+	}
+
+	if err := ReportObjectDrift(recorder, "StorageClass", newStorageClass("a"), newStorageClass("b")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if drifted := GetDriftedObjects(); !reflect.DeepEqual(drifted, expected) {
+		t.Errorf("expected changed object %v to be drifted, got %v", expected, drifted)
+	}
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+)
+
+// Driver configuration with customer managed encryption keys (CMK) of
+// StorageClasses.
+//
+// The operator API does not have a typed field for driver configuration yet,
+// therefore it's read from spec.unsupportedConfigOverrides of the Storage CR
+// (in-tree StorageClasses) or ClusterCSIDriver (StorageClasses of the CSI
+// driver):
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    driverConfig:
+//	      aws:
+//	        kmsKeyARN: arn:aws:kms:us-east-1:123456789012:key/abcd...
+//	      azure:
+//	        diskEncryptionSet:
+//	          subscriptionID: 00000000-0000-0000-0000-000000000000
+//	          resourceGroup: my-rg
+//	          name: my-des
+//	      gcp:
+//	        kmsKey:
+//	          projectID: my-project
+//	          location: global
+//	          keyRing: my-ring
+//	          name: my-key
+//	      ibmcloud:
+//	        keyProtect:
+//	          crn: crn:v1:bluemix:public:kms:us-south:a/1234...:5678...:key:abcd...
+//
+// The structure mirrors ClusterCSIDriver spec.driverConfig, so it can be
+// moved there once the API is available. Only the section of the current
+// platform is used, the others are ignored.
+
+const (
+	awsKMSKeyParameter        = "kmsKeyId"
+	azureDiskEncryptionSetKey = "diskEncryptionSetID"
+	gcpKMSKeyParameter        = "disk-encryption-kms-key"
+	ibmEncryptedParameter     = "encrypted"
+	ibmEncryptionKeyParameter = "encryptionKey"
+
+	gcpDefaultKeyLocation = "global"
+)
+
+var (
+	awsKMSKeyARNRegexp     = regexp.MustCompile(`^arn:(aws|aws-cn|aws-us-gov|aws-iso|aws-iso-b):kms:[a-z0-9-]+:[0-9]{12}:(key|alias)/.+$`)
+	azureSubscriptionRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	azureGroupRegexp       = regexp.MustCompile(`^[\w.\-()]{0,89}[\w\-()]$`)
+	azureNameRegexp        = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,80}$`)
+	gcpProjectIDRegexp     = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+	gcpKeyNameRegexp       = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,63}$`)
+	gcpLocationRegexp      = regexp.MustCompile(`^[a-z0-9-]+$`)
+	ibmKeyCRNRegexp        = regexp.MustCompile(`^crn:v1:[a-z]+:[a-z-]+:(kms|hs-crypto):[a-z0-9-]+:a/[0-9a-f]+:[0-9a-f-]+:key:[0-9a-f-]+$`)
+)
+
+// DriverConfig is the driverConfig in unsupportedConfigOverrides.
+type DriverConfig struct {
+	AWS      *AWSDriverConfig      `json:"aws,omitempty"`
+	Azure    *AzureDriverConfig    `json:"azure,omitempty"`
+	GCP      *GCPDriverConfig      `json:"gcp,omitempty"`
+	IBMCloud *IBMCloudDriverConfig `json:"ibmcloud,omitempty"`
+}
+
+type AWSDriverConfig struct {
+	KMSKeyARN string `json:"kmsKeyARN,omitempty"`
+}
+
+type AzureDriverConfig struct {
+	DiskEncryptionSet *AzureDiskEncryptionSet `json:"diskEncryptionSet,omitempty"`
+}
+
+type AzureDiskEncryptionSet struct {
+	SubscriptionID string `json:"subscriptionID"`
+	ResourceGroup  string `json:"resourceGroup"`
+	Name           string `json:"name"`
+}
+
+type GCPDriverConfig struct {
+	KMSKey *GCPKMSKey `json:"kmsKey,omitempty"`
+}
+
+type GCPKMSKey struct {
+	ProjectID string `json:"projectID"`
+	Location  string `json:"location,omitempty"`
+	KeyRing   string `json:"keyRing"`
+	Name      string `json:"name"`
+}
+
+type IBMCloudDriverConfig struct {
+	KeyProtect *IBMKeyProtect `json:"keyProtect,omitempty"`
+}
+
+// IBMKeyProtect is a root key in IBM Key Protect or Hyper Protect Crypto
+// Services.
+type IBMKeyProtect struct {
+	CRN string `json:"crn"`
+}
+
+// GetDriverConfig parses driverConfig from the operator unsupportedConfigOverrides.
+// It returns nil when there is no driverConfig.
+func GetDriverConfig(opSpec *operatorapi.OperatorSpec) (*DriverConfig, error) {
+	cfg := &DriverConfig{}
+	found, err := GetUnsupportedConfigOverride(opSpec, "driverConfig", cfg)
+	if err != nil || !found {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// GetEncryptionParameters returns StorageClass parameters that enable
+// encryption with a customer managed key on the given platform. It returns
+// an error when the key configuration is invalid.
+func GetEncryptionParameters(platform configv1.PlatformType, cfg *DriverConfig) (map[string]string, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	switch platform {
+	case configv1.AWSPlatformType:
+		if cfg.AWS == nil || cfg.AWS.KMSKeyARN == "" {
+			return nil, nil
+		}
+		if !awsKMSKeyARNRegexp.MatchString(cfg.AWS.KMSKeyARN) {
+			return nil, fmt.Errorf("driverConfig.aws.kmsKeyARN %q is not a valid KMS key ARN", cfg.AWS.KMSKeyARN)
+		}
+		return map[string]string{awsKMSKeyParameter: cfg.AWS.KMSKeyARN}, nil
+
+	case configv1.AzurePlatformType:
+		if cfg.Azure == nil || cfg.Azure.DiskEncryptionSet == nil {
+			return nil, nil
+		}
+		des := cfg.Azure.DiskEncryptionSet
+		if !azureSubscriptionRegex.MatchString(des.SubscriptionID) {
+			return nil, fmt.Errorf("driverConfig.azure.diskEncryptionSet.subscriptionID %q is not a valid subscription ID", des.SubscriptionID)
+		}
+		if !azureGroupRegexp.MatchString(des.ResourceGroup) {
+			return nil, fmt.Errorf("driverConfig.azure.diskEncryptionSet.resourceGroup %q is not a valid resource group name", des.ResourceGroup)
+		}
+		if !azureNameRegexp.MatchString(des.Name) {
+			return nil, fmt.Errorf("driverConfig.azure.diskEncryptionSet.name %q is not a valid disk encryption set name", des.Name)
+		}
+		id := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/diskEncryptionSets/%s", des.SubscriptionID, des.ResourceGroup, des.Name)
+		return map[string]string{azureDiskEncryptionSetKey: id}, nil
+
+	case configv1.GCPPlatformType:
+		if cfg.GCP == nil || cfg.GCP.KMSKey == nil {
+			return nil, nil
+		}
+		key := cfg.GCP.KMSKey
+		location := key.Location
+		if location == "" {
+			location = gcpDefaultKeyLocation
+		}
+		if !gcpProjectIDRegexp.MatchString(key.ProjectID) {
+			return nil, fmt.Errorf("driverConfig.gcp.kmsKey.projectID %q is not a valid project ID", key.ProjectID)
+		}
+		if !gcpLocationRegexp.MatchString(location) {
+			return nil, fmt.Errorf("driverConfig.gcp.kmsKey.location %q is not a valid location", location)
+		}
+		if !gcpKeyNameRegexp.MatchString(key.KeyRing) {
+			return nil, fmt.Errorf("driverConfig.gcp.kmsKey.keyRing %q is not a valid key ring name", key.KeyRing)
+		}
+		if !gcpKeyNameRegexp.MatchString(key.Name) {
+			return nil, fmt.Errorf("driverConfig.gcp.kmsKey.name %q is not a valid key name", key.Name)
+		}
+		id := fmt.Sprintf("projects/%s/locations/%s/keyRings/%s/cryptoKeys/%s", key.ProjectID, location, key.KeyRing, key.Name)
+		return map[string]string{gcpKMSKeyParameter: id}, nil
+
+	case configv1.IBMCloudPlatformType:
+		if cfg.IBMCloud == nil || cfg.IBMCloud.KeyProtect == nil {
+			return nil, nil
+		}
+		crn := cfg.IBMCloud.KeyProtect.CRN
+		if !ibmKeyCRNRegexp.MatchString(crn) {
+			return nil, fmt.Errorf("driverConfig.ibmcloud.keyProtect.crn %q is not a valid root key CRN", crn)
+		}
+		return map[string]string{ibmEncryptedParameter: "true", ibmEncryptionKeyParameter: crn}, nil
+	}
+	return nil, nil
+}
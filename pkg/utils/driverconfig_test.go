@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGetEncryptionParameters(t *testing.T) {
+	tests := []struct {
+		name           string
+		platform       configv1.PlatformType
+		overrides      string
+		expectedParams map[string]string
+		expectErr      bool
+	}{
+		{
+			name:     "no overrides",
+			platform: configv1.AWSPlatformType,
+		},
+		{
+			name:           "AWS KMS key",
+			platform:       configv1.AWSPlatformType,
+			overrides:      `{"driverConfig": {"aws": {"kmsKeyARN": "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"}}}`,
+			expectedParams: map[string]string{awsKMSKeyParameter: "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"},
+		},
+		{
+			name:      "invalid AWS KMS key",
+			platform:  configv1.AWSPlatformType,
+			overrides: `{"driverConfig": {"aws": {"kmsKeyARN": "my-key"}}}`,
+			expectErr: true,
+		},
+		{
+			name:           "Azure disk encryption set",
+			platform:       configv1.AzurePlatformType,
+			overrides:      `{"driverConfig": {"azure": {"diskEncryptionSet": {"subscriptionID": "00000000-0000-0000-0000-000000000000", "resourceGroup": "my-rg", "name": "my-des"}}}}`,
+			expectedParams: map[string]string{azureDiskEncryptionSetKey: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/my-rg/providers/Microsoft.Compute/diskEncryptionSets/my-des"},
+		},
+		{
+			name:      "invalid Azure subscription",
+			platform:  configv1.AzurePlatformType,
+			overrides: `{"driverConfig": {"azure": {"diskEncryptionSet": {"subscriptionID": "foo", "resourceGroup": "my-rg", "name": "my-des"}}}}`,
+			expectErr: true,
+		},
+		{
+			name:           "GCP KMS key with default location",
+			platform:       configv1.GCPPlatformType,
+			overrides:      `{"driverConfig": {"gcp": {"kmsKey": {"projectID": "my-project", "keyRing": "my-ring", "name": "my-key"}}}}`,
+			expectedParams: map[string]string{gcpKMSKeyParameter: "projects/my-project/locations/global/keyRings/my-ring/cryptoKeys/my-key"},
+		},
+		{
+			name:           "IBM Key Protect root key",
+			platform:       configv1.IBMCloudPlatformType,
+			overrides:      `{"driverConfig": {"ibmcloud": {"keyProtect": {"crn": "crn:v1:bluemix:public:kms:us-south:a/0123456789abcdef:12345678-1234-1234-1234-123456789012:key:abcdef01-2345-6789-abcd-ef0123456789"}}}}`,
+			expectedParams: map[string]string{ibmEncryptedParameter: "true", ibmEncryptionKeyParameter: "crn:v1:bluemix:public:kms:us-south:a/0123456789abcdef:12345678-1234-1234-1234-123456789012:key:abcdef01-2345-6789-abcd-ef0123456789"},
+		},
+		{
+			name:      "invalid IBM key CRN",
+			platform:  configv1.IBMCloudPlatformType,
+			overrides: `{"driverConfig": {"ibmcloud": {"keyProtect": {"crn": "my-key"}}}}`,
+			expectErr: true,
+		},
+		{
+			name:      "config of another platform is ignored",
+			platform:  configv1.GCPPlatformType,
+			overrides: `{"driverConfig": {"aws": {"kmsKeyARN": "my-key"}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opSpec := &operatorapi.OperatorSpec{
+				UnsupportedConfigOverrides: runtime.RawExtension{Raw: []byte(test.overrides)},
+			}
+			cfg, err := GetDriverConfig(opSpec)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			params, err := GetEncryptionParameters(test.platform, cfg)
+			if err != nil && !test.expectErr {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if err == nil && test.expectErr {
+				t.Errorf("expected error, got none")
+			}
+			if !cmp.Equal(params, test.expectedParams) {
+				t.Errorf("unexpected parameters: %s", cmp.Diff(test.expectedParams, params))
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Resources whose fields are not known to the typed API vendored in CSO.
+// They're read from listers of csoclients.Clients.DynamicInformers.
+var (
+	FeatureGateResource = schema.GroupVersionResource{
+		Group:    configv1.GroupName,
+		Version:  "v1",
+		Resource: "featuregates",
+	}
+	ClusterVersionResource = schema.GroupVersionResource{
+		Group:    configv1.GroupName,
+		Version:  "v1",
+		Resource: "clusterversions",
+	}
+	InfrastructureResource = schema.GroupVersionResource{
+		Group:    configv1.GroupName,
+		Version:  "v1",
+		Resource: "infrastructures",
+	}
+)
+
+// getUnstructured returns object with given name from a lister of a dynamic
+// informer.
+func getUnstructured(lister cache.GenericLister, name string) (*unstructured.Unstructured, error) {
+	obj, err := lister.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T of %s", obj, name)
+	}
+	return u, nil
+}
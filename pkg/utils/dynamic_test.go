@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newGenericLister(t *testing.T, objs ...*unstructured.Unstructured) cache.GenericLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, obj := range objs {
+		if err := indexer.Add(obj); err != nil {
+			t.Fatalf("failed to add object: %s", err)
+		}
+	}
+	return cache.NewGenericLister(indexer, FeatureGateResource.GroupResource())
+}
+
+func TestGetFeatureGateWithStatus(t *testing.T) {
+	fg := &configv1.FeatureGate{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+	status := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"featureGates": []interface{}{
+				map[string]interface{}{
+					"version":  "4.14.0",
+					"enabled":  []interface{}{map[string]interface{}{"name": "Old"}},
+					"disabled": []interface{}{},
+				},
+				map[string]interface{}{
+					"version":  "4.15.0",
+					"enabled":  []interface{}{map[string]interface{}{"name": "New"}},
+					"disabled": []interface{}{map[string]interface{}{"name": "Off"}},
+				},
+			},
+		},
+	}}
+	status.SetName("cluster")
+	lister := newGenericLister(t, status)
+
+	result, err := GetFeatureGateWithStatus(lister, fg, "4.15.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !FeatureGateEnabled(result, "New") || FeatureGateEnabled(result, "Old") {
+		t.Errorf("expected only features of version 4.15.0 enabled, got %v", GetEnabledFeatures(result))
+	}
+	if !reflect.DeepEqual(result.Spec.CustomNoUpgrade.Disabled, []string{"Off"}) {
+		t.Errorf("expected disabled feature Off, got %v", result.Spec.CustomNoUpgrade.Disabled)
+	}
+
+	result, err = GetFeatureGateWithStatus(lister, fg, "4.16.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result != fg {
+		t.Errorf("expected unchanged FeatureGate for unknown version, got %+v", result)
+	}
+}
+
+func TestGetCapabilities(t *testing.T) {
+	cv := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"enabledCapabilities": []interface{}{CapabilityStorage},
+			},
+		},
+	}}
+	cv.SetName(clusterVersionName)
+
+	capabilities, err := GetCapabilities(newGenericLister(t, cv))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !capabilities.Enabled(CapabilityStorage) || capabilities.Enabled(CapabilityCSISnapshot) {
+		t.Errorf("expected only %s enabled, got %+v", CapabilityStorage, capabilities)
+	}
+
+	// Without ClusterVersion all capabilities are enabled.
+	capabilities, err = GetCapabilities(newGenericLister(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !capabilities.Enabled(CapabilityCSISnapshot) {
+		t.Errorf("expected all capabilities enabled without ClusterVersion")
+	}
+}
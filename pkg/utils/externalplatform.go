@@ -0,0 +1,27 @@
+package utils
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+// GetExternalPlatformName returns Infrastructure
+// spec.platformSpec.external.platformName, i.e. name of the third-party
+// cloud provider of a cluster with External platform. The typed API vendored
+// in CSO does not know the field, Infrastructure is read from a lister of
+// InfrastructureResource.
+func GetExternalPlatformName(lister cache.GenericLister) (string, error) {
+	obj, err := getUnstructured(lister, infrastructureName)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	name, _, err := unstructured.NestedString(obj.Object, "spec", "platformSpec", "external", "platformName")
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
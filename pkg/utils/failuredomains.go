@@ -0,0 +1,37 @@
+package utils
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+// GetFailureDomainZones returns zones of vSphere failure domains from
+// Infrastructure spec.platformSpec.vsphere.failureDomains. It's empty on
+// other platforms and in vSphere clusters without zones. The typed API
+// vendored in CSO does not know the field, Infrastructure is read from a
+// lister of InfrastructureResource.
+func GetFailureDomainZones(lister cache.GenericLister) ([]string, error) {
+	obj, err := getUnstructured(lister, infrastructureName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	failureDomains, _, err := unstructured.NestedSlice(obj.Object, "spec", "platformSpec", "vsphere", "failureDomains")
+	if err != nil {
+		return nil, err
+	}
+	var zones []string
+	for _, item := range failureDomains {
+		failureDomain, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if zone, _, _ := unstructured.NestedString(failureDomain, "zone"); zone != "" {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
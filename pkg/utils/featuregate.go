@@ -0,0 +1,83 @@
+package utils
+
+import (
+	configv1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+// GetEnabledFeatures returns list of enabled feature gates from FeatureGate CR.
+func GetEnabledFeatures(fg *configv1.FeatureGate) []string {
+	if fg.Spec.FeatureSet == "" {
+		return nil
+	}
+	if fg.Spec.FeatureSet == configv1.CustomNoUpgrade {
+		return fg.Spec.CustomNoUpgrade.Enabled
+	}
+	gates := configv1.FeatureSets[fg.Spec.FeatureSet]
+	if gates == nil {
+		return nil
+	}
+	return gates.Enabled
+}
+
+// FeatureGateEnabled returns true if a given feature is enabled in FeatureGate CR.
+func FeatureGateEnabled(fg *configv1.FeatureGate, feature string) bool {
+	enabledFeatures := GetEnabledFeatures(fg)
+	for _, f := range enabledFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFeatureGateWithStatus returns FeatureGate CR with features that are
+// enabled and disabled in its status for the given release version, as
+// rendered by the cluster config operator from the payload. Mapping of
+// FeatureSets to features in the API vendored in CSO may not match the
+// payload. The features are returned as a CustomNoUpgrade FeatureSet, so
+// GetEnabledFeatures and FeatureGateEnabled use them. The FeatureGate CR is
+// returned unchanged when its status does not list the version, e.g. during
+// installation. The typed API vendored in CSO does not know the status
+// fields, the status is read from a lister of FeatureGateResource.
+func GetFeatureGateWithStatus(lister cache.GenericLister, fg *configv1.FeatureGate, version string) (*configv1.FeatureGate, error) {
+	obj, err := getUnstructured(lister, fg.Name)
+	if err != nil {
+		return nil, err
+	}
+	details, found, err := unstructured.NestedSlice(obj.Object, "status", "featureGates")
+	if err != nil || !found {
+		return fg, err
+	}
+	for _, d := range details {
+		detail, ok := d.(map[string]interface{})
+		if !ok || detail["version"] != version {
+			continue
+		}
+		fgCopy := fg.DeepCopy()
+		fgCopy.Spec.FeatureSet = configv1.CustomNoUpgrade
+		fgCopy.Spec.CustomNoUpgrade = &configv1.CustomFeatureGates{
+			Enabled:  featureNames(detail["enabled"]),
+			Disabled: featureNames(detail["disabled"]),
+		}
+		return fgCopy, nil
+	}
+	return fg, nil
+}
+
+// featureNames returns names of features in status of FeatureGate CR.
+func featureNames(attributes interface{}) []string {
+	list, _ := attributes.([]interface{})
+	var names []string
+	for _, a := range list {
+		attribute, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := attribute["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"os"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	fipsEnabledPath = "/proc/sys/crypto/fips_enabled"
+
+	// Environment variable that switches Go binaries built with the RHEL Go
+	// toolchain to FIPS validated crypto.
+	golangFIPSEnv = "GOLANG_FIPS"
+)
+
+// IsFIPSEnabled returns true when the kernel runs in FIPS mode. All nodes of
+// a FIPS cluster run in FIPS mode, including the one with CSO.
+func IsFIPSEnabled() (bool, error) {
+	data, err := os.ReadFile(fipsEnabledPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(data)) == "1", nil
+}
+
+// InjectFIPSEnv returns a copy of the Deployment with all its containers
+// forced to use FIPS validated crypto.
+func InjectFIPSEnv(deployment *appsv1.Deployment) *appsv1.Deployment {
+	return InjectEnv(deployment, golangFIPSEnv, "1")
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// Prefix of pod template annotations with hashes of objects that the pods
+	// depend on. Any change of the hash rolls out the pods.
+	dependencyHashAnnotationPrefix = "operator.openshift.io/dep-"
+	// Length of the object hash in names of dependency annotations. Names of
+	// the objects do not fit into the 63 characters of an annotation name.
+	dependencyKeyLength = 16
+
+	// ConfigHashAnnotation is annotation of Deployments and their pod
+	// templates with hash of the whole rendered pod configuration.
+	ConfigHashAnnotation = "operator.openshift.io/config-hash"
+)
+
+// InjectDependencyHashes returns a copy of the Deployment with pod template
+// annotated with hashes of content of given Secrets and ConfigMaps, so the
+// Deployment is rolled out when any of them changes.
+func InjectDependencyHashes(deployment *appsv1.Deployment, secrets []*corev1.Secret, configMaps []*corev1.ConfigMap) (*appsv1.Deployment, error) {
+	deploymentCopy := deployment.DeepCopy()
+	annotations := deploymentCopy.Spec.Template.Annotations
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for _, secret := range secrets {
+		hash, err := hashObject(secret.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute hash of Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		annotations[dependencyAnnotationName(secret.Namespace, secret.Name, "secret")] = hash
+	}
+	for _, cm := range configMaps {
+		hash, err := hashObject([]interface{}{cm.Data, cm.BinaryData})
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute hash of ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+		annotations[dependencyAnnotationName(cm.Namespace, cm.Name, "configmap")] = hash
+	}
+	deploymentCopy.Spec.Template.Annotations = annotations
+	return deploymentCopy, nil
+}
+
+// InjectConfigHash returns a copy of the Deployment with the Deployment and
+// its pod template annotated with hash of the rendered pod configuration,
+// i.e. images, env. vars, volumes and all other pod template annotations,
+// including hashes of dependencies added by InjectDependencyHashes. It must
+// be called as the last step before the Deployment is applied.
+// The hash changes if and only if the rendered configuration changes, so
+// pods are rolled out exactly when their configuration changes and it's
+// possible to check which configuration a running pod uses.
+func InjectConfigHash(deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	deploymentCopy := deployment.DeepCopy()
+	annotations := map[string]string{}
+	for k, v := range deploymentCopy.Spec.Template.Annotations {
+		if k != ConfigHashAnnotation {
+			annotations[k] = v
+		}
+	}
+	hash, err := hashObject([]interface{}{annotations, deploymentCopy.Spec.Template.Spec})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute config hash of Deployment %s/%s: %w", deployment.Namespace, deployment.Name, err)
+	}
+
+	annotations[ConfigHashAnnotation] = hash
+	deploymentCopy.Spec.Template.Annotations = annotations
+	if deploymentCopy.Annotations == nil {
+		deploymentCopy.Annotations = map[string]string{}
+	}
+	deploymentCopy.Annotations[ConfigHashAnnotation] = hash
+	return deploymentCopy, nil
+}
+
+// dependencyAnnotationName returns name of the annotation with hash of the
+// object, operator.openshift.io/dep-<hash of the object kind, namespace and
+// name>.
+func dependencyAnnotationName(namespace, name, kind string) string {
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(kind+"/"+namespace+"/"+name)))
+	return dependencyHashAnnotationPrefix + key[:dependencyKeyLength]
+}
+
+func hashObject(obj interface{}) (string, error) {
+	// json.Marshal sorts map keys, the result is stable.
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
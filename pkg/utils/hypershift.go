@@ -0,0 +1,44 @@
+package utils
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// GuestKubeconfigSecretName is name of Secret in the hosted control
+	// plane namespace with kubeconfig of the guest cluster.
+	GuestKubeconfigSecretName = "service-network-admin-kubeconfig"
+	guestKubeconfigKey        = "kubeconfig"
+	guestKubeconfigVolumeName = "guest-kubeconfig"
+	guestKubeconfigDir        = "/etc/guest-kubeconfig"
+	// GuestKubeconfigEnv is env. var of CSI driver operators with path to
+	// kubeconfig of the guest cluster.
+	GuestKubeconfigEnv = "GUEST_KUBECONFIG"
+)
+
+// InjectGuestKubeconfig returns a copy of the Deployment of a CSI driver
+// operator running in a hosted control plane with kubeconfig of the guest
+// cluster mounted to all its containers and passed in GuestKubeconfigEnv.
+// Its namespace is set to the control plane namespace.
+func InjectGuestKubeconfig(deployment *appsv1.Deployment, namespace string) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	deploymentCopy.Namespace = namespace
+	podSpec := &deploymentCopy.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: guestKubeconfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: GuestKubeconfigSecretName,
+			},
+		},
+	})
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      guestKubeconfigVolumeName,
+			MountPath: guestKubeconfigDir,
+			ReadOnly:  true,
+		})
+	}
+	return InjectEnv(deploymentCopy, GuestKubeconfigEnv, guestKubeconfigDir+"/"+guestKubeconfigKey)
+}
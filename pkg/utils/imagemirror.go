@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	opv1alpha1listers "github.com/openshift/client-go/operator/listers/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Matches "image: <reference>" in YAML assets, incl. list items and quoted
+// references.
+var imageReferenceRegexp = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?image:\s*)(["']?)([^\s"'#]+)(["']?)`)
+
+// GetImageMirrors returns mirrors of the image repository configured by
+// ImageContentSourcePolicies, in the order of their priority.
+func GetImageMirrors(policies []*operatorv1alpha1.ImageContentSourcePolicy, image string) []string {
+	var mirrors []string
+	for _, policy := range policies {
+		for _, rdm := range policy.Spec.RepositoryDigestMirrors {
+			if imageMatchesSource(image, rdm.Source) {
+				mirrors = append(mirrors, rdm.Mirrors...)
+			}
+		}
+	}
+	return mirrors
+}
+
+// MirrorImageReference returns the image reference rewritten to the first
+// mirror of the most specific ImageContentSourcePolicy source that matches
+// the image. The image is returned unchanged when it's not mirrored.
+// CRI-O uses the mirrors only for images pulled by digest, while assets may
+// reference images by tag with a hardcoded registry host, therefore the
+// reference itself must point to the mirror.
+func MirrorImageReference(policies []*operatorv1alpha1.ImageContentSourcePolicy, image string) string {
+	source, mirror := "", ""
+	for _, policy := range policies {
+		for _, rdm := range policy.Spec.RepositoryDigestMirrors {
+			if len(rdm.Mirrors) == 0 || !imageMatchesSource(image, rdm.Source) {
+				continue
+			}
+			if len(rdm.Source) > len(source) {
+				source, mirror = rdm.Source, rdm.Mirrors[0]
+			}
+		}
+	}
+	if source == "" {
+		return image
+	}
+	return mirror + strings.TrimPrefix(image, source)
+}
+
+// RewriteImageReferences rewrites all image references in a YAML asset to
+// their mirrors, see MirrorImageReference. References that are not rendered
+// yet (i.e. contain ${...} or {{...}} placeholders) are left untouched.
+func RewriteImageReferences(content []byte, policies []*operatorv1alpha1.ImageContentSourcePolicy) []byte {
+	if len(policies) == 0 {
+		return content
+	}
+	return imageReferenceRegexp.ReplaceAllFunc(content, func(match []byte) []byte {
+		parts := imageReferenceRegexp.FindSubmatch(match)
+		image := string(parts[3])
+		if strings.Contains(image, "${") || strings.Contains(image, "{{") {
+			return match
+		}
+		mirrored := MirrorImageReference(policies, image)
+		return []byte(string(parts[1]) + string(parts[2]) + mirrored + string(parts[4]))
+	})
+}
+
+// MirroredAssetFunc returns AssetFunc that rewrites image references in
+// assets returned by assetFunc according to ImageContentSourcePolicies,
+// so static assets with hardcoded registry hosts work in mirrored
+// (disconnected) clusters.
+func MirroredAssetFunc(assetFunc resourceapply.AssetFunc, icspLister opv1alpha1listers.ImageContentSourcePolicyLister) resourceapply.AssetFunc {
+	return func(name string) ([]byte, error) {
+		content, err := assetFunc(name)
+		if err != nil {
+			return nil, err
+		}
+		policies, err := icspLister.List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		return RewriteImageReferences(content, policies), nil
+	}
+}
+
+func imageMatchesSource(image, source string) bool {
+	if !strings.HasPrefix(image, source) {
+		return false
+	}
+	rest := image[len(source):]
+	return rest == "" || strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "@")
+}
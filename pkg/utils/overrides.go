@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// GetUnsupportedConfigOverride parses field with given name from
+// spec.unsupportedConfigOverrides of the operator CR into the provided
+// object. It returns false when the field is not set. It is used for
+// configuration that does not have a typed API field yet.
+func GetUnsupportedConfigOverride(opSpec *operatorapi.OperatorSpec, name string, into interface{}) (bool, error) {
+	if len(opSpec.UnsupportedConfigOverrides.Raw) == 0 {
+		return false, nil
+	}
+	jsonBytes, err := yaml.ToJSON(opSpec.UnsupportedConfigOverrides.Raw)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse unsupportedConfigOverrides: %w", err)
+	}
+	overrides := map[string]json.RawMessage{}
+	if err := json.Unmarshal(jsonBytes, &overrides); err != nil {
+		return false, fmt.Errorf("failed to parse unsupportedConfigOverrides: %w", err)
+	}
+	raw, found := overrides[name]
+	if !found || string(raw) == "null" {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, into); err != nil {
+		return false, fmt.Errorf("failed to parse unsupportedConfigOverrides.%s: %w", name, err)
+	}
+	return true, nil
+}
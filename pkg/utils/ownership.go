@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OwnedByLabel is label of all objects created by CSO, so they can be
+	// found and removed on uninstall.
+	OwnedByLabel = "storage.openshift.io/owned-by"
+	// OwnedByLabelValue is value of OwnedByLabel.
+	OwnedByLabelValue = "cluster-storage-operator"
+	// ComponentLabel is label with name of CSO component that created the
+	// object. Objects of a component that CSO does not ship any longer are
+	// garbage collected.
+	ComponentLabel = "storage.openshift.io/component"
+)
+
+// OwnedBySelector is label selector of all objects created by CSO.
+var OwnedBySelector = OwnedByLabel + "=" + OwnedByLabelValue
+
+// SetOwnedByLabel labels the object as owned by the CSO component.
+func SetOwnedByLabel(obj metav1.Object, component string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[OwnedByLabel] = OwnedByLabelValue
+	labels[ComponentLabel] = component
+	obj.SetLabels(labels)
+}
+
+// OwnedAssetFunc returns AssetFunc that labels objects returned by assetFunc
+// as owned by the CSO component. The objects are returned as JSON.
+func OwnedAssetFunc(assetFunc resourceapply.AssetFunc, component string) resourceapply.AssetFunc {
+	return func(name string) ([]byte, error) {
+		obj, err := ReadUnstructuredAsset(assetFunc, name)
+		if err != nil {
+			return nil, err
+		}
+		SetOwnedByLabel(obj, component)
+		return obj.MarshalJSON()
+	}
+}
+
+// ReadUnstructuredAsset returns the named YAML asset as Unstructured.
+func ReadUnstructuredAsset(assetFunc resourceapply.AssetFunc, name string) (*unstructured.Unstructured, error) {
+	content, err := assetFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse asset %s: %w", name, err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(data); err != nil {
+		return nil, fmt.Errorf("failed to decode asset %s: %w", name, err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"fmt"
+
+	operatorapi "github.com/openshift/api/operator/v1"
+)
+
+// Kinds of objects whose reconciliation can be paused.
+const (
+	PausedKindDeployment   = "Deployment"
+	PausedKindStorageClass = "StorageClass"
+)
+
+// PausedResource is an object that CSO does not reconcile, so it can be
+// modified manually for debugging.
+type PausedResource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (r PausedResource) String() string {
+	if r.Namespace == "" {
+		return r.Kind + " " + r.Name
+	}
+	return r.Kind + " " + r.Namespace + "/" + r.Name
+}
+
+// GetPausedResources returns objects whose reconciliation is paused. The
+// operator API does not have a typed field for it, it's a break-glass
+// debugging option read from Storage CR spec.unsupportedConfigOverrides:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    pausedResources:
+//	    - kind: Deployment
+//	      namespace: openshift-cluster-csi-drivers
+//	      name: aws-ebs-csi-driver-operator
+//	    - kind: StorageClass
+//	      name: gp2-csi
+func GetPausedResources(opSpec *operatorapi.OperatorSpec) ([]PausedResource, error) {
+	var resources []PausedResource
+	if _, err := GetUnsupportedConfigOverride(opSpec, "pausedResources", &resources); err != nil {
+		return nil, err
+	}
+	for _, r := range resources {
+		if r.Kind != PausedKindDeployment && r.Kind != PausedKindStorageClass {
+			return nil, fmt.Errorf("unsupportedConfigOverrides.pausedResources: unsupported kind %q, expected %s or %s", r.Kind, PausedKindDeployment, PausedKindStorageClass)
+		}
+		if r.Name == "" {
+			return nil, fmt.Errorf("unsupportedConfigOverrides.pausedResources: missing name of %s", r.Kind)
+		}
+	}
+	return resources, nil
+}
+
+// IsResourcePaused returns true when reconciliation of the object is paused.
+func IsResourcePaused(opSpec *operatorapi.OperatorSpec, kind, namespace, name string) (bool, error) {
+	resources, err := GetPausedResources(opSpec)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources {
+		if r.Kind == kind && r.Namespace == namespace && r.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// StorageDefaultsConfigMapName is name of ConfigMap with cluster-wide
+	// values, synced by CSO to namespaces of all operands.
+	StorageDefaultsConfigMapName = "storage-defaults"
+
+	// Keys of the storage defaults ConfigMap.
+	StorageDefaultsPlatformKey               = "platform"
+	StorageDefaultsControlPlaneTopologyKey   = "controlPlaneTopology"
+	StorageDefaultsInfrastructureTopologyKey = "infrastructureTopology"
+	StorageDefaultsClusterIDKey              = "clusterID"
+	StorageDefaultsInfrastructureNameKey     = "infrastructureName"
+	StorageDefaultsHTTPProxyKey              = "httpProxy"
+	StorageDefaultsHTTPSProxyKey             = "httpsProxy"
+	StorageDefaultsNoProxyKey                = "noProxy"
+	StorageDefaultsFIPSKey                   = "fips"
+	StorageDefaultsLogLevelKey               = "logLevel"
+
+	storageDefaultsVolumeName = "storage-defaults"
+	// StorageDefaultsDir is directory where the storage defaults ConfigMap
+	// is mounted, one file per key.
+	StorageDefaultsDir = "/etc/storage-defaults"
+)
+
+// InjectStorageDefaults returns a copy of the Deployment with the storage
+// defaults ConfigMap mounted to all its containers. The ConfigMap is
+// optional, so the Deployment starts before CSO syncs it. Kubelet updates
+// the mounted files when the ConfigMap changes, the pods are not restarted.
+func InjectStorageDefaults(deployment *appsv1.Deployment) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	podSpec := &deploymentCopy.Spec.Template.Spec
+	optional := true
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: storageDefaultsVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: StorageDefaultsConfigMapName},
+				Optional:             &optional,
+			},
+		},
+	})
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      storageDefaultsVolumeName,
+			MountPath: StorageDefaultsDir,
+			ReadOnly:  true,
+		})
+	}
+	return deploymentCopy
+}
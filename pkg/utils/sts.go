@@ -0,0 +1,162 @@
+package utils
+
+import (
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	configlisters "github.com/openshift/client-go/config/listers/config/v1"
+	oplisters "github.com/openshift/client-go/operator/listers/operator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	authenticationConfigName  = "cluster"
+	cloudCredentialConfigName = "cluster"
+
+	// BoundSATokenDir is the directory where a projected ServiceAccount token
+	// is mounted in operand pods when the cluster uses short-lived tokens.
+	BoundSATokenDir = "/var/run/secrets/openshift/serviceaccount"
+	// BoundSATokenPath is path to the projected ServiceAccount token.
+	BoundSATokenPath = BoundSATokenDir + "/token"
+
+	boundSATokenVolumeName = "bound-sa-token"
+	// DefaultBoundSATokenExpiration is the default lifetime of projected
+	// ServiceAccount tokens, in seconds. Kubelet refreshes the token when 80%
+	// of its lifetime has passed.
+	DefaultBoundSATokenExpiration = 3600
+
+	// ServiceAccountIssuerAnnotation is annotation of operand pod templates
+	// with the ServiceAccount issuer of their projected tokens. Tokens of
+	// the old issuer are not accepted by the cloud after the issuer changes,
+	// the annotation rolls out the pods with new ones.
+	ServiceAccountIssuerAnnotation = "storage.openshift.io/service-account-issuer"
+)
+
+// IsShortLivedTokenMode returns true when the cluster uses short-lived cloud
+// credentials, i.e. AWS STS, Azure or GCP Workload Identity. In this mode
+// CCO runs in Manual mode and the cluster has a custom ServiceAccount issuer
+// that the cloud trusts.
+func IsShortLivedTokenMode(authLister configlisters.AuthenticationLister, cloudCredentialLister oplisters.CloudCredentialLister) (bool, error) {
+	cloudCredential, err := cloudCredentialLister.Get(cloudCredentialConfigName)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if cloudCredential.Spec.CredentialsMode != operatorapi.CloudCredentialsModeManual {
+		return false, nil
+	}
+
+	authentication, err := authLister.Get(authenticationConfigName)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return ShortLivedTokenMode(cloudCredential, authentication), nil
+}
+
+// GetServiceAccountIssuer returns the ServiceAccount issuer of the cluster,
+// or an empty string for the default one.
+func GetServiceAccountIssuer(authLister configlisters.AuthenticationLister) (string, error) {
+	authentication, err := authLister.Get(authenticationConfigName)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return authentication.Spec.ServiceAccountIssuer, nil
+}
+
+// ShortLivedTokenMode is IsShortLivedTokenMode for already fetched
+// CloudCredential and Authentication.
+func ShortLivedTokenMode(cloudCredential *operatorapi.CloudCredential, authentication *configv1.Authentication) bool {
+	return cloudCredential.Spec.CredentialsMode == operatorapi.CloudCredentialsModeManual && authentication.Spec.ServiceAccountIssuer != ""
+}
+
+// GetCloudIdentity returns cloud identity of a CSI driver set by the cluster
+// admin in ClusterCSIDriver for clusters with short-lived credentials, e.g.
+// ARN of the IAM role the driver assumes on AWS. The operator API does not
+// have a typed field for it yet, it's read from
+// spec.unsupportedConfigOverrides:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    cloudIdentity:
+//	      roleARN: arn:aws:iam::123456789012:role/my-cluster-ebs-csi-driver
+func GetCloudIdentity(opSpec *operatorapi.OperatorSpec) (map[string]string, error) {
+	identity := map[string]string{}
+	if _, err := GetUnsupportedConfigOverride(opSpec, "cloudIdentity", &identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// GetBoundSATokenAudience returns audience of projected ServiceAccount tokens
+// for given CSI driver configured by the cluster admin. The operator API does
+// not have a typed field for it yet, it's read from Storage CR
+// spec.unsupportedConfigOverrides:
+//
+//	spec:
+//	  unsupportedConfigOverrides:
+//	    boundSATokenAudiences:
+//	      pd.csi.storage.gke.io: //iam.googleapis.com/projects/.../providers/...
+//
+// Empty string is returned when the audience is not overridden.
+func GetBoundSATokenAudience(opSpec *operatorapi.OperatorSpec, csiDriverName string) (string, error) {
+	audiences := map[string]string{}
+	if _, err := GetUnsupportedConfigOverride(opSpec, "boundSATokenAudiences", &audiences); err != nil {
+		return "", err
+	}
+	return audiences[csiDriverName], nil
+}
+
+// InjectBoundSATokenVolume returns a copy of the Deployment with a projected
+// ServiceAccount token with given audience and expiration mounted to all its
+// containers.
+func InjectBoundSATokenVolume(deployment *appsv1.Deployment, audience string, expiration int64) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	podSpec := &deploymentCopy.Spec.Template.Spec
+	if expiration == 0 {
+		expiration = DefaultBoundSATokenExpiration
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: boundSATokenVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+							Audience:          audience,
+							ExpirationSeconds: &expiration,
+							Path:              "token",
+						},
+					},
+				},
+			},
+		},
+	})
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      boundSATokenVolumeName,
+			MountPath: BoundSATokenDir,
+			ReadOnly:  true,
+		})
+	}
+	return deploymentCopy
+}
+
+// InjectServiceAccountIssuer returns a copy of the Deployment with
+// ServiceAccountIssuerAnnotation of given issuer in its pod template.
+func InjectServiceAccountIssuer(deployment *appsv1.Deployment, issuer string) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	if deploymentCopy.Spec.Template.Annotations == nil {
+		deploymentCopy.Spec.Template.Annotations = map[string]string{}
+	}
+	deploymentCopy.Spec.Template.Annotations[ServiceAccountIssuerAnnotation] = issuer
+	return deploymentCopy
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorapi "github.com/openshift/api/operator/v1"
+	configlisters "github.com/openshift/client-go/config/listers/config/v1"
+	oplisters "github.com/openshift/client-go/operator/listers/operator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestIsShortLivedTokenMode(t *testing.T) {
+	tests := []struct {
+		name            string
+		credentialsMode operatorapi.CloudCredentialsMode
+		issuer          string
+		noCloudCred     bool
+		noAuth          bool
+		expected        bool
+	}{
+		{
+			name:            "Manual mode with custom issuer",
+			credentialsMode: operatorapi.CloudCredentialsModeManual,
+			issuer:          "https://oidc.example.com",
+			expected:        true,
+		},
+		{
+			name:            "Manual mode with default issuer",
+			credentialsMode: operatorapi.CloudCredentialsModeManual,
+		},
+		{
+			name:            "Mint mode with custom issuer",
+			credentialsMode: operatorapi.CloudCredentialsModeMint,
+			issuer:          "https://oidc.example.com",
+		},
+		{
+			name:        "missing CloudCredential",
+			issuer:      "https://oidc.example.com",
+			noCloudCred: true,
+		},
+		{
+			name:            "missing Authentication",
+			credentialsMode: operatorapi.CloudCredentialsModeManual,
+			noAuth:          true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if !test.noAuth {
+				authIndexer.Add(&configv1.Authentication{
+					ObjectMeta: metav1.ObjectMeta{Name: authenticationConfigName},
+					Spec:       configv1.AuthenticationSpec{ServiceAccountIssuer: test.issuer},
+				})
+			}
+			cloudCredIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if !test.noCloudCred {
+				cloudCredIndexer.Add(&operatorapi.CloudCredential{
+					ObjectMeta: metav1.ObjectMeta{Name: cloudCredentialConfigName},
+					Spec:       operatorapi.CloudCredentialSpec{CredentialsMode: test.credentialsMode},
+				})
+			}
+
+			shortLived, err := IsShortLivedTokenMode(configlisters.NewAuthenticationLister(authIndexer), oplisters.NewCloudCredentialLister(cloudCredIndexer))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if shortLived != test.expected {
+				t.Errorf("expected short-lived token mode %v, got %v", test.expected, shortLived)
+			}
+		})
+	}
+}
+
+func TestInjectBoundSATokenVolume(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "operator"}, {Name: "sidecar"}},
+				},
+			},
+		},
+	}
+
+	injected := InjectBoundSATokenVolume(deployment, "sts.amazonaws.com", 0)
+
+	if len(deployment.Spec.Template.Spec.Volumes) != 0 {
+		t.Errorf("expected the original Deployment to be unchanged")
+	}
+	volumes := injected.Spec.Template.Spec.Volumes
+	if len(volumes) != 1 || volumes[0].Projected == nil || len(volumes[0].Projected.Sources) != 1 {
+		t.Fatalf("expected one projected volume, got %+v", volumes)
+	}
+	token := volumes[0].Projected.Sources[0].ServiceAccountToken
+	if token == nil || token.Audience != "sts.amazonaws.com" || token.Path != "token" {
+		t.Fatalf("expected ServiceAccount token with audience sts.amazonaws.com, got %+v", token)
+	}
+	if *token.ExpirationSeconds != DefaultBoundSATokenExpiration {
+		t.Errorf("expected default expiration %d, got %d", DefaultBoundSATokenExpiration, *token.ExpirationSeconds)
+	}
+	for _, container := range injected.Spec.Template.Spec.Containers {
+		if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].Name != volumes[0].Name || container.VolumeMounts[0].MountPath != BoundSATokenDir {
+			t.Errorf("expected container %s to mount the token to %s, got %+v", container.Name, BoundSATokenDir, container.VolumeMounts)
+		}
+	}
+}
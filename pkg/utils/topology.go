@@ -0,0 +1,36 @@
+package utils
+
+import (
+	configv1 "github.com/openshift/api/config/v1"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const masterNodeRoleLabel = "node-role.kubernetes.io/master"
+
+// RunsOnSingleReplica returns true when the Deployment runs on nodes with
+// SingleReplica topology, e.g. on single node clusters. Deployments with
+// master node selector run on control plane nodes, the others on
+// infrastructure nodes.
+func RunsOnSingleReplica(infra *configv1.Infrastructure, deployment *appsv1.Deployment) bool {
+	if _, found := deployment.Spec.Template.Spec.NodeSelector[masterNodeRoleLabel]; found {
+		return infra.Status.ControlPlaneTopology == configv1.SingleReplicaTopologyMode
+	}
+	return infra.Status.InfrastructureTopology == configv1.SingleReplicaTopologyMode
+}
+
+// InjectSingleReplica returns a copy of the Deployment adapted to a single
+// node: with one replica, without pod anti-affinity and with Recreate
+// strategy. The second replica or a surge pod of a rolling update could
+// never be scheduled and the Deployment would be Progressing forever.
+func InjectSingleReplica(deployment *appsv1.Deployment) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	replicas := int32(1)
+	deploymentCopy.Spec.Replicas = &replicas
+	if affinity := deploymentCopy.Spec.Template.Spec.Affinity; affinity != nil {
+		affinity.PodAntiAffinity = nil
+	}
+	deploymentCopy.Spec.Strategy = appsv1.DeploymentStrategy{
+		Type: appsv1.RecreateDeploymentStrategyType,
+	}
+	return deploymentCopy
+}
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// Namespace of the cloud provider config referenced by Infrastructure.
+	cloudConfigNamespace = "openshift-config"
+
+	vSphereWorkspaceSection     = "Workspace"
+	vSphereVirtualCenterSection = "VirtualCenter"
+)
+
+// VSphereCloudConfig is the legacy vSphere cloud provider config
+// (cloud.conf), which lists vCenters and datacenters of the cluster. The
+// vendored Infrastructure API does not have vSphere failure domains yet, so
+// the config is the only source of vCenters of clusters that span several
+// vCenters or datacenters.
+type VSphereCloudConfig struct {
+	// VCenters of the cluster, the one in [Workspace] first.
+	VCenters []VSphereVCenter
+	// sections are all sections of the config in their original order.
+	sections []*iniSection
+}
+
+// VSphereVCenter is a vCenter with datacenters used by the cluster.
+type VSphereVCenter struct {
+	Server      string
+	Datacenters []string
+}
+
+type iniSection struct {
+	name       string
+	subsection string
+	keys       []string
+	values     map[string]string
+}
+
+// GetVSphereCloudConfig returns the vSphere cloud provider config referenced
+// by Infrastructure spec.cloudConfig.
+func GetVSphereCloudConfig(configMapLister corelisters.ConfigMapLister, infrastructure *configv1.Infrastructure) (*VSphereCloudConfig, error) {
+	ref := infrastructure.Spec.CloudConfig
+	if ref.Name == "" {
+		return nil, fmt.Errorf("Infrastructure %s does not reference vSphere cloud config in spec.cloudConfig", infrastructure.Name)
+	}
+	cm, err := configMapLister.ConfigMaps(cloudConfigNamespace).Get(ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	key := ref.Key
+	if key == "" {
+		key = "config"
+	}
+	data, found := cm.Data[key]
+	if !found {
+		return nil, fmt.Errorf("ConfigMap %s/%s does not contain key %s", cloudConfigNamespace, ref.Name, key)
+	}
+	return ParseVSphereCloudConfig(data)
+}
+
+// ParseVSphereCloudConfig parses vSphere cloud provider config. vCenters are
+// collected from [VirtualCenter "<server>"] sections and from [Workspace],
+// which lists the vCenter and datacenter of the cluster in older configs.
+func ParseVSphereCloudConfig(data string) (*VSphereCloudConfig, error) {
+	sections, err := parseINI(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &VSphereCloudConfig{sections: sections}
+
+	datacenters := map[string][]string{}
+	var servers []string
+	addDatacenters := func(server string, dcs ...string) {
+		if _, found := datacenters[server]; !found {
+			servers = append(servers, server)
+		}
+		for _, dc := range dcs {
+			if dc = strings.TrimSpace(dc); dc != "" && !containsString(datacenters[server], dc) {
+				datacenters[server] = append(datacenters[server], dc)
+			}
+		}
+	}
+	for _, s := range sections {
+		if s.name == vSphereWorkspaceSection && s.values["server"] != "" {
+			addDatacenters(s.values["server"], s.values["datacenter"])
+		}
+	}
+	for _, s := range sections {
+		if s.name == vSphereVirtualCenterSection {
+			if s.subsection == "" {
+				return nil, fmt.Errorf("section [%s] of vSphere cloud config does not name the vCenter server", vSphereVirtualCenterSection)
+			}
+			addDatacenters(s.subsection, strings.Split(s.values["datacenters"], ",")...)
+		}
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("vSphere cloud config does not list any vCenter")
+	}
+	for _, server := range servers {
+		if len(datacenters[server]) == 0 {
+			return nil, fmt.Errorf("vSphere cloud config does not list any datacenter of vCenter %s", server)
+		}
+		cfg.VCenters = append(cfg.VCenters, VSphereVCenter{Server: server, Datacenters: datacenters[server]})
+	}
+	return cfg, nil
+}
+
+// IsMultiZone returns true when the cluster spans several vCenters or
+// datacenters. Volumes can be provisioned only with topology then, each
+// datacenter has its own datastores.
+func (c *VSphereCloudConfig) IsMultiZone() bool {
+	return len(c.VCenters) > 1 || len(c.VCenters[0].Datacenters) > 1
+}
+
+// String returns the config with a [VirtualCenter] section for each vCenter
+// that lists all its datacenters, including the one in [Workspace]. Other
+// sections and keys are kept as they are.
+func (c *VSphereCloudConfig) String() string {
+	var b strings.Builder
+	writeSection := func(s *iniSection) {
+		if s.subsection != "" {
+			fmt.Fprintf(&b, "[%s %s]\n", s.name, strconv.Quote(s.subsection))
+		} else {
+			fmt.Fprintf(&b, "[%s]\n", s.name)
+		}
+		for _, k := range s.keys {
+			fmt.Fprintf(&b, "%s = %s\n", k, strconv.Quote(s.values[k]))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, s := range c.sections {
+		if s.name != vSphereVirtualCenterSection {
+			writeSection(s)
+		}
+	}
+	for _, vc := range c.VCenters {
+		s := &iniSection{name: vSphereVirtualCenterSection, subsection: vc.Server, values: map[string]string{}}
+		// Keep other keys of the vCenter, e.g. its port.
+		for _, orig := range c.sections {
+			if orig.name == vSphereVirtualCenterSection && orig.subsection == vc.Server {
+				for _, k := range orig.keys {
+					if !containsString(s.keys, k) {
+						s.keys = append(s.keys, k)
+					}
+					s.values[k] = orig.values[k]
+				}
+			}
+		}
+		if !containsString(s.keys, "datacenters") {
+			s.keys = append(s.keys, "datacenters")
+		}
+		s.values["datacenters"] = strings.Join(vc.Datacenters, ",")
+		writeSection(s)
+	}
+	return b.String()
+}
+
+// Servers returns sorted servers of all vCenters.
+func (c *VSphereCloudConfig) Servers() []string {
+	var servers []string
+	for _, vc := range c.VCenters {
+		servers = append(servers, vc.Server)
+	}
+	sort.Strings(servers)
+	return servers
+}
+
+// parseINI parses the gcfg-style INI format used by cloud provider configs.
+func parseINI(data string) ([]*iniSection, error) {
+	var sections []*iniSection
+	var current *iniSection
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("invalid section header on line %d: %s", lineNr, line)
+			}
+			header := strings.TrimSpace(line[1 : len(line)-1])
+			current = &iniSection{values: map[string]string{}}
+			current.name = header
+			if i := strings.IndexAny(header, " \t"); i > 0 {
+				current.name = header[:i]
+				sub, err := strconv.Unquote(strings.TrimSpace(header[i:]))
+				if err != nil {
+					return nil, fmt.Errorf("invalid section header on line %d: %s", lineNr, line)
+				}
+				current.subsection = sub
+			}
+			sections = append(sections, current)
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d is not in any section: %s", lineNr, line)
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid line %d: %s", lineNr, line)
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value on line %d: %s", lineNr, line)
+			}
+			value = unquoted
+		}
+		if _, found := current.values[key]; !found {
+			current.keys = append(current.keys, key)
+		}
+		current.values[key] = value
+	}
+	return sections, scanner.Err()
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
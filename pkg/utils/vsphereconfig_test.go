@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVSphereCloudConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           string
+		expectedVCenters []VSphereVCenter
+		expectedConfig   string
+		expectErr        bool
+	}{
+		{
+			name: "single vCenter in Workspace",
+			config: `
+[Global]
+secret-name = "vsphere-creds"
+secret-namespace = "kube-system"
+
+[Workspace]
+server = "vcenter.example.com"
+datacenter = "DC1"
+default-datastore = "ds1"
+`,
+			expectedVCenters: []VSphereVCenter{{Server: "vcenter.example.com", Datacenters: []string{"DC1"}}},
+			expectedConfig: `[Global]
+secret-name = "vsphere-creds"
+secret-namespace = "kube-system"
+
+[Workspace]
+server = "vcenter.example.com"
+datacenter = "DC1"
+default-datastore = "ds1"
+
+[VirtualCenter "vcenter.example.com"]
+datacenters = "DC1"
+
+`,
+		},
+		{
+			name: "multiple vCenters and datacenters",
+			config: `
+[Workspace]
+server = "vcenter-1.example.com"
+datacenter = "DC1"
+
+[VirtualCenter "vcenter-1.example.com"]
+port = "443"
+datacenters = "DC2"
+
+[VirtualCenter "vcenter-2.example.com"]
+datacenters = "DC3, DC4"
+`,
+			expectedVCenters: []VSphereVCenter{
+				{Server: "vcenter-1.example.com", Datacenters: []string{"DC1", "DC2"}},
+				{Server: "vcenter-2.example.com", Datacenters: []string{"DC3", "DC4"}},
+			},
+			expectedConfig: `[Workspace]
+server = "vcenter-1.example.com"
+datacenter = "DC1"
+
+[VirtualCenter "vcenter-1.example.com"]
+port = "443"
+datacenters = "DC1,DC2"
+
+[VirtualCenter "vcenter-2.example.com"]
+datacenters = "DC3,DC4"
+
+`,
+		},
+		{
+			name: "vCenter without datacenters",
+			config: `
+[VirtualCenter "vcenter.example.com"]
+port = "443"
+`,
+			expectErr: true,
+		},
+		{
+			name:      "no vCenter",
+			config:    "[Global]\n",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := ParseVSphereCloudConfig(test.config)
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(cfg.VCenters, test.expectedVCenters) {
+				t.Errorf("expected vCenters %+v, got %+v", test.expectedVCenters, cfg.VCenters)
+			}
+			if cfg.String() != test.expectedConfig {
+				t.Errorf("expected config:\n%s\ngot:\n%s", test.expectedConfig, cfg.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ControllerWorkers is number of workers of controllers, keyed by name of
+// the controller. Controllers of CSI driver operators are keyed by their
+// name without the condition prefix of the driver, e.g.
+// CSIDriverOperatorDeployment, so the number applies to all CSI drivers.
+// Controllers not listed run with a single worker.
+type ControllerWorkers map[string]int
+
+// NewControllerWorkers returns ControllerWorkers parsed from the
+// --controller-workers command line flag.
+func NewControllerWorkers(workers map[string]int) (ControllerWorkers, error) {
+	for name, count := range workers {
+		if count < 1 {
+			return nil, fmt.Errorf("invalid number of workers of controller %s: %d, it must be at least 1", name, count)
+		}
+	}
+	return ControllerWorkers(workers), nil
+}
+
+// Get returns number of workers of the named controller. prefix is the
+// condition prefix of a CSI driver or an empty string for controllers that
+// do not belong to a CSI driver.
+func (w ControllerWorkers) Get(prefix, name string) int {
+	if count, found := w[strings.TrimPrefix(name, prefix)]; found {
+		return count
+	}
+	return 1
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// WorkloadManagementAnnotation is annotation of pods that run on CPUs
+	// reserved for management workloads on clusters with CPU partitioning.
+	WorkloadManagementAnnotation = "target.workload.openshift.io/management"
+	workloadManagementValue      = `{"effect": "PreferredDuringScheduling"}`
+
+	cpuPartitioningAllNodes = "AllNodes"
+	infrastructureName      = "cluster"
+)
+
+// IsCPUPartitioningEnabled returns true when Infrastructure
+// status.cpuPartitioning is AllNodes. The typed API vendored in CSO does not
+// know the field, Infrastructure is read from a lister of
+// InfrastructureResource.
+func IsCPUPartitioningEnabled(lister cache.GenericLister) (bool, error) {
+	obj, err := getUnstructured(lister, infrastructureName)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	mode, _, err := unstructured.NestedString(obj.Object, "status", "cpuPartitioning")
+	if err != nil {
+		return false, err
+	}
+	return mode == cpuPartitioningAllNodes, nil
+}
+
+// InjectWorkloadManagementAnnotation returns a copy of the Deployment with
+// WorkloadManagementAnnotation on its pod template, so its pods are pinned
+// to the management CPU pool. Namespace of the Deployment must allow
+// management workloads.
+func InjectWorkloadManagementAnnotation(deployment *appsv1.Deployment) *appsv1.Deployment {
+	deploymentCopy := deployment.DeepCopy()
+	if deploymentCopy.Spec.Template.Annotations == nil {
+		deploymentCopy.Spec.Template.Annotations = map[string]string{}
+	}
+	deploymentCopy.Spec.Template.Annotations[WorkloadManagementAnnotation] = workloadManagementValue
+	return deploymentCopy
+}